@@ -14,9 +14,11 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
@@ -25,6 +27,10 @@ import (
 
 var lock sync.Mutex
 
+// started tracks when activations were created, to report activation durations. It's shared by all
+// managers in the process, so activations deleted by the cleanup manager are forgotten too.
+var started sync.Map
+
 var log = logger.NewLogger("coa.runtime")
 
 type ActivationsManager struct {
@@ -130,6 +136,7 @@ func (m *ActivationsManager) UpsertSpec(ctx context.Context, name string, spec m
 	if err != nil {
 		return err
 	}
+	started.Store(name, time.Now())
 	return nil
 }
 
@@ -149,6 +156,7 @@ func (m *ActivationsManager) DeleteSpec(ctx context.Context, name string) error
 			"resource": "activations",
 		},
 	})
+	started.Delete(name)
 	return err
 }
 
@@ -218,5 +226,25 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 	if err != nil {
 		return err
 	}
+	t.observeActivation(name, current)
 	return nil
 }
+
+// observeActivation reports the duration of an activation once it stops being active. Activations
+// created before this process started are measured from their first status report.
+func (t *ActivationsManager) observeActivation(name string, current model.ActivationStatus) {
+	if current.IsActive {
+		started.LoadOrStore(name, time.Now())
+		return
+	}
+	if v, ok := started.LoadAndDelete(name); ok {
+		outcome := metrics.OutcomeFailure
+		switch current.Status {
+		case v1alpha2.Done:
+			outcome = metrics.OutcomeSuccess
+		case v1alpha2.Paused:
+			outcome = metrics.OutcomePaused
+		}
+		metrics.ObserveActivation(outcome, time.Since(v.(time.Time)))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +54,28 @@ func TestCleanupOldActivationSpec(t *testing.T) {
 	_, err = manager.GetSpec(context.Background(), "test")
 	assert.NotNil(t, err)
 }
+
+func TestActivationStartTimesAreForgotten(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test-done", model.ActivationSpec{})
+	assert.Nil(t, err)
+	_, ok := started.Load("test-done")
+	assert.True(t, ok)
+	err = manager.ReportStatus(context.Background(), "test-done", model.ActivationStatus{Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	_, ok = started.Load("test-done")
+	assert.False(t, ok)
+
+	err = manager.UpsertSpec(context.Background(), "test-deleted", model.ActivationSpec{})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "test-deleted", model.ActivationStatus{Status: v1alpha2.Running, IsActive: true})
+	assert.Nil(t, err)
+	err = manager.DeleteSpec(context.Background(), "test-deleted")
+	assert.Nil(t, err)
+	_, ok = started.Load("test-deleted")
+	assert.False(t, ok)
+}
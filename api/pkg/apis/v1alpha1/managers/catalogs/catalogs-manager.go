@@ -117,19 +117,9 @@ func (m *CatalogsManager) ValidateSpec(ctx context.Context, spec model.CatalogSp
 			err = v1alpha2.NewCOAError(err, "schema not found", v1alpha2.ValidateFailed)
 			return utils.SchemaResult{Valid: false}, err
 		}
-		if s, ok := schema.Spec.Properties["spec"]; ok {
-			var schemaObj utils.Schema
-			jData, _ := json.Marshal(s)
-			err = json.Unmarshal(jData, &schemaObj)
-			if err != nil {
-				err = v1alpha2.NewCOAError(err, "invalid schema", v1alpha2.ValidateFailed)
-				return utils.SchemaResult{Valid: false}, err
-			}
-			return schemaObj.CheckProperties(spec.Properties, nil)
-		} else {
-			err = v1alpha2.NewCOAError(fmt.Errorf("schema not found"), "schema validation error", v1alpha2.ValidateFailed)
-			return utils.SchemaResult{Valid: false}, err
-		}
+		var result utils.SchemaResult
+		result, err = utils.CheckCatalogSchema(schema.Spec.Properties, spec.Properties)
+		return result, err
 	}
 	return utils.SchemaResult{Valid: true}, nil
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	config "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
@@ -240,6 +241,7 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 		s.saveSummary(iCtx, deployment, summary, scope)
 		return summary, err
 	}
+	metrics.ObservePlanSteps(len(plan.Steps))
 
 	col := api_utils.MergeCollection(deployment.Solution.Metadata, deployment.Instance.Metadata)
 	dep := deployment
@@ -266,6 +268,9 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 			s.saveSummary(ctx, deployment, summary, scope)
 			return summary, err
 		}
+		if p, ok := provider.(secret.IWithSecretProvider); ok {
+			p.SetSecretProvider(s.SecretProvoider)
+		}
 
 		if previousDesiredState != nil {
 			testState := MergeDeploymentStates(&previousDesiredState.State, currentState)
@@ -302,7 +307,9 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 		// }
 
 		for i := 0; i < retryCount; i++ {
+			stepStart := time.Now()
 			componentResults, stepError = (provider.(tgt.ITargetProvider)).Apply(iCtx, dep, step, false)
+			metrics.ObserveReconcile(providerType(deployment.Targets[step.Target], step.Role), metrics.Outcome(stepError), time.Since(stepStart))
 			if stepError == nil {
 				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults})
 				break
@@ -344,20 +351,32 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 	return summary, nil
 }
 func (s *SolutionManager) saveSummary(ctx context.Context, deployment model.DeploymentSpec, summary model.SummarySpec, scope string) {
+	result := model.SummaryResult{
+		Summary:    summary,
+		Generation: deployment.Generation,
+		Time:       time.Now().UTC(),
+	}
 	// TODO: delete this state when time expires. This should probably be invoked by the vendor (via GetSummary method, for instance)
 	s.StateProvider.Upsert(ctx, states.UpsertRequest{
 		Value: states.StateEntry{
-			ID: fmt.Sprintf("%s-%s", "summary", deployment.Instance.Name),
-			Body: model.SummaryResult{
-				Summary:    summary,
-				Generation: deployment.Generation,
-				Time:       time.Now().UTC(),
-			},
+			ID:   fmt.Sprintf("%s-%s", "summary", deployment.Instance.Name),
+			Body: result,
 		},
 		Metadata: map[string]string{
 			"scope": scope,
 		},
 	})
+	// summary updates are relayed to watch clients by the events vendor
+	if s.VendorContext != nil && s.Config.Properties["publishSummary"] == "true" {
+		s.VendorContext.Publish("summary", v1alpha2.Event{
+			Metadata: map[string]string{
+				"objectType": "instance",
+				"instance":   deployment.Instance.Name,
+				"scope":      scope,
+			},
+			Body: result,
+		})
+	}
 }
 func (s *SolutionManager) canSkipStep(ctx context.Context, step model.DeploymentStep, target string, provider tgt.ITargetProvider, currentComponents []model.ComponentSpec, state model.DeploymentState) bool {
 
@@ -423,6 +442,9 @@ func (s *SolutionManager) Get(ctx context.Context, deployment model.DeploymentSp
 			log.Errorf(" M (Solution): failed to create provider: %+v", err)
 			return ret, nil, err
 		}
+		if p, ok := provider.(secret.IWithSecretProvider); ok {
+			p.SetSecretProvider(s.SecretProvoider)
+		}
 		var components []model.ComponentSpec
 		components, err = (provider.(tgt.ITargetProvider)).Get(iCtx, deployment, step.Components)
 		if err != nil {
@@ -459,6 +481,22 @@ func (s *SolutionManager) Poll() []error {
 func (s *SolutionManager) Reconcil() []error {
 	return nil
 }
+
+// providerType returns the provider type bound to a role on a target, used as a metrics label
+func providerType(target model.TargetSpec, role string) string {
+	if role == "" || role == "container" {
+		role = "instance"
+	}
+	for _, topology := range target.Topologies {
+		for _, binding := range topology.Bindings {
+			if binding.Role == role {
+				return binding.Provider
+			}
+		}
+	}
+	return "unknown"
+}
+
 func findAgent(target model.TargetSpec) string {
 	for _, c := range target.Components {
 		if v, ok := c.Properties[model.ContainerImage]; ok {
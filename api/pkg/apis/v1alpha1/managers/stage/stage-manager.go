@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
@@ -23,6 +24,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
@@ -274,7 +276,9 @@ func (s *StageManager) HandleDirectTriggerEvent(ctx context.Context, triggerData
 		return status
 	}
 
-	outputs, _, err := provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, triggerData.Inputs)
+	start := time.Now()
+	outputs, pause, err := provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, triggerData.Inputs)
+	metrics.ObserveStageExecution(triggerData.Provider, stageOutcome(pause, err), time.Since(start))
 
 	result := TaskResult{
 		Outputs: outputs,
@@ -302,6 +306,12 @@ func (s *StageManager) HandleDirectTriggerEvent(ctx context.Context, triggerData
 	status.IsActive = false
 	return status
 }
+func stageOutcome(pause bool, err error) string {
+	if err == nil && pause {
+		return metrics.OutcomePaused
+	}
+	return metrics.Outcome(err)
+}
 func carryOutPutsToErrorStatus(outputs map[string]interface{}, err error, site string) map[string]interface{} {
 	ret := make(map[string]interface{})
 	statusKey := "__status"
@@ -500,7 +510,9 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 				} else {
 					var outputs map[string]interface{}
 					var pause bool
+					start := time.Now()
 					outputs, pause, err = provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputCopy)
+					metrics.ObserveStageExecution(triggerData.Provider, stageOutcome(pause, err), time.Since(start))
 
 					if pause {
 						pauseRequested = true
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package users
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+
+	// argon2id parameters follow the OWASP password storage recommendations
+	argon2Memory  uint32 = 19 * 1024
+	argon2Time    uint32 = 2
+	argon2Threads uint8  = 1
+	argon2KeyLen  uint32 = 32
+	saltLength           = 16
+)
+
+// legacyHash is the original FNV-based password hash. It's only used to verify
+// existing hashes (prefixed with "H") so they can be migrated on next login.
+func legacyHash(name string, s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name + "." + s + ".salt"))
+	return fmt.Sprintf("H%d", h.Sum32())
+}
+
+func hashPassword(algorithm string, password string) (string, error) {
+	switch algorithm {
+	case HashBcrypt:
+		data, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case HashArgon2id, "":
+		salt := make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("password hash algorithm '%s' is not supported", algorithm)
+	}
+}
+
+// verifyPassword checks a password against a stored hash. needsRehash is true when the
+// password matches but the hash was produced by a different (or legacy) algorithm.
+func verifyPassword(algorithm string, name string, password string, hash string) (ok bool, needsRehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		ok = verifyArgon2id(password, hash)
+		return ok, ok && algorithm == HashBcrypt
+	case strings.HasPrefix(hash, "$2"):
+		ok = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+		return ok, ok && algorithm != HashBcrypt
+	case strings.HasPrefix(hash, "H"):
+		ok = subtle.ConstantTimeCompare([]byte(legacyHash(name, password)), []byte(hash)) == 1
+		return ok, ok
+	default:
+		return false, false
+	}
+}
+
+func verifyArgon2id(password string, hash string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
@@ -8,9 +8,18 @@ package users
 
 import (
 	"context"
-	"fmt"
-	"hash/fnv"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -22,15 +31,33 @@ import (
 
 var log = logger.NewLogger("coa.runtime")
 
+const (
+	DefaultMaxFailedLogins = 5
+	DefaultLockoutSeconds  = 300
+)
+
 type UsersManager struct {
 	managers.Manager
-	StateProvider states.IStateProvider
+	StateProvider   states.IStateProvider
+	HashAlgorithm   string
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+	lock            sync.Mutex
 }
 
 type UserState struct {
-	Id           string   `json:"id"`
-	PasswordHash string   `json:"passwordHash,omitempty"`
-	Roles        []string `json:"roles,omitempty"`
+	Id            string              `json:"id"`
+	PasswordHash  string              `json:"passwordHash,omitempty"`
+	Roles         []string            `json:"roles,omitempty"`
+	FailedLogins  int                 `json:"failedLogins,omitempty"`
+	LockedUntil   int64               `json:"lockedUntil,omitempty"`
+	RefreshTokens []RefreshTokenState `json:"refreshTokens,omitempty"`
+}
+
+// RefreshTokenState is a hashed refresh token issued to a user
+type RefreshTokenState struct {
+	Hash      string `json:"hash"`
+	ExpiresAt int64  `json:"expiresAt"`
 }
 
 func (s *UsersManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -41,6 +68,25 @@ func (s *UsersManager) Init(context *contexts.VendorContext, config managers.Man
 		return err
 	}
 
+	s.HashAlgorithm = HashArgon2id
+	if val, ok := config.Properties["passwordHash"]; ok {
+		if val != HashArgon2id && val != HashBcrypt {
+			return v1alpha2.NewCOAError(nil, "passwordHash must be either 'argon2id' or 'bcrypt'", v1alpha2.BadConfig)
+		}
+		s.HashAlgorithm = val
+	}
+	s.MaxFailedLogins = DefaultMaxFailedLogins
+	if val, ok := config.Properties["maxFailedLogins"]; ok {
+		if i, err := strconv.Atoi(val); err == nil {
+			s.MaxFailedLogins = i
+		}
+	}
+	s.LockoutDuration = DefaultLockoutSeconds * time.Second
+	if val, ok := config.Properties["lockoutSeconds"]; ok {
+		if i, err := strconv.Atoi(val); err == nil {
+			s.LockoutDuration = time.Duration(i) * time.Second
+		}
+	}
 	return nil
 }
 func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
@@ -56,12 +102,6 @@ func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	return err
 }
 
-func hash(name string, s string) string {
-	h := fnv.New32a()
-	h.Write([]byte(name + "." + s + ".salt"))
-	return fmt.Sprintf("H%d", h.Sum32())
-}
-
 func (t *UsersManager) UpsertUser(ctx context.Context, name string, password string, roles []string) error {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "UpsertUser",
@@ -70,23 +110,111 @@ func (t *UsersManager) UpsertUser(ctx context.Context, name string, password str
 	defer observ_utils.CloseSpanWithError(span, &err)
 
 	log.Debug(" M (Users) : upsert user")
-	upsertRequest := states.UpsertRequest{
-		Value: states.StateEntry{
-			ID: name,
-			Body: UserState{
-				Id:           name,
-				PasswordHash: hash(name, password),
-				Roles:        roles,
-			},
-		},
+	var passwordHash string
+	passwordHash, err = hashPassword(t.HashAlgorithm, password)
+	if err != nil {
+		log.Debugf(" M (Users) : failed to hash password - %s", err)
+		return err
 	}
-	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	err = t.saveUser(ctx, UserState{
+		Id:           name,
+		PasswordHash: passwordHash,
+		Roles:        roles,
+	})
 	if err != nil {
 		log.Debugf(" M (Users) : failed to upsert user - %s", err)
 		return err
 	}
 	return nil
 }
+
+func (t *UsersManager) GetUser(ctx context.Context, name string) (UserState, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "GetUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var user UserState
+	user, err = t.getUser(ctx, name)
+	return user, err
+}
+
+func (t *UsersManager) ListUsers(ctx context.Context) ([]UserState, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ListUsers",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var entries []states.StateEntry
+	entries, _, err = t.StateProvider.List(ctx, states.ListRequest{})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]UserState, 0)
+	for _, e := range entries {
+		var user UserState
+		user, err = toUserState(e.Body)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, user)
+	}
+	return ret, nil
+}
+
+// SetRoles replaces the roles of an existing user
+func (t *UsersManager) SetRoles(ctx context.Context, name string, roles []string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "SetRoles",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var user UserState
+	user, err = t.getUser(ctx, name)
+	if err != nil {
+		return err
+	}
+	user.Roles = roles
+	err = t.saveUser(ctx, user)
+	return err
+}
+
+// ChangePassword sets a new password after verifying the current one. All refresh tokens of
+// the user are revoked.
+func (t *UsersManager) ChangePassword(ctx context.Context, name string, oldPassword string, newPassword string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ChangePassword",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	if _, ok := t.CheckUser(ctx, name, oldPassword); !ok {
+		err = v1alpha2.NewCOAError(nil, "current password is incorrect", v1alpha2.Unauthorized)
+		return err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var user UserState
+	user, err = t.getUser(ctx, name)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash, err = hashPassword(t.HashAlgorithm, newPassword)
+	if err != nil {
+		return err
+	}
+	user.RefreshTokens = nil
+	err = t.saveUser(ctx, user)
+	return err
+}
+
+// CheckUser authenticates a user. Accounts are locked for LockoutDuration after MaxFailedLogins
+// consecutive failures, and passwords stored with a legacy or non-preferred hash are re-hashed.
 func (t *UsersManager) CheckUser(ctx context.Context, name string, password string) ([]string, bool) {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "CheckUser",
@@ -95,21 +223,192 @@ func (t *UsersManager) CheckUser(ctx context.Context, name string, password stri
 	defer observ_utils.CloseSpanWithError(span, &err)
 
 	log.Debug(" M (Users) : check user")
-	getRequest := states.GetRequest{
-		ID: name,
-	}
-	user, err := t.StateProvider.Get(ctx, getRequest)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	user, err := t.getUser(ctx, name)
 	if err != nil {
 		log.Debugf(" M (Users) : failed to read user - %s", err)
 		return nil, false
 	}
 
-	if v, ok := user.Body.(UserState); ok {
-		if hash(name, password) == v.PasswordHash {
-			log.Debug(" M (Users) : user authenticated")
-			return v.Roles, true
+	now := time.Now()
+	if user.LockedUntil > now.Unix() {
+		log.Debug(" M (Users) : user is locked out")
+		return nil, false
+	}
+	ok, needsRehash := verifyPassword(t.HashAlgorithm, name, password, user.PasswordHash)
+	if !ok {
+		user.FailedLogins++
+		if t.MaxFailedLogins > 0 && user.FailedLogins >= t.MaxFailedLogins {
+			log.Debug(" M (Users) : too many failed logins, locking user")
+			user.LockedUntil = now.Add(t.LockoutDuration).Unix()
+			user.FailedLogins = 0
 		}
+		err = t.saveUser(ctx, user)
+		log.Debug(" M (Users) : authentication failed")
+		return nil, false
 	}
-	log.Debug(" M (Users) : authentication failed")
-	return nil, false
+	changed := user.FailedLogins != 0 || user.LockedUntil != 0
+	user.FailedLogins = 0
+	user.LockedUntil = 0
+	if needsRehash {
+		log.Debug(" M (Users) : migrating password hash")
+		if hash, err := hashPassword(t.HashAlgorithm, password); err == nil {
+			user.PasswordHash = hash
+			changed = true
+		}
+	}
+	if changed {
+		err = t.saveUser(ctx, user)
+		if err != nil {
+			log.Debugf(" M (Users) : failed to update user - %s", err)
+		}
+	}
+	log.Debug(" M (Users) : user authenticated")
+	return user.Roles, true
+}
+
+// IssueRefreshToken creates a refresh token for a user. Only a hash of the token is stored.
+func (t *UsersManager) IssueRefreshToken(ctx context.Context, name string, lifetime time.Duration) (string, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "IssueRefreshToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var user UserState
+	user, err = t.getUser(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString([]byte(name)) + "." + base64.RawURLEncoding.EncodeToString(secret)
+	now := time.Now().Unix()
+	tokens := make([]RefreshTokenState, 0, len(user.RefreshTokens)+1)
+	for _, r := range user.RefreshTokens {
+		if r.ExpiresAt > now {
+			tokens = append(tokens, r)
+		}
+	}
+	user.RefreshTokens = append(tokens, RefreshTokenState{
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(lifetime).Unix(),
+	})
+	err = t.saveUser(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RedeemRefreshToken validates and consumes a refresh token, returning the user it was issued to
+func (t *UsersManager) RedeemRefreshToken(ctx context.Context, token string) (string, []string, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "RedeemRefreshToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var user UserState
+	user, err = t.removeRefreshToken(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.LockedUntil > time.Now().Unix() {
+		err = v1alpha2.NewCOAError(nil, "user is locked out", v1alpha2.Unauthorized)
+		return "", nil, err
+	}
+	return user.Id, user.Roles, nil
+}
+
+// RevokeRefreshToken invalidates a single refresh token
+func (t *UsersManager) RevokeRefreshToken(ctx context.Context, token string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "RevokeRefreshToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	_, err = t.removeRefreshToken(ctx, token)
+	return err
+}
+
+func (t *UsersManager) removeRefreshToken(ctx context.Context, token string) (UserState, error) {
+	invalid := v1alpha2.NewCOAError(nil, "refresh token is invalid", v1alpha2.Unauthorized)
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return UserState{}, invalid
+	}
+	name, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return UserState{}, invalid
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	user, err := t.getUser(ctx, string(name))
+	if err != nil {
+		return UserState{}, invalid
+	}
+	hash := hashToken(token)
+	now := time.Now().Unix()
+	found := false
+	tokens := make([]RefreshTokenState, 0, len(user.RefreshTokens))
+	for _, r := range user.RefreshTokens {
+		if subtle.ConstantTimeCompare([]byte(r.Hash), []byte(hash)) == 1 {
+			found = r.ExpiresAt > now
+			continue
+		}
+		if r.ExpiresAt > now {
+			tokens = append(tokens, r)
+		}
+	}
+	if !found {
+		return UserState{}, invalid
+	}
+	user.RefreshTokens = tokens
+	err = t.saveUser(ctx, user)
+	return user, err
+}
+
+func (t *UsersManager) getUser(ctx context.Context, name string) (UserState, error) {
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID: name,
+	})
+	if err != nil {
+		return UserState{}, err
+	}
+	return toUserState(entry.Body)
+}
+
+func (t *UsersManager) saveUser(ctx context.Context, user UserState) error {
+	_, err := t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   user.Id,
+			Body: user,
+		},
+	})
+	return err
+}
+
+func toUserState(body interface{}) (UserState, error) {
+	if v, ok := body.(UserState); ok {
+		return v, nil
+	}
+	var user UserState
+	data, _ := json.Marshal(body)
+	err := json.Unmarshal(data, &user)
+	if err != nil {
+		return user, v1alpha2.NewCOAError(err, "state entry is not a user", v1alpha2.InternalError)
+	}
+	return user, nil
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
 }
@@ -8,7 +8,9 @@ package users
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -81,3 +83,139 @@ func TestUpsertAndCheck(t *testing.T) {
 	err = manager.DeleteUser(context.Background(), "test")
 	assert.Nil(t, err)
 }
+
+func initManager(properties map[string]string) (*UsersManager, error) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &UsersManager{}
+	properties["providers.state"] = "StateProvider"
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, managers.ManagerConfig{Properties: properties}, providers)
+	return manager, err
+}
+
+func TestPasswordHashAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{HashArgon2id, HashBcrypt} {
+		manager, err := initManager(map[string]string{"passwordHash": algorithm})
+		assert.Nil(t, err)
+		err = manager.UpsertUser(context.Background(), "test", "password", nil)
+		assert.Nil(t, err)
+		user, err := manager.GetUser(context.Background(), "test")
+		assert.Nil(t, err)
+		assert.NotContains(t, user.PasswordHash, "password")
+		_, res := manager.CheckUser(context.Background(), "test", "password")
+		assert.True(t, res)
+	}
+}
+
+func TestSaltedHashesDiffer(t *testing.T) {
+	h1, err := hashPassword(HashArgon2id, "password")
+	assert.Nil(t, err)
+	h2, err := hashPassword(HashArgon2id, "password")
+	assert.Nil(t, err)
+	assert.NotEqual(t, h1, h2)
+}
+
+func TestInvalidHashAlgorithm(t *testing.T) {
+	_, err := initManager(map[string]string{"passwordHash": "md5"})
+	assert.NotNil(t, err)
+}
+
+func TestLegacyHashMigration(t *testing.T) {
+	manager, err := initManager(map[string]string{})
+	assert.Nil(t, err)
+	err = manager.saveUser(context.Background(), UserState{
+		Id:           "test",
+		PasswordHash: legacyHash("test", "password"),
+		Roles:        []string{"testrole"},
+	})
+	assert.Nil(t, err)
+	roles, res := manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, res)
+	assert.Equal(t, []string{"testrole"}, roles)
+	user, err := manager.GetUser(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(user.PasswordHash, "$argon2id$"))
+	_, res = manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, res)
+}
+
+func TestLockout(t *testing.T) {
+	manager, err := initManager(map[string]string{"maxFailedLogins": "2", "lockoutSeconds": "60"})
+	assert.Nil(t, err)
+	err = manager.UpsertUser(context.Background(), "test", "password", nil)
+	assert.Nil(t, err)
+	_, res := manager.CheckUser(context.Background(), "test", "wrong")
+	assert.False(t, res)
+	_, res = manager.CheckUser(context.Background(), "test", "wrong")
+	assert.False(t, res)
+	_, res = manager.CheckUser(context.Background(), "test", "password")
+	assert.False(t, res)
+
+	user, _ := manager.GetUser(context.Background(), "test")
+	user.LockedUntil = time.Now().Add(-time.Second).Unix()
+	manager.saveUser(context.Background(), user)
+	_, res = manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, res)
+}
+
+func TestRefreshTokens(t *testing.T) {
+	manager, err := initManager(map[string]string{})
+	assert.Nil(t, err)
+	err = manager.UpsertUser(context.Background(), "test", "password", []string{"testrole"})
+	assert.Nil(t, err)
+	token, err := manager.IssueRefreshToken(context.Background(), "test", time.Hour)
+	assert.Nil(t, err)
+	name, roles, err := manager.RedeemRefreshToken(context.Background(), token)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", name)
+	assert.Equal(t, []string{"testrole"}, roles)
+	// refresh tokens can only be used once
+	_, _, err = manager.RedeemRefreshToken(context.Background(), token)
+	assert.NotNil(t, err)
+
+	token, err = manager.IssueRefreshToken(context.Background(), "test", -time.Second)
+	assert.Nil(t, err)
+	_, _, err = manager.RedeemRefreshToken(context.Background(), token)
+	assert.NotNil(t, err)
+
+	_, _, err = manager.RedeemRefreshToken(context.Background(), "garbage")
+	assert.NotNil(t, err)
+}
+
+func TestChangePassword(t *testing.T) {
+	manager, err := initManager(map[string]string{})
+	assert.Nil(t, err)
+	err = manager.UpsertUser(context.Background(), "test", "password", nil)
+	assert.Nil(t, err)
+	token, err := manager.IssueRefreshToken(context.Background(), "test", time.Hour)
+	assert.Nil(t, err)
+	err = manager.ChangePassword(context.Background(), "test", "wrong", "newpassword")
+	assert.NotNil(t, err)
+	err = manager.ChangePassword(context.Background(), "test", "password", "newpassword")
+	assert.Nil(t, err)
+	_, res := manager.CheckUser(context.Background(), "test", "password")
+	assert.False(t, res)
+	_, res = manager.CheckUser(context.Background(), "test", "newpassword")
+	assert.True(t, res)
+	_, _, err = manager.RedeemRefreshToken(context.Background(), token)
+	assert.NotNil(t, err)
+}
+
+func TestListUsersAndSetRoles(t *testing.T) {
+	manager, err := initManager(map[string]string{})
+	assert.Nil(t, err)
+	manager.UpsertUser(context.Background(), "user1", "password", nil)
+	manager.UpsertUser(context.Background(), "user2", "password", []string{"reader"})
+	err = manager.SetRoles(context.Background(), "user1", []string{"administrator"})
+	assert.Nil(t, err)
+	list, err := manager.ListUsers(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	user, err := manager.GetUser(context.Background(), "user1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, user.Roles)
+	err = manager.SetRoles(context.Background(), "user3", []string{"administrator"})
+	assert.NotNil(t, err)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 )
 
 type PropertyDesc struct {
@@ -29,6 +30,10 @@ type ValidationRule struct {
 	OptionalProperties        []string       `json:"optionalProperties"`
 	RequiredMetadata          []string       `json:"requiredMetadata"`
 	OptionalMetadata          []string       `json:"optionalMetadata"`
+	// at most one property of each group can be set
+	ExclusiveProperties [][]string `json:"exclusiveProperties,omitempty"`
+	// properties that must be positive durations, such as "30s", when they're set
+	DurationProperties []string `json:"durationProperties,omitempty"`
 	// a provider that supports scope isolation can deploy to specified scopes other than the "default" scope.
 	// instances from different scopes are isolated from each other.
 	ScopeIsolation bool `json:"supportScopes,omitempty"`
@@ -169,5 +174,26 @@ func (v ValidationRule) validateComponent(component ComponentSpec) error {
 		}
 	}
 
+	// exclusive properties can't be set together
+	for _, group := range v.ExclusiveProperties {
+		set := ""
+		for _, p := range group {
+			if ReadPropertyCompat(component.Properties, p, nil) == "" {
+				continue
+			}
+			if set != "" {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("component %s can't have both %s and %s", component.Name, set, p), v1alpha2.BadRequest)
+			}
+			set = p
+		}
+	}
+
+	// duration properties must be positive durations
+	for _, p := range v.DurationProperties {
+		if _, err := utils.ParsePositiveDuration(ReadPropertyCompat(component.Properties, p, nil), 0); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("%s of component %s must be a duration", p, component.Name), v1alpha2.BadRequest)
+		}
+	}
+
 	return nil
 }
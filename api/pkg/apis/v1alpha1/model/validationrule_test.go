@@ -161,3 +161,36 @@ func TestValidateComponentType(t *testing.T) {
 	equal := validationRule.Validate(components)
 	assert.Errorf(t, equal, "required property 'requiredComponentType' is missing")
 }
+
+func TestValidateExclusiveProperties(t *testing.T) {
+	validationRule := ValidationRule{
+		ExclusiveProperties: [][]string{{"body", "bodyTemplate"}},
+	}
+	err := validationRule.Validate([]ComponentSpec{
+		{Name: "web", Properties: map[string]interface{}{"body": "{}"}},
+		{Name: "api", Properties: map[string]interface{}{"bodyTemplate": "{}", "url": "http://api"}},
+	})
+	assert.Nil(t, err)
+	err = validationRule.Validate([]ComponentSpec{
+		{Name: "web", Properties: map[string]interface{}{"body": "{}", "bodyTemplate": "{}"}},
+	})
+	assert.EqualError(t, err, "component web can't have both body and bodyTemplate")
+}
+
+func TestValidateDurationProperties(t *testing.T) {
+	validationRule := ValidationRule{
+		DurationProperties: []string{"timeout"},
+	}
+	err := validationRule.Validate([]ComponentSpec{
+		{Name: "web", Properties: map[string]interface{}{"timeout": "30s"}},
+		{Name: "api"},
+	})
+	assert.Nil(t, err)
+	for _, timeout := range []string{"30", "-1s", "0s"} {
+		err = validationRule.Validate([]ComponentSpec{
+			{Name: "web", Properties: map[string]interface{}{"timeout": timeout}},
+		})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "timeout of component web must be a duration")
+	}
+}
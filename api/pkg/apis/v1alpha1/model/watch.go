@@ -0,0 +1,23 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import "time"
+
+// WatchEvent is a pub/sub event relayed to watch clients, such as `maestro watch`. Kind, Name and Scope
+// identify the object the event is about; Campaign is set on activation events.
+type WatchEvent struct {
+	Id       uint64            `json:"id"`
+	Topic    string            `json:"topic"`
+	Kind     string            `json:"kind,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Campaign string            `json:"campaign,omitempty"`
+	Scope    string            `json:"scope,omitempty"`
+	Time     time.Time         `json:"time"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Body     interface{}       `json:"body,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package providers
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateStageProviders(t *testing.T) {
+	factory := SymphonyProviderFactory{}
+	for _, providerType := range stage.ProviderTypes {
+		provider, err := factory.CreateProvider(providerType, nil)
+		// an unknown type creates neither a provider nor an error
+		assert.True(t, provider != nil || err != nil, "provider type %s is unknown", providerType)
+	}
+	provider, err := factory.CreateProvider("providers.stage.unknown", nil)
+	assert.Nil(t, provider)
+	assert.Nil(t, err)
+}
@@ -12,6 +12,21 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 )
 
+// ProviderTypes are the stage provider types the provider factory creates
+var ProviderTypes = []string{
+	"providers.stage.counter",
+	"providers.stage.create",
+	"providers.stage.delay",
+	"providers.stage.http",
+	"providers.stage.list",
+	"providers.stage.materialize",
+	"providers.stage.mock",
+	"providers.stage.patch",
+	"providers.stage.remote",
+	"providers.stage.script",
+	"providers.stage.wait",
+}
+
 type IStageProvider interface {
 	// Return values: map[string]interface{} - outputs, bool - should the activation be paused (wait for a remote event), error
 	Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error)
@@ -0,0 +1,753 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package compose
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/docker"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/internal/dockerutil"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	// DocumentProperty holds an inline compose document
+	DocumentProperty = "compose.document"
+	// CatalogProperty names a catalog whose "compose" property holds the compose document
+	CatalogProperty = "compose.catalog"
+	// ServicesProperty is reported by Get with the state of each service of a component
+	ServicesProperty        = "compose.services"
+	catalogDocumentProperty = "compose"
+
+	// the labels docker compose sets, so that the projects also show up in docker compose ls and ps
+	projectLabel         = "com.docker.compose.project"
+	serviceLabel         = "com.docker.compose.service"
+	networkLabel         = "com.docker.compose.network"
+	volumeLabel          = "com.docker.compose.volume"
+	containerNumberLabel = "com.docker.compose.container-number"
+	configHashLabel      = "com.docker.compose.config-hash"
+
+	instanceLabel  = "symphony.instance"
+	scopeLabel     = "symphony.scope"
+	solutionLabel  = "symphony.solution"
+	componentLabel = "symphony.component"
+)
+
+// service states reported by Get, in addition to the container states reported by Docker
+const (
+	StateMissing  = "missing"
+	StateOutdated = "outdated"
+	StateOrphaned = "orphaned"
+	stateRunning  = "running"
+)
+
+var (
+	invalidProjectCharacters = regexp.MustCompile("[^a-z0-9_-]")
+	validProjectName         = regexp.MustCompile("^[a-z0-9][a-z0-9_-]*$")
+)
+
+type ComposeTargetProviderConfig struct {
+	Name string `json:"name"`
+}
+
+type ComposeTargetProvider struct {
+	Config  ComposeTargetProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func ComposeTargetProviderConfigFromMap(properties map[string]string) (ComposeTargetProviderConfig, error) {
+	ret := ComposeTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	return ret, nil
+}
+func (i *ComposeTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := ComposeTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func (i *ComposeTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	i.Context = ctx
+}
+
+func (i *ComposeTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("Compose Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (Compose Target): Init()")
+
+	composeConfig, err := toComposeTargetProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (Compose Target): expected ComposeTargetProviderConfig: %+v", err)
+		return err
+	}
+
+	i.Config = composeConfig
+	return nil
+}
+func toComposeTargetProviderConfig(config providers.IProviderConfig) (ComposeTargetProviderConfig, error) {
+	ret := ComposeTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// ProjectName is the compose project of an instance. Compose project names may only contain lowercase
+// letters, digits, dashes and underscores, and must start with a letter or a digit. Instances of the
+// default scope are named after the instance, and instances of other scopes after the scope and the
+// instance. A name that isn't a valid project name, or that includes a scope, is lowercased and stripped
+// of the other characters, and gets a hash of the scope and the instance appended, so that instances such
+// as "My.App" and "myapp", or the same instance in two scopes, get different projects.
+func ProjectName(scope string, instance string) string {
+	if instance == "" {
+		return ""
+	}
+	name, key := instance, instance
+	if scope != "" && scope != "default" {
+		name, key = scope+"-"+instance, scope+"\x00"+instance
+	} else if validProjectName.MatchString(instance) {
+		return instance
+	}
+	ret := invalidProjectCharacters.ReplaceAllString(strings.ToLower(name), "")
+	ret = strings.TrimLeft(ret, "_-")
+	hash := sha256.Sum256([]byte(key))
+	if ret == "" {
+		return hex.EncodeToString(hash[:])[:8]
+	}
+	return ret + "-" + hex.EncodeToString(hash[:])[:8]
+}
+
+// ContainerName is the name of the container of a service, as docker compose names it
+func ContainerName(project string, service string) string {
+	return project + "-" + service + "-1"
+}
+
+// Get reports, for each component that has containers, the state of its services in compose.services.
+// A component whose services all run with the desired configuration reports its compose.document or
+// compose.catalog property as in the reference. Otherwise it reports the state of its services in their
+// place, so that change detection deploys it again.
+func (i *ComposeTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Compose Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Compose Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	project := ProjectName(deployment.Instance.Scope, deployment.Instance.Name)
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		sLog.Errorf("  P (Compose Target): failed to create docker client: %+v", err)
+		return nil, err
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		var containers []types.Container
+		containers, err = listContainers(ctx, cli, deployment.Instance.Scope, project, reference.Component.Name)
+		if err != nil {
+			sLog.Errorf("  P (Compose Target): failed to list containers: %+v", err)
+			return nil, err
+		}
+		if len(containers) == 0 {
+			continue
+		}
+
+		states := make(map[string]string)
+		for _, c := range containers {
+			states[c.Labels[serviceLabel]] = StateOrphaned
+		}
+		document, docErr := i.readDocument(ctx, deployment, reference.Component)
+		if docErr != nil {
+			// the component is reported as drifted, and deploying it reports the error
+			sLog.Errorf("  P (Compose Target): failed to read the compose document of %s: %+v", reference.Component.Name, docErr)
+		} else {
+			for name, service := range document.Services {
+				states[name] = StateMissing
+				spec, specErr := buildServiceSpec(deployment, project, reference.Component.Name, name, service, document)
+				for _, c := range containers {
+					if c.Labels[serviceLabel] != name {
+						continue
+					}
+					if specErr != nil || c.Labels[configHashLabel] != spec.Config.Labels[configHashLabel] {
+						states[name] = StateOutdated
+					} else {
+						states[name] = c.State
+					}
+				}
+			}
+		}
+
+		data, _ := json.Marshal(states)
+		component := model.ComponentSpec{
+			Name: reference.Component.Name,
+			Properties: map[string]interface{}{
+				ServicesProperty: string(data),
+			},
+		}
+		inSync := docErr == nil
+		for _, state := range states {
+			if state != stateRunning {
+				inSync = false
+			}
+		}
+		for _, property := range []string{DocumentProperty, CatalogProperty} {
+			if v, ok := reference.Component.Properties[property]; ok {
+				if inSync {
+					component.Properties[property] = v
+				} else {
+					component.Properties[property] = string(data)
+				}
+			}
+		}
+		ret = append(ret, component)
+	}
+
+	return ret, nil
+}
+
+func (i *ComposeTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Compose Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Compose Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun {
+		err = nil
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+
+	project := ProjectName(deployment.Instance.Scope, deployment.Instance.Name)
+	if project == "" {
+		err = v1alpha2.NewCOAError(nil, "an instance name is required for the compose project name", v1alpha2.BadRequest)
+		return ret, err
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		sLog.Errorf("  P (Compose Target): failed to create docker client: %+v", err)
+		return ret, err
+	}
+
+	for _, component := range step.Components {
+		if component.Action == "update" {
+			err = i.up(ctx, cli, deployment, project, component.Component)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Compose Target): failed to deploy %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
+			}
+		} else {
+			err = down(ctx, cli, deployment.Instance.Scope, project, component.Component.Name)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Compose Target): failed to remove %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (*ComposeTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		RequiredProperties:    []string{},
+		OptionalProperties:    []string{DocumentProperty, CatalogProperty},
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: DocumentProperty, IgnoreCase: false, SkipIfMissing: true},
+			{Name: CatalogProperty, IgnoreCase: false, SkipIfMissing: true},
+		},
+		InstanceIsolation: true,
+	}
+}
+
+// readDocument reads the compose document of a component, either inline or from a catalog, and injects
+// values such as ${{$instance()}} into it
+func (i *ComposeTargetProvider) readDocument(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) (Document, error) {
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+	var source interface{}
+	if v, ok := component.Properties[DocumentProperty]; ok {
+		source = v
+	} else if v, ok := component.Properties[CatalogProperty]; ok {
+		if i.Context == nil || i.Context.SiteInfo.CurrentSite.BaseUrl == "" {
+			return Document{}, v1alpha2.NewCOAError(nil, "catalog references require a Symphony API endpoint", v1alpha2.BadConfig)
+		}
+		name := model.ResolveString(fmt.Sprintf("%v", v), injections)
+		catalog, err := utils.GetCatalog(
+			ctx,
+			i.Context.SiteInfo.CurrentSite.BaseUrl,
+			name,
+			i.Context.SiteInfo.CurrentSite.Username,
+			i.Context.SiteInfo.CurrentSite.Password)
+		if err != nil {
+			return Document{}, err
+		}
+		if catalog.Spec == nil || catalog.Spec.Properties[catalogDocumentProperty] == nil {
+			return Document{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("catalog '%s' doesn't have a '%s' property", name, catalogDocumentProperty), v1alpha2.BadRequest)
+		}
+		source = catalog.Spec.Properties[catalogDocumentProperty]
+	} else {
+		return Document{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("component '%s' has neither %s nor %s property", component.Name, DocumentProperty, CatalogProperty), v1alpha2.BadRequest)
+	}
+
+	// a document may also be given as an object, which is JSON, and so YAML, once marshalled
+	data, ok := source.(string)
+	if !ok {
+		bytes, err := json.Marshal(source)
+		if err != nil {
+			return Document{}, v1alpha2.NewCOAError(err, "compose document is not valid", v1alpha2.BadRequest)
+		}
+		data = string(bytes)
+	}
+	return ParseDocument(model.ResolveString(data, injections))
+}
+
+// up brings the services of a component up, in dependency order. Services whose container runs with
+// the desired configuration are left alone, and the containers of services that were removed from the
+// document are removed.
+func (i *ComposeTargetProvider) up(ctx context.Context, cli *client.Client, deployment model.DeploymentSpec, project string, component model.ComponentSpec) error {
+	document, err := i.readDocument(ctx, deployment, component)
+	if err != nil {
+		return err
+	}
+	order, err := document.StartOrder()
+	if err != nil {
+		return err
+	}
+
+	// services of a project share a namespace, so two components can't define the same service
+	projectContainers, err := listContainers(ctx, cli, deployment.Instance.Scope, project, "")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]types.Container)
+	for _, c := range projectContainers {
+		service := c.Labels[serviceLabel]
+		if c.Labels[componentLabel] == component.Name {
+			existing[service] = c
+		} else if _, ok := document.Services[service]; ok {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' is already defined by component '%s'", service, c.Labels[componentLabel]), v1alpha2.BadRequest)
+		}
+	}
+
+	specs := make([]serviceSpec, 0, len(order))
+	for _, name := range order {
+		spec, err := buildServiceSpec(deployment, project, component.Name, name, document.Services[name], document)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	if err := ensureNetworks(ctx, cli, project, document); err != nil {
+		return err
+	}
+	if err := ensureVolumes(ctx, cli, project, document); err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		name := spec.Service
+		if c, ok := existing[name]; ok {
+			if c.Labels[configHashLabel] == spec.Config.Labels[configHashLabel] && c.State == stateRunning {
+				sLog.Infof("  P (Compose Target): service %s of project %s is up to date", name, project)
+				continue
+			}
+			if err := dockerutil.RemoveContainer(ctx, cli, c.ID); err != nil {
+				return err
+			}
+		}
+		if err := createContainer(ctx, cli, spec); err != nil {
+			return err
+		}
+	}
+
+	for name, c := range existing {
+		if _, ok := document.Services[name]; !ok {
+			sLog.Infof("  P (Compose Target): removing orphaned service %s of project %s", name, project)
+			if err := dockerutil.RemoveContainer(ctx, cli, c.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// down removes the containers of a component. The networks of the project are removed with its last
+// container, while volumes are kept, as docker compose down does.
+func down(ctx context.Context, cli *client.Client, scope string, project string, component string) error {
+	containers, err := listContainers(ctx, cli, scope, project, component)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if err := dockerutil.RemoveContainer(ctx, cli, c.ID); err != nil {
+			return err
+		}
+	}
+
+	remaining, err := listContainers(ctx, cli, scope, project, "")
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return nil
+	}
+	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", projectLabel+"="+project)),
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range networks {
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listContainers lists the containers that a scope has in a project, or in one of its components
+func listContainers(ctx context.Context, cli *client.Client, scope string, project string, component string) ([]types.Container, error) {
+	args := filters.NewArgs(filters.Arg("label", projectLabel+"="+project), filters.Arg("label", scopeLabel+"="+scope))
+	if component != "" {
+		args.Add("label", componentLabel+"="+component)
+	}
+	return cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+}
+
+// createContainer creates and starts the container of a service. A missing image is pulled first.
+func createContainer(ctx context.Context, cli *client.Client, spec serviceSpec) error {
+	created, err := cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, spec.NetworkingConfig, nil, spec.Name)
+	if client.IsErrNotFound(err) {
+		sLog.Infof("  P (Compose Target): pulling image %s", spec.Config.Image)
+		var reader io.ReadCloser
+		reader, err = cli.ImagePull(ctx, spec.Config.Image, types.ImagePullOptions{})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		created, err = cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, spec.NetworkingConfig, nil, spec.Name)
+	}
+	if err != nil {
+		return err
+	}
+	// a container is created on its first network, and connected to the others before it starts
+	for _, n := range spec.ExtraNetworks {
+		err = cli.NetworkConnect(ctx, n, created.ID, &network.EndpointSettings{Aliases: []string{spec.Service}})
+		if err != nil {
+			return err
+		}
+	}
+	return cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{})
+}
+
+func ensureNetworks(ctx context.Context, cli *client.Client, project string, document Document) error {
+	existing, err := cli.NetworkList(ctx, types.NetworkListOptions{})
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, n := range existing {
+		names[n.Name] = true
+	}
+	for _, key := range usedNetworks(document) {
+		name := networkName(project, key, document)
+		if names[name] {
+			continue
+		}
+		resource := document.Networks[key]
+		if resource != nil && resource.External {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("external network '%s' doesn't exist", name), v1alpha2.BadRequest)
+		}
+		options := types.NetworkCreate{
+			CheckDuplicate: true,
+			Labels: map[string]string{
+				projectLabel: project,
+				networkLabel: key,
+			},
+		}
+		if resource != nil {
+			options.Driver = resource.Driver
+			for k, v := range resource.Labels {
+				options.Labels[k] = v
+			}
+		}
+		if _, err := cli.NetworkCreate(ctx, name, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureVolumes(ctx context.Context, cli *client.Client, project string, document Document) error {
+	for key, resource := range document.Volumes {
+		if resource != nil && resource.External {
+			continue
+		}
+		options := volumetypes.VolumeCreateBody{
+			Name: volumeName(project, key, document),
+			Labels: map[string]string{
+				projectLabel: project,
+				volumeLabel:  key,
+			},
+		}
+		if resource != nil {
+			options.Driver = resource.Driver
+			for k, v := range resource.Labels {
+				options.Labels[k] = v
+			}
+		}
+		// creating a volume that exists is a no-op
+		if _, err := cli.VolumeCreate(ctx, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usedNetworks lists the networks the services are attached to. Services that specify neither networks
+// nor a network mode are attached to the default network.
+func usedNetworks(document Document) []string {
+	used := make(map[string]bool)
+	for _, service := range document.Services {
+		for _, n := range serviceNetworks(service) {
+			used[n] = true
+		}
+	}
+	ret := make([]string, 0, len(used))
+	for n := range used {
+		ret = append(ret, n)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func serviceNetworks(service Service) []string {
+	if service.NetworkMode != "" {
+		return nil
+	}
+	if len(service.Networks) == 0 {
+		return []string{"default"}
+	}
+	return service.Networks
+}
+
+func networkName(project string, key string, document Document) string {
+	if resource := document.Networks[key]; resource != nil && resource.Name != "" {
+		return resource.Name
+	}
+	if resource := document.Networks[key]; resource != nil && resource.External {
+		return key
+	}
+	return project + "_" + key
+}
+
+func volumeName(project string, key string, document Document) string {
+	if resource := document.Volumes[key]; resource != nil && resource.Name != "" {
+		return resource.Name
+	}
+	if resource := document.Volumes[key]; resource != nil && resource.External {
+		return key
+	}
+	return project + "_" + key
+}
+
+type serviceSpec struct {
+	Name             string
+	Service          string
+	Config           *container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+	ExtraNetworks    []string
+}
+
+// buildServiceSpec converts a service to a container configuration. The configuration is hashed into
+// a label, which tells whether the container of a service needs to be recreated.
+func buildServiceSpec(deployment model.DeploymentSpec, project string, component string, name string, service Service, document Document) (serviceSpec, error) {
+	spec := serviceSpec{
+		Name:    ContainerName(project, name),
+		Service: name,
+		Config: &container.Config{
+			Image:      service.Image,
+			Entrypoint: []string(service.Entrypoint),
+			Cmd:        []string(service.Command),
+			User:       service.User,
+			WorkingDir: service.WorkingDir,
+			Hostname:   service.Hostname,
+			Labels:     make(map[string]string),
+		},
+		HostConfig: &container.HostConfig{
+			Privileged: service.Privileged,
+		},
+	}
+
+	env := make([]string, 0, len(service.Environment))
+	for k, v := range service.Environment {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	spec.Config.Env = env
+
+	for k, v := range service.Labels {
+		spec.Config.Labels[k] = v
+	}
+	spec.Config.Labels[projectLabel] = project
+	spec.Config.Labels[serviceLabel] = name
+	spec.Config.Labels[containerNumberLabel] = "1"
+	spec.Config.Labels[instanceLabel] = deployment.Instance.Name
+	spec.Config.Labels[scopeLabel] = deployment.Instance.Scope
+	spec.Config.Labels[solutionLabel] = deployment.Instance.Solution
+	spec.Config.Labels[componentLabel] = component
+
+	if len(service.Ports) > 0 {
+		ports := make([]string, 0, len(service.Ports))
+		for _, p := range service.Ports {
+			ports = append(ports, string(p))
+		}
+		exposed, bindings, err := nat.ParsePortSpecs(ports)
+		if err != nil {
+			return spec, v1alpha2.NewCOAError(err, fmt.Sprintf("service '%s' has invalid ports", name), v1alpha2.BadRequest)
+		}
+		spec.Config.ExposedPorts = exposed
+		spec.HostConfig.PortBindings = bindings
+	}
+
+	for _, v := range service.Volumes {
+		m := mount.Mount{
+			Type:     mount.Type(v.Type),
+			Source:   v.Source,
+			Target:   v.Target,
+			ReadOnly: v.ReadOnly,
+		}
+		if v.Type == "volume" && v.Source != "" {
+			m.Source = volumeName(project, v.Source, document)
+		}
+		spec.HostConfig.Mounts = append(spec.HostConfig.Mounts, m)
+	}
+
+	if service.Restart != "" {
+		policy, err := docker.ParseRestartPolicy(service.Restart)
+		if err != nil {
+			return spec, err
+		}
+		spec.HostConfig.RestartPolicy = policy
+	}
+
+	if service.Healthcheck != nil {
+		check := docker.HealthCheck{
+			Test:        []string(service.Healthcheck.Test),
+			Interval:    service.Healthcheck.Interval,
+			Timeout:     service.Healthcheck.Timeout,
+			StartPeriod: service.Healthcheck.StartPeriod,
+			Retries:     service.Healthcheck.Retries,
+		}
+		// as in docker compose, a test given as a string runs in a shell
+		if len(check.Test) > 0 && check.Test[0] != "CMD" && check.Test[0] != "CMD-SHELL" && check.Test[0] != "NONE" {
+			check.Test = []string{"CMD-SHELL", strings.Join(check.Test, " ")}
+		}
+		if service.Healthcheck.Disable {
+			check = docker.HealthCheck{Test: []string{"NONE"}}
+		}
+		config, err := check.ToHealthConfig()
+		if err != nil {
+			return spec, err
+		}
+		spec.Config.Healthcheck = config
+	}
+
+	if service.NetworkMode != "" {
+		spec.HostConfig.NetworkMode = container.NetworkMode(service.NetworkMode)
+	} else {
+		networks := serviceNetworks(service)
+		first := networkName(project, networks[0], document)
+		spec.HostConfig.NetworkMode = container.NetworkMode(first)
+		spec.NetworkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				first: {Aliases: []string{name}},
+			},
+		}
+		for _, n := range networks[1:] {
+			spec.ExtraNetworks = append(spec.ExtraNetworks, networkName(project, n, document))
+		}
+	}
+
+	data, _ := json.Marshal(spec)
+	hash := sha256.Sum256(data)
+	spec.Config.Labels[configHashLabel] = hex.EncodeToString(hash[:])
+	return spec, nil
+}
@@ -0,0 +1,491 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package compose
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/internal/dockertest"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `
+services:
+  web:
+    image: nginx:1.25
+    command: nginx -g "daemon off;"
+    ports:
+      - "8080:80"
+    environment:
+      - INSTANCE=${{$instance()}}
+    depends_on:
+      db:
+        condition: service_started
+    networks: [front, back]
+  db:
+    image: redis:7
+    restart: unless-stopped
+    volumes:
+      - data:/data
+    networks:
+      back: {}
+    healthcheck:
+      test: redis-cli ping
+      interval: 10s
+networks:
+  front:
+  back:
+volumes:
+  data:
+`
+
+func TestComposeTargetProviderConfigFromMapNil(t *testing.T) {
+	_, err := ComposeTargetProviderConfigFromMap(nil)
+	assert.Nil(t, err)
+}
+func TestInitWithMap(t *testing.T) {
+	provider := ComposeTargetProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name": "name",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "name", provider.Config.Name)
+}
+
+func TestProjectName(t *testing.T) {
+	assert.Equal(t, "my-instance", ProjectName("default", "my-instance"))
+	assert.Equal(t, "my-instance", ProjectName("", "my-instance"))
+	assert.Regexp(t, "^my-instance-[0-9a-f]{8}$", ProjectName("default", "My-Instance"))
+	assert.Regexp(t, "^instance_1-[0-9a-f]{8}$", ProjectName("default", "-instance_1."))
+	assert.Regexp(t, "^[0-9a-f]{8}$", ProjectName("default", "--"))
+	assert.Equal(t, "", ProjectName("default", ""))
+	// names that only differ in the characters that are stripped get different projects
+	assert.Equal(t, "myapp", ProjectName("default", "myapp"))
+	assert.NotEqual(t, ProjectName("default", "myapp"), ProjectName("default", "My.App"))
+	assert.NotEqual(t, ProjectName("default", "MyApp"), ProjectName("default", "My.App"))
+	assert.Equal(t, ProjectName("default", "My.App"), ProjectName("default", "My.App"))
+	// an instance gets a project per scope
+	assert.Regexp(t, "^scope1-myapp-[0-9a-f]{8}$", ProjectName("scope1", "myapp"))
+	assert.NotEqual(t, ProjectName("scope1", "myapp"), ProjectName("scope2", "myapp"))
+	assert.NotEqual(t, ProjectName("default", "scope1-myapp"), ProjectName("scope1", "myapp"))
+	assert.NotEqual(t, ProjectName("a-b", "c"), ProjectName("a", "b-c"))
+}
+
+func TestParseDocument(t *testing.T) {
+	document, err := ParseDocument(testDocument)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(document.Services))
+
+	web := document.Services["web"]
+	assert.Equal(t, shellCommand{"nginx", "-g", "daemon off;"}, web.Command)
+	assert.Equal(t, mapOrList{"INSTANCE": "${{$instance()}}"}, web.Environment)
+	assert.Equal(t, keyList{"db"}, web.DependsOn)
+	assert.Equal(t, keyList{"front", "back"}, web.Networks)
+	assert.Equal(t, []portSpec{"8080:80"}, web.Ports)
+
+	db := document.Services["db"]
+	assert.Equal(t, []volumeSpec{{Type: "volume", Source: "data", Target: "/data"}}, db.Volumes)
+	assert.Equal(t, keyList{"back"}, db.Networks)
+	assert.Equal(t, shellCommand{"redis-cli", "ping"}, db.Healthcheck.Test)
+
+	order, err := document.StartOrder()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db", "web"}, order)
+}
+
+func TestParseDocumentLongSyntax(t *testing.T) {
+	document, err := ParseDocument(`{
+		"services": {
+			"app": {
+				"image": "app:1",
+				"environment": {"DEBUG": true, "EMPTY": null},
+				"labels": ["tier=backend"],
+				"ports": [80, {"target": 443, "published": 8443, "host_ip": "127.0.0.1", "protocol": "tcp"}],
+				"volumes": ["/var/log:/logs:ro", {"type": "tmpfs", "target": "/tmp"}, "/cache"]
+			}
+		}
+	}`)
+	assert.Nil(t, err)
+	app := document.Services["app"]
+	assert.Equal(t, mapOrList{"DEBUG": "true", "EMPTY": ""}, app.Environment)
+	assert.Equal(t, mapOrList{"tier": "backend"}, app.Labels)
+	assert.Equal(t, []portSpec{"80", "127.0.0.1:8443:443/tcp"}, app.Ports)
+	assert.Equal(t, []volumeSpec{
+		{Type: "bind", Source: "/var/log", Target: "/logs", ReadOnly: true},
+		{Type: "tmpfs", Target: "/tmp"},
+		{Type: "volume", Target: "/cache"},
+	}, app.Volumes)
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	documents := map[string]string{
+		"no services":          `version: "3"`,
+		"build":                "services:\n  app:\n    build: .\n",
+		"no image":             "services:\n  app:\n    command: run\n",
+		"undefined network":    "services:\n  app:\n    image: app\n    networks: [other]\n",
+		"undefined volume":     "services:\n  app:\n    image: app\n    volumes: ['data:/data']\n",
+		"relative bind":        "services:\n  app:\n    image: app\n    volumes: ['./data:/data']\n",
+		"undefined dependency": "services:\n  app:\n    image: app\n    depends_on: [db]\n",
+		"circular":             "services:\n  a:\n    image: a\n    depends_on: [b]\n  b:\n    image: b\n    depends_on: [a]\n",
+		"not yaml":             "services: [",
+	}
+	for name, document := range documents {
+		_, err := ParseDocument(document)
+		assert.NotNil(t, err, name)
+		coaErr, ok := err.(v1alpha2.COAError)
+		assert.True(t, ok, name)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, name)
+	}
+}
+
+func TestApply(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	deployment, step := conformance.Deployment("default", "instance1", "update", composeComponent("app", testDocument))
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, result["app"].Status)
+
+	// the dependency starts first, and the missing images are pulled
+	assert.Equal(t, []string{"instance1-db-1", "instance1-web-1"}, api.CreatedNames)
+	assert.Equal(t, []string{"redis:7", "nginx:1.25"}, api.Pulled)
+	assert.True(t, api.Networks["instance1_front"] != nil)
+	assert.True(t, api.Networks["instance1_back"] != nil)
+	assert.Equal(t, "instance1", api.Volumes["instance1_data"][projectLabel])
+
+	web := api.Container("instance1-web-1")
+	assert.Equal(t, "running", web.State)
+	assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, []string(web.Config.Cmd))
+	assert.Equal(t, []string{"INSTANCE=instance1"}, web.Config.Env)
+	assert.Equal(t, "instance1", web.Config.Labels[projectLabel])
+	assert.Equal(t, "web", web.Config.Labels[serviceLabel])
+	assert.Equal(t, "app", web.Config.Labels[componentLabel])
+	assert.Equal(t, "instance1", web.Config.Labels[instanceLabel])
+	assert.Equal(t, "8080", web.HostConfig.PortBindings["80/tcp"][0].HostPort)
+	assert.Equal(t, container.NetworkMode("instance1_front"), web.HostConfig.NetworkMode)
+	assert.Equal(t, []string{"web"}, web.NetworkingConfig.EndpointsConfig["instance1_front"].Aliases)
+	assert.Equal(t, []string{"instance1_front", "instance1_back"}, web.Networks)
+
+	db := api.Container("instance1-db-1")
+	assert.Equal(t, "unless-stopped", db.HostConfig.RestartPolicy.Name)
+	assert.Equal(t, []mount.Mount{{Type: mount.TypeVolume, Source: "instance1_data", Target: "/data"}}, db.HostConfig.Mounts)
+	assert.Equal(t, []string{"CMD-SHELL", "redis-cli ping"}, db.Config.Healthcheck.Test)
+	assert.Equal(t, []string{"instance1_back"}, db.Networks)
+}
+
+func TestApplyRecreatesOnlyChangedServices(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	deployment, step := conformance.Deployment("default", "instance1", "update", composeComponent("app", testDocument))
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(api.CreatedNames))
+
+	// nothing changed
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(api.CreatedNames))
+
+	// only the changed service is recreated
+	changed := strings.Replace(testDocument, "nginx:1.25", "nginx:1.26", 1)
+	deployment, step = conformance.Deployment("default", "instance1", "update", composeComponent("app", changed))
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"instance1-db-1", "instance1-web-1", "instance1-web-1"}, api.CreatedNames)
+	assert.Equal(t, "nginx:1.26", api.Container("instance1-web-1").Config.Image)
+
+	// a stopped service is started again
+	api.Container("instance1-db-1").State = "exited"
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(api.CreatedNames))
+	assert.Equal(t, "running", api.Container("instance1-db-1").State)
+
+	// the container of a removed service is removed
+	deployment, step = conformance.Deployment("default", "instance1", "update", composeComponent("app", "services:\n  db:\n    image: redis:7\n"))
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container("instance1-web-1"))
+	assert.NotNil(t, api.Container("instance1-db-1"))
+}
+
+func TestGet(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+	component := composeComponent("app", testDocument)
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+
+	// a component that isn't deployed isn't returned
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "app", components[0].Name)
+	assert.Equal(t, testDocument, components[0].Properties[DocumentProperty])
+	assert.Equal(t, `{"db":"running","web":"running"}`, components[0].Properties[ServicesProperty])
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+
+	// a service that stopped is reported, and the component is deployed again
+	api.Container("instance1-web-1").State = "exited"
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"db":"running","web":"exited"}`, components[0].Properties[ServicesProperty])
+	assert.True(t, rule.IsComponentChanged(components[0], component))
+
+	// so is a service whose configuration changed, or that was added
+	api.Container("instance1-web-1").State = "running"
+	changed := strings.Replace(testDocument, "redis:7", "redis:7.2", 1)
+	changed = strings.Replace(changed, "services:\n", "services:\n  cache:\n    image: memcached:1\n", 1)
+	changedComponent := composeComponent("app", changed)
+	components, err = provider.Get(context.Background(), deployment, []model.ComponentStep{{Action: "update", Component: changedComponent}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"cache":"missing","db":"outdated","web":"running"}`, components[0].Properties[ServicesProperty])
+	assert.True(t, rule.IsComponentChanged(components[0], changedComponent))
+
+	// and a service that was removed from the document
+	removed := composeComponent("app", "services:\n  db:\n    image: redis:7\n")
+	components, err = provider.Get(context.Background(), deployment, []model.ComponentStep{{Action: "update", Component: removed}})
+	assert.Nil(t, err)
+	assert.Contains(t, components[0].Properties[ServicesProperty], `"web":"orphaned"`)
+	assert.True(t, rule.IsComponentChanged(components[0], removed))
+}
+
+func TestRemove(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	app := composeComponent("app", testDocument)
+	cache := composeComponent("cache", "services:\n  cache:\n    image: memcached:1\n")
+	deployment, step := conformance.Deployment("default", "instance1", "update", app, cache)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(api.Containers))
+	assert.NotNil(t, api.Networks["instance1_default"])
+
+	// removing a component keeps the services and networks of the other components
+	deployment, step = conformance.Deployment("default", "instance1", "delete", app)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, result["app"].Status)
+	assert.Equal(t, 1, len(api.Containers))
+	assert.NotNil(t, api.Container("instance1-cache-1"))
+	assert.Equal(t, 3, len(api.Networks))
+
+	// removing the last component removes the networks, but keeps the volumes
+	deployment, step = conformance.Deployment("default", "instance1", "delete", cache)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(api.Containers))
+	assert.Equal(t, 0, len(api.Networks))
+	assert.Equal(t, 1, len(api.Volumes))
+
+	// removing a component that isn't deployed succeeds
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+}
+
+func TestInstanceIsolation(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	for _, instance := range []string{"instance1", "instance2"} {
+		deployment, step := conformance.Deployment("default", instance, "update", composeComponent("app", testDocument))
+		_, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 4, len(api.Containers))
+
+	deployment, step := conformance.Deployment("default", "instance1", "delete", composeComponent("app", testDocument))
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container("instance1-web-1"))
+	assert.NotNil(t, api.Container("instance2-web-1"))
+	assert.Nil(t, api.Networks["instance1_front"])
+	assert.NotNil(t, api.Networks["instance2_front"])
+}
+
+func TestScopeIsolation(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	for _, scope := range []string{"scope1", "scope2"} {
+		deployment, step := conformance.Deployment(scope, "instance1", "update", composeComponent("app", testDocument))
+		_, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 4, len(api.Containers))
+	web1 := api.Container(ContainerName(ProjectName("scope1", "instance1"), "web"))
+	web2 := api.Container(ContainerName(ProjectName("scope2", "instance1"), "web"))
+	if !assert.NotNil(t, web1) || !assert.NotNil(t, web2) {
+		return
+	}
+	assert.Equal(t, "scope1", web1.Config.Labels[scopeLabel])
+	assert.Equal(t, "scope2", web2.Config.Labels[scopeLabel])
+
+	// deploying one scope again leaves the containers of the other alone
+	deployment, step := conformance.Deployment("scope1", "instance1", "update", composeComponent("app", testDocument))
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, web2.ID, api.Container(ContainerName(ProjectName("scope2", "instance1"), "web")).ID)
+
+	deployment, step = conformance.Deployment("scope1", "instance1", "delete", composeComponent("app", testDocument))
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container(ContainerName(ProjectName("scope1", "instance1"), "web")))
+	assert.NotNil(t, api.Container(ContainerName(ProjectName("scope2", "instance1"), "web")))
+	assert.Equal(t, 2, len(api.Containers))
+}
+
+func TestApplyServiceConflict(t *testing.T) {
+	newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	deployment, step := conformance.Deployment("default", "instance1", "update",
+		composeComponent("app1", "services:\n  web:\n    image: nginx:1.25\n"),
+		composeComponent("app2", "services:\n  web:\n    image: nginx:1.25\n"))
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Updated, result["app1"].Status)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["app2"].Status)
+	assert.Contains(t, result["app2"].Message, "already defined by component 'app1'")
+}
+
+func TestApplyInvalidDocument(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+
+	documents := []string{
+		"services:\n  web:\n    image: nginx\n    restart: sometimes\n",
+		"services:\n  web:\n    image: nginx\n    ports: ['http:80']\n",
+		"services:\n  web:\n    image: nginx\n    healthcheck:\n      test: [CMD, 'true']\n      interval: often\n",
+	}
+	for _, document := range documents {
+		deployment, step := conformance.Deployment("default", "instance1", "update", composeComponent("app", document))
+		result, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err, document)
+		assert.Equal(t, v1alpha2.UpdateFailed, result["app"].Status, document)
+	}
+	assert.Equal(t, 0, len(api.CreatedNames))
+
+	deployment, step := conformance.Deployment("default", "instance1", "update", model.ComponentSpec{Name: "app", Properties: map[string]interface{}{}})
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestApplyCatalog(t *testing.T) {
+	api := newFakeDockerAPI(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/users/auth":
+			response = map[string]string{"accessToken": "test-token"}
+		case "/catalogs/registry/redis-compose":
+			response = model.CatalogState{
+				Id: "redis-compose",
+				Spec: &model.CatalogSpec{
+					Properties: map[string]interface{}{
+						"compose": map[string]interface{}{
+							"services": map[string]interface{}{
+								"db": map[string]interface{}{
+									"image":       "redis:7",
+									"environment": map[string]interface{}{"TARGET": "${{$target()}}"},
+								},
+							},
+						},
+					},
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+
+	provider := &ComposeTargetProvider{}
+	assert.Nil(t, provider.Init(ComposeTargetProviderConfig{}))
+	provider.SetContext(&contexts.ManagerContext{
+		SiteInfo: v1alpha2.SiteInfo{
+			CurrentSite: v1alpha2.SiteConnection{
+				BaseUrl:  ts.URL + "/",
+				Username: "admin",
+				Password: "",
+			},
+		},
+	})
+
+	component := model.ComponentSpec{
+		Name:       "cache",
+		Properties: map[string]interface{}{CatalogProperty: "<redis-compose>"},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	deployment.ActiveTarget = "target1"
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"TARGET=target1"}, api.Container("instance1-db-1").Config.Env)
+
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, "<redis-compose>", components[0].Properties[CatalogProperty])
+
+	// a missing catalog fails the deployment
+	component.Properties[CatalogProperty] = "other"
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &ComposeTargetProvider{}
+	err := provider.Init(ComposeTargetProviderConfig{})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
+
+func composeComponent(name string, document string) model.ComponentSpec {
+	return model.ComponentSpec{
+		Name: name,
+		Type: "docker-compose",
+		Properties: map[string]interface{}{
+			DocumentProperty: document,
+		},
+	}
+}
+
+func newFakeDockerAPI(t *testing.T) *dockertest.FakeDockerAPI {
+	api := dockertest.NewFakeDockerAPI(t)
+	api.RequirePull = true
+	return api
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/google/shlex"
+	"sigs.k8s.io/yaml"
+)
+
+// Document is the subset of the compose specification the provider supports
+type Document struct {
+	Services map[string]Service   `json:"services"`
+	Networks map[string]*Resource `json:"networks,omitempty"`
+	Volumes  map[string]*Resource `json:"volumes,omitempty"`
+}
+
+// Resource is a top-level network or volume
+type Resource struct {
+	Name     string    `json:"name,omitempty"`
+	Driver   string    `json:"driver,omitempty"`
+	External bool      `json:"external,omitempty"`
+	Labels   mapOrList `json:"labels,omitempty"`
+}
+
+// Service is a compose service. The build, deploy and extends keys aren't supported.
+type Service struct {
+	Image       string       `json:"image"`
+	Build       interface{}  `json:"build,omitempty"`
+	Command     shellCommand `json:"command,omitempty"`
+	Entrypoint  shellCommand `json:"entrypoint,omitempty"`
+	Environment mapOrList    `json:"environment,omitempty"`
+	Ports       []portSpec   `json:"ports,omitempty"`
+	Volumes     []volumeSpec `json:"volumes,omitempty"`
+	Networks    keyList      `json:"networks,omitempty"`
+	NetworkMode string       `json:"network_mode,omitempty"`
+	Restart     string       `json:"restart,omitempty"`
+	Labels      mapOrList    `json:"labels,omitempty"`
+	Healthcheck *healthcheck `json:"healthcheck,omitempty"`
+	DependsOn   keyList      `json:"depends_on,omitempty"`
+	User        string       `json:"user,omitempty"`
+	WorkingDir  string       `json:"working_dir,omitempty"`
+	Hostname    string       `json:"hostname,omitempty"`
+	Privileged  bool         `json:"privileged,omitempty"`
+}
+
+type healthcheck struct {
+	Test        shellCommand `json:"test,omitempty"`
+	Interval    string       `json:"interval,omitempty"`
+	Timeout     string       `json:"timeout,omitempty"`
+	StartPeriod string       `json:"start_period,omitempty"`
+	Retries     int          `json:"retries,omitempty"`
+	Disable     bool         `json:"disable,omitempty"`
+}
+
+// shellCommand is a command given either as a list or as a string, which is split like a shell would
+type shellCommand []string
+
+func (c *shellCommand) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*c = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	words, err := shlex.Split(s)
+	if err != nil {
+		return err
+	}
+	*c = words
+	return nil
+}
+
+// mapOrList is a map given either as a map or as a list of key=value pairs, such as environment
+// variables and labels
+type mapOrList map[string]string
+
+func (m *mapOrList) UnmarshalJSON(data []byte) error {
+	ret := make(mapOrList)
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		for _, item := range list {
+			pair := strings.SplitN(item, "=", 2)
+			if len(pair) == 2 {
+				ret[pair[0]] = pair[1]
+			} else {
+				ret[pair[0]] = ""
+			}
+		}
+		*m = ret
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for k, v := range values {
+		if v == nil {
+			ret[k] = ""
+		} else {
+			ret[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	*m = ret
+	return nil
+}
+
+// keyList is a list of names given either as a list or as the keys of a map, such as the networks and
+// the dependencies of a service
+type keyList []string
+
+func (k *keyList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*k = list
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	ret := make(keyList, 0, len(values))
+	for key := range values {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	*k = ret
+	return nil
+}
+
+// portSpec is a port in the short syntax, such as "127.0.0.1:8080:80/tcp". The long syntax is converted
+// to the short one.
+type portSpec string
+
+func (p *portSpec) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*p = portSpec(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*p = portSpec(n.String())
+		return nil
+	}
+	var long struct {
+		Target    json.Number `json:"target"`
+		Published json.Number `json:"published"`
+		Protocol  string      `json:"protocol"`
+		HostIP    string      `json:"host_ip"`
+	}
+	if err := json.Unmarshal(data, &long); err != nil {
+		return err
+	}
+	if long.Target == "" {
+		return fmt.Errorf("port %s has no target", string(data))
+	}
+	spec := long.Target.String()
+	if long.Published != "" {
+		spec = long.Published.String() + ":" + spec
+		if long.HostIP != "" {
+			spec = long.HostIP + ":" + spec
+		}
+	}
+	if long.Protocol != "" {
+		spec = spec + "/" + long.Protocol
+	}
+	*p = portSpec(spec)
+	return nil
+}
+
+// volumeSpec is a mount of a service
+type volumeSpec struct {
+	Type     string `json:"type"`
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+// UnmarshalJSON reads both the long syntax and the short one, such as "data:/var/lib/data:ro". A short
+// source that is a path is a bind mount, otherwise it names a volume.
+func (v *volumeSpec) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		type long volumeSpec
+		var l long
+		if err := json.Unmarshal(data, &l); err != nil {
+			return err
+		}
+		*v = volumeSpec(l)
+		if v.Type == "" {
+			v.Type = "volume"
+		}
+		return nil
+	}
+	parts := strings.Split(s, ":")
+	switch len(parts) {
+	case 1:
+		*v = volumeSpec{Type: "volume", Target: parts[0]}
+		return nil
+	case 2, 3:
+		*v = volumeSpec{Type: "volume", Source: parts[0], Target: parts[1]}
+		if isPath(parts[0]) {
+			v.Type = "bind"
+		}
+		if len(parts) == 3 {
+			for _, option := range strings.Split(parts[2], ",") {
+				switch option {
+				case "ro":
+					v.ReadOnly = true
+				case "rw":
+				default:
+					return fmt.Errorf("volume '%s' has an unsupported option '%s'", s, option)
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("volume '%s' is not valid", s)
+	}
+}
+
+func isPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~")
+}
+
+// ParseDocument parses and validates a compose document, given as YAML or JSON
+func ParseDocument(data string) (Document, error) {
+	ret := Document{}
+	if err := yaml.Unmarshal([]byte(data), &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, "compose document is not valid", v1alpha2.BadRequest)
+	}
+	if len(ret.Services) == 0 {
+		return ret, v1alpha2.NewCOAError(nil, "compose document doesn't have any services", v1alpha2.BadRequest)
+	}
+	for name, service := range ret.Services {
+		if service.Build != nil {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' builds its image, which is not supported", name), v1alpha2.BadRequest)
+		}
+		if service.Image == "" {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' doesn't have an image", name), v1alpha2.BadRequest)
+		}
+		if service.NetworkMode != "" && len(service.Networks) > 0 {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' has both network_mode and networks", name), v1alpha2.BadRequest)
+		}
+		for _, network := range service.Networks {
+			if _, ok := ret.Networks[network]; !ok && network != "default" {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' refers to undefined network '%s'", name, network), v1alpha2.BadRequest)
+			}
+		}
+		for _, volume := range service.Volumes {
+			switch volume.Type {
+			case "bind":
+				if !strings.HasPrefix(volume.Source, "/") {
+					return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' mounts '%s', but bind mounts must be absolute paths", name, volume.Source), v1alpha2.BadRequest)
+				}
+			case "volume":
+				if _, ok := ret.Volumes[volume.Source]; !ok && volume.Source != "" {
+					return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' refers to undefined volume '%s'", name, volume.Source), v1alpha2.BadRequest)
+				}
+			case "tmpfs":
+			default:
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' has a volume of unsupported type '%s'", name, volume.Type), v1alpha2.BadRequest)
+			}
+		}
+		for _, dependency := range service.DependsOn {
+			if _, ok := ret.Services[dependency]; !ok {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' depends on undefined service '%s'", name, dependency), v1alpha2.BadRequest)
+			}
+		}
+	}
+	if _, err := ret.StartOrder(); err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// StartOrder sorts the services so that each service comes after the services it depends on. Services
+// that don't depend on each other are sorted by name.
+func (d Document) StartOrder() ([]string, error) {
+	names := make([]string, 0, len(d.Services))
+	for name := range d.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]string, 0, len(names))
+	// 0: not visited, 1: visiting, 2: done
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("service '%s' has circular dependencies", name), v1alpha2.BadRequest)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		dependencies := append([]string{}, d.Services[name].DependsOn...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ret = append(ret, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
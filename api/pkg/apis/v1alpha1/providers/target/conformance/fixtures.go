@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package conformance
+
+import (
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+// Deployment builds a deployment of components to an instance of solution1 on target1, and the step
+// that applies action to each of them
+func Deployment(scope string, instance string, action string, components ...model.ComponentSpec) (model.DeploymentSpec, model.DeploymentStep) {
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{
+			Name:     instance,
+			Scope:    scope,
+			Solution: "solution1",
+		},
+		Solution: model.SolutionSpec{
+			Components: components,
+		},
+		ActiveTarget:        "target1",
+		ComponentStartIndex: 0,
+		ComponentEndIndex:   len(components),
+	}
+	step := model.DeploymentStep{}
+	for _, component := range components {
+		step.Components = append(step.Components, model.ComponentStep{
+			Action:    action,
+			Component: component,
+		})
+	}
+	return deployment, step
+}
+
+// FakeSecretProvider serves secrets from a map of objects to their fields
+type FakeSecretProvider struct {
+	Secrets map[string]map[string]string
+}
+
+func (f *FakeSecretProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (f *FakeSecretProvider) Get(object string, field string) (string, error) {
+	if v, ok := f.Secrets[object][field]; ok {
+		return v, nil
+	}
+	return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("secret %s/%s not found", object, field), v1alpha2.NotFound)
+}
@@ -8,14 +8,21 @@ package docker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/internal/dockerutil"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -26,6 +33,42 @@ import (
 
 var sLog = logger.NewLogger("coa.runtime")
 
+const (
+	instanceLabel  = "symphony.instance"
+	scopeLabel     = "symphony.scope"
+	solutionLabel  = "symphony.solution"
+	componentLabel = "symphony.component"
+	// configHashLabel is the hash of the container configuration, which tells whether a container
+	// needs to be recreated
+	configHashLabel = "symphony.config-hash"
+	// propertyLabelPrefix prefixes the labels that record the component properties a container was
+	// created with. Environment variables aren't recorded, as they may carry secrets.
+	propertyLabelPrefix = "symphony.property."
+)
+
+// recordedProperties are the component properties that are recorded in container labels
+var recordedProperties = []string{
+	model.ContainerImage,
+	"container.args",
+	"container.commands",
+	"container.healthcheck",
+	"container.labels",
+	"container.network",
+	"container.ports",
+	"container.resources",
+	"container.restartPolicy",
+	"container.volumeMounts",
+}
+
+// HealthCheck is the format of the container.healthcheck property
+type HealthCheck struct {
+	Test        []string `json:"test"`
+	Interval    string   `json:"interval,omitempty"`
+	Timeout     string   `json:"timeout,omitempty"`
+	StartPeriod string   `json:"startPeriod,omitempty"`
+	Retries     int      `json:"retries,omitempty"`
+}
+
 type DockerTargetProviderConfig struct {
 	Name string `json:"name"`
 }
@@ -91,6 +134,12 @@ func (i *DockerTargetProvider) Get(ctx context.Context, deployment model.Deploym
 
 	sLog.Infof("  P (Docker Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		sLog.Errorf("  P (Docker Target): failed to create docker client: %+v", err)
@@ -98,64 +147,69 @@ func (i *DockerTargetProvider) Get(ctx context.Context, deployment model.Deploym
 	}
 
 	ret := make([]model.ComponentSpec, 0)
-	for _, component := range references {
+	for _, reference := range references {
 		var info types.ContainerJSON
-		info, err = cli.ContainerInspect(ctx, component.Component.Name)
-		if err == nil {
-			name := info.Name
-			if len(name) > 0 && name[0] == '/' {
-				name = name[1:]
-			}
-			component := model.ComponentSpec{
-				Name:       name,
-				Properties: make(map[string]interface{}),
-			}
-			// container.args
-			if len(info.Args) > 0 {
-				argsData, _ := json.Marshal(info.Args)
-				component.Properties["container.args"] = string(argsData)
-			}
-			// container.image
-			component.Properties[model.ContainerImage] = info.Config.Image
-			if info.HostConfig != nil {
-				resources, _ := json.Marshal(info.HostConfig.Resources)
-				component.Properties["container.resources"] = string(resources)
+		info, err = inspectContainer(ctx, cli, deployment, reference.Component.Name)
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				err = nil
+				continue
 			}
-			// container.ports
-			if info.NetworkSettings != nil && len(info.NetworkSettings.Ports) > 0 {
-				ports, _ := json.Marshal(info.NetworkSettings.Ports)
-				component.Properties["container.ports"] = string(ports)
-			}
-			// container.cmd
-			if len(info.Config.Cmd) > 0 {
-				cmdData, _ := json.Marshal(info.Config.Cmd)
-				component.Properties["container.commands"] = string(cmdData)
-			}
-			// container.volumeMounts
-			if len(info.Mounts) > 0 {
-				volumeData, _ := json.Marshal(info.Mounts)
-				component.Properties["container.volumeMounts"] = string(volumeData)
+			sLog.Errorf("  P (Docker Target): failed to inspect container: %+v", err)
+			return nil, err
+		}
+		component := model.ComponentSpec{
+			Name:       reference.Component.Name,
+			Properties: make(map[string]interface{}),
+		}
+		// container.args
+		if len(info.Config.Cmd) > 0 {
+			argsData, _ := json.Marshal(info.Config.Cmd)
+			component.Properties["container.args"] = string(argsData)
+		}
+		// container.image
+		component.Properties[model.ContainerImage] = info.Config.Image
+		if info.HostConfig != nil {
+			resources, _ := json.Marshal(info.HostConfig.Resources)
+			component.Properties["container.resources"] = string(resources)
+		}
+		// container.ports
+		if info.NetworkSettings != nil && len(info.NetworkSettings.Ports) > 0 {
+			ports, _ := json.Marshal(info.NetworkSettings.Ports)
+			component.Properties["container.ports"] = string(ports)
+		}
+		// container.commands
+		if len(info.Config.Entrypoint) > 0 {
+			cmdData, _ := json.Marshal(info.Config.Entrypoint)
+			component.Properties["container.commands"] = string(cmdData)
+		}
+		// container.volumeMounts
+		if len(info.Mounts) > 0 {
+			volumeData, _ := json.Marshal(info.Mounts)
+			component.Properties["container.volumeMounts"] = string(volumeData)
+		}
+		// the properties a container was created with are recorded in its labels, so that they compare
+		// equal to the desired properties, whichever way Docker reports them
+		for k, v := range info.Config.Labels {
+			if strings.HasPrefix(k, propertyLabelPrefix) {
+				component.Properties[strings.TrimPrefix(k, propertyLabelPrefix)] = v
 			}
-			// get environment varibles that are passed in by the reference
-			env := info.Config.Env
-			if len(env) > 0 {
-				for _, e := range env {
-					pair := strings.Split(e, "=")
-					if len(pair) == 2 {
-						for _, s := range references {
-							if s.Component.Name == component.Name {
-								for k, _ := range s.Component.Properties {
-									if k == "env."+pair[0] {
-										component.Properties[k] = pair[1]
-									}
-								}
-							}
-						}
+		}
+		// get environment varibles that are passed in by the reference. A variable that matches the
+		// reference once values are injected is returned as in the reference.
+		for _, e := range info.Config.Env {
+			pair := strings.SplitN(e, "=", 2)
+			if len(pair) == 2 {
+				if v, ok := reference.Component.Properties["env."+pair[0]]; ok {
+					if model.ResolveString(fmt.Sprintf("%v", v), injections) == pair[1] {
+						component.Properties["env."+pair[0]] = v
+					} else {
+						component.Properties["env."+pair[0]] = pair[1]
 					}
 				}
 			}
-			ret = append(ret, component)
 		}
+		ret = append(ret, component)
 	}
 
 	return ret, nil
@@ -196,44 +250,37 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 
 	for _, component := range step.Components {
 		if component.Action == "update" {
-			image := model.ReadPropertyCompat(component.Component.Properties, model.ContainerImage, injections)
-			resources := model.ReadPropertyCompat(component.Component.Properties, "container.resources", injections)
-			if image == "" {
-				err = errors.New("component doesn't have container.image property")
+			var spec containerSpec
+			spec, err = buildContainerSpec(deployment, component.Component, injections)
+			if err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
 					Message: err.Error(),
 				}
-				sLog.Errorf("  P (Helm Target): component doesn't have container.image property")
+				sLog.Errorf("  P (Docker Target): failed to read container properties: %+v", err)
 				return ret, err
 			}
 
-			alreadyRunning := true
-			_, err = cli.ContainerInspect(ctx, component.Component.Name)
-			if err != nil { //TODO: check if the error is ErrNotFound
-				alreadyRunning = false
+			var info types.ContainerJSON
+			info, err = inspectContainer(ctx, cli, deployment, component.Component.Name)
+			if err != nil && !client.IsErrNotFound(err) {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Docker Target): failed to inspect container: %+v", err)
+				return ret, err
 			}
-
-			// TODO: I don't think we need to do an explict image pull here, as Docker will pull the image upon cache miss
-			// reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
-			// if err != nil {
-			// 	observ_utils.CloseSpanWithError(span, &err)
-			// 	sLog.Errorf("  P (Docker Target): failed to pull docker image: %+v", err)
-			// 	return err
-			// }
-
-			// defer reader.Close()
-			// io.Copy(os.Stdout, reader)
-
-			if alreadyRunning {
-				err = cli.ContainerStop(context.TODO(), component.Component.Name, nil)
-				if err != nil {
-					if !client.IsErrNotFound(err) {
-						sLog.Errorf("  P (Docker Target): failed to stop a running container: %+v", err)
-						return ret, err
+			if err == nil {
+				if info.Config != nil && info.Config.Labels[configHashLabel] == spec.Config.Labels[configHashLabel] && info.State != nil && info.State.Running {
+					sLog.Infof("  P (Docker Target): container %s is up to date", spec.Name)
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.Updated,
+						Message: "",
 					}
+					continue
 				}
-				err = cli.ContainerRemove(context.TODO(), component.Component.Name, types.ContainerRemoveOptions{})
+				err = dockerutil.RemoveContainer(ctx, cli, info.ID)
 				if err != nil {
 					ret[component.Component.Name] = model.ComponentResultSpec{
 						Status:  v1alpha2.UpdateFailed,
@@ -244,36 +291,8 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				}
 			}
 
-			// prepare environment variables
-			env := make([]string, 0)
-			for k, v := range component.Component.Properties {
-				if strings.HasPrefix(k, "env.") {
-					env = append(env, strings.TrimPrefix(k, "env.")+"="+v.(string))
-				}
-			}
-
-			containerConfig := container.Config{
-				Image: image,
-				Env:   env,
-			}
-			var hostConfig *container.HostConfig
-			if resources != "" {
-				var resourceSpec container.Resources
-				err = json.Unmarshal([]byte(resources), &resourceSpec)
-				if err != nil {
-					ret[component.Component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.UpdateFailed,
-						Message: err.Error(),
-					}
-					sLog.Errorf("  P (Docker Target): failed to read container resource settings: %+v", err)
-					return ret, err
-				}
-				hostConfig = &container.HostConfig{
-					Resources: resourceSpec,
-				}
-			}
-			var container container.ContainerCreateCreatedBody
-			container, err = cli.ContainerCreate(context.TODO(), &containerConfig, hostConfig, nil, nil, component.Component.Name)
+			var created container.ContainerCreateCreatedBody
+			created, err = cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, nil, nil, spec.Name)
 			if err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
@@ -283,7 +302,7 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				return ret, err
 			}
 
-			if err = cli.ContainerStart(context.TODO(), container.ID, types.ContainerStartOptions{}); err != nil {
+			if err = cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
 					Message: err.Error(),
@@ -296,19 +315,21 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 				Message: "",
 			}
 		} else {
-			err = cli.ContainerStop(context.TODO(), component.Component.Name, nil)
-			if err != nil {
-				if !client.IsErrNotFound(err) {
-					sLog.Errorf("  P (Docker Target): failed to stop a running container: %+v", err)
-					return ret, err
-				}
+			var info types.ContainerJSON
+			info, err = inspectContainer(ctx, cli, deployment, component.Component.Name)
+			if err == nil {
+				err = dockerutil.RemoveContainer(ctx, cli, info.ID)
 			}
-			err = cli.ContainerRemove(context.TODO(), component.Component.Name, types.ContainerRemoveOptions{})
 			if err != nil {
 				if !client.IsErrNotFound(err) {
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.DeleteFailed,
+						Message: err.Error(),
+					}
 					sLog.Errorf("  P (Docker Target): failed to remove existing container: %+v", err)
 					return ret, err
 				}
+				err = nil
 			}
 			ret[component.Component.Name] = model.ComponentResultSpec{
 				Status:  v1alpha2.Deleted,
@@ -319,17 +340,249 @@ func (i *DockerTargetProvider) Apply(ctx context.Context, deployment model.Deplo
 	return ret, nil
 }
 
+// ContainerName is the name of the container of an instance's component. Names join the instance and
+// the component for readability, and end with a hash of the scope, the instance and the component, so
+// that names stay unique whatever the names contain. The ownership labels, not the name, tell which
+// component a container belongs to.
+func ContainerName(scope string, instance string, component string) string {
+	if instance == "" {
+		return component
+	}
+	hash := sha256.Sum256([]byte(scope + "\x00" + instance + "\x00" + component))
+	return fmt.Sprintf("%s-%s-%s", instance, component, hex.EncodeToString(hash[:])[:8])
+}
+
+// inspectContainer finds the container of a component. Containers named after the instance and the
+// component only are found if their ownership labels match, and containers created before they were
+// named after their instance are found if they have no instance label. A container that has the name
+// but belongs to another component is never returned.
+func inspectContainer(ctx context.Context, cli *client.Client, deployment model.DeploymentSpec, component string) (types.ContainerJSON, error) {
+	name := ContainerName(deployment.Instance.Scope, deployment.Instance.Name, component)
+	info, err := cli.ContainerInspect(ctx, name)
+	if err == nil {
+		if !ownsContainer(info, deployment, component) {
+			return info, v1alpha2.NewCOAError(nil, fmt.Sprintf("container %s belongs to another component", name), v1alpha2.InternalError)
+		}
+		return info, nil
+	}
+	if !client.IsErrNotFound(err) || name == component {
+		return info, err
+	}
+	if previous, previousErr := cli.ContainerInspect(ctx, deployment.Instance.Name+"-"+component); previousErr == nil && ownsContainer(previous, deployment, component) {
+		return previous, nil
+	}
+	legacy, legacyErr := cli.ContainerInspect(ctx, component)
+	if legacyErr == nil && legacy.Config != nil && legacy.Config.Labels[instanceLabel] == "" {
+		return legacy, nil
+	}
+	return info, err
+}
+
+// ownsContainer checks the ownership labels of a container
+func ownsContainer(info types.ContainerJSON, deployment model.DeploymentSpec, component string) bool {
+	if info.Config == nil {
+		return false
+	}
+	labels := info.Config.Labels
+	return labels[instanceLabel] == deployment.Instance.Name && labels[scopeLabel] == deployment.Instance.Scope && labels[componentLabel] == component
+}
+
 func (*DockerTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
-		RequiredProperties:    []string{model.ContainerImage},
-		OptionalProperties:    []string{"container.resources"},
+		RequiredProperties: []string{model.ContainerImage},
+		OptionalProperties: []string{
+			"container.args",
+			"container.commands",
+			"container.healthcheck",
+			"container.labels",
+			"container.network",
+			"container.ports",
+			"container.resources",
+			"container.restartPolicy",
+			"container.volumeMounts",
+			"env.*",
+		},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
 		ChangeDetectionProperties: []model.PropertyDesc{
 			{Name: model.ContainerImage, IgnoreCase: false, SkipIfMissing: false},
+			{Name: "container.args", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.commands", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.healthcheck", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.labels", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.network", IgnoreCase: false, SkipIfMissing: true},
 			{Name: "container.ports", IgnoreCase: false, SkipIfMissing: true},
 			{Name: "container.resources", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.restartPolicy", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.volumeMounts", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "env.*", IgnoreCase: false, SkipIfMissing: true},
+		},
+		InstanceIsolation: true,
+	}
+}
+
+type containerSpec struct {
+	Name       string
+	Config     *container.Config
+	HostConfig *container.HostConfig
+}
+
+// buildContainerSpec reads the container configuration from a component's properties. JSON properties
+// take the formats of the Docker API, except for container.healthcheck, which takes durations such as
+// "30s".
+func buildContainerSpec(deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) (containerSpec, error) {
+	spec := containerSpec{
+		Name: ContainerName(deployment.Instance.Scope, deployment.Instance.Name, component.Name),
+		Config: &container.Config{
+			Image:  model.ReadPropertyCompat(component.Properties, model.ContainerImage, injections),
+			Labels: make(map[string]string),
 		},
+		HostConfig: &container.HostConfig{},
+	}
+	if spec.Config.Image == "" {
+		return spec, v1alpha2.NewCOAError(nil, "component doesn't have container.image property", v1alpha2.BadRequest)
+	}
+
+	// prepare environment variables
+	env := make([]string, 0)
+	for k, v := range component.Properties {
+		if strings.HasPrefix(k, "env.") {
+			env = append(env, strings.TrimPrefix(k, "env.")+"="+model.ResolveString(fmt.Sprintf("%v", v), injections))
+		}
+	}
+	sort.Strings(env)
+	spec.Config.Env = env
+
+	if err := readJSONProperty(component, "container.commands", injections, &spec.Config.Entrypoint); err != nil {
+		return spec, err
+	}
+	if err := readJSONProperty(component, "container.args", injections, &spec.Config.Cmd); err != nil {
+		return spec, err
+	}
+	if err := readJSONProperty(component, "container.resources", injections, &spec.HostConfig.Resources); err != nil {
+		return spec, err
+	}
+	if err := readJSONProperty(component, "container.volumeMounts", injections, &spec.HostConfig.Mounts); err != nil {
+		return spec, err
+	}
+	if err := readJSONProperty(component, "container.labels", injections, &spec.Config.Labels); err != nil {
+		return spec, err
+	}
+	var ports nat.PortMap
+	if err := readJSONProperty(component, "container.ports", injections, &ports); err != nil {
+		return spec, err
+	}
+	if len(ports) > 0 {
+		spec.HostConfig.PortBindings = ports
+		spec.Config.ExposedPorts = make(nat.PortSet)
+		for port := range ports {
+			spec.Config.ExposedPorts[port] = struct{}{}
+		}
+	}
+	if network := model.ReadPropertyCompat(component.Properties, "container.network", injections); network != "" {
+		spec.HostConfig.NetworkMode = container.NetworkMode(network)
 	}
+	if policy := model.ReadPropertyCompat(component.Properties, "container.restartPolicy", injections); policy != "" {
+		restartPolicy, err := ParseRestartPolicy(policy)
+		if err != nil {
+			return spec, err
+		}
+		spec.HostConfig.RestartPolicy = restartPolicy
+	}
+	var healthCheck HealthCheck
+	if err := readJSONProperty(component, "container.healthcheck", injections, &healthCheck); err != nil {
+		return spec, err
+	}
+	if len(healthCheck.Test) > 0 {
+		healthConfig, err := healthCheck.ToHealthConfig()
+		if err != nil {
+			return spec, err
+		}
+		spec.Config.Healthcheck = healthConfig
+	}
+
+	if spec.Config.Labels == nil {
+		spec.Config.Labels = make(map[string]string)
+	}
+	spec.Config.Labels[instanceLabel] = deployment.Instance.Name
+	spec.Config.Labels[scopeLabel] = deployment.Instance.Scope
+	spec.Config.Labels[solutionLabel] = deployment.Instance.Solution
+	spec.Config.Labels[componentLabel] = component.Name
+	for _, property := range recordedProperties {
+		if v, ok := component.Properties[property]; ok {
+			spec.Config.Labels[propertyLabelPrefix+property] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	// the hash covers the resolved configuration, so it also changes when an environment variable or
+	// an injected value does
+	data, _ := json.Marshal(spec)
+	hash := sha256.Sum256(data)
+	spec.Config.Labels[configHashLabel] = hex.EncodeToString(hash[:])
+	return spec, nil
+}
+
+func readJSONProperty(component model.ComponentSpec, key string, injections *model.ValueInjections, target interface{}) error {
+	v := model.ReadPropertyCompat(component.Properties, key, injections)
+	if v == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(v), target); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("property '%s' is not valid", key), v1alpha2.BadRequest)
+	}
+	return nil
+}
+
+// ParseRestartPolicy parses restart policies in the format of docker run's --restart flag, such as
+// "unless-stopped" or "on-failure:3"
+func ParseRestartPolicy(policy string) (container.RestartPolicy, error) {
+	ret := container.RestartPolicy{}
+	parts := strings.SplitN(policy, ":", 2)
+	switch parts[0] {
+	case "no", "always", "unless-stopped":
+		if len(parts) > 1 {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' doesn't take a retry count", parts[0]), v1alpha2.BadRequest)
+		}
+	case "on-failure":
+		if len(parts) > 1 {
+			count, err := strconv.Atoi(parts[1])
+			if err != nil || count < 0 {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' has an invalid retry count", policy), v1alpha2.BadRequest)
+			}
+			ret.MaximumRetryCount = count
+		}
+	default:
+		return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' is not supported", policy), v1alpha2.BadRequest)
+	}
+	ret.Name = parts[0]
+	return ret, nil
+}
+
+// ToHealthConfig converts a health check to the Docker API format
+func (h HealthCheck) ToHealthConfig() (*container.HealthConfig, error) {
+	ret := &container.HealthConfig{
+		Test:    h.Test,
+		Retries: h.Retries,
+	}
+	durations := []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"interval", h.Interval, &ret.Interval},
+		{"timeout", h.Timeout, &ret.Timeout},
+		{"startPeriod", h.StartPeriod, &ret.StartPeriod},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("health check %s '%s' is not a valid duration", d.name, d.value), v1alpha2.BadRequest)
+		}
+		*d.target = duration
+	}
+	return ret, nil
 }
@@ -10,9 +10,15 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/internal/dockertest"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -147,11 +153,12 @@ func TestDockerTargetProviderRemove(t *testing.T) {
 }
 
 func TestGet(t *testing.T) {
+	dockertest.NewFakeDockerAPI(t)
 	config := DockerTargetProviderConfig{}
 	provider := DockerTargetProvider{}
 	err := provider.Init(config)
 	assert.Nil(t, err)
-	_, err = provider.Get(context.Background(), model.DeploymentSpec{
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{
 		Solution: model.SolutionSpec{
 			Components: []model.ComponentSpec{
 				{
@@ -177,9 +184,11 @@ func TestGet(t *testing.T) {
 		},
 	})
 	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
 }
 
 func TestApply(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
 	config := DockerTargetProviderConfig{}
 	provider := DockerTargetProvider{}
 	err := provider.Init(config)
@@ -205,7 +214,8 @@ func TestApply(t *testing.T) {
 		},
 	}
 	_, err = provider.Apply(context.Background(), deployment, step, false)
-	assert.NotNil(t, err)
+	assert.Nil(t, err)
+	assert.NotNil(t, api.Container("redis-test"))
 
 	step = model.DeploymentStep{
 		Components: []model.ComponentStep{
@@ -217,6 +227,253 @@ func TestApply(t *testing.T) {
 	}
 	_, err = provider.Apply(context.Background(), deployment, step, false)
 	assert.Nil(t, err)
+	assert.Nil(t, api.Container("redis-test"))
+
+	// deleting a container that doesn't exist succeeds
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+}
+
+func fullComponent() model.ComponentSpec {
+	return model.ComponentSpec{
+		Name: "web",
+		Type: "container",
+		Properties: map[string]interface{}{
+			model.ContainerImage:      "nginx:1.25",
+			"container.ports":         `{"80/tcp":[{"HostIp":"","HostPort":"8080"}]}`,
+			"container.volumeMounts":  `[{"Type":"bind","Source":"/srv/web","Target":"/usr/share/nginx/html","ReadOnly":true}]`,
+			"container.commands":      `["nginx"]`,
+			"container.args":          `["-g","daemon off;"]`,
+			"container.network":       "edge",
+			"container.restartPolicy": "on-failure:3",
+			"container.labels":        `{"tier":"frontend"}`,
+			"container.healthcheck":   `{"test":["CMD","curl","-f","http://localhost"],"interval":"30s","timeout":"5s","retries":3}`,
+			"env.SITE":                "${{$instance()}}",
+		},
+	}
+}
+
+func applyComponent(provider *DockerTargetProvider, instance string, component model.ComponentSpec, action string) (map[string]model.ComponentResultSpec, error) {
+	return applyScopedComponent(provider, "default", instance, component, action)
+}
+
+func applyScopedComponent(provider *DockerTargetProvider, scope string, instance string, component model.ComponentSpec, action string) (map[string]model.ComponentResultSpec, error) {
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{Name: instance, Scope: scope, Solution: "solution1"},
+		Solution: model.SolutionSpec{Components: []model.ComponentSpec{component}},
+	}
+	step := model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: action, Component: component}},
+	}
+	return provider.Apply(context.Background(), deployment, step, false)
+}
+
+func TestApplyContainerProperties(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	_, err := applyComponent(provider, "instance1", fullComponent(), "update")
+	assert.Nil(t, err)
+
+	c := api.Container(ContainerName("default", "instance1", "web"))
+	if !assert.NotNil(t, c) {
+		return
+	}
+	assert.Equal(t, "running", c.State)
+	assert.Equal(t, "nginx:1.25", c.Config.Image)
+	assert.Equal(t, []string{"nginx"}, []string(c.Config.Entrypoint))
+	assert.Equal(t, []string{"-g", "daemon off;"}, []string(c.Config.Cmd))
+	assert.Equal(t, []string{"SITE=instance1"}, c.Config.Env)
+	assert.Contains(t, c.Config.ExposedPorts, nat.Port("80/tcp"))
+	assert.Equal(t, "8080", c.HostConfig.PortBindings["80/tcp"][0].HostPort)
+	assert.Equal(t, []mount.Mount{{Type: mount.TypeBind, Source: "/srv/web", Target: "/usr/share/nginx/html", ReadOnly: true}}, c.HostConfig.Mounts)
+	assert.Equal(t, container.NetworkMode("edge"), c.HostConfig.NetworkMode)
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, c.HostConfig.RestartPolicy)
+	assert.Equal(t, []string{"CMD", "curl", "-f", "http://localhost"}, c.Config.Healthcheck.Test)
+	assert.Equal(t, 30*time.Second, c.Config.Healthcheck.Interval)
+	assert.Equal(t, 5*time.Second, c.Config.Healthcheck.Timeout)
+	assert.Equal(t, 3, c.Config.Healthcheck.Retries)
+	assert.Equal(t, "frontend", c.Config.Labels["tier"])
+	assert.Equal(t, "instance1", c.Config.Labels[instanceLabel])
+	assert.Equal(t, "default", c.Config.Labels[scopeLabel])
+	assert.Equal(t, "solution1", c.Config.Labels[solutionLabel])
+	assert.Equal(t, "web", c.Config.Labels[componentLabel])
+}
+
+func TestApplyRecreatesOnlyChangedContainers(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	component := fullComponent()
+	_, err := applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+	_, err = applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(api.CreatedNames))
+
+	component.Properties["env.SITE"] = "changed"
+	_, err = applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(api.CreatedNames))
+	assert.Equal(t, []string{"SITE=changed"}, api.Container(ContainerName("default", "instance1", "web")).Config.Env)
+
+	// a stopped container is recreated even if it hasn't changed
+	api.Container(ContainerName("default", "instance1", "web")).State = "exited"
+	_, err = applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(api.CreatedNames))
+}
+
+func TestInstanceIsolation(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	_, err := applyComponent(provider, "instance1", fullComponent(), "update")
+	assert.Nil(t, err)
+	_, err = applyComponent(provider, "instance2", fullComponent(), "update")
+	assert.Nil(t, err)
+	assert.NotNil(t, api.Container(ContainerName("default", "instance1", "web")))
+	assert.NotNil(t, api.Container(ContainerName("default", "instance2", "web")))
+
+	_, err = applyComponent(provider, "instance1", fullComponent(), "delete")
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container(ContainerName("default", "instance1", "web")))
+	assert.NotNil(t, api.Container(ContainerName("default", "instance2", "web")))
+}
+
+func TestScopeIsolation(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	_, err := applyScopedComponent(provider, "scope1", "instance1", fullComponent(), "update")
+	assert.Nil(t, err)
+	_, err = applyScopedComponent(provider, "scope2", "instance1", fullComponent(), "update")
+	assert.Nil(t, err)
+	assert.NotEqual(t, ContainerName("scope1", "instance1", "web"), ContainerName("scope2", "instance1", "web"))
+	assert.Equal(t, "scope1", api.Container(ContainerName("scope1", "instance1", "web")).Config.Labels[scopeLabel])
+	assert.Equal(t, "scope2", api.Container(ContainerName("scope2", "instance1", "web")).Config.Labels[scopeLabel])
+
+	_, err = applyScopedComponent(provider, "scope1", "instance1", fullComponent(), "delete")
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container(ContainerName("scope1", "instance1", "web")))
+	assert.NotNil(t, api.Container(ContainerName("scope2", "instance1", "web")))
+}
+
+func TestContainerName(t *testing.T) {
+	// instance and component names that join to the same string still get different names
+	assert.NotEqual(t, ContainerName("default", "a-b", "c"), ContainerName("default", "a", "b-c"))
+	assert.Equal(t, ContainerName("default", "a", "b"), ContainerName("default", "a", "b"))
+	assert.Equal(t, "web", ContainerName("default", "", "web"))
+}
+
+func TestApplyKeepsForeignContainer(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	// a container of another component that has the name is neither replaced nor removed
+	name := ContainerName("default", "instance1", "web")
+	api.Add(name, &container.Config{Image: "nginx:1.24", Labels: map[string]string{instanceLabel: "instance2", scopeLabel: "default", componentLabel: "web"}}, &container.HostConfig{})
+	ret, err := applyComponent(provider, "instance1", fullComponent(), "update")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["web"].Status)
+	_, err = applyComponent(provider, "instance1", fullComponent(), "delete")
+	assert.NotNil(t, err)
+	assert.Equal(t, "nginx:1.24", api.Container(name).Config.Image)
+}
+
+func TestGetRecordedProperties(t *testing.T) {
+	dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	component := fullComponent()
+	_, err := applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+
+	deployment := model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1", Scope: "default"}}
+	components, err := provider.Get(context.Background(), deployment, []model.ComponentStep{{Action: "update", Component: component}})
+	assert.Nil(t, err)
+	if !assert.Equal(t, 1, len(components)) {
+		return
+	}
+	assert.Equal(t, "web", components[0].Name)
+	for property, value := range component.Properties {
+		assert.Equal(t, value, components[0].Properties[property], property)
+	}
+
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+	component.Properties["container.ports"] = `{"80/tcp":[{"HostIp":"","HostPort":"9090"}]}`
+	assert.True(t, rule.IsComponentChanged(components[0], component))
+}
+
+func TestGetLegacyContainer(t *testing.T) {
+	api := dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	// containers created before they were named after their instance are still found, and replaced
+	api.Add("web", &container.Config{Image: "nginx:1.24"}, &container.HostConfig{})
+	component := fullComponent()
+	deployment := model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1", Scope: "default"}}
+	components, err := provider.Get(context.Background(), deployment, []model.ComponentStep{{Action: "update", Component: component}})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(components)) {
+		assert.Equal(t, "nginx:1.24", components[0].Properties[model.ContainerImage])
+	}
+
+	_, err = applyComponent(provider, "instance1", component, "update")
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container("web"))
+	assert.NotNil(t, api.Container(ContainerName("default", "instance1", "web")))
+
+	// so are containers named after the instance and the component only, if their labels match
+	api.Add("instance1-db", &container.Config{Image: "postgres:15", Labels: map[string]string{instanceLabel: "instance1", scopeLabel: "default", componentLabel: "db"}}, &container.HostConfig{})
+	api.Add("instance2-db", &container.Config{Image: "postgres:15", Labels: map[string]string{instanceLabel: "instance3", scopeLabel: "default", componentLabel: "db"}}, &container.HostConfig{})
+	db := model.ComponentSpec{Name: "db", Type: "container", Properties: map[string]interface{}{model.ContainerImage: "postgres:16"}}
+	_, err = applyComponent(provider, "instance1", db, "update")
+	assert.Nil(t, err)
+	assert.Nil(t, api.Container("instance1-db"))
+	assert.Equal(t, "postgres:16", api.Container(ContainerName("default", "instance1", "db")).Config.Image)
+	_, err = applyComponent(provider, "instance2", db, "update")
+	assert.Nil(t, err)
+	assert.NotNil(t, api.Container("instance2-db"))
+}
+
+func TestApplyInvalidProperties(t *testing.T) {
+	dockertest.NewFakeDockerAPI(t)
+	provider := &DockerTargetProvider{}
+	assert.Nil(t, provider.Init(DockerTargetProviderConfig{}))
+
+	for property, value := range map[string]string{
+		"container.ports":         "8080",
+		"container.restartPolicy": "sometimes",
+		"container.healthcheck":   `{"test":["CMD","true"],"interval":"often"}`,
+	} {
+		component := fullComponent()
+		component.Properties[property] = value
+		ret, err := applyComponent(provider, "instance1", component, "update")
+		assert.NotNil(t, err, property)
+		assert.Equal(t, v1alpha2.UpdateFailed, ret["web"].Status, property)
+	}
+}
+
+func TestParseRestartPolicy(t *testing.T) {
+	policy, err := ParseRestartPolicy("unless-stopped")
+	assert.Nil(t, err)
+	assert.Equal(t, container.RestartPolicy{Name: "unless-stopped"}, policy)
+	policy, err = ParseRestartPolicy("on-failure")
+	assert.Nil(t, err)
+	assert.Equal(t, container.RestartPolicy{Name: "on-failure"}, policy)
+	_, err = ParseRestartPolicy("always:3")
+	assert.NotNil(t, err)
+	_, err = ParseRestartPolicy("on-failure:x")
+	assert.NotNil(t, err)
 }
 
 func TestConformanceSuite(t *testing.T) {
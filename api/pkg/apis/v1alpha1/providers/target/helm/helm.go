@@ -8,6 +8,7 @@ package helm
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,14 +17,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
-	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/google/uuid"
@@ -31,11 +35,18 @@ import (
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
 )
 
 var sLog = logger.NewLogger("coa.runtime")
@@ -43,6 +54,15 @@ var sLog = logger.NewLogger("coa.runtime")
 const (
 	DEFAULT_NAMESPACE = "default"
 	TEMP_CHART_DIR    = "/tmp/symphony/charts"
+	// DEFAULT_TIMEOUT is how long Helm waits for resources, hooks and rollbacks of charts without a timeout
+	DEFAULT_TIMEOUT = 5 * time.Minute
+	// DEFAULT_VALUES_KEY is the key of a Secret that holds chart values when a values source doesn't name one
+	DEFAULT_VALUES_KEY = "values.yaml"
+
+	// repoTagPrefix marks the chart metadata tag the provider remembers the chart repo in
+	repoTagPrefix = "SYM:"
+	// postRendererAnnotation is the chart annotation the provider remembers the post renderer in
+	postRendererAnnotation = "symphony/post-renderer"
 )
 
 type (
@@ -62,17 +82,40 @@ type (
 		InstallClient   *action.Install
 		UpgradeClient   *action.Upgrade
 		UninstallClient *action.Uninstall
+		GetClient       *action.Get
+		// KubeClient reads the Secrets of values sources and chart repo credentials. It's created from the
+		// Helm configuration when it isn't set.
+		KubeClient   kubernetes.Interface
+		actionConfig *action.Configuration
 	}
 	// HelmProperty is the property for the Helm chart
 	HelmProperty struct {
-		Chart  HelmChartProperty      `json:"chart"`
-		Values map[string]interface{} `json:"values,omitempty"`
+		Chart        HelmChartProperty      `json:"chart"`
+		Values       map[string]interface{} `json:"values,omitempty"`
+		ValuesFrom   []HelmValuesSource     `json:"valuesFrom,omitempty"`
+		PostRenderer *HelmPostRenderer      `json:"postRenderer,omitempty"`
 	}
 	// HelmChartProperty is the property for the Helm Charts
 	HelmChartProperty struct {
-		Repo    string `json:"repo"`
-		Version string `json:"version"`
-		Wait    bool   `json:"wait"`
+		Repo            string `json:"repo"`
+		Version         string `json:"version"`
+		Wait            bool   `json:"wait"`
+		Atomic          bool   `json:"atomic,omitempty"`
+		Timeout         string `json:"timeout,omitempty"`
+		CreateNamespace *bool  `json:"createNamespace,omitempty"`
+		AuthSecret      string `json:"authSecret,omitempty"`
+	}
+	// HelmValuesSource is a source of chart values, either a catalog or a Kubernetes Secret
+	HelmValuesSource struct {
+		Catalog  string `json:"catalog,omitempty"`
+		Secret   string `json:"secret,omitempty"`
+		Key      string `json:"key,omitempty"`
+		Optional bool   `json:"optional,omitempty"`
+	}
+	// HelmPostRenderer is a command the rendered manifests of a chart are piped through before they're applied
+	HelmPostRenderer struct {
+		Command string   `json:"command"`
+		Args    []string `json:"args,omitempty"`
 	}
 )
 
@@ -133,7 +176,7 @@ func (i *HelmTargetProvider) Init(config providers.IProviderConfig) error {
 		},
 	)
 	var err error
-	defer utils.CloseSpanWithError(span, &err)
+	defer observ_utils.CloseSpanWithError(span, &err)
 	sLog.Info("  P (Helm Target): Init()")
 
 	err = initChartsDir()
@@ -190,11 +233,18 @@ func (i *HelmTargetProvider) Init(config providers.IProviderConfig) error {
 		}
 	}
 
+	i.setActionConfig(actionConfig)
+	return nil
+}
+
+// setActionConfig creates the Helm clients of the provider
+func (i *HelmTargetProvider) setActionConfig(actionConfig *action.Configuration) {
+	i.actionConfig = actionConfig
 	i.ListClient = action.NewList(actionConfig)
 	i.InstallClient = action.NewInstall(actionConfig)
 	i.UninstallClient = action.NewUninstall(actionConfig)
 	i.UpgradeClient = action.NewUpgrade(actionConfig)
-	return nil
+	i.GetClient = action.NewGet(actionConfig)
 }
 
 // getActionConfig returns an action configuration
@@ -238,7 +288,7 @@ func (i *HelmTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 		},
 	)
 	var err error
-	defer utils.CloseSpanWithError(span, &err)
+	defer observ_utils.CloseSpanWithError(span, &err)
 	sLog.Infof("  P (Helm Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 	i.ListClient.Deployed = true
 	var results []*release.Release
@@ -252,22 +302,7 @@ func (i *HelmTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 	for _, component := range references {
 		for _, res := range results {
 			if (deployment.Instance.Scope == "" || res.Namespace == deployment.Instance.Scope) && res.Name == component.Component.Name {
-				repo := ""
-				if strings.HasPrefix(res.Chart.Metadata.Tags, "SYM:") { //we use this special metadata tag to remember the chart URL
-					repo = res.Chart.Metadata.Tags[4:]
-				}
-
-				ret = append(ret, model.ComponentSpec{
-					Name: res.Name,
-					Type: "helm.v3",
-					Properties: map[string]interface{}{
-						"chart": map[string]string{
-							"repo":    repo,
-							"version": res.Chart.Metadata.Version,
-						},
-						"values": res.Config,
-					},
-				})
+				ret = append(ret, i.getReleaseComponent(ctx, deployment, component.Component, res))
 			}
 		}
 	}
@@ -275,27 +310,92 @@ func (i *HelmTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 	return ret, nil
 }
 
+// getReleaseComponent reports the chart and the values of a release. The chart, values and valuesFrom properties
+// of the reference are reported as they are when the release is in sync with them, so that change detection only
+// sees actual differences.
+func (i *HelmTargetProvider) getReleaseComponent(ctx context.Context, deployment model.DeploymentSpec, reference model.ComponentSpec, res *release.Release) model.ComponentSpec {
+	repo := getReleaseRepo(res)
+	ret := model.ComponentSpec{
+		Name: res.Name,
+		Type: "helm.v3",
+		Properties: map[string]interface{}{
+			"chart": map[string]string{
+				"repo":    repo,
+				"version": res.Chart.Metadata.Version,
+			},
+			"values": res.Config,
+		},
+	}
+
+	helmProp, err := getHelmPropertyFromComponent(reference)
+	if err != nil {
+		return ret
+	}
+	if helmProp.Chart.Repo == repo && (helmProp.Chart.Version == "" || helmProp.Chart.Version == res.Chart.Metadata.Version) {
+		ret.Properties["chart"] = reference.Properties["chart"]
+	}
+	values, err := i.resolveValues(ctx, deployment, helmProp)
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to resolve values of %s: %+v", reference.Name, err)
+		return ret
+	}
+	if valuesEqual(values, res.Config) {
+		for _, key := range []string{"values", "valuesFrom"} {
+			if v, ok := reference.Properties[key]; ok {
+				ret.Properties[key] = v
+			}
+		}
+	}
+	return ret
+}
+
+// getReleaseRepo returns the chart repo of a release, which the provider remembers in a chart metadata tag
+func getReleaseRepo(res *release.Release) string {
+	if res.Chart == nil || res.Chart.Metadata == nil || !strings.HasPrefix(res.Chart.Metadata.Tags, repoTagPrefix) {
+		return ""
+	}
+	return res.Chart.Metadata.Tags[len(repoTagPrefix):]
+}
+
 // GetValidationRule returns the validation rule for this provider
 func (*HelmTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
 		RequiredProperties:    []string{"chart"},
-		OptionalProperties:    []string{"values"},
+		OptionalProperties:    []string{"values", "valuesFrom", "postRenderer"},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
 		ChangeDetectionProperties: []model.PropertyDesc{
-			{Name: "chart", IgnoreCase: false, SkipIfMissing: true}, //TODO: deep change detection on interface{}
+			{Name: "chart", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "values", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "valuesFrom", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "postRenderer", IgnoreCase: false, SkipIfMissing: true},
 		},
 	}
 }
 
 // downloadFile will download a url to a local file. It's efficient because it will
 func downloadFile(url string, fileName string) error {
-	resp, err := http.Get(url)
+	return downloadFileWithAuth(url, fileName, "", "")
+}
+
+// downloadFileWithAuth downloads a url to a local file, with basic authentication when a username is given
+func downloadFileWithAuth(url string, fileName string, username string, password string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
 
 	fileHandle, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
 	if err != nil {
@@ -317,7 +417,7 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 		},
 	)
 	var err error
-	defer utils.CloseSpanWithError(span, &err)
+	defer observ_utils.CloseSpanWithError(span, &err)
 	sLog.Infof("  P (Helm Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
 	components := step.GetComponents()
@@ -334,57 +434,12 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 
 	for _, component := range step.Components {
 		if component.Action == "update" {
-			var helmProp *HelmProperty
-			helmProp, err = getHelmPropertyFromComponent(component.Component)
+			var result model.ComponentResultSpec
+			result, err = i.upsertRelease(ctx, deployment, component.Component)
+			ret[component.Component.Name] = result
 			if err != nil {
-				sLog.Errorf("  P (Helm Target): failed to get Helm properties: %+v", err)
-				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
-				}
 				return ret, err
 			}
-
-			var fileName string
-			fileName, err = i.pullChart(&helmProp.Chart)
-			if err != nil {
-				sLog.Errorf("  P (Helm Target): failed to pull chart: %+v", err)
-				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
-				}
-				return ret, err
-			}
-			defer os.Remove(fileName)
-
-			var chart *chart.Chart
-			chart, err = loader.Load(fileName)
-			if err != nil {
-				sLog.Errorf("  P (Helm Target): failed to load chart: %+v", err)
-				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
-				}
-				return ret, err
-			}
-
-			chart.Metadata.Tags = "SYM:" + helmProp.Chart.Repo //this is not used by Helm SDK, we use this to carry repo info
-			i.configureUpsertClients(component.Component.Name, &helmProp.Chart, &deployment)
-
-			if _, err = i.UpgradeClient.Run(component.Component.Name, chart, helmProp.Values); err != nil {
-				if _, err = i.InstallClient.Run(chart, helmProp.Values); err != nil {
-					sLog.Errorf("  P (Helm Target): failed to apply: %+v", err)
-					ret[component.Component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.UpdateFailed,
-						Message: err.Error(),
-					}
-					return ret, err
-				}
-			}
-			ret[component.Component.Name] = model.ComponentResultSpec{
-				Status:  v1alpha2.Updated,
-				Message: "",
-			}
 		} else {
 			if component.Component.Type == "helm.v3" {
 				_, err = i.UninstallClient.Run(component.Component.Name)
@@ -409,25 +464,282 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 	return ret, nil
 }
 
-func (i *HelmTargetProvider) pullChart(chart *HelmChartProperty) (fileName string, err error) {
+// upsertRelease installs or upgrades the release of a component. A release that already runs the same chart
+// with the same values isn't upgraded.
+func (i *HelmTargetProvider) upsertRelease(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) (model.ComponentResultSpec, error) {
+	helmProp, err := getHelmPropertyFromComponent(component)
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to get Helm properties: %+v", err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+
+	values, err := i.resolveValues(ctx, deployment, helmProp)
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to resolve chart values: %+v", err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+
+	fileName, err := i.pullChart(ctx, &helmProp.Chart, getReleaseNamespace(deployment))
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to pull chart: %+v", err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	defer os.Remove(fileName)
+
+	chart, err := loader.Load(fileName)
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to load chart: %+v", err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+
+	chart.Metadata.Tags = repoTagPrefix + helmProp.Chart.Repo //this is not used by Helm SDK, we use this to carry repo info
+	var postRenderer postrender.PostRenderer
+	if helmProp.PostRenderer != nil {
+		postRenderer, err = postrender.NewExec(helmProp.PostRenderer.Command, helmProp.PostRenderer.Args...)
+		if err != nil {
+			sLog.Errorf("  P (Helm Target): failed to create post renderer: %+v", err)
+			return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+		}
+		if chart.Metadata.Annotations == nil {
+			chart.Metadata.Annotations = make(map[string]string)
+		}
+		chart.Metadata.Annotations[postRendererAnnotation] = strings.Join(append([]string{helmProp.PostRenderer.Command}, helmProp.PostRenderer.Args...), " ")
+	}
+
+	current, err := i.GetClient.Run(component.Name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		sLog.Errorf("  P (Helm Target): failed to get release %s: %+v", component.Name, err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	if current != nil && isReleaseUpToDate(current, chart, values) {
+		sLog.Infof("  P (Helm Target): release %s is up to date, skipping upgrade", component.Name)
+		return model.ComponentResultSpec{Status: v1alpha2.Untouched, Message: "release is up to date"}, nil
+	}
+
+	i.configureUpsertClients(component.Name, &helmProp.Chart, &deployment)
+	i.InstallClient.PostRenderer = postRenderer
+	i.UpgradeClient.PostRenderer = postRenderer
+	if current != nil {
+		_, err = i.UpgradeClient.Run(component.Name, chart, values)
+	} else {
+		_, err = i.InstallClient.Run(chart, values)
+	}
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to apply: %+v", err)
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	return model.ComponentResultSpec{Status: v1alpha2.Updated, Message: ""}, nil
+}
+
+// isReleaseUpToDate checks if a deployed release runs a chart with the given values
+func isReleaseUpToDate(current *release.Release, chart *chart.Chart, values map[string]interface{}) bool {
+	if current.Info == nil || current.Info.Status != release.StatusDeployed || current.Chart == nil || current.Chart.Metadata == nil {
+		return false
+	}
+	return current.Chart.Metadata.Name == chart.Metadata.Name &&
+		current.Chart.Metadata.Version == chart.Metadata.Version &&
+		current.Chart.Metadata.Tags == chart.Metadata.Tags &&
+		current.Chart.Metadata.Annotations[postRendererAnnotation] == chart.Metadata.Annotations[postRendererAnnotation] &&
+		valuesEqual(current.Config, values)
+}
+
+// resolveValues merges the values of a chart. Values sources are merged in order, and inline values last, so
+// that later values override earlier ones, as with multiple values files in Helm.
+func (i *HelmTargetProvider) resolveValues(ctx context.Context, deployment model.DeploymentSpec, helmProp *HelmProperty) (map[string]interface{}, error) {
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+	ret := make(map[string]interface{})
+	for _, source := range helmProp.ValuesFrom {
+		var values map[string]interface{}
+		var err error
+		if source.Catalog != "" {
+			values, err = i.readCatalogValues(ctx, model.ResolveString(source.Catalog, injections), source.Key)
+		} else {
+			values, err = i.readSecretValues(ctx, getReleaseNamespace(deployment), model.ResolveString(source.Secret, injections), source.Key)
+		}
+		if err != nil {
+			if source.Optional && v1alpha2.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		ret = mergeValues(ret, values)
+	}
+	values, err := parseValues(helmProp.Values)
+	if err != nil {
+		return nil, err
+	}
+	return mergeValues(ret, values), nil
+}
+
+// readCatalogValues reads chart values from the properties of a catalog, or from one of them when a key is given
+func (i *HelmTargetProvider) readCatalogValues(ctx context.Context, name string, key string) (map[string]interface{}, error) {
+	if i.Context == nil || i.Context.SiteInfo.CurrentSite.BaseUrl == "" {
+		return nil, v1alpha2.NewCOAError(nil, "catalog values require a Symphony API endpoint", v1alpha2.BadConfig)
+	}
+	catalog, err := utils.GetCatalog(
+		ctx,
+		i.Context.SiteInfo.CurrentSite.BaseUrl,
+		name,
+		i.Context.SiteInfo.CurrentSite.Username,
+		i.Context.SiteInfo.CurrentSite.Password)
+	if err != nil {
+		return nil, err
+	}
+	if catalog.Spec == nil {
+		return make(map[string]interface{}), nil
+	}
+	if key == "" {
+		return parseValues(catalog.Spec.Properties)
+	}
+	v, ok := catalog.Spec.Properties[key]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("catalog '%s' doesn't have a '%s' property", name, key), v1alpha2.NotFound)
+	}
+	return parseValues(v)
+}
+
+// readSecretValues reads chart values from a key of a Kubernetes Secret in the namespace of the release
+func (i *HelmTargetProvider) readSecretValues(ctx context.Context, namespace string, name string, key string) (map[string]interface{}, error) {
+	if key == "" {
+		key = DEFAULT_VALUES_KEY
+	}
+	secret, err := i.readSecret(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret '%s' doesn't have a '%s' key", name, key), v1alpha2.NotFound)
+	}
+	return parseValues(string(data))
+}
+
+// readSecret reads a Kubernetes Secret
+func (i *HelmTargetProvider) readSecret(ctx context.Context, namespace string, name string) (*corev1.Secret, error) {
+	if i.KubeClient == nil {
+		if i.actionConfig == nil {
+			return nil, v1alpha2.NewCOAError(nil, "Helm provider isn't initialized", v1alpha2.BadConfig)
+		}
+		client, err := i.actionConfig.KubernetesClientSet()
+		if err != nil {
+			return nil, err
+		}
+		i.KubeClient = client
+	}
+	secret, err := i.KubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("secret '%s' isn't found in namespace '%s'", name, namespace), v1alpha2.NotFound)
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+// parseValues converts chart values given as an object, or as a YAML or JSON string, to the form Helm stores them in
+func parseValues(source interface{}) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	if source == nil {
+		return ret, nil
+	}
+	data, ok := source.(string)
+	if !ok {
+		bytes, err := json.Marshal(source)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to marshal chart values", v1alpha2.BadRequest)
+		}
+		data = string(bytes)
+	}
+	if err := yaml.Unmarshal([]byte(data), &ret); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "chart values aren't a YAML or JSON object", v1alpha2.BadRequest)
+	}
+	if ret == nil {
+		ret = make(map[string]interface{})
+	}
+	return ret, nil
+}
+
+// mergeValues merges src into a copy of dst. Nested objects are merged, and other values of src replace those of dst.
+func mergeValues(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(dst))
+	for k, v := range dst {
+		ret[k] = v
+	}
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]interface{}); ok {
+			if dstMap, ok := ret[k].(map[string]interface{}); ok {
+				ret[k] = mergeValues(dstMap, srcMap)
+				continue
+			}
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+// valuesEqual compares chart values, treating missing values as empty ones
+func valuesEqual(a map[string]interface{}, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	na, err := parseValues(a)
+	if err != nil {
+		return false
+	}
+	nb, err := parseValues(b)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func (i *HelmTargetProvider) pullChart(ctx context.Context, chart *HelmChartProperty, namespace string) (fileName string, err error) {
 	fileName = fmt.Sprintf("%s/%s.tgz", TEMP_CHART_DIR, uuid.New().String())
 
+	var username, password string
+	username, password, err = i.getRepoCredentials(ctx, chart, namespace)
+	if err != nil {
+		sLog.Errorf("  P (Helm Target): failed to read chart repo credentials: %+v", err)
+		return "", err
+	}
+
 	var pullRes *registry.PullResult
 	if strings.HasSuffix(chart.Repo, ".tgz") && strings.HasPrefix(chart.Repo, "http") {
-		err = downloadFile(chart.Repo, fileName)
+		err = downloadFileWithAuth(chart.Repo, fileName, username, password)
 		if err != nil {
 			sLog.Errorf("  P (Helm Target): failed to download chart from repo: %+v", err)
 			return "", err
 		}
 	} else {
+		repo := strings.TrimPrefix(chart.Repo, "oci://")
+		options := []registry.ClientOption{}
+		if username != "" {
+			// credentials are kept in a file of their own, so that they don't outlive the pull
+			credentialsFile := fmt.Sprintf("%s/%s.json", TEMP_CHART_DIR, uuid.New().String())
+			defer os.Remove(credentialsFile)
+			options = append(options, registry.ClientOptCredentialsFile(credentialsFile))
+		}
+
 		var regClient *registry.Client
-		regClient, err = registry.NewClient()
+		regClient, err = registry.NewClient(options...)
 		if err != nil {
 			sLog.Errorf("  P (Helm Target): failed to create registry client: %+v", err)
 			return
 		}
 
-		pullRes, err = regClient.Pull(fmt.Sprintf("%s:%s", chart.Repo, chart.Version), registry.PullOptWithChart(true))
+		if username != "" {
+			err = regClient.Login(getRegistryHost(repo), registry.LoginOptBasicAuth(username, password))
+			if err != nil {
+				sLog.Errorf("  P (Helm Target): failed to log in to registry: %+v", err)
+				return
+			}
+		}
+
+		pullRes, err = regClient.Pull(fmt.Sprintf("%s:%s", repo, chart.Version), registry.PullOptWithChart(true))
 		if err != nil {
 			sLog.Errorf("  P (Helm Target): failed to pull chart from repo: %+v", err)
 			return
@@ -442,18 +754,88 @@ func (i *HelmTargetProvider) pullChart(chart *HelmChartProperty) (fileName strin
 	return fileName, nil
 }
 
-func (i *HelmTargetProvider) configureUpsertClients(name string, componentProps *HelmChartProperty, deployment *model.DeploymentSpec) {
+// getRepoCredentials reads the credentials of a chart repo from the Secret named by chart.authSecret. The Secret
+// either has username and password keys, or is a kubernetes.io/dockerconfigjson Secret.
+func (i *HelmTargetProvider) getRepoCredentials(ctx context.Context, chart *HelmChartProperty, namespace string) (string, string, error) {
+	if chart.AuthSecret == "" {
+		return "", "", nil
+	}
+	secret, err := i.readSecret(ctx, namespace, chart.AuthSecret)
+	if err != nil {
+		return "", "", err
+	}
+	if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		return getDockerConfigCredentials(data, getRegistryHost(chart.Repo))
+	}
+	username := string(secret.Data["username"])
+	if username == "" {
+		return "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("secret '%s' has neither a username nor a %s key", chart.AuthSecret, corev1.DockerConfigJsonKey), v1alpha2.BadConfig)
+	}
+	return username, string(secret.Data["password"]), nil
+}
+
+// getDockerConfigCredentials reads the credentials of a registry from a .dockerconfigjson document
+func getDockerConfigCredentials(data []byte, host string) (string, string, error) {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", v1alpha2.NewCOAError(err, "failed to parse docker config", v1alpha2.BadConfig)
+	}
+	for k, v := range config.Auths {
+		if getRegistryHost(k) != host {
+			continue
+		}
+		if v.Username != "" {
+			return v.Username, v.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(v.Auth)
+		if err != nil {
+			return "", "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to decode the credentials of '%s' in docker config", host), v1alpha2.BadConfig)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("docker config has no credentials for '%s'", host), v1alpha2.BadConfig)
+}
+
+// getRegistryHost returns the host of a chart repo or registry address
+func getRegistryHost(repo string) string {
+	for _, prefix := range []string{"oci://", "https://", "http://"} {
+		repo = strings.TrimPrefix(repo, prefix)
+	}
+	host, _, _ := strings.Cut(repo, "/")
+	return host
+}
+
+func getReleaseNamespace(deployment model.DeploymentSpec) string {
 	if deployment.Instance.Scope == "" {
-		i.InstallClient.Namespace = DEFAULT_NAMESPACE
-		i.UpgradeClient.Namespace = DEFAULT_NAMESPACE
-	} else {
-		i.InstallClient.Namespace = deployment.Instance.Scope
-		i.UpgradeClient.Namespace = deployment.Instance.Scope
+		return DEFAULT_NAMESPACE
 	}
+	return deployment.Instance.Scope
+}
 
+func (i *HelmTargetProvider) configureUpsertClients(name string, componentProps *HelmChartProperty, deployment *model.DeploymentSpec) {
+	i.InstallClient.Namespace = getReleaseNamespace(*deployment)
+	i.UpgradeClient.Namespace = getReleaseNamespace(*deployment)
+
+	timeout := DEFAULT_TIMEOUT
+	if componentProps.Timeout != "" {
+		// the timeout is validated by validateProps
+		timeout, _ = time.ParseDuration(componentProps.Timeout)
+	}
 	i.InstallClient.Wait = componentProps.Wait
 	i.UpgradeClient.Wait = componentProps.Wait
-	i.InstallClient.CreateNamespace = true
+	i.InstallClient.Atomic = componentProps.Atomic
+	i.UpgradeClient.Atomic = componentProps.Atomic
+	i.UpgradeClient.CleanupOnFail = componentProps.Atomic
+	i.InstallClient.Timeout = timeout
+	i.UpgradeClient.Timeout = timeout
+	i.InstallClient.CreateNamespace = componentProps.CreateNamespace == nil || *componentProps.CreateNamespace
 	i.InstallClient.ReleaseName = name
 	i.InstallClient.IsUpgrade = true
 	i.UpgradeClient.Install = true
@@ -480,6 +862,22 @@ func validateProps(props *HelmProperty) (*HelmProperty, error) {
 		return nil, errors.New("chart repo is required")
 	}
 
+	if props.Chart.Timeout != "" {
+		if _, err := time.ParseDuration(props.Chart.Timeout); err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid chart timeout '%s'", props.Chart.Timeout), v1alpha2.BadRequest)
+		}
+	}
+
+	for _, source := range props.ValuesFrom {
+		if (source.Catalog == "") == (source.Secret == "") {
+			return nil, v1alpha2.NewCOAError(nil, "each values source must have either a catalog or a secret", v1alpha2.BadRequest)
+		}
+	}
+
+	if props.PostRenderer != nil && props.PostRenderer.Command == "" {
+		return nil, v1alpha2.NewCOAError(nil, "post renderer command is required", v1alpha2.BadRequest)
+	}
+
 	return props, nil
 }
 
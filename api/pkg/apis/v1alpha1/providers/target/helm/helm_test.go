@@ -8,14 +8,36 @@ package helm
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
 )
 
@@ -555,6 +577,295 @@ func TestGetActionConfig(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestHelmTargetProviderApplyValuesFrom(t *testing.T) {
+	charts := newChartServer(t, "", "")
+	defer charts.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/users/auth":
+			response = map[string]string{"accessToken": "test-token"}
+		case "/catalogs/registry/demo-config":
+			response = model.CatalogState{
+				Id: "demo-config",
+				Spec: &model.CatalogSpec{
+					Properties: map[string]interface{}{
+						"helm": map[string]interface{}{
+							"nested": map[string]interface{}{"b": 3},
+						},
+					},
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer api.Close()
+
+	provider := newFakeHelmProvider(t, &kubefake.PrintingKubeClient{Out: io.Discard}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-values", Namespace: "default"},
+		Data: map[string][]byte{
+			"values.yaml": []byte("greeting: from-secret\nreplicas: 2\nnested:\n  a: 1\n  b: 2\n"),
+		},
+	})
+	provider.SetContext(&contexts.ManagerContext{
+		SiteInfo: v1alpha2.SiteInfo{
+			CurrentSite: v1alpha2.SiteConnection{
+				BaseUrl:  api.URL + "/",
+				Username: "admin",
+				Password: "",
+			},
+		},
+	})
+
+	component := model.ComponentSpec{
+		Name: "demo",
+		Type: "helm.v3",
+		Properties: map[string]interface{}{
+			"chart": map[string]interface{}{
+				"repo": charts.URL + "/demo-0.1.0.tgz",
+			},
+			"valuesFrom": []interface{}{
+				map[string]interface{}{"secret": "demo-values"},
+				map[string]interface{}{"catalog": "demo-config", "key": "helm"},
+				map[string]interface{}{"secret": "other-values", "optional": true},
+			},
+			"values": map[string]interface{}{
+				"greeting": "inline",
+			},
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["demo"].Status)
+
+	rel, err := provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Equal(t, 1, rel.Version)
+	assert.Equal(t, map[string]interface{}{
+		"greeting": "inline",
+		"replicas": float64(2),
+		"nested":   map[string]interface{}{"a": float64(1), "b": float64(3)},
+	}, rel.Config)
+	assert.Contains(t, rel.Manifest, `greeting: "inline"`)
+
+	// the release is in sync, so it isn't upgraded again
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Untouched, ret["demo"].Status)
+	rel, err = provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Equal(t, 1, rel.Version)
+
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(components))
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+
+	// a changed value upgrades the release, and the release drifts from the previous component
+	changed := component
+	changed.Properties = map[string]interface{}{}
+	for k, v := range component.Properties {
+		changed.Properties[k] = v
+	}
+	changed.Properties["values"] = map[string]interface{}{"greeting": "changed"}
+	components, err = provider.Get(context.Background(), deployment, []model.ComponentStep{{Action: "update", Component: changed}})
+	require.Nil(t, err)
+	assert.True(t, rule.IsComponentChanged(components[0], changed))
+
+	deployment, step = conformance.Deployment("", "instance1", "update", changed)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["demo"].Status)
+	rel, err = provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Equal(t, 2, rel.Version)
+	assert.Equal(t, "changed", rel.Config["greeting"])
+
+	// a values source that isn't optional must exist
+	changed.Properties["valuesFrom"] = []interface{}{map[string]interface{}{"secret": "other-values"}}
+	deployment, step = conformance.Deployment("", "instance1", "update", changed)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["demo"].Status)
+}
+
+func TestHelmTargetProviderApplyAtomic(t *testing.T) {
+	charts := newChartServer(t, "", "")
+	defer charts.Close()
+
+	failing := &kubefake.FailingKubeClient{
+		PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+		WaitError:          errors.New("timed out waiting for the condition"),
+	}
+	provider := newFakeHelmProvider(t, failing)
+	component := model.ComponentSpec{
+		Name: "demo",
+		Type: "helm.v3",
+		Properties: map[string]interface{}{
+			"chart": map[string]interface{}{
+				"repo":            charts.URL + "/demo-0.1.0.tgz",
+				"atomic":          true,
+				"timeout":         "1s",
+				"createNamespace": false,
+			},
+		},
+	}
+
+	// a failed atomic install is uninstalled
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["demo"].Status)
+	_, err = provider.GetClient.Run("demo")
+	assert.True(t, errors.Is(err, driver.ErrReleaseNotFound))
+
+	provider.actionConfig.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+
+	// a failed atomic upgrade is rolled back
+	provider.actionConfig.KubeClient = &failOnceKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	component.Properties["values"] = map[string]interface{}{"greeting": "changed"}
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["demo"].Status)
+	rel, err := provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Equal(t, 3, rel.Version)
+	assert.Equal(t, release.StatusDeployed, rel.Info.Status)
+	assert.Equal(t, 0, len(rel.Config))
+}
+
+func TestHelmTargetProviderApplyPostRenderer(t *testing.T) {
+	charts := newChartServer(t, "", "")
+	defer charts.Close()
+
+	provider := newFakeHelmProvider(t, &kubefake.PrintingKubeClient{Out: io.Discard})
+	component := model.ComponentSpec{
+		Name: "demo",
+		Type: "helm.v3",
+		Properties: map[string]interface{}{
+			"chart": map[string]interface{}{
+				"repo": charts.URL + "/demo-0.1.0.tgz",
+			},
+			"postRenderer": map[string]interface{}{
+				"command": "sed",
+				"args":    []string{"s/name: demo/name: rendered/"},
+			},
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	rel, err := provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Contains(t, rel.Manifest, "name: rendered")
+
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Untouched, ret["demo"].Status)
+
+	// a changed post renderer upgrades the release
+	component.Properties["postRenderer"] = map[string]interface{}{
+		"command": "sed",
+		"args":    []string{"s/name: demo/name: rendered-again/"},
+	}
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["demo"].Status)
+	rel, err = provider.GetClient.Run("demo")
+	require.Nil(t, err)
+	assert.Contains(t, rel.Manifest, "name: rendered-again")
+}
+
+func TestHelmTargetProviderApplyRepoAuth(t *testing.T) {
+	charts := newChartServer(t, "user", "pass")
+	defer charts.Close()
+	host := strings.TrimPrefix(charts.URL, "http://")
+
+	provider := newFakeHelmProvider(t, &kubefake.PrintingKubeClient{Out: io.Discard},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo-basic", Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo-docker", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`, host, base64.StdEncoding.EncodeToString([]byte("user:pass")))),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo-wrong", Namespace: "default"},
+			Data:       map[string][]byte{"username": []byte("user"), "password": []byte("wrong")},
+		},
+	)
+
+	for secret, succeeds := range map[string]bool{"repo-basic": true, "repo-docker": true, "repo-wrong": false, "": false, "repo-missing": false} {
+		chart := map[string]interface{}{
+			"repo": charts.URL + "/demo-0.1.0.tgz",
+		}
+		if secret != "" {
+			chart["authSecret"] = secret
+		}
+		component := model.ComponentSpec{
+			Name:       "demo-" + secret,
+			Type:       "helm.v3",
+			Properties: map[string]interface{}{"chart": chart},
+		}
+		deployment, step := conformance.Deployment("", "instance1", "update", component)
+		_, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.Equal(t, succeeds, err == nil, secret)
+	}
+}
+
+func TestHelmTargetProviderApplyInvalidProperties(t *testing.T) {
+	provider := newFakeHelmProvider(t, &kubefake.PrintingKubeClient{Out: io.Discard})
+	for _, properties := range []map[string]interface{}{
+		{"chart": map[string]interface{}{"repo": "example.com/charts/demo", "timeout": "soon"}},
+		{"chart": map[string]interface{}{"repo": "example.com/charts/demo"}, "valuesFrom": []interface{}{map[string]interface{}{"key": "values.yaml"}}},
+		{"chart": map[string]interface{}{"repo": "example.com/charts/demo"}, "valuesFrom": []interface{}{map[string]interface{}{"catalog": "a", "secret": "b"}}},
+		{"chart": map[string]interface{}{"repo": "example.com/charts/demo"}, "postRenderer": map[string]interface{}{"args": []string{"a"}}},
+	} {
+		deployment, step := conformance.Deployment("", "instance1", "update", model.ComponentSpec{Name: "demo", Type: "helm.v3", Properties: properties})
+		ret, err := provider.Apply(context.Background(), deployment, step, false)
+		coaErr, ok := err.(v1alpha2.COAError)
+		require.True(t, ok, properties)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, properties)
+		assert.Equal(t, v1alpha2.UpdateFailed, ret["demo"].Status, properties)
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	merged := mergeValues(map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 2},
+		"d": []interface{}{1, 2},
+		"e": "x",
+	}, map[string]interface{}{
+		"a": map[string]interface{}{"c": 3},
+		"d": []interface{}{3},
+		"e": map[string]interface{}{"f": "y"},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 3},
+		"d": []interface{}{3},
+		"e": map[string]interface{}{"f": "y"},
+	}, merged)
+}
+
+func TestGetRegistryHost(t *testing.T) {
+	assert.Equal(t, "myregistry.azurecr.io", getRegistryHost("oci://myregistry.azurecr.io/charts/demo"))
+	assert.Equal(t, "myregistry.azurecr.io", getRegistryHost("myregistry.azurecr.io/charts/demo"))
+	assert.Equal(t, "localhost:5000", getRegistryHost("https://localhost:5000"))
+}
+
 // TestConformanceSuite tests the HelmTargetProvider for conformance
 func TestConformanceSuite(t *testing.T) {
 	provider := &HelmTargetProvider{}
@@ -562,3 +873,67 @@ func TestConformanceSuite(t *testing.T) {
 	assert.Nil(t, err)
 	conformance.ConformanceSuite(t, provider)
 }
+
+// newFakeHelmProvider creates a provider that keeps releases in memory and applies them with a fake Kubernetes client
+func newFakeHelmProvider(t *testing.T, kubeClient kube.Interface, objects ...runtime.Object) *HelmTargetProvider {
+	require.Nil(t, initChartsDir())
+	provider := &HelmTargetProvider{KubeClient: k8sfake.NewSimpleClientset(objects...)}
+	provider.setActionConfig(&action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   kubeClient,
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(format string, v ...interface{}) {},
+	})
+	return provider
+}
+
+// failOnceKubeClient fails to wait for resources once, so that a rollback after a failed upgrade succeeds
+type failOnceKubeClient struct {
+	kubefake.PrintingKubeClient
+	failed bool
+}
+
+func (c *failOnceKubeClient) Wait(resources kube.ResourceList, timeout time.Duration) error {
+	if !c.failed {
+		c.failed = true
+		return errors.New("timed out waiting for the condition")
+	}
+	return nil
+}
+
+// newChartServer serves a packaged demo chart as /demo-0.1.0.tgz, with basic authentication when a username is given
+func newChartServer(t *testing.T, username string, password string) *httptest.Server {
+	demo := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "demo",
+			Version:    "0.1.0",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\ndata:\n  greeting: {{ .Values.greeting | quote }}\n"),
+			},
+		},
+		Values: map[string]interface{}{"greeting": "hello"},
+	}
+	path, err := chartutil.Save(demo, t.TempDir())
+	require.Nil(t, err)
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username != "" {
+			u, p, ok := r.BasicAuth()
+			if !ok || u != username || p != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if r.URL.Path != "/demo-0.1.0.tgz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+}
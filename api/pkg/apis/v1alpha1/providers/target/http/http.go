@@ -11,8 +11,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -20,18 +26,38 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
 var sLog = logger.NewLogger("coa.runtime")
 
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultSuccessCodes = "200-299"
+)
+
 type HttpTargetProviderConfig struct {
 	Name string `json:"name"`
+	// Timeout is the timeout of requests of components that don't set http.timeout. It defaults to 30s.
+	Timeout string `json:"timeout,omitempty"`
 }
 
 type HttpTargetProvider struct {
-	Config  HttpTargetProviderConfig
-	Context *contexts.ManagerContext
+	Config         HttpTargetProviderConfig
+	Context        *contexts.ManagerContext
+	SecretProvider secret.ISecretProvider
+}
+
+// BodyTemplateData is what http.bodyTemplate is rendered with
+type BodyTemplateData struct {
+	Instance  string
+	Solution  string
+	Target    string
+	Component string
+	// Properties are the component properties, with values injected
+	Properties map[string]interface{}
 }
 
 func HttpTargetProviderConfigFromMap(properties map[string]string) (HttpTargetProviderConfig, error) {
@@ -39,6 +65,12 @@ func HttpTargetProviderConfigFromMap(properties map[string]string) (HttpTargetPr
 	if v, ok := properties["name"]; ok {
 		ret.Name = v
 	}
+	if v, ok := properties["timeout"]; ok {
+		if _, err := utils.ParsePositiveDuration(v, defaultTimeout); err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid http provider config, 'timeout' must be a duration", v1alpha2.BadConfig)
+		}
+		ret.Timeout = v
+	}
 	return ret, nil
 }
 
@@ -54,6 +86,10 @@ func (s *HttpTargetProvider) SetContext(ctx *contexts.ManagerContext) {
 	s.Context = ctx
 }
 
+func (s *HttpTargetProvider) SetSecretProvider(provider secret.ISecretProvider) {
+	s.SecretProvider = provider
+}
+
 func (i *HttpTargetProvider) Init(config providers.IProviderConfig) error {
 	_, span := observability.StartSpan("Http Target Provider", context.TODO(), &map[string]string{
 		"method": "Init",
@@ -68,6 +104,10 @@ func (i *HttpTargetProvider) Init(config providers.IProviderConfig) error {
 		sLog.Errorf("  P(HTTP Target): expected HttpTargetProviderConfig: %+v", err)
 		return err
 	}
+	if _, err = utils.ParsePositiveDuration(updateConfig.Timeout, defaultTimeout); err != nil {
+		err = v1alpha2.NewCOAError(err, "invalid http provider config, 'timeout' must be a duration", v1alpha2.BadConfig)
+		return err
+	}
 	i.Config = updateConfig
 
 	return nil
@@ -81,8 +121,13 @@ func toHttpTargetProviderConfig(config providers.IProviderConfig) (HttpTargetPro
 	err = json.Unmarshal(data, &ret)
 	return ret, err
 }
+
+// Get reads back the components that have a http.getUrl. A component whose readback contains its body is
+// reported as in the solution. Otherwise, the readback is reported in place of its body, so that change
+// detection deploys it again. Components without a http.getUrl aren't reported, as the provider can't
+// tell their state.
 func (i *HttpTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
-	_, span := observability.StartSpan("Http Target Provider", ctx, &map[string]string{
+	ctx, span := observability.StartSpan("Http Target Provider", ctx, &map[string]string{
 		"method": "Get",
 	})
 	var err error = nil
@@ -90,8 +135,59 @@ func (i *HttpTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 
 	sLog.Infof("  P(HTTP Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
-	// This provider doesn't remember what it does, so it always return nil when asked
-	return nil, nil
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		component := reference.Component
+		url := model.ReadPropertyCompat(component.Properties, "http.getUrl", injections)
+		if url == "" {
+			continue
+		}
+		var status int
+		var body []byte
+		status, body, err = i.send(ctx, deployment, component, http.MethodGet, url, nil, injections)
+		if err != nil {
+			sLog.Errorf("  P(HTTP Target): failed to read back %s: %+v", component.Name, err)
+			return nil, err
+		}
+		if status == http.StatusNotFound || status == http.StatusGone {
+			continue
+		}
+		if status < 200 || status > 299 {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("reading back %s responded %d: %s", component.Name, status, string(body)), v1alpha2.InternalError)
+			sLog.Errorf("  P(HTTP Target): %+v", err)
+			return nil, err
+		}
+
+		current := model.ComponentSpec{
+			Name:       component.Name,
+			Type:       component.Type,
+			Properties: make(map[string]interface{}),
+		}
+		for k, v := range component.Properties {
+			current.Properties[k] = v
+		}
+		var desired string
+		desired, err = renderBody(deployment, component, injections)
+		if err != nil {
+			sLog.Errorf("  P(HTTP Target): failed to render the body of %s: %+v", component.Name, err)
+			return nil, err
+		}
+		if !bodyMatches(desired, string(body)) {
+			if _, ok := component.Properties["http.bodyTemplate"]; ok {
+				current.Properties["http.bodyTemplate"] = string(body)
+			} else {
+				current.Properties["http.body"] = string(body)
+			}
+		}
+		ret = append(ret, current)
+	}
+	return ret, nil
 }
 
 func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
@@ -114,6 +210,12 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 	if err != nil {
 		return nil, err
 	}
+	for _, component := range components {
+		err = validateComponent(component)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if isDryRun {
 		err = nil
 		return nil, nil
@@ -122,7 +224,16 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 	ret := step.PrepareResultMap()
 	for _, component := range step.Components {
 		if component.Action == "update" {
-			body := model.ReadPropertyCompat(component.Component.Properties, "http.body", injections)
+			body := ""
+			body, err = renderBody(deployment, component.Component, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P(HTTP Target): %v", err)
+				return ret, err
+			}
 			url := model.ReadPropertyCompat(component.Component.Properties, "http.url", injections)
 			method := model.ReadPropertyCompat(component.Component.Properties, "http.method", injections)
 
@@ -138,9 +249,9 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 			if method == "" {
 				method = "POST"
 			}
-			jsonData := []byte(body)
-			var request *http.Request
-			request, err = http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+			var status int
+			var response []byte
+			status, response, err = i.send(ctx, deployment, component.Component, method, url, []byte(body), injections)
 			if err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
@@ -149,45 +260,322 @@ func (i *HttpTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				sLog.Errorf("  P(HTTP Target): %v", err)
 				return ret, err
 			}
-			request.Header.Set("Content-Type", "application/json; charset=UTF-8")
-
-			client := &http.Client{}
-			var resp *http.Response
-			resp, err = client.Do(request)
-			if err != nil {
+			codes := model.ReadPropertyCompat(component.Component.Properties, "http.successCodes", nil)
+			if !isSuccess(status, codes) {
 				ret[component.Component.Name] = model.ComponentResultSpec{
 					Status:  v1alpha2.UpdateFailed,
-					Message: err.Error(),
+					Message: string(response),
 				}
+				err = fmt.Errorf("HTTP request responded %d, which isn't a success code", status)
 				sLog.Errorf("  P(HTTP Target): %v", err)
 				return ret, err
 			}
-			if resp.StatusCode != http.StatusOK {
-				bodyBytes, err := io.ReadAll(resp.Body)
-				var message string
-				if err != nil {
-					message = err.Error()
-				} else {
-					message = string(bodyBytes)
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
+			}
+		} else {
+			url := model.ReadPropertyCompat(component.Component.Properties, "http.deleteUrl", injections)
+			if url == "" {
+				// there's nothing to remove
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.Deleted,
+					Message: "",
 				}
+				continue
+			}
+			var status int
+			var response []byte
+			status, response, err = i.send(ctx, deployment, component.Component, http.MethodDelete, url, nil, injections)
+			if err != nil {
 				ret[component.Component.Name] = model.ComponentResultSpec{
-					Status:  v1alpha2.UpdateFailed,
-					Message: message,
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P(HTTP Target): %v", err)
+				return ret, err
+			}
+			// a resource that's already gone is removed
+			codes := model.ReadPropertyCompat(component.Component.Properties, "http.successCodes", nil)
+			if !isSuccess(status, codes) && status != http.StatusNotFound && status != http.StatusGone {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: string(response),
 				}
-				err = errors.New("HTTP request didn't respond 200 OK")
+				err = fmt.Errorf("HTTP request responded %d, which isn't a success code", status)
 				sLog.Errorf("  P(HTTP Target): %v", err)
 				return ret, err
 			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
+			}
 		}
 	}
 	return ret, nil
 }
 func (*HttpTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
-		RequiredProperties:    []string{"http.url"},
-		OptionalProperties:    []string{"http.method", "http.body"},
+		RequiredProperties: []string{"http.url"},
+		OptionalProperties: []string{
+			"http.method",
+			"http.body",
+			"http.bodyTemplate",
+			"http.getUrl",
+			"http.deleteUrl",
+			"http.successCodes",
+			"http.headers",
+			"http.auth",
+			"http.authSecret",
+			"http.timeout",
+		},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
+		ExclusiveProperties:   [][]string{{"http.body", "http.bodyTemplate"}},
+		DurationProperties:    []string{"http.timeout"},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: "http.*", IgnoreCase: false, SkipIfMissing: true},
+		},
+	}
+}
+
+// send sends a request with the headers, the authentication and the timeout of a component, and returns
+// the status code and the body of the response
+func (i *HttpTargetProvider) send(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec, method string, url string, body []byte, injections *model.ValueInjections) (int, []byte, error) {
+	timeout, _ := utils.ParsePositiveDuration(i.Config.Timeout, defaultTimeout)
+	if v := model.ReadPropertyCompat(component.Properties, "http.timeout", nil); v != "" {
+		var err error
+		timeout, err = utils.ParsePositiveDuration(v, timeout)
+		if err != nil {
+			return 0, nil, v1alpha2.NewCOAError(err, "http.timeout must be a duration", v1alpha2.BadRequest)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+	headers, err := readHeaders(component, injections)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range headers {
+		request.Header.Set(k, v)
 	}
+	if err := i.authenticate(request, component, injections); err != nil {
+		return 0, nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// authenticate sets the Authorization header from the secret named by http.authSecret. Bearer
+// authentication reads the token field of the secret, and basic authentication reads its username and
+// password fields.
+func (i *HttpTargetProvider) authenticate(request *http.Request, component model.ComponentSpec, injections *model.ValueInjections) error {
+	auth := strings.ToLower(model.ReadPropertyCompat(component.Properties, "http.auth", nil))
+	if auth == "" {
+		return nil
+	}
+	object := model.ReadPropertyCompat(component.Properties, "http.authSecret", injections)
+	if i.SecretProvider == nil {
+		return v1alpha2.NewCOAError(nil, "http provider needs a secret provider to authenticate requests", v1alpha2.MissingConfig)
+	}
+	switch auth {
+	case "bearer":
+		token, err := i.SecretProvider.Get(object, "token")
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read the token from secret '%s'", object), v1alpha2.MissingConfig)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	case "basic":
+		username, err := i.SecretProvider.Get(object, "username")
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read the username from secret '%s'", object), v1alpha2.MissingConfig)
+		}
+		password, err := i.SecretProvider.Get(object, "password")
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read the password from secret '%s'", object), v1alpha2.MissingConfig)
+		}
+		request.SetBasicAuth(username, password)
+	}
+	return nil
+}
+
+func validateComponent(component model.ComponentSpec) error {
+	if v := model.ReadPropertyCompat(component.Properties, "http.successCodes", nil); v != "" {
+		if _, err := parseSuccessCodes(v); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("http.successCodes of component %s is invalid", component.Name), v1alpha2.BadRequest)
+		}
+	}
+	switch strings.ToLower(model.ReadPropertyCompat(component.Properties, "http.auth", nil)) {
+	case "":
+	case "bearer", "basic":
+		if model.ReadPropertyCompat(component.Properties, "http.authSecret", nil) == "" {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("component %s needs http.authSecret to authenticate", component.Name), v1alpha2.BadRequest)
+		}
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("http.auth of component %s must be 'bearer' or 'basic'", component.Name), v1alpha2.BadRequest)
+	}
+	if _, err := readHeaders(component, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// renderBody is http.body, or http.bodyTemplate rendered with the component. A body given as an object
+// is sent as JSON.
+func renderBody(deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) (string, error) {
+	if v, ok := component.Properties["http.bodyTemplate"]; ok {
+		tmpl, err := template.New(component.Name).Option("missingkey=error").Parse(fmt.Sprintf("%v", v))
+		if err != nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("http.bodyTemplate of component %s is invalid", component.Name), v1alpha2.BadRequest)
+		}
+		data := BodyTemplateData{
+			Instance:   deployment.Instance.Name,
+			Solution:   deployment.Instance.Solution,
+			Target:     deployment.ActiveTarget,
+			Component:  component.Name,
+			Properties: make(map[string]interface{}),
+		}
+		for k, v := range component.Properties {
+			if s, ok := v.(string); ok {
+				data.Properties[k] = model.ResolveString(s, injections)
+			} else {
+				data.Properties[k] = v
+			}
+		}
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to render http.bodyTemplate of component %s", component.Name), v1alpha2.BadRequest)
+		}
+		return buffer.String(), nil
+	}
+	v, ok := component.Properties["http.body"]
+	if !ok || v == nil {
+		return "", nil
+	}
+	if _, ok := v.(string); !ok {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return model.ResolveString(string(data), injections), nil
+	}
+	return model.ReadPropertyCompat(component.Properties, "http.body", injections), nil
+}
+
+// readHeaders reads http.headers, given either as an object or as a JSON string
+func readHeaders(component model.ComponentSpec, injections *model.ValueInjections) (map[string]string, error) {
+	v, ok := component.Properties["http.headers"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	var data []byte
+	if s, ok := v.(string); ok {
+		data = []byte(s)
+	} else {
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	headers := make(map[string]interface{})
+	if err := json.Unmarshal(data, &headers); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("http.headers of component %s must be an object", component.Name), v1alpha2.BadRequest)
+	}
+	ret := make(map[string]string, len(headers))
+	for k, v := range headers {
+		ret[k] = model.ResolveString(fmt.Sprintf("%v", v), injections)
+	}
+	return ret, nil
+}
+
+// bodyMatches tells whether a readback contains the desired body. JSON objects match when every field of
+// the desired object has the same value in the readback, and other bodies match when they're equal.
+func bodyMatches(desired string, readback string) bool {
+	if strings.TrimSpace(desired) == "" {
+		return true
+	}
+	var desiredValue, readbackValue interface{}
+	if json.Unmarshal([]byte(desired), &desiredValue) != nil || json.Unmarshal([]byte(readback), &readbackValue) != nil {
+		return strings.TrimSpace(desired) == strings.TrimSpace(readback)
+	}
+	return contains(readbackValue, desiredValue)
+}
+
+func contains(readback interface{}, desired interface{}) bool {
+	desiredObject, ok := desired.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(readback, desired)
+	}
+	readbackObject, ok := readback.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for k, v := range desiredObject {
+		if rv, ok := readbackObject[k]; !ok || !contains(rv, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSuccess tells whether a status code is one of http.successCodes, 2xx by default
+func isSuccess(status int, codes string) bool {
+	if codes == "" {
+		codes = defaultSuccessCodes
+	}
+	ranges, err := parseSuccessCodes(codes)
+	if err != nil {
+		return false
+	}
+	for _, r := range ranges {
+		if status >= r[0] && status <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSuccessCodes parses a comma-separated list of status codes and ranges, such as "200,202,300-399"
+func parseSuccessCodes(codes string) ([][2]int, error) {
+	ret := make([][2]int, 0)
+	for _, code := range strings.Split(codes, ",") {
+		code = strings.TrimSpace(code)
+		bounds := strings.SplitN(code, "-", 2)
+		low, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' isn't a status code", code)
+		}
+		high := low
+		if len(bounds) == 2 {
+			high, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || high < low {
+				return nil, fmt.Errorf("'%s' isn't a range of status codes", code)
+			}
+		}
+		ret = append(ret, [2]int{low, high})
+	}
+	return ret, nil
 }
@@ -8,12 +8,16 @@ package http
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -38,6 +42,22 @@ func TestHttpTargetProviderConfigFromMap(t *testing.T) {
 	require.Nil(t, err)
 }
 
+// TestHttpTargetProviderConfigFromMapInvalidTimeout tests that HttpTargetProviderConfigFromMap returns an error when the timeout isn't a duration
+func TestHttpTargetProviderConfigFromMapInvalidTimeout(t *testing.T) {
+	config, err := HttpTargetProviderConfigFromMap(map[string]string{
+		"timeout": "10s",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "10s", config.Timeout)
+
+	_, err = HttpTargetProviderConfigFromMap(map[string]string{
+		"timeout": "soon",
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	require.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
 // TestHttpTargetProviderInitWithMap tests that HttpTargetProvider.InitWithMap returns nil when passed a non empty map
 func TestHttpTargetProviderInitWithMap(t *testing.T) {
 	provider := HttpTargetProvider{}
@@ -302,6 +322,307 @@ func TestHttpTargetProviderRemove(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// TestHttpTargetProviderApplySuccessCodes tests that any 2xx response succeeds, unless http.successCodes is set
+func TestHttpTargetProviderApplySuccessCodes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{})
+	assert.Nil(t, err)
+
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url": ts.URL,
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["http-component"].Status)
+
+	component.Properties["http.successCodes"] = "200"
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: "queued"}, ret["http-component"])
+
+	component.Properties["http.successCodes"] = "200, 201-204"
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+}
+
+// TestHttpTargetProviderApplyRequest tests that requests carry the rendered body, the headers and the credentials of a component
+func TestHttpTargetProviderApplyRequest(t *testing.T) {
+	var lock sync.Mutex
+	requests := make([]*http.Request, 0)
+	bodies := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{})
+	assert.Nil(t, err)
+	provider.SetSecretProvider(&conformance.FakeSecretProvider{Secrets: map[string]map[string]string{
+		"webhook": {"token": "abc", "username": "user", "password": "pass"},
+	}})
+
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url":          ts.URL + "/hooks/${{$instance()}}",
+			"http.method":       "PUT",
+			"http.bodyTemplate": `{"instance": "{{.Instance}}", "target": "{{.Target}}", "version": "{{index .Properties "version"}}"}`,
+			"http.headers":      map[string]interface{}{"X-Site": "${{$target()}}"},
+			"http.auth":         "bearer",
+			"http.authSecret":   "webhook",
+			"version":           "${{$solution()}}-2",
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(requests))
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/hooks/instance1", requests[0].URL.Path)
+	assert.Equal(t, "Bearer abc", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "target1", requests[0].Header.Get("X-Site"))
+	assert.Equal(t, `{"instance": "instance1", "target": "target1", "version": "solution1-2"}`, bodies[0])
+
+	// a body given as an object is sent as JSON, and headers can be a JSON string
+	delete(component.Properties, "http.bodyTemplate")
+	component.Properties["http.body"] = map[string]interface{}{"instance": "${{$instance()}}"}
+	component.Properties["http.headers"] = `{"X-Site": "hq"}`
+	component.Properties["http.auth"] = "basic"
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(requests))
+	username, password, ok := requests[1].BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+	assert.Equal(t, "hq", requests[1].Header.Get("X-Site"))
+	assert.Equal(t, `{"instance":"instance1"}`, bodies[1])
+
+	// credentials can't be read without a secret provider
+	provider.SecretProvider = nil
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(requests))
+}
+
+// TestHttpTargetProviderApplyTimeout tests that a request that takes longer than http.timeout fails
+func TestHttpTargetProviderApplyTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{Timeout: "100ms"})
+	assert.Nil(t, err)
+
+	component := model.ComponentSpec{
+		Name: "http-component",
+		Properties: map[string]interface{}{
+			"http.url": ts.URL,
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", component)
+	start := time.Now()
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["http-component"].Status)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	component.Properties["http.timeout"] = "50ms"
+	deployment, step = conformance.Deployment("", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+// TestHttpTargetProviderApplyInvalidComponents tests that Apply rejects invalid properties
+func TestHttpTargetProviderApplyInvalidComponents(t *testing.T) {
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{})
+	assert.Nil(t, err)
+	for _, properties := range []map[string]interface{}{
+		{"http.url": "http://localhost", "http.body": "{}", "http.bodyTemplate": "{}"},
+		{"http.url": "http://localhost", "http.successCodes": "ok"},
+		{"http.url": "http://localhost", "http.successCodes": "299-200"},
+		{"http.url": "http://localhost", "http.timeout": "soon"},
+		{"http.url": "http://localhost", "http.auth": "digest", "http.authSecret": "webhook"},
+		{"http.url": "http://localhost", "http.auth": "bearer"},
+		{"http.url": "http://localhost", "http.headers": "X-Site: hq"},
+	} {
+		deployment, step := conformance.Deployment("", "instance1", "update", model.ComponentSpec{Name: "http-component", Properties: properties})
+		_, err = provider.Apply(context.Background(), deployment, step, true)
+		coaErr, ok := err.(v1alpha2.COAError)
+		require.True(t, ok, properties)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, properties)
+	}
+}
+
+// TestHttpTargetProviderGetReadback tests that Get reads back components with a http.getUrl
+func TestHttpTargetProviderGetReadback(t *testing.T) {
+	var lock sync.Mutex
+	resources := map[string]string{
+		"/resources/instance1": `{"instance": "instance1", "replicas": 2, "status": "ready"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.URL.Path == "/broken" {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		v, ok := resources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{})
+	assert.Nil(t, err)
+
+	components := []model.ComponentSpec{
+		{
+			Name: "in-sync",
+			Properties: map[string]interface{}{
+				"http.url":    ts.URL + "/resources",
+				"http.getUrl": ts.URL + "/resources/${{$instance()}}",
+				"http.body":   `{"instance": "${{$instance()}}", "replicas": 2}`,
+			},
+		},
+		{
+			Name: "missing",
+			Properties: map[string]interface{}{
+				"http.url":    ts.URL + "/resources",
+				"http.getUrl": ts.URL + "/resources/other",
+			},
+		},
+		{
+			Name: "webhook",
+			Properties: map[string]interface{}{
+				"http.url": ts.URL + "/hooks",
+			},
+		},
+	}
+	deployment, step := conformance.Deployment("", "instance1", "update", components...)
+	ret, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(ret))
+	assert.Equal(t, "in-sync", ret[0].Name)
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(ret[0], components[0]))
+
+	// the resource drifted
+	lock.Lock()
+	resources["/resources/instance1"] = `{"instance": "instance1", "replicas": 1}`
+	lock.Unlock()
+	ret, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(ret))
+	assert.Equal(t, `{"instance": "instance1", "replicas": 1}`, ret[0].Properties["http.body"])
+	assert.True(t, rule.IsComponentChanged(ret[0], components[0]))
+
+	components[0].Properties["http.getUrl"] = ts.URL + "/broken"
+	deployment, step = conformance.Deployment("", "instance1", "update", components[0])
+	_, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.NotNil(t, err)
+}
+
+// TestHttpTargetProviderRemoveDeleteUrl tests that removing a component with a http.deleteUrl sends a DELETE request
+func TestHttpTargetProviderRemoveDeleteUrl(t *testing.T) {
+	var lock sync.Mutex
+	deleted := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/resources/gone":
+			http.NotFound(w, r)
+		case "/resources/locked":
+			http.Error(w, "locked", http.StatusConflict)
+		default:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer ts.Close()
+
+	provider := HttpTargetProvider{}
+	err := provider.Init(HttpTargetProviderConfig{})
+	assert.Nil(t, err)
+
+	components := []model.ComponentSpec{}
+	for _, name := range []string{"instance1", "gone"} {
+		components = append(components, model.ComponentSpec{
+			Name: name,
+			Properties: map[string]interface{}{
+				"http.url":       ts.URL + "/resources",
+				"http.deleteUrl": ts.URL + "/resources/" + name,
+			},
+		})
+	}
+	components = append(components, model.ComponentSpec{
+		Name:       "webhook",
+		Properties: map[string]interface{}{"http.url": ts.URL + "/hooks"},
+	})
+	deployment, step := conformance.Deployment("", "instance1", "delete", components...)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/resources/instance1"}, deleted)
+	for _, component := range components {
+		assert.Equal(t, v1alpha2.Deleted, ret[component.Name].Status)
+	}
+
+	component := model.ComponentSpec{
+		Name: "locked",
+		Properties: map[string]interface{}{
+			"http.url":       ts.URL + "/resources",
+			"http.deleteUrl": ts.URL + "/resources/locked",
+		},
+	}
+	deployment, step = conformance.Deployment("", "instance1", "delete", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.DeleteFailed, Message: "locked\n"}, ret["locked"])
+}
+
+// TestBodyMatches tests that a readback body matches when it contains the desired body
+func TestBodyMatches(t *testing.T) {
+	assert.True(t, bodyMatches("", "anything"))
+	assert.True(t, bodyMatches(`{"a": {"b": 1}}`, `{"a": {"b": 1, "c": 2}, "d": 3}`))
+	assert.False(t, bodyMatches(`{"a": {"b": 1}}`, `{"a": {"b": 2}}`))
+	assert.False(t, bodyMatches(`{"a": [1, 2]}`, `{"a": [1]}`))
+	assert.True(t, bodyMatches("plain text", "plain text\n"))
+	assert.False(t, bodyMatches("plain text", "other text"))
+}
+
 // TestReadProperty tests that ReadProperty returns the correct value
 func TestReadProperty(t *testing.T) {
 	url := "https://manual-approval.azurewebsites.net:443/api/approval/triggers/manual/invoke?api-version=2022-05-01&sp=%2Ftriggers%2Fmanual%2Frun&sv=1.0&sig=<redacted>"
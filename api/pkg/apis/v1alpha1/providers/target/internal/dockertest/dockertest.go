@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// Package dockertest provides a fake Docker Engine API for the tests of the docker and compose target
+// providers
+package dockertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	volumetypes "github.com/docker/docker/api/types/volume"
+)
+
+// Container is a container of the fake API
+type Container struct {
+	ID               string
+	Name             string
+	State            string
+	Config           *container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+	Networks         []string
+}
+
+// FakeDockerAPI serves the container, image, network and volume endpoints of the Docker Engine API the
+// providers use
+type FakeDockerAPI struct {
+	lock sync.Mutex
+	// RequirePull fails creating a container whose image hasn't been pulled, as the engine does
+	RequirePull  bool
+	Containers   map[string]*Container
+	Networks     map[string]*types.NetworkResource
+	Volumes      map[string]map[string]string
+	Images       map[string]bool
+	Pulled       []string
+	CreatedNames []string
+}
+
+// NewFakeDockerAPI starts a fake API for the duration of a test. The providers' clients reach it through
+// DOCKER_HOST.
+func NewFakeDockerAPI(t *testing.T) *FakeDockerAPI {
+	api := &FakeDockerAPI{
+		Containers: make(map[string]*Container),
+		Networks:   make(map[string]*types.NetworkResource),
+		Volumes:    make(map[string]map[string]string),
+		Images:     make(map[string]bool),
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	t.Setenv("DOCKER_HOST", strings.Replace(server.URL, "http://", "tcp://", 1))
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+	return api
+}
+
+// Container returns the container of a name, or nil
+func (f *FakeDockerAPI) Container(name string) *Container {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.Containers[name]
+}
+
+// Add adds a created container
+func (f *FakeDockerAPI) Add(name string, config *container.Config, hostConfig *container.HostConfig) *Container {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.add(name, config, hostConfig, nil)
+}
+
+func (f *FakeDockerAPI) add(name string, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) *Container {
+	c := &Container{
+		ID:               "id-" + name,
+		Name:             name,
+		State:            "created",
+		Config:           config,
+		HostConfig:       hostConfig,
+		NetworkingConfig: networkingConfig,
+	}
+	f.Containers[name] = c
+	return c
+}
+
+// findContainer looks a container up by name or ID
+func (f *FakeDockerAPI) findContainer(key string) *Container {
+	for _, c := range f.Containers {
+		if c.Name == key || c.ID == key {
+			return c
+		}
+	}
+	return nil
+}
+
+func (f *FakeDockerAPI) findNetwork(key string) *types.NetworkResource {
+	for _, n := range f.Networks {
+		if n.Name == key || n.ID == key {
+			return n
+		}
+	}
+	return nil
+}
+
+func (c *Container) inspect() types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.ID,
+			Name:       "/" + c.Name,
+			State:      &types.ContainerState{Status: c.State, Running: c.State == "running"},
+			HostConfig: c.HostConfig,
+		},
+		Config:          c.Config,
+		NetworkSettings: &types.NetworkSettings{},
+	}
+}
+
+// matchLabels tells whether labels match the label filters of a list request
+func matchLabels(r *http.Request, labels map[string]string) bool {
+	var args map[string]map[string]bool
+	if data := r.URL.Query().Get("filters"); data != "" {
+		json.Unmarshal([]byte(data), &args)
+	}
+	for filter := range args["label"] {
+		pair := strings.SplitN(filter, "=", 2)
+		if v, ok := labels[pair[0]]; !ok || (len(pair) == 2 && v != pair[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FakeDockerAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// strip the API version, such as /v1.41
+	p := r.URL.Path
+	if strings.HasPrefix(p, "/v") {
+		p = p[strings.Index(p[1:], "/")+1:]
+	}
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	notFound := func(message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": message})
+	}
+
+	switch {
+	case r.Method == http.MethodGet && p == "/containers/json":
+		ret := make([]types.Container, 0)
+		for _, c := range f.Containers {
+			if matchLabels(r, c.Config.Labels) {
+				ret = append(ret, types.Container{ID: c.ID, Names: []string{"/" + c.Name}, Image: c.Config.Image, Labels: c.Config.Labels, State: c.State})
+			}
+		}
+		json.NewEncoder(w).Encode(ret)
+	case r.Method == http.MethodPost && p == "/containers/create":
+		var body struct {
+			container.Config
+			HostConfig       *container.HostConfig
+			NetworkingConfig *network.NetworkingConfig
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		name := r.URL.Query().Get("name")
+		if f.findContainer(name) != nil {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Conflict"}`))
+			return
+		}
+		if f.RequirePull && !f.Images[body.Config.Image] {
+			notFound("No such image: " + body.Config.Image)
+			return
+		}
+		config := body.Config
+		c := f.add(name, &config, body.HostConfig, body.NetworkingConfig)
+		if body.HostConfig != nil && f.findNetwork(string(body.HostConfig.NetworkMode)) != nil {
+			c.Networks = append(c.Networks, string(body.HostConfig.NetworkMode))
+		}
+		f.CreatedNames = append(f.CreatedNames, name)
+		json.NewEncoder(w).Encode(container.ContainerCreateCreatedBody{ID: c.ID})
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "containers" && segments[2] == "json":
+		c := f.findContainer(segments[1])
+		if c == nil {
+			notFound("No such container")
+			return
+		}
+		json.NewEncoder(w).Encode(c.inspect())
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "containers" && (segments[2] == "start" || segments[2] == "stop"):
+		c := f.findContainer(segments[1])
+		if c == nil {
+			notFound("No such container")
+			return
+		}
+		if segments[2] == "start" {
+			c.State = "running"
+		} else {
+			c.State = "exited"
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && len(segments) == 2 && segments[0] == "containers":
+		c := f.findContainer(segments[1])
+		if c == nil {
+			notFound("No such container")
+			return
+		}
+		delete(f.Containers, c.Name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && p == "/images/create":
+		image := path.Base(r.URL.Query().Get("fromImage")) + ":" + r.URL.Query().Get("tag")
+		f.Images[image] = true
+		f.Pulled = append(f.Pulled, image)
+		w.Write([]byte(`{"status":"Downloaded newer image"}`))
+	case r.Method == http.MethodGet && p == "/networks":
+		ret := make([]types.NetworkResource, 0)
+		for _, n := range f.Networks {
+			if matchLabels(r, n.Labels) {
+				ret = append(ret, *n)
+			}
+		}
+		json.NewEncoder(w).Encode(ret)
+	case r.Method == http.MethodPost && p == "/networks/create":
+		var body types.NetworkCreateRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if f.findNetwork(body.Name) != nil {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"network already exists"}`))
+			return
+		}
+		f.Networks[body.Name] = &types.NetworkResource{ID: "net-" + body.Name, Name: body.Name, Driver: body.Driver, Labels: body.Labels}
+		json.NewEncoder(w).Encode(types.NetworkCreateResponse{ID: "net-" + body.Name})
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "networks" && segments[2] == "connect":
+		var body types.NetworkConnect
+		json.NewDecoder(r.Body).Decode(&body)
+		n := f.findNetwork(segments[1])
+		c := f.findContainer(body.Container)
+		if n == nil || c == nil {
+			notFound("No such network or container")
+			return
+		}
+		c.Networks = append(c.Networks, n.Name)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete && len(segments) == 2 && segments[0] == "networks":
+		n := f.findNetwork(segments[1])
+		if n == nil {
+			notFound("No such network")
+			return
+		}
+		delete(f.Networks, n.Name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && p == "/volumes/create":
+		var body volumetypes.VolumeCreateBody
+		json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := f.Volumes[body.Name]; !ok {
+			f.Volumes[body.Name] = body.Labels
+		}
+		json.NewEncoder(w).Encode(types.Volume{Name: body.Name, Labels: f.Volumes[body.Name]})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// Package dockerutil holds the Docker client helpers the docker and compose target providers share
+package dockerutil
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// RemoveContainer stops and removes a container. A container that's already gone isn't an error.
+func RemoveContainer(ctx context.Context, cli *client.Client, id string) error {
+	err := cli.ContainerStop(ctx, id, nil)
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	err = cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{})
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -8,10 +8,13 @@ package k8s
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -27,9 +30,12 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -45,6 +51,8 @@ const (
 	SERVICES     string = "services"
 	SERVICES_NS  string = "ns-services"
 	SERVICES_HNS string = "hns-services" //TODO: future versions
+	// jobSpecHashAnnotation is the hash of the spec a Job was created with
+	jobSpecHashAnnotation string = "symphony/job-spec-hash"
 )
 
 type K8sTargetProviderConfig struct {
@@ -58,6 +66,7 @@ type K8sTargetProviderConfig struct {
 	DeleteEmptyNamespace bool   `json:"deleteEmptyNamespace"`
 	RetryCount           int    `json:"retryCount"`
 	RetryIntervalInSec   int    `json:"retryIntervalInSec"`
+	WaitForRollout       bool   `json:"waitForRollout"`
 }
 
 type K8sTargetProvider struct {
@@ -112,6 +121,16 @@ func K8sTargetProviderConfigFromMap(properties map[string]string) (K8sTargetProv
 			ret.DeleteEmptyNamespace = bVal
 		}
 	}
+	if v, ok := properties["projector"]; ok {
+		ret.Projector = v
+	}
+	if v, ok := properties["waitForRollout"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'waitForRollout' setting of K8s reference provider", v1alpha2.BadConfig)
+		}
+		ret.WaitForRollout = bVal
+	}
 	if v, ok := properties["retryCount"]; ok && v != "" {
 		ival, err := strconv.Atoi(v)
 		if err != nil {
@@ -251,23 +270,26 @@ func (i *K8sTargetProvider) Get(ctx context.Context, dep model.DeploymentSpec, r
 
 	var components []model.ComponentSpec
 
-	switch i.Config.DeploymentStrategy {
-	case "", SINGLE_POD:
-		components, err = i.getDeployment(ctx, dep.Instance.Scope, dep.Instance.Name)
+	projector, err := createProjector(i.Config.Projector)
+	if err != nil {
+		log.Debugf("  P (K8s Target Provider): failed to create projector: %s", err.Error())
+		return nil, err
+	}
+	kind, perComponent := i.getLayout(projector)
+
+	if !perComponent {
+		components, err = i.getWorkload(ctx, dep.Instance.Scope, dep.Instance.Name, kind)
 		if err != nil {
 			log.Debugf("  P (K8s Target Provider): failed to get - %s", err.Error())
 			return nil, err
 		}
-	case SERVICES, SERVICES_NS:
+	} else {
 		components = make([]model.ComponentSpec, 0)
-		scope := dep.Instance.Scope
-		if i.Config.DeploymentStrategy == SERVICES_NS {
-			scope = dep.Instance.Name
-		}
+		scope := i.getComponentScope(dep)
 		slice := dep.GetComponentSlice()
 		for _, component := range slice {
 			var cComponents []model.ComponentSpec
-			cComponents, err = i.getDeployment(ctx, scope, component.Name)
+			cComponents, err = i.getWorkload(ctx, scope, component.Name, kind)
 			if err != nil {
 				log.Debugf("  P (K8s Target Provider) - failed to get: %s", err.Error())
 				return nil, err
@@ -300,6 +322,64 @@ func (i *K8sTargetProvider) Get(ctx context.Context, dep model.DeploymentSpec, r
 
 	return components, nil
 }
+
+// getLayout returns the kind of the workloads, and if each component gets its own workload. Components share a
+// workload with the single pod strategy, unless the projector deploys each component as its own workload.
+func (i *K8sTargetProvider) getLayout(projector IK8sProjector) (string, bool) {
+	perComponent := i.Config.DeploymentStrategy == SERVICES || i.Config.DeploymentStrategy == SERVICES_NS
+	if p, ok := projector.(projectors.IWorkloadProjector); ok {
+		return p.Kind(), perComponent || p.PerComponent()
+	}
+	return projectors.KIND_DEPLOYMENT, perComponent
+}
+
+// getComponentScope returns the namespace of the workloads of components that get their own workload
+func (i *K8sTargetProvider) getComponentScope(dep model.DeploymentSpec) string {
+	if i.Config.DeploymentStrategy == SERVICES_NS {
+		return dep.Instance.Name
+	}
+	return dep.Instance.Scope
+}
+
+// getWorkload reads the components of a workload, or nil if the workload doesn't exist
+func (i *K8sTargetProvider) getWorkload(ctx context.Context, scope string, name string, kind string) ([]model.ComponentSpec, error) {
+	var template *apiv1.PodTemplateSpec
+	var err error
+	switch kind {
+	case projectors.KIND_STATEFULSET:
+		var statefulSet *v1.StatefulSet
+		statefulSet, err = i.Client.AppsV1().StatefulSets(scope).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			template = &statefulSet.Spec.Template
+		}
+	case projectors.KIND_DAEMONSET:
+		var daemonSet *v1.DaemonSet
+		daemonSet, err = i.Client.AppsV1().DaemonSets(scope).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			template = &daemonSet.Spec.Template
+		}
+	case projectors.KIND_JOB:
+		var job *batchv1.Job
+		job, err = i.Client.BatchV1().Jobs(scope).Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			template = &job.Spec.Template
+		}
+	default:
+		return i.getDeployment(ctx, scope, name)
+	}
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	components, err := podTemplateToComponents(*template)
+	if err != nil {
+		log.Infof("  P (K8s Target Provider): getWorkload failed - %s", err.Error())
+		return nil, err
+	}
+	return components, nil
+}
 func (i *K8sTargetProvider) removeService(ctx context.Context, scope string, serviceName string) error {
 	svc, err := i.Client.CoreV1().Services(scope).Get(ctx, serviceName, metav1.GetOptions{})
 	if err == nil && svc != nil {
@@ -324,6 +404,25 @@ func (i *K8sTargetProvider) removeDeployment(ctx context.Context, scope string,
 
 	return nil
 }
+func (i *K8sTargetProvider) removeWorkload(ctx context.Context, scope string, name string, kind string) error {
+	foregroundDeletion := metav1.DeletePropagationForeground
+	options := metav1.DeleteOptions{PropagationPolicy: &foregroundDeletion}
+	var err error
+	switch kind {
+	case projectors.KIND_STATEFULSET:
+		err = i.Client.AppsV1().StatefulSets(scope).Delete(ctx, name, options)
+	case projectors.KIND_DAEMONSET:
+		err = i.Client.AppsV1().DaemonSets(scope).Delete(ctx, name, options)
+	case projectors.KIND_JOB:
+		err = i.Client.BatchV1().Jobs(scope).Delete(ctx, name, options)
+	default:
+		return i.removeDeployment(ctx, scope, name)
+	}
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
 func (i *K8sTargetProvider) removeNamespace(ctx context.Context, scope string, retryCount int, retryIntervalInSec int) error {
 	_, err := i.Client.CoreV1().Namespaces().Get(ctx, scope, metav1.GetOptions{})
 	if err != nil {
@@ -433,6 +532,78 @@ func (i *K8sTargetProvider) upsertDeployment(ctx context.Context, scope string,
 	}
 	return nil
 }
+func (i *K8sTargetProvider) upsertStatefulSet(ctx context.Context, scope string, name string, statefulSet *v1.StatefulSet) error {
+	existing, err := i.Client.AppsV1().StatefulSets(scope).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	if k8s_errors.IsNotFound(err) {
+		_, err = i.Client.AppsV1().StatefulSets(scope).Create(ctx, statefulSet, metav1.CreateOptions{})
+	} else {
+		statefulSet.ResourceVersion = existing.ResourceVersion
+		_, err = i.Client.AppsV1().StatefulSets(scope).Update(ctx, statefulSet, metav1.UpdateOptions{})
+	}
+	return err
+}
+func (i *K8sTargetProvider) upsertDaemonSet(ctx context.Context, scope string, name string, daemonSet *v1.DaemonSet) error {
+	existing, err := i.Client.AppsV1().DaemonSets(scope).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	if k8s_errors.IsNotFound(err) {
+		_, err = i.Client.AppsV1().DaemonSets(scope).Create(ctx, daemonSet, metav1.CreateOptions{})
+	} else {
+		daemonSet.ResourceVersion = existing.ResourceVersion
+		_, err = i.Client.AppsV1().DaemonSets(scope).Update(ctx, daemonSet, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// replaceJob creates a Job. The pod template of a Job can't be updated, so an existing Job is deleted
+// and created again, but only if its spec changed: a Job that already ran to completion isn't run again.
+// The spec is compared through a hash annotation, as the API server adds defaults and labels to it.
+func (i *K8sTargetProvider) replaceJob(ctx context.Context, scope string, name string, job *batchv1.Job) error {
+	data, err := json.Marshal(job.Spec)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	if job.Annotations == nil {
+		job.Annotations = make(map[string]string)
+	}
+	job.Annotations[jobSpecHashAnnotation] = hex.EncodeToString(hash[:])
+
+	existing, err := i.Client.BatchV1().Jobs(scope).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if existing.Annotations[jobSpecHashAnnotation] == job.Annotations[jobSpecHashAnnotation] {
+			log.Debugf("  P (K8s Target Provider): job %s is unchanged", name)
+			return nil
+		}
+		backgroundDeletion := metav1.DeletePropagationBackground
+		err = i.Client.BatchV1().Jobs(scope).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &backgroundDeletion})
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return err
+		}
+	}
+	_, err = i.Client.BatchV1().Jobs(scope).Create(ctx, job, metav1.CreateOptions{})
+	return err
+}
+func (i *K8sTargetProvider) upsertWorkload(ctx context.Context, scope string, name string, workload runtime.Object) error {
+	switch w := workload.(type) {
+	case *v1.Deployment:
+		return i.upsertDeployment(ctx, scope, name, w)
+	case *v1.StatefulSet:
+		return i.upsertStatefulSet(ctx, scope, name, w)
+	case *v1.DaemonSet:
+		return i.upsertDaemonSet(ctx, scope, name, w)
+	case *batchv1.Job:
+		return i.replaceJob(ctx, scope, name, w)
+	}
+	return v1alpha2.NewCOAError(nil, fmt.Sprintf("workload type %T is unsupported", workload), v1alpha2.BadConfig)
+}
 func (i *K8sTargetProvider) upsertService(ctx context.Context, scope string, name string, service *apiv1.Service) error {
 	existing, err := i.Client.CoreV1().Services(scope).Get(ctx, name, metav1.GetOptions{})
 	if err != nil && !k8s_errors.IsNotFound(err) {
@@ -453,17 +624,30 @@ func (i *K8sTargetProvider) deployComponents(ctx context.Context, span trace.Spa
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
 
+	metadata, err = serviceMetadata(metadata, components)
+	if err != nil {
+		log.Debugf("  P (K8s Target Provider): failed to apply (service properties): %s", err.Error())
+		return err
+	}
 	deployment, err := componentsToDeployment(scope, name, metadata, components, instanceName)
+	if err != nil {
+		log.Debugf("  P (K8s Target Provider): failed to apply: %s", err.Error())
+		return err
+	}
+	var workload runtime.Object = deployment
 	if projector != nil {
 		err = projector.ProjectDeployment(scope, name, metadata, components, deployment)
 		if err != nil {
 			log.Debugf("  P (K8s Target Provider): failed to project deployment: %s", err.Error())
 			return err
 		}
-	}
-	if err != nil {
-		log.Debugf("  P (K8s Target Provider): failed to apply: %s", err.Error())
-		return err
+		if p, ok := projector.(projectors.IWorkloadProjector); ok {
+			workload, err = p.ProjectWorkload(scope, name, metadata, components, deployment)
+			if err != nil {
+				log.Debugf("  P (K8s Target Provider): failed to project workload: %s", err.Error())
+				return err
+			}
+		}
 	}
 	service, err := metadataToService(scope, name, metadata)
 	if err != nil {
@@ -485,8 +669,9 @@ func (i *K8sTargetProvider) deployComponents(ctx context.Context, span trace.Spa
 		return err
 	}
 
-	log.Debug("  P (K8s Target Provider): creating deployment")
-	err = i.upsertDeployment(ctx, scope, name, deployment)
+	kind, _ := i.getLayout(projector)
+	log.Debugf("  P (K8s Target Provider): creating %s", kind)
+	err = i.upsertWorkload(ctx, scope, name, workload)
 	if err != nil {
 		log.Debugf("  P (K8s Target Provider): failed to apply (API): %s", err.Error())
 		return err
@@ -500,8 +685,125 @@ func (i *K8sTargetProvider) deployComponents(ctx context.Context, span trace.Spa
 			return err
 		}
 	}
+
+	if i.Config.WaitForRollout {
+		err = i.waitForRollout(ctx, scope, name, kind)
+		if err != nil {
+			log.Debugf("  P (K8s Target Provider): failed to roll out: %s", err.Error())
+			return err
+		}
+	}
 	return nil
 }
+
+// waitForRollout checks if a workload is rolled out up to RetryCount times, RetryIntervalInSec seconds apart
+func (i *K8sTargetProvider) waitForRollout(ctx context.Context, scope string, name string, kind string) error {
+	count := i.Config.RetryCount
+	if count < 1 {
+		count = 1
+	}
+	for attempt := 1; ; attempt++ {
+		ready, err := i.isWorkloadReady(ctx, scope, name, kind)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		if attempt >= count {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s '%s' isn't rolled out after %d checks", kind, name, count), v1alpha2.InternalError)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * time.Duration(i.Config.RetryIntervalInSec)):
+		}
+	}
+}
+func (i *K8sTargetProvider) isWorkloadReady(ctx context.Context, scope string, name string, kind string) (bool, error) {
+	switch kind {
+	case projectors.KIND_STATEFULSET:
+		statefulSet, err := i.Client.AppsV1().StatefulSets(scope).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isStatefulSetReady(statefulSet), nil
+	case projectors.KIND_DAEMONSET:
+		daemonSet, err := i.Client.AppsV1().DaemonSets(scope).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isDaemonSetReady(daemonSet), nil
+	case projectors.KIND_JOB:
+		job, err := i.Client.BatchV1().Jobs(scope).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isJobReady(job)
+	default:
+		deployment, err := i.Client.AppsV1().Deployments(scope).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isDeploymentReady(deployment)
+	}
+}
+
+// isDeploymentReady checks if all replicas of a Deployment are updated and available, the way kubectl rollout status
+// does. A Deployment that exceeded its progress deadline returns an error, as it won't become ready.
+func isDeploymentReady(deployment *v1.Deployment) (bool, error) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, nil
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == v1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("deployment '%s' exceeded its progress deadline", deployment.Name), v1alpha2.InternalError)
+		}
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.UpdatedReplicas >= replicas && status.Replicas <= status.UpdatedReplicas && status.AvailableReplicas >= status.UpdatedReplicas, nil
+}
+func isStatefulSetReady(statefulSet *v1.StatefulSet) bool {
+	if statefulSet.Spec.UpdateStrategy.Type == v1.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	return status.ReadyReplicas >= replicas && status.UpdatedReplicas >= replicas && status.CurrentRevision == status.UpdateRevision
+}
+func isDaemonSetReady(daemonSet *v1.DaemonSet) bool {
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return false
+	}
+	status := daemonSet.Status
+	return status.UpdatedNumberScheduled >= status.DesiredNumberScheduled && status.NumberAvailable >= status.DesiredNumberScheduled
+}
+
+// isJobReady checks if a Job completed. A failed Job returns an error, as it won't complete.
+func isJobReady(job *batchv1.Job) (bool, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != apiv1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("job '%s' failed: %s", job.Name, condition.Message), v1alpha2.InternalError)
+		}
+	}
+	return false, nil
+}
 func (*K8sTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
 		RequiredProperties:    []string{model.ContainerImage},
@@ -512,6 +814,13 @@ func (*K8sTargetProvider) GetValidationRule(ctx context.Context) model.Validatio
 		ChangeDetectionProperties: []model.PropertyDesc{
 			{Name: model.ContainerImage, IgnoreCase: true, SkipIfMissing: false},
 			{Name: "env.*", IgnoreCase: true, SkipIfMissing: true},
+			{Name: "container.env", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.envFrom", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.livenessProbe", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.readinessProbe", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.startupProbe", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "container.initContainers", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "service.*", IgnoreCase: false, SkipIfMissing: true},
 		},
 	}
 }
@@ -542,30 +851,33 @@ func (i *K8sTargetProvider) Apply(ctx context.Context, dep model.DeploymentSpec,
 		return ret, err
 	}
 
-	switch i.Config.DeploymentStrategy {
-	case "", SINGLE_POD:
+	kind, perComponent := i.getLayout(projector)
+	if !perComponent {
 		updated := step.GetUpdatedComponents()
 		if len(updated) > 0 {
 			err = i.deployComponents(ctx, span, dep.Instance.Scope, dep.Instance.Name, dep.Instance.Metadata, components, projector, dep.Instance.Name)
 			if err != nil {
+				for _, component := range updated {
+					ret[component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.UpdateFailed,
+						Message: err.Error(),
+					}
+				}
 				log.Debugf("  P (K8s Target Provider): failed to apply components: %s", err.Error())
 				return ret, err
 			}
 		}
 		deleted := step.GetDeletedComponents()
 		if len(deleted) > 0 {
-			serviceName := dep.Instance.Name
-			if v, ok := dep.Instance.Metadata["service.name"]; ok && v != "" {
-				serviceName = v
-			}
+			serviceName := getServiceName(dep.Instance.Name, dep.Instance.Metadata, deleted)
 			err = i.removeService(ctx, dep.Instance.Scope, serviceName)
 			if err != nil {
 				log.Debugf("failed to remove service: %s", err.Error())
 				return ret, err
 			}
-			err = i.removeDeployment(ctx, dep.Instance.Scope, dep.Instance.Name)
+			err = i.removeWorkload(ctx, dep.Instance.Scope, dep.Instance.Name, kind)
 			if err != nil {
-				log.Debugf("failed to remove deployment: %s", err.Error())
+				log.Debugf("failed to remove workload: %s", err.Error())
 				return ret, err
 			}
 			if i.Config.DeleteEmptyNamespace {
@@ -575,76 +887,64 @@ func (i *K8sTargetProvider) Apply(ctx context.Context, dep model.DeploymentSpec,
 				}
 			}
 		}
-	case SERVICES, SERVICES_NS:
-		updated := step.GetUpdatedComponents()
-		if len(updated) > 0 {
-			scope := dep.Instance.Scope
-			if i.Config.DeploymentStrategy == SERVICES_NS {
-				scope = dep.Instance.Name
-			}
-			for _, component := range components {
-				if dep.Instance.Metadata != nil {
-					if v, ok := dep.Instance.Metadata[ENV_NAME]; ok && v != "" {
-						if component.Metadata == nil {
-							component.Metadata = make(map[string]string)
-						}
-						component.Metadata[ENV_NAME] = v
+	} else {
+		scope := i.getComponentScope(dep)
+		for _, component := range step.GetUpdatedComponents() {
+			if dep.Instance.Metadata != nil {
+				if v, ok := dep.Instance.Metadata[ENV_NAME]; ok && v != "" {
+					if component.Metadata == nil {
+						component.Metadata = make(map[string]string)
 					}
+					component.Metadata[ENV_NAME] = v
 				}
-				err = i.deployComponents(ctx, span, scope, component.Name, component.Metadata, []model.ComponentSpec{component}, projector, dep.Instance.Name)
-				if err != nil {
-					log.Debugf("  P (K8s Target Provider): failed to apply components: %s", err.Error())
-					return ret, err
+			}
+			err = i.deployComponents(ctx, span, scope, component.Name, component.Metadata, []model.ComponentSpec{component}, projector, dep.Instance.Name)
+			if err != nil {
+				ret[component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
 				}
+				log.Debugf("  P (K8s Target Provider): failed to apply components: %s", err.Error())
+				return ret, err
 			}
 		}
-		deleted := step.GetDeletedComponents()
-		if len(deleted) > 0 {
-			scope := dep.Instance.Scope
-			if i.Config.DeploymentStrategy == SERVICES_NS {
-				scope = dep.Instance.Name
-			}
-			for _, component := range deleted {
-				serviceName := component.Name
-				if component.Metadata != nil {
-					if v, ok := component.Metadata["service.name"]; ok {
-						serviceName = v
-					}
+		for _, component := range step.GetDeletedComponents() {
+			serviceName := getServiceName(component.Name, component.Metadata, []model.ComponentSpec{component})
+			err = i.removeService(ctx, scope, serviceName)
+			if err != nil {
+				ret[component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
 				}
-				err = i.removeService(ctx, scope, serviceName)
-				if err != nil {
-					ret[component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.DeleteFailed,
-						Message: err.Error(),
-					}
-					log.Debugf("failed to remove service: %s", err.Error())
-					return ret, err
+				log.Debugf("failed to remove service: %s", err.Error())
+				return ret, err
+			}
+			err = i.removeWorkload(ctx, scope, component.Name, kind)
+			if err != nil {
+				ret[component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
 				}
-				err = i.removeDeployment(ctx, scope, component.Name)
+				log.Debugf("failed to remove workload: %s", err.Error())
+				return ret, err
+			}
+			if i.Config.DeleteEmptyNamespace {
+				err = i.removeNamespace(ctx, dep.Instance.Scope, i.Config.RetryCount, i.Config.RetryIntervalInSec)
 				if err != nil {
-					ret[component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.DeleteFailed,
-						Message: err.Error(),
-					}
-					log.Debugf("failed to remove deployment: %s", err.Error())
-					return ret, err
-				}
-				if i.Config.DeleteEmptyNamespace {
-					err = i.removeNamespace(ctx, dep.Instance.Scope, i.Config.RetryCount, i.Config.RetryIntervalInSec)
-					if err != nil {
-						log.Debugf("failed to remove namespace: %s", err.Error())
-					}
+					log.Debugf("failed to remove namespace: %s", err.Error())
 				}
 			}
-
 		}
 	}
 	err = nil
 	return ret, nil
 }
 func deploymentToComponents(deployment v1.Deployment) ([]model.ComponentSpec, error) {
-	components := make([]model.ComponentSpec, len(deployment.Spec.Template.Spec.Containers))
-	for i, c := range deployment.Spec.Template.Spec.Containers {
+	return podTemplateToComponents(deployment.Spec.Template)
+}
+func podTemplateToComponents(template apiv1.PodTemplateSpec) ([]model.ComponentSpec, error) {
+	components := make([]model.ComponentSpec, len(template.Spec.Containers))
+	for i, c := range template.Spec.Containers {
 		component := model.ComponentSpec{
 			Name:       c.Name,
 			Properties: make(map[string]interface{}),
@@ -675,8 +975,31 @@ func deploymentToComponents(deployment v1.Deployment) ([]model.ComponentSpec, er
 			component.Properties["container.volumeMounts"] = string(volumeMounts)
 		}
 		if len(c.Env) > 0 {
+			references := make([]apiv1.EnvVar, 0)
 			for _, e := range c.Env {
-				component.Properties["env."+e.Name] = e.Value
+				if e.ValueFrom != nil {
+					references = append(references, e)
+				} else {
+					component.Properties["env."+e.Name] = e.Value
+				}
+			}
+			if len(references) > 0 {
+				env, _ := json.Marshal(references)
+				component.Properties["container.env"] = string(env)
+			}
+		}
+		if len(c.EnvFrom) > 0 {
+			envFrom, _ := json.Marshal(c.EnvFrom)
+			component.Properties["container.envFrom"] = string(envFrom)
+		}
+		for property, probe := range map[string]*apiv1.Probe{
+			"container.livenessProbe":  c.LivenessProbe,
+			"container.readinessProbe": c.ReadinessProbe,
+			"container.startupProbe":   c.StartupProbe,
+		} {
+			if probe != nil {
+				data, _ := json.Marshal(probe)
+				component.Properties[property] = string(data)
 			}
 		}
 		components[i] = component
@@ -732,6 +1055,90 @@ func metadataToService(scope string, name string, metadata map[string]string) (*
 	}
 	return &service, nil
 }
+
+// serviceMetadata adds the service.* properties of components to the metadata of a workload, where the metadata
+// doesn't set them. The container ports of the components whose service.expose property is true are exposed, unless
+// service ports are set.
+func serviceMetadata(metadata map[string]string, components []model.ComponentSpec) (map[string]string, error) {
+	ret := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		ret[k] = v
+	}
+	exposed := make([]apiv1.ServicePort, 0)
+	for _, c := range components {
+		for k, v := range c.Properties {
+			if !strings.HasPrefix(k, "service.") || k == "service.expose" {
+				continue
+			}
+			if _, ok := ret[k]; !ok {
+				ret[k] = fmt.Sprintf("%v", v)
+			}
+		}
+		v, ok := c.Properties["service.expose"]
+		if !ok {
+			continue
+		}
+		expose, err := strconv.ParseBool(fmt.Sprintf("%v", v))
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the 'service.expose' property of component '%s'", c.Name), v1alpha2.BadRequest)
+		}
+		if !expose {
+			continue
+		}
+		ports := make([]apiv1.ContainerPort, 0)
+		if v, ok := c.Properties["container.ports"]; ok && v != "" {
+			err = readJSONProperty(v, &ports)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, port := range ports {
+			duplicate := false
+			for _, e := range exposed {
+				if e.Port == port.ContainerPort {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				exposed = append(exposed, apiv1.ServicePort{
+					Name:       fmt.Sprintf("port%d", port.ContainerPort),
+					Port:       port.ContainerPort,
+					TargetPort: intstr.FromInt(int(port.ContainerPort)),
+					Protocol:   port.Protocol,
+				})
+			}
+		}
+	}
+	if _, ok := ret["service.ports"]; !ok && len(exposed) > 0 {
+		data, _ := json.Marshal(exposed)
+		ret["service.ports"] = string(data)
+	}
+	return ret, nil
+}
+
+// getServiceName returns the name of the service of a workload
+func getServiceName(name string, metadata map[string]string, components []model.ComponentSpec) string {
+	collection, err := serviceMetadata(metadata, components)
+	if err != nil {
+		collection = metadata
+	}
+	if v, ok := collection["service.name"]; ok && v != "" {
+		return v
+	}
+	return name
+}
+
+// readJSONProperty reads a property that is either a JSON string or an object
+func readJSONProperty(value interface{}, out interface{}) error {
+	if s, ok := value.(string); ok {
+		return json.Unmarshal([]byte(s), out)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
 func int32Ptr(i int32) *int32 { return &i }
 func componentsToDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, instanceName string) (*v1.Deployment, error) {
 	deployment := v1.Deployment{
@@ -804,11 +1211,47 @@ func componentsToDeployment(scope string, name string, metadata map[string]strin
 			}
 			container.VolumeMounts = mounts
 		}
-		for k, v := range c.Properties {
+		for property, probe := range map[string]**apiv1.Probe{
+			"container.livenessProbe":  &container.LivenessProbe,
+			"container.readinessProbe": &container.ReadinessProbe,
+			"container.startupProbe":   &container.StartupProbe,
+		} {
+			if v, ok := c.Properties[property]; ok && v != "" {
+				*probe = &apiv1.Probe{}
+				e := readJSONProperty(v, *probe)
+				if e != nil {
+					return nil, v1alpha2.NewCOAError(e, fmt.Sprintf("invalid '%s' property of component '%s'", property, c.Name), v1alpha2.BadRequest)
+				}
+			}
+		}
+		if v, ok := c.Properties["container.envFrom"]; ok && v != "" {
+			envFrom := make([]apiv1.EnvFromSource, 0)
+			e := readJSONProperty(v, &envFrom)
+			if e != nil {
+				return nil, v1alpha2.NewCOAError(e, fmt.Sprintf("invalid 'container.envFrom' property of component '%s'", c.Name), v1alpha2.BadRequest)
+			}
+			container.EnvFrom = envFrom
+		}
+		if v, ok := c.Properties["container.env"]; ok && v != "" {
+			env := make([]apiv1.EnvVar, 0)
+			e := readJSONProperty(v, &env)
+			if e != nil {
+				return nil, v1alpha2.NewCOAError(e, fmt.Sprintf("invalid 'container.env' property of component '%s'", c.Name), v1alpha2.BadRequest)
+			}
+			container.Env = append(container.Env, env...)
+		}
+		// env.* properties are added in the order of their names, so that the pod template doesn't change between
+		// deployments of the same component
+		keys := make([]string, 0, len(c.Properties))
+		for k := range c.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
 			// Transitioning from map[string]string to map[string]interface{}
 			// for now we'll assume that all relevant values are strings till we
 			// refactor the code to handle the new format
-			sv := fmt.Sprintf("%v", v)
+			sv := fmt.Sprintf("%v", c.Properties[k])
 			if strings.HasPrefix(k, "env.") {
 				if container.Env == nil {
 					container.Env = make([]apiv1.EnvVar, 0)
@@ -819,6 +1262,14 @@ func componentsToDeployment(scope string, name string, metadata map[string]strin
 				})
 			}
 		}
+		if v, ok := c.Properties["container.initContainers"]; ok && v != "" {
+			initContainers := make([]apiv1.Container, 0)
+			e := readJSONProperty(v, &initContainers)
+			if e != nil {
+				return nil, v1alpha2.NewCOAError(e, fmt.Sprintf("invalid 'container.initContainers' property of component '%s'", c.Name), v1alpha2.BadRequest)
+			}
+			deployment.Spec.Template.Spec.InitContainers = append(deployment.Spec.Template.Spec.InitContainers, initContainers...)
+		}
 		agentName := metadata[ENV_NAME]
 		if agentName != "" {
 			if container.Env == nil {
@@ -863,16 +1314,10 @@ func componentsToDeployment(scope string, name string, metadata map[string]strin
 }
 
 func createProjector(projector string) (IK8sProjector, error) {
-	switch projector {
-	case "noop":
-		return &projectors.NoOpProjector{}, nil
-	case "":
+	if projector == "" {
 		return nil, nil
 	}
-	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("project type '%s' is unsupported", projector), v1alpha2.BadConfig)
+	return projectors.CreateProjector(projector)
 }
 
-type IK8sProjector interface {
-	ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error
-	ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error
-}
+type IK8sProjector = projectors.IK8sProjector
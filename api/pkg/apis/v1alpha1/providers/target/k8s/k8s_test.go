@@ -13,10 +13,14 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/k8s/projectors"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
@@ -518,6 +522,394 @@ func TestNullProjector(t *testing.T) {
 	assert.Nil(t, projector)
 }
 
+func TestK8sTargetProviderConfigFromMapWaitForRollout(t *testing.T) {
+	config, err := K8sTargetProviderConfigFromMap(map[string]string{
+		"projector":      "statefulset",
+		"waitForRollout": "true",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "statefulset", config.Projector)
+	assert.True(t, config.WaitForRollout)
+	_, err = K8sTargetProviderConfigFromMap(map[string]string{
+		"waitForRollout": "sometimes",
+	})
+	assert.NotNil(t, err)
+}
+
+func workloadTestSteps() (model.DeploymentSpec, model.DeploymentStep, model.DeploymentStep) {
+	component := model.ComponentSpec{
+		Name: "test-1",
+		Properties: map[string]interface{}{
+			"container.image": "nginx:latest",
+			"container.ports": "[{\"containerPort\":8080}]",
+			"service.expose":  "true",
+		},
+		Metadata: map[string]string{},
+	}
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{
+			Name:  "instance-1",
+			Scope: "default",
+		},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{component},
+		},
+		ComponentStartIndex: 0,
+		ComponentEndIndex:   1,
+	}
+	updateStep := model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: "update", Component: component}},
+	}
+	deleteStep := model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: "delete", Component: component}},
+	}
+	return deployment, updateStep, deleteStep
+}
+
+func TestApplyStatefulSet(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{Projector: "statefulset"}, Client: client}
+	deployment, updateStep, deleteStep := workloadTestSteps()
+
+	_, err := provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	statefulSet, err := client.AppsV1().StatefulSets("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "instance-1", statefulSet.Spec.ServiceName)
+	assert.Equal(t, "nginx:latest", statefulSet.Spec.Template.Spec.Containers[0].Image)
+	service, err := client.CoreV1().Services("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+
+	components, err := provider.Get(context.Background(), deployment, updateStep.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "nginx:latest", components[0].Properties["container.image"])
+
+	_, err = provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+
+	_, err = provider.Apply(context.Background(), deployment, deleteStep, false)
+	assert.Nil(t, err)
+	_, err = client.AppsV1().StatefulSets("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+	_, err = client.CoreV1().Services("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+}
+
+func TestApplyDaemonSet(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{Projector: "daemonset"}, Client: client}
+	deployment, updateStep, deleteStep := workloadTestSteps()
+
+	_, err := provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	_, err = client.AppsV1().DaemonSets("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = client.AppsV1().Deployments("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+
+	components, err := provider.Get(context.Background(), deployment, updateStep.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+
+	_, err = provider.Apply(context.Background(), deployment, deleteStep, false)
+	assert.Nil(t, err)
+	_, err = client.AppsV1().DaemonSets("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+}
+
+func TestApplyJob(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{Projector: "job"}, Client: client}
+	deployment, updateStep, deleteStep := workloadTestSteps()
+
+	_, err := provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	job, err := client.BatchV1().Jobs("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, apiv1.RestartPolicyOnFailure, job.Spec.Template.Spec.RestartPolicy)
+	assert.NotEmpty(t, job.Annotations[jobSpecHashAnnotation])
+
+	// an unchanged Job isn't run again
+	job.Status.Succeeded = 1
+	_, err = client.BatchV1().Jobs("default").UpdateStatus(context.Background(), job, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	_, err = provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	job, err = client.BatchV1().Jobs("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), job.Status.Succeeded)
+
+	// a changed Job is replaced, as its pod template can't be updated
+	deployment.Instance.Metadata = map[string]string{"job.restartPolicy": "Never"}
+	_, err = provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	job, err = client.BatchV1().Jobs("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, apiv1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	assert.Equal(t, int32(0), job.Status.Succeeded)
+
+	_, err = provider.Apply(context.Background(), deployment, deleteStep, false)
+	assert.Nil(t, err)
+	_, err = client.BatchV1().Jobs("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+}
+
+func TestApplyDeploymentPerComponent(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{Projector: "deployment"}, Client: client}
+	deployment, updateStep, deleteStep := workloadTestSteps()
+
+	_, err := provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.Nil(t, err)
+	_, err = client.AppsV1().Deployments("default").Get(context.Background(), "test-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = client.AppsV1().Deployments("default").Get(context.Background(), "instance-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+
+	components, err := provider.Get(context.Background(), deployment, updateStep.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+
+	_, err = provider.Apply(context.Background(), deployment, deleteStep, false)
+	assert.Nil(t, err)
+	_, err = client.AppsV1().Deployments("default").Get(context.Background(), "test-1", metav1.GetOptions{})
+	assert.True(t, k8s_errors.IsNotFound(err))
+}
+
+func TestApplyUpdateFailed(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{DeploymentStrategy: SERVICES}, Client: client}
+	deployment, updateStep, _ := workloadTestSteps()
+	updateStep.Components[0].Component.Properties["container.livenessProbe"] = "not json"
+
+	ret, err := provider.Apply(context.Background(), deployment, updateStep, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["test-1"].Status)
+}
+
+func TestComponentsToDeploymentPodSettings(t *testing.T) {
+	component := model.ComponentSpec{
+		Name: "symphony-agent",
+		Properties: map[string]interface{}{
+			"container.image":          "nginx:latest",
+			"container.livenessProbe":  `{"httpGet":{"path":"/healthz","port":8080},"periodSeconds":10}`,
+			"container.readinessProbe": map[string]interface{}{"tcpSocket": map[string]interface{}{"port": 8080}},
+			"container.startupProbe":   `{"exec":{"command":["cat","/tmp/ready"]}}`,
+			"container.envFrom":        `[{"configMapRef":{"name":"settings"}},{"secretRef":{"name":"credentials"}}]`,
+			"container.env":            `[{"name":"PASSWORD","valueFrom":{"secretKeyRef":{"name":"credentials","key":"password"}}}]`,
+			"container.initContainers": `[{"name":"init","image":"busybox","command":["sh","-c","echo init"]}]`,
+			"env.B":                    "b",
+			"env.A":                    "a",
+		},
+	}
+	deployment, err := componentsToDeployment("default", "name", nil, []model.ComponentSpec{component}, "instance-1")
+	assert.Nil(t, err)
+	container := deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "/healthz", container.LivenessProbe.HTTPGet.Path)
+	assert.Equal(t, int32(10), container.LivenessProbe.PeriodSeconds)
+	assert.Equal(t, int32(8080), container.ReadinessProbe.TCPSocket.Port.IntVal)
+	assert.Equal(t, []string{"cat", "/tmp/ready"}, container.StartupProbe.Exec.Command)
+	assert.Equal(t, 2, len(container.EnvFrom))
+	assert.Equal(t, "settings", container.EnvFrom[0].ConfigMapRef.Name)
+	assert.Equal(t, "credentials", container.EnvFrom[1].SecretRef.Name)
+	assert.Equal(t, 3, len(container.Env))
+	assert.Equal(t, "PASSWORD", container.Env[0].Name)
+	assert.Equal(t, "password", container.Env[0].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, "A", container.Env[1].Name)
+	assert.Equal(t, "B", container.Env[2].Name)
+	assert.Equal(t, 1, len(deployment.Spec.Template.Spec.InitContainers))
+	assert.Equal(t, "busybox", deployment.Spec.Template.Spec.InitContainers[0].Image)
+
+	components, err := deploymentToComponents(*deployment)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "a", components[0].Properties["env.A"])
+	assert.NotContains(t, components[0].Properties, "env.PASSWORD")
+	assert.Contains(t, components[0].Properties["container.env"], "secretKeyRef")
+	assert.Contains(t, components[0].Properties["container.envFrom"], "settings")
+	assert.Contains(t, components[0].Properties["container.livenessProbe"], "/healthz")
+	assert.Contains(t, components[0].Properties["container.readinessProbe"], "tcpSocket")
+	assert.Contains(t, components[0].Properties["container.startupProbe"], "/tmp/ready")
+}
+
+func TestComponentsToDeploymentBadProbe(t *testing.T) {
+	component := model.ComponentSpec{
+		Name: "symphony-agent",
+		Properties: map[string]interface{}{
+			"container.image":         "nginx:latest",
+			"container.livenessProbe": "not json",
+		},
+	}
+	_, err := componentsToDeployment("default", "name", nil, []model.ComponentSpec{component}, "instance-1")
+	assert.NotNil(t, err)
+}
+
+func TestServiceMetadataExpose(t *testing.T) {
+	components := []model.ComponentSpec{
+		{
+			Name: "a",
+			Properties: map[string]interface{}{
+				"container.ports": `[{"containerPort":8080},{"containerPort":9090,"protocol":"UDP"}]`,
+				"service.expose":  "true",
+				"service.type":    "LoadBalancer",
+			},
+		},
+		{
+			Name: "b",
+			Properties: map[string]interface{}{
+				"container.ports": `[{"containerPort":8080}]`,
+				"service.expose":  true,
+			},
+		},
+		{
+			Name: "c",
+			Properties: map[string]interface{}{
+				"container.ports": `[{"containerPort":7070}]`,
+				"service.expose":  "false",
+			},
+		},
+	}
+	metadata, err := serviceMetadata(map[string]string{"service.name": "web"}, components)
+	assert.Nil(t, err)
+	assert.Equal(t, "web", metadata["service.name"])
+	assert.Equal(t, "LoadBalancer", metadata["service.type"])
+	assert.NotContains(t, metadata, "service.expose")
+
+	service, err := metadataToService("default", "name", metadata)
+	assert.Nil(t, err)
+	assert.Equal(t, "web", service.Name)
+	assert.Equal(t, apiv1.ServiceTypeLoadBalancer, service.Spec.Type)
+	assert.Equal(t, 2, len(service.Spec.Ports))
+	assert.Equal(t, "port8080", service.Spec.Ports[0].Name)
+	assert.Equal(t, int32(8080), service.Spec.Ports[0].TargetPort.IntVal)
+	assert.Equal(t, apiv1.ProtocolUDP, service.Spec.Ports[1].Protocol)
+
+	assert.Equal(t, "web", getServiceName("name", map[string]string{"service.name": "web"}, components))
+	assert.Equal(t, "name", getServiceName("name", nil, components))
+}
+
+func TestServiceMetadataExplicitPorts(t *testing.T) {
+	metadata, err := serviceMetadata(map[string]string{
+		"service.ports": `[{"name":"http","port":80}]`,
+	}, []model.ComponentSpec{
+		{
+			Name: "a",
+			Properties: map[string]interface{}{
+				"container.ports": `[{"containerPort":8080}]`,
+				"service.expose":  "true",
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"name":"http","port":80}]`, metadata["service.ports"])
+	_, err = serviceMetadata(nil, []model.ComponentSpec{
+		{
+			Name:       "a",
+			Properties: map[string]interface{}{"service.expose": "maybe"},
+		},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestIsDeploymentReady(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(2)},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			AvailableReplicas:  1,
+		},
+	}
+	ready, err := isDeploymentReady(deployment)
+	assert.Nil(t, err)
+	assert.False(t, ready)
+	deployment.Status.AvailableReplicas = 2
+	ready, err = isDeploymentReady(deployment)
+	assert.Nil(t, err)
+	assert.True(t, ready)
+	deployment.Generation = 3
+	ready, _ = isDeploymentReady(deployment)
+	assert.False(t, ready)
+	deployment.Status.ObservedGeneration = 3
+	deployment.Status.Conditions = []appsv1.DeploymentCondition{
+		{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded"},
+	}
+	_, err = isDeploymentReady(deployment)
+	assert.NotNil(t, err)
+}
+
+func TestIsStatefulSetReady(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{Replicas: int32Ptr(2)},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   2,
+			UpdatedReplicas: 2,
+			CurrentRevision: "a",
+			UpdateRevision:  "b",
+		},
+	}
+	assert.False(t, isStatefulSetReady(statefulSet))
+	statefulSet.Status.CurrentRevision = "b"
+	assert.True(t, isStatefulSetReady(statefulSet))
+	statefulSet.Status.ReadyReplicas = 1
+	assert.False(t, isStatefulSetReady(statefulSet))
+	statefulSet.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	assert.True(t, isStatefulSetReady(statefulSet))
+}
+
+func TestIsDaemonSetReady(t *testing.T) {
+	daemonSet := &appsv1.DaemonSet{
+		Status: appsv1.DaemonSetStatus{
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberAvailable:        2,
+		},
+	}
+	assert.False(t, isDaemonSetReady(daemonSet))
+	daemonSet.Status.NumberAvailable = 3
+	assert.True(t, isDaemonSetReady(daemonSet))
+}
+
+func TestIsJobReady(t *testing.T) {
+	job := &batchv1.Job{}
+	ready, err := isJobReady(job)
+	assert.Nil(t, err)
+	assert.False(t, ready)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: apiv1.ConditionTrue}}
+	ready, err = isJobReady(job)
+	assert.Nil(t, err)
+	assert.True(t, ready)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: apiv1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	_, err = isJobReady(job)
+	assert.NotNil(t, err)
+}
+
+func TestWaitForRollout(t *testing.T) {
+	client := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1)},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1},
+	})
+	provider := &K8sTargetProvider{Config: K8sTargetProviderConfig{RetryCount: 2, RetryIntervalInSec: 0}, Client: client}
+	err := provider.waitForRollout(context.Background(), "default", "ready", projectors.KIND_DEPLOYMENT)
+	assert.Nil(t, err)
+	err = provider.waitForRollout(context.Background(), "default", "pending", projectors.KIND_DEPLOYMENT)
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Contains(t, coaErr.Message, "after 2 checks")
+	err = provider.waitForRollout(context.Background(), "default", "missing", projectors.KIND_DEPLOYMENT)
+	assert.NotNil(t, err)
+}
+
 // Conformance: you should call the conformance suite to ensure provider conformance
 func TestConformanceSuite(t *testing.T) {
 	provider := &K8sTargetProvider{}
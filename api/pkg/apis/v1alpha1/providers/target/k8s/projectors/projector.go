@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package projectors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	v1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// workload kinds the K8s target provider manages
+const (
+	KIND_DEPLOYMENT  string = "Deployment"
+	KIND_STATEFULSET string = "StatefulSet"
+	KIND_DAEMONSET   string = "DaemonSet"
+	KIND_JOB         string = "Job"
+)
+
+// IK8sProjector adjusts the Deployment and the Service the K8s target provider builds from components
+type IK8sProjector interface {
+	ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error
+	ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error
+}
+
+// IWorkloadProjector is implemented by projectors that deploy components as another kind of workload than a Deployment,
+// or that deploy each component as its own workload regardless of the deployment strategy
+type IWorkloadProjector interface {
+	IK8sProjector
+	// Kind returns the kind of the workloads the projector builds
+	Kind() string
+	// PerComponent reports if each component gets its own workload
+	PerComponent() bool
+	// ProjectWorkload builds the workload from the projected Deployment
+	ProjectWorkload(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) (runtime.Object, error)
+}
+
+// ProjectorFactory creates a projector
+type ProjectorFactory func() IK8sProjector
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]ProjectorFactory{
+		"noop":        func() IK8sProjector { return &NoOpProjector{} },
+		"deployment":  func() IK8sProjector { return &DeploymentProjector{} },
+		"statefulset": func() IK8sProjector { return &StatefulSetProjector{} },
+		"daemonset":   func() IK8sProjector { return &DaemonSetProjector{} },
+		"job":         func() IK8sProjector { return &JobProjector{} },
+	}
+)
+
+// RegisterProjector makes a projector available to the projector setting of the K8s target provider
+func RegisterProjector(name string, factory ProjectorFactory) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if name == "" || factory == nil {
+		return v1alpha2.NewCOAError(nil, "a projector needs a name and a factory", v1alpha2.BadConfig)
+	}
+	if _, ok := registry[name]; ok {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("projector '%s' is already registered", name), v1alpha2.BadConfig)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// CreateProjector creates a registered projector
+func CreateProjector(name string) (IK8sProjector, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	if factory, ok := registry[name]; ok {
+		return factory(), nil
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("project type '%s' is unsupported", name), v1alpha2.BadConfig)
+}
+
+// GetProjectorNames returns the names of the registered projectors, sorted
+func GetProjectorNames() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	ret := make([]string, 0, len(registry))
+	for name := range registry {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package projectors
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateProjector(t *testing.T) {
+	for _, name := range []string{"noop", "deployment", "statefulset", "daemonset", "job"} {
+		projector, err := CreateProjector(name)
+		assert.Nil(t, err)
+		assert.NotNil(t, projector)
+	}
+}
+
+func TestCreateProjectorUnsupported(t *testing.T) {
+	_, err := CreateProjector("unknown")
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestRegisterProjector(t *testing.T) {
+	err := RegisterProjector("test-register", func() IK8sProjector { return &NoOpProjector{} })
+	assert.Nil(t, err)
+	projector, err := CreateProjector("test-register")
+	assert.Nil(t, err)
+	assert.IsType(t, &NoOpProjector{}, projector)
+	assert.Contains(t, GetProjectorNames(), "test-register")
+}
+
+func TestRegisterProjectorDuplicate(t *testing.T) {
+	err := RegisterProjector("noop", func() IK8sProjector { return &NoOpProjector{} })
+	assert.NotNil(t, err)
+}
+
+func TestRegisterProjectorNoName(t *testing.T) {
+	err := RegisterProjector("", func() IK8sProjector { return &NoOpProjector{} })
+	assert.NotNil(t, err)
+}
+
+func TestGetProjectorNames(t *testing.T) {
+	names := GetProjectorNames()
+	assert.Subset(t, names, []string{"daemonset", "deployment", "job", "noop", "statefulset"})
+	assert.IsIncreasing(t, names)
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package projectors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeploymentProjector deploys each component as its own Deployment
+type DeploymentProjector struct {
+}
+
+func (p *DeploymentProjector) ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error {
+	return nil
+}
+func (p *DeploymentProjector) ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error {
+	return nil
+}
+func (p *DeploymentProjector) Kind() string {
+	return KIND_DEPLOYMENT
+}
+func (p *DeploymentProjector) PerComponent() bool {
+	return true
+}
+func (p *DeploymentProjector) ProjectWorkload(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) (runtime.Object, error) {
+	return deployment, nil
+}
+
+// StatefulSetProjector deploys components as a StatefulSet. The StatefulSet is governed by the service of the
+// components, and takes its volume claim templates from the statefulset.volumeClaimTemplates metadata.
+type StatefulSetProjector struct {
+}
+
+func (p *StatefulSetProjector) ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error {
+	return nil
+}
+func (p *StatefulSetProjector) ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error {
+	return nil
+}
+func (p *StatefulSetProjector) Kind() string {
+	return KIND_STATEFULSET
+}
+func (p *StatefulSetProjector) PerComponent() bool {
+	return false
+}
+func (p *StatefulSetProjector) ProjectWorkload(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) (runtime.Object, error) {
+	statefulSet := &v1.StatefulSet{
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: v1.StatefulSetSpec{
+			Replicas:    deployment.Spec.Replicas,
+			Selector:    deployment.Spec.Selector,
+			Template:    deployment.Spec.Template,
+			ServiceName: readMetadata(metadata, "service.name", name),
+		},
+	}
+	if v, ok := metadata["statefulset.volumeClaimTemplates"]; ok && v != "" {
+		claims := make([]apiv1.PersistentVolumeClaim, 0)
+		err := json.Unmarshal([]byte(v), &claims)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "invalid statefulset.volumeClaimTemplates metadata", v1alpha2.BadConfig)
+		}
+		statefulSet.Spec.VolumeClaimTemplates = claims
+	}
+	if v, ok := metadata["statefulset.podManagementPolicy"]; ok && v != "" {
+		policy := v1.PodManagementPolicyType(v)
+		if policy != v1.OrderedReadyPodManagement && policy != v1.ParallelPodManagement {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid statefulset.podManagementPolicy metadata '%s'. Expected: %s or %s", v, v1.OrderedReadyPodManagement, v1.ParallelPodManagement), v1alpha2.BadConfig)
+		}
+		statefulSet.Spec.PodManagementPolicy = policy
+	}
+	return statefulSet, nil
+}
+
+// DaemonSetProjector deploys components as a DaemonSet, which runs a pod on each node
+type DaemonSetProjector struct {
+}
+
+func (p *DaemonSetProjector) ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error {
+	return nil
+}
+func (p *DaemonSetProjector) ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error {
+	return nil
+}
+func (p *DaemonSetProjector) Kind() string {
+	return KIND_DAEMONSET
+}
+func (p *DaemonSetProjector) PerComponent() bool {
+	return false
+}
+func (p *DaemonSetProjector) ProjectWorkload(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) (runtime.Object, error) {
+	return &v1.DaemonSet{
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: v1.DaemonSetSpec{
+			Selector: deployment.Spec.Selector,
+			Template: deployment.Spec.Template,
+		},
+	}, nil
+}
+
+// JobProjector deploys components as a Job, which runs them to completion. The job.restartPolicy metadata is the
+// restart policy of the pod (OnFailure by default), and the job.backoffLimit metadata is how many times it's retried.
+type JobProjector struct {
+}
+
+func (p *JobProjector) ProjectDeployment(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) error {
+	return nil
+}
+func (p *JobProjector) ProjectService(scope string, name string, metadata map[string]string, service *apiv1.Service) error {
+	return nil
+}
+func (p *JobProjector) Kind() string {
+	return KIND_JOB
+}
+func (p *JobProjector) PerComponent() bool {
+	return false
+}
+func (p *JobProjector) ProjectWorkload(scope string, name string, metadata map[string]string, components []model.ComponentSpec, deployment *v1.Deployment) (runtime.Object, error) {
+	template := deployment.Spec.Template
+	policy := apiv1.RestartPolicy(readMetadata(metadata, "job.restartPolicy", string(apiv1.RestartPolicyOnFailure)))
+	if policy != apiv1.RestartPolicyOnFailure && policy != apiv1.RestartPolicyNever {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid job.restartPolicy metadata '%s'. Expected: %s or %s", policy, apiv1.RestartPolicyOnFailure, apiv1.RestartPolicyNever), v1alpha2.BadConfig)
+	}
+	template.Spec.RestartPolicy = policy
+	job := &batchv1.Job{
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: batchv1.JobSpec{
+			Template: template,
+		},
+	}
+	if v, ok := metadata["job.backoffLimit"]; ok && v != "" {
+		limit, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "invalid int value in the job.backoffLimit metadata", v1alpha2.BadConfig)
+		}
+		backoffLimit := int32(limit)
+		job.Spec.BackoffLimit = &backoffLimit
+	}
+	return job, nil
+}
+
+func readMetadata(metadata map[string]string, key string, defaultVal string) string {
+	if v, ok := metadata[key]; ok && v != "" {
+		return v
+	}
+	return defaultVal
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package projectors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testDeployment() *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "name"}},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "name"}},
+				Spec: apiv1.PodSpec{
+					Containers:    []apiv1.Container{{Name: "name", Image: "nginx"}},
+					RestartPolicy: apiv1.RestartPolicyAlways,
+				},
+			},
+		},
+	}
+}
+
+func TestDeploymentProjector(t *testing.T) {
+	projector := &DeploymentProjector{}
+	assert.Equal(t, KIND_DEPLOYMENT, projector.Kind())
+	assert.True(t, projector.PerComponent())
+	deployment := testDeployment()
+	workload, err := projector.ProjectWorkload("default", "name", nil, nil, deployment)
+	assert.Nil(t, err)
+	assert.Equal(t, deployment, workload)
+}
+
+func TestStatefulSetProjector(t *testing.T) {
+	projector := &StatefulSetProjector{}
+	assert.Equal(t, KIND_STATEFULSET, projector.Kind())
+	assert.False(t, projector.PerComponent())
+	workload, err := projector.ProjectWorkload("default", "name", map[string]string{
+		"service.name":                     "name-svc",
+		"statefulset.podManagementPolicy":  "Parallel",
+		"statefulset.volumeClaimTemplates": `[{"metadata":{"name":"data"},"spec":{"accessModes":["ReadWriteOnce"]}}]`,
+	}, nil, testDeployment())
+	assert.Nil(t, err)
+	statefulSet, ok := workload.(*appsv1.StatefulSet)
+	assert.True(t, ok)
+	assert.Equal(t, "name", statefulSet.Name)
+	assert.Equal(t, int32(2), *statefulSet.Spec.Replicas)
+	assert.Equal(t, "name-svc", statefulSet.Spec.ServiceName)
+	assert.Equal(t, appsv1.ParallelPodManagement, statefulSet.Spec.PodManagementPolicy)
+	assert.Equal(t, 1, len(statefulSet.Spec.VolumeClaimTemplates))
+	assert.Equal(t, "data", statefulSet.Spec.VolumeClaimTemplates[0].Name)
+	assert.Equal(t, "nginx", statefulSet.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestStatefulSetProjectorDefaultServiceName(t *testing.T) {
+	projector := &StatefulSetProjector{}
+	workload, err := projector.ProjectWorkload("default", "name", nil, nil, testDeployment())
+	assert.Nil(t, err)
+	assert.Equal(t, "name", workload.(*appsv1.StatefulSet).Spec.ServiceName)
+}
+
+func TestStatefulSetProjectorBadMetadata(t *testing.T) {
+	projector := &StatefulSetProjector{}
+	_, err := projector.ProjectWorkload("default", "name", map[string]string{
+		"statefulset.volumeClaimTemplates": "not json",
+	}, nil, testDeployment())
+	assert.NotNil(t, err)
+	_, err = projector.ProjectWorkload("default", "name", map[string]string{
+		"statefulset.podManagementPolicy": "Random",
+	}, nil, testDeployment())
+	assert.NotNil(t, err)
+}
+
+func TestDaemonSetProjector(t *testing.T) {
+	projector := &DaemonSetProjector{}
+	assert.Equal(t, KIND_DAEMONSET, projector.Kind())
+	assert.False(t, projector.PerComponent())
+	workload, err := projector.ProjectWorkload("default", "name", nil, nil, testDeployment())
+	assert.Nil(t, err)
+	daemonSet, ok := workload.(*appsv1.DaemonSet)
+	assert.True(t, ok)
+	assert.Equal(t, "name", daemonSet.Name)
+	assert.Equal(t, "name", daemonSet.Spec.Selector.MatchLabels["app"])
+	assert.Equal(t, "nginx", daemonSet.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestJobProjector(t *testing.T) {
+	projector := &JobProjector{}
+	assert.Equal(t, KIND_JOB, projector.Kind())
+	assert.False(t, projector.PerComponent())
+	workload, err := projector.ProjectWorkload("default", "name", nil, nil, testDeployment())
+	assert.Nil(t, err)
+	job, ok := workload.(*batchv1.Job)
+	assert.True(t, ok)
+	assert.Equal(t, apiv1.RestartPolicyOnFailure, job.Spec.Template.Spec.RestartPolicy)
+	assert.Nil(t, job.Spec.BackoffLimit)
+
+	workload, err = projector.ProjectWorkload("default", "name", map[string]string{
+		"job.restartPolicy": "Never",
+		"job.backoffLimit":  "4",
+	}, nil, testDeployment())
+	assert.Nil(t, err)
+	job = workload.(*batchv1.Job)
+	assert.Equal(t, apiv1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	assert.Equal(t, int32(4), *job.Spec.BackoffLimit)
+}
+
+func TestJobProjectorBadMetadata(t *testing.T) {
+	projector := &JobProjector{}
+	_, err := projector.ProjectWorkload("default", "name", map[string]string{
+		"job.restartPolicy": "Always",
+	}, nil, testDeployment())
+	assert.NotNil(t, err)
+	_, err = projector.ProjectWorkload("default", "name", map[string]string{
+		"job.backoffLimit": "many",
+	}, nil, testDeployment())
+	assert.NotNil(t, err)
+}
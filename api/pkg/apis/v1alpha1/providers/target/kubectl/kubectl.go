@@ -10,12 +10,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -29,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/discovery"
@@ -41,6 +46,21 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
+const (
+	// DEFAULT_FIELD_MANAGER is the field manager of server-side apply requests
+	DEFAULT_FIELD_MANAGER = "symphony"
+	// DEFAULT_WAIT_TIMEOUT is how long Apply waits for the objects of a component to become ready
+	DEFAULT_WAIT_TIMEOUT = 5 * time.Minute
+	// DEFAULT_POLL_INTERVAL is how often Apply checks if objects are ready
+	DEFAULT_POLL_INTERVAL = 2 * time.Second
+
+	// INVENTORY_PREFIX is the name prefix of the ConfigMaps that record the objects of components
+	INVENTORY_PREFIX               = "symphony-inventory-"
+	INVENTORY_OBJECTS_KEY          = "objects"
+	INVENTORY_INSTANCE_ANNOTATION  = "symphony/instance"
+	INVENTORY_COMPONENT_ANNOTATION = "symphony/component"
+)
+
 var (
 	decUnstructured = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 	sLog            = logger.NewLogger("coa.runtime")
@@ -49,11 +69,12 @@ var (
 type (
 	// KubectlTargetProviderConfig is the configuration for the kubectl target provider
 	KubectlTargetProviderConfig struct {
-		Name       string `json:"name,omitempty"`
-		ConfigType string `json:"configType,omitempty"`
-		ConfigData string `json:"configData,omitempty"`
-		Context    string `json:"context,omitempty"`
-		InCluster  bool   `json:"inCluster"`
+		Name         string `json:"name,omitempty"`
+		ConfigType   string `json:"configType,omitempty"`
+		ConfigData   string `json:"configData,omitempty"`
+		Context      string `json:"context,omitempty"`
+		InCluster    bool   `json:"inCluster"`
+		FieldManager string `json:"fieldManager,omitempty"`
 	}
 
 	// KubectlTargetProvider is the kubectl target provider
@@ -63,8 +84,33 @@ type (
 		Client          kubernetes.Interface
 		DynamicClient   dynamic.Interface
 		DiscoveryClient *discovery.DiscoveryClient
-		Mapper          *restmapper.DeferredDiscoveryRESTMapper
+		Mapper          meta.RESTMapper
 		RESTConfig      *rest.Config
+		pollInterval    time.Duration
+	}
+
+	// componentObject is an object of a component, with the client of its resource
+	componentObject struct {
+		obj *unstructured.Unstructured
+		dr  dynamic.ResourceInterface
+	}
+
+	// ApplyOptions are the options of ApplyDocuments
+	ApplyOptions struct {
+		// Wait waits for the objects to become ready, for WaitTimeout at most
+		Wait        bool
+		WaitTimeout time.Duration
+		// Annotations are added to the inventory of the component
+		Annotations map[string]string
+	}
+
+	// inventoryEntry identifies an object a component applied
+	inventoryEntry struct {
+		Group     string `json:"group,omitempty"`
+		Version   string `json:"version"`
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace,omitempty"`
+		Name      string `json:"name"`
 	}
 )
 
@@ -83,6 +129,9 @@ func KubectlTargetProviderConfigFromMap(properties map[string]string) (KubectlTa
 	if v, ok := properties["context"]; ok {
 		ret.Context = v
 	}
+	if v, ok := properties["fieldManager"]; ok {
+		ret.FieldManager = v
+	}
 	if v, ok := properties["inCluster"]; ok {
 		val := v
 		if val != "" {
@@ -169,6 +218,13 @@ func (i *KubectlTargetProvider) Init(config providers.IProviderConfig) error {
 		return err
 	}
 
+	err = i.initClients(kConfig)
+	return err
+}
+
+// initClients creates the clients of the cluster at kConfig
+func (i *KubectlTargetProvider) initClients(kConfig *rest.Config) error {
+	var err error
 	i.Client, err = kubernetes.NewForConfig(kConfig)
 	if err != nil {
 		sLog.Errorf("  P (Kubectl Target): %+v", err)
@@ -204,7 +260,7 @@ func toKubectlTargetProviderConfig(config providers.IProviderConfig) (KubectlTar
 	return ret, err
 }
 
-// Get gets the artifacts for a deployment
+// Get gets the artifacts for a deployment. A component is reported only when all of its objects exist.
 func (i *KubectlTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
 	ctx, span := observability.StartSpan(
 		"Kubectl Target Provider",
@@ -218,70 +274,29 @@ func (i *KubectlTargetProvider) Get(ctx context.Context, deployment model.Deploy
 
 	ret := make([]model.ComponentSpec, 0)
 	for _, component := range references {
-		if v, ok := component.Component.Properties["yaml"].(string); ok {
-			chanMes, chanErr := readYaml(v)
-			stop := false
-			for !stop {
-				select {
-				case dataBytes, ok := <-chanMes:
-					if !ok {
-						err = errors.New("failed to receive from data channel")
-						sLog.Error("  P (Kubectl Target): +%v", err)
-						return nil, err
-					}
-
-					_, err = i.getCustomResource(ctx, dataBytes, deployment.Instance.Scope)
-					if err != nil {
-						if kerrors.IsNotFound(err) {
-							sLog.Infof("  P (Kubectl Target): resource not found: %s", err)
-							continue
-						}
-						sLog.Error("  P (Kubectl Target): failed to read object: +%v", err)
-						return nil, err
-					}
-
-					ret = append(ret, component.Component)
-					stop = true //we do early stop as soon as we found the first resource. we may want to support different strategy in the future
-
-				case err, ok := <-chanErr:
-					if !ok {
-						err = errors.New("failed to receive from error channel")
-						sLog.Error("  P (Kubectl Target): +%v", err)
-						return nil, err
-					}
-
-					if err == io.EOF {
-						stop = true
-					} else {
-						sLog.Error("  P (Kubectl Target): failed to apply Yaml: +%v", err)
-						return nil, err
-					}
-				}
-			}
-		} else if component.Component.Properties["resource"] != nil {
-			var dataBytes []byte
-			dataBytes, err = json.Marshal(component.Component.Properties["resource"])
-			if err != nil {
-				sLog.Error("  P (Kubectl Target): failed to get deployment bytes from component: +%v", err)
-				return nil, err
-			}
+		var objects []componentObject
+		objects, err = i.readComponentObjects(component.Component, deployment.Instance.Scope)
+		if err != nil {
+			sLog.Errorf("  P (Kubectl Target): failed to read objects of %s: %+v", component.Component.Name, err)
+			return nil, err
+		}
 
-			_, err = i.getCustomResource(ctx, dataBytes, deployment.Instance.Scope)
+		found := len(objects) > 0
+		for _, object := range objects {
+			_, err = object.dr.Get(ctx, object.obj.GetName(), metav1.GetOptions{})
 			if err != nil {
 				if kerrors.IsNotFound(err) {
 					sLog.Infof("  P (Kubectl Target): resource not found: %s", err)
-					continue
+					err = nil
+					found = false
+					break
 				}
-				sLog.Error("  P (Kubectl Target): failed to read object: +%v", err)
+				sLog.Errorf("  P (Kubectl Target): failed to read object: %+v", err)
 				return nil, err
 			}
-
+		}
+		if found {
 			ret = append(ret, component.Component)
-
-		} else {
-			err = errors.New("component doesn't have yaml or resource property")
-			sLog.Error("  P (Kubectl Target): component doesn't have yaml or resource property")
-			return nil, err
 		}
 	}
 
@@ -306,134 +321,217 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 	if err != nil {
 		return nil, err
 	}
+	for _, component := range components {
+		if _, _, err = getWaitOptions(component); err != nil {
+			return nil, err
+		}
+	}
 	if isDryRun {
 		return nil, nil
 	}
 
 	ret := step.PrepareResultMap()
-	components = step.GetUpdatedComponents()
-	if len(components) > 0 {
-		for _, component := range components {
-			if component.Type == "yaml.k8s" {
-				if v, ok := component.Properties["yaml"].(string); ok {
-					chanMes, chanErr := readYaml(v)
-					stop := false
-					for !stop {
-						select {
-						case dataBytes, ok := <-chanMes:
-							if !ok {
-								err = errors.New("failed to receive from data channel")
-								sLog.Error("  P (Kubectl Target):  +%v", err)
-								return ret, err
-							}
-
-							i.ensureNamespace(ctx, deployment.Instance.Scope)
-							err = i.applyCustomResource(ctx, dataBytes, deployment.Instance.Scope)
-							if err != nil {
-								sLog.Error("  P (Kubectl Target):  failed to apply Yaml: +%v", err)
-								return ret, err
-							}
-
-						case err, ok := <-chanErr:
-							if !ok {
-								err = errors.New("failed to receive from error channel")
-								sLog.Error("  P (Kubectl Target):  +%v", err)
-								return ret, err
-							}
-
-							if err == io.EOF {
-								stop = true
-							} else {
-								sLog.Error("  P (Kubectl Target):  failed to apply Yaml: +%v", err)
-								return ret, err
-							}
-						}
-					}
-				} else if component.Properties["resource"] != nil {
-					var dataBytes []byte
-					dataBytes, err = json.Marshal(component.Properties["resource"])
-					if err != nil {
-						sLog.Error("  P (Kubectl Target): failed to convert resource data to bytes: +%v", err)
-						return ret, err
-					}
-
-					i.ensureNamespace(ctx, deployment.Instance.Scope)
-					err = i.applyCustomResource(ctx, dataBytes, deployment.Instance.Scope)
-					if err != nil {
-						sLog.Error("  P (Kubectl Target):  failed to apply custom resource: +%v", err)
-						return ret, err
-					}
-
-				} else {
-					err = errors.New("component doesn't have yaml property or resource property")
-					sLog.Error("  P (Kubectl Target):  component doesn't have yaml property or resource property")
-					return ret, err
+	for _, component := range step.GetUpdatedComponents() {
+		if component.Type == "yaml.k8s" {
+			err = i.applyComponent(ctx, deployment, component)
+			if err != nil {
+				sLog.Errorf("  P (Kubectl Target): failed to apply %s: %+v", component.Name, err)
+				ret[component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
 				}
+				return ret, err
+			}
+			ret[component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
 			}
 		}
 	}
-	components = step.GetDeletedComponents()
-	if len(components) > 0 {
-		for _, component := range components {
-			if component.Type == "yaml.k8s" {
-				if v, ok := component.Properties["yaml"].(string); ok {
-					chanMes, chanErr := readYaml(v)
-					stop := false
-					for !stop {
-						select {
-						case dataBytes, ok := <-chanMes:
-							if !ok {
-								err = errors.New("failed to receive from data channel")
-								sLog.Error("  P (Kubectl Target):  +%v", err)
-								return ret, err
-							}
-
-							err = i.deleteCustomResource(ctx, dataBytes, deployment.Instance.Scope)
-							if err != nil {
-								sLog.Error("  P (Kubectl Target): failed to read object: +%v", err)
-								return ret, err
-							}
-
-						case err, ok := <-chanErr:
-							if !ok {
-								err = errors.New("failed to receive from error channel")
-								sLog.Error("  P (Kubectl Target): +%v", err)
-								return ret, err
-							}
-
-							if err == io.EOF {
-								stop = true
-							} else {
-								sLog.Error("  P (Kubectl Target): failed to remove resource: +%v", err)
-								return ret, err
-							}
-						}
-					}
-				} else if component.Properties["resource"] != nil {
-					var dataBytes []byte
-					dataBytes, err = json.Marshal(component.Properties["resource"])
-					if err != nil {
-						sLog.Error("  P (Kubectl Target): failed to convert resource data to bytes: +%v", err)
-						return ret, err
-					}
-
-					err = i.deleteCustomResource(ctx, dataBytes, deployment.Instance.Scope)
-					if err != nil {
-						sLog.Error("  P (Kubectl Target): failed to delete custom resource: +%v", err)
-						return ret, err
-					}
-
-				} else {
-					err = errors.New("component doesn't have yaml property or resource property")
-					sLog.Error("  P (Kubectl Target): component doesn't have yaml property or resource property")
-					return ret, err
+	for _, component := range step.GetDeletedComponents() {
+		if component.Type == "yaml.k8s" {
+			err = i.deleteComponent(ctx, deployment, component)
+			if err != nil {
+				sLog.Errorf("  P (Kubectl Target): failed to remove %s: %+v", component.Name, err)
+				ret[component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
 				}
+				return ret, err
+			}
+			ret[component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
 			}
 		}
 	}
 	return ret, nil
 }
 
+// applyComponent applies the objects of a component with server-side apply, and prunes the objects the component
+// had before that it no longer has
+func (i *KubectlTargetProvider) applyComponent(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) error {
+	wait, timeout, err := getWaitOptions(component)
+	if err != nil {
+		return err
+	}
+	documents, err := readComponentDocuments(component)
+	if err != nil {
+		return err
+	}
+	return i.ApplyDocuments(ctx, deployment, component.Name, documents, ApplyOptions{Wait: wait, WaitTimeout: timeout})
+}
+
+// ApplyDocuments applies the objects in YAML or JSON documents as the objects of a component, with server-side apply.
+// Objects the component had before that aren't in the documents are pruned, and the inventory of the component is
+// replaced. If applying or pruning fails, the inventory records the objects applied so far along with the previous
+// ones, and keeps its previous annotations, so that no object is left untracked and the component is applied again.
+func (i *KubectlTargetProvider) ApplyDocuments(ctx context.Context, deployment model.DeploymentSpec, component string, documents [][]byte, options ApplyOptions) error {
+	objects, err := i.buildComponentObjects(documents, deployment.Instance.Scope)
+	if err != nil {
+		return err
+	}
+	inventory, err := i.getInventory(ctx, deployment, component)
+	if err != nil {
+		return err
+	}
+	previous := make([]inventoryEntry, 0)
+	var previousAnnotations map[string]string
+	if inventory != nil {
+		previous, err = parseInventory(inventory)
+		if err != nil {
+			return err
+		}
+		previousAnnotations = inventory.Annotations
+	}
+
+	err = i.ensureNamespace(ctx, deployment.Instance.Scope)
+	if err != nil {
+		return err
+	}
+	entries := make([]inventoryEntry, 0, len(objects))
+	for _, object := range objects {
+		err = i.applyObject(ctx, object)
+		if err != nil {
+			i.writePartialInventory(ctx, deployment, component, entries, previous, previousAnnotations)
+			return err
+		}
+		entries = append(entries, newInventoryEntry(object.obj))
+	}
+
+	// the inventory is only replaced once the objects are pruned, so that a failed prune is retried
+	err = i.pruneObjects(ctx, previous, entries)
+	if err != nil {
+		i.writePartialInventory(ctx, deployment, component, entries, previous, previousAnnotations)
+		return err
+	}
+	err = i.writeInventory(ctx, deployment, component, entries, options.Annotations)
+	if err != nil {
+		return err
+	}
+
+	if options.Wait {
+		return i.waitForObjects(ctx, objects, options.WaitTimeout)
+	}
+	return nil
+}
+
+// writePartialInventory records the objects applied before a failure along with the previous objects of a component.
+// Failing to write it is only logged, as the failure that interrupted the apply is the one reported.
+func (i *KubectlTargetProvider) writePartialInventory(ctx context.Context, deployment model.DeploymentSpec, component string, applied []inventoryEntry, previous []inventoryEntry, annotations map[string]string) {
+	if len(applied) == 0 {
+		return
+	}
+	entries := append([]inventoryEntry{}, applied...)
+	for _, entry := range previous {
+		if !containsInventoryEntry(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := i.writeInventory(ctx, deployment, component, entries, annotations); err != nil {
+		sLog.Errorf("  P (Kubectl Target): failed to record the objects applied before the failure: %+v", err)
+	}
+}
+
+// deleteComponent deletes the objects of a component. The objects in the inventory of the component are deleted when
+// there is one, and the objects in the component otherwise.
+func (i *KubectlTargetProvider) deleteComponent(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) error {
+	found, err := i.DeleteInventory(ctx, deployment, component.Name)
+	if err != nil || found {
+		return err
+	}
+
+	documents, err := readComponentDocuments(component)
+	if err != nil {
+		return err
+	}
+	objects, err := i.buildComponentObjects(documents, deployment.Instance.Scope)
+	if err != nil {
+		return err
+	}
+	for idx := len(objects) - 1; idx >= 0; idx-- {
+		err = objects[idx].dr.Delete(ctx, objects[idx].obj.GetName(), metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			sLog.Errorf("  P (Kubectl Target): failed to delete object: %+v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteInventory deletes the objects in the inventory of a component, and then the inventory. It returns false if
+// the component has no inventory.
+func (i *KubectlTargetProvider) DeleteInventory(ctx context.Context, deployment model.DeploymentSpec, component string) (bool, error) {
+	entries, found, err := i.readInventory(ctx, deployment, component)
+	if err != nil || !found {
+		return false, err
+	}
+	err = i.pruneObjects(ctx, entries, nil)
+	if err != nil {
+		return true, err
+	}
+	err = i.Client.CoreV1().ConfigMaps(getInventoryNamespace(deployment)).Delete(ctx, getInventoryName(deployment, component), metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+// ReadInventoryStatus returns the annotations of the inventory of a component, and if all the objects in the
+// inventory exist. It returns nil annotations if the component has no inventory.
+func (i *KubectlTargetProvider) ReadInventoryStatus(ctx context.Context, deployment model.DeploymentSpec, component string) (map[string]string, bool, error) {
+	configMap, err := i.getInventory(ctx, deployment, component)
+	if err != nil || configMap == nil {
+		return nil, false, err
+	}
+	entries, err := parseInventory(configMap)
+	if err != nil {
+		return nil, false, err
+	}
+	annotations := configMap.Annotations
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, entry := range entries {
+		var dr dynamic.ResourceInterface
+		dr, err = i.getInventoryEntryResource(entry)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				return annotations, false, nil
+			}
+			return nil, false, err
+		}
+		_, err = dr.Get(ctx, entry.Name, metav1.GetOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				return annotations, false, nil
+			}
+			return nil, false, err
+		}
+	}
+	return annotations, true, nil
+}
+
 // ensureNamespace ensures that the namespace exists
 func (k *KubectlTargetProvider) ensureNamespace(ctx context.Context, namespace string) error {
 	_, span := observability.StartSpan(
@@ -474,7 +572,7 @@ func (k *KubectlTargetProvider) ensureNamespace(ctx context.Context, namespace s
 func (*KubectlTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
 	return model.ValidationRule{
 		RequiredProperties:    []string{},
-		OptionalProperties:    []string{"yaml", "resource"},
+		OptionalProperties:    []string{"yaml", "resource", "wait", "waitTimeout"},
 		RequiredComponentType: "",
 		RequiredMetadata:      []string{},
 		OptionalMetadata:      []string{},
@@ -486,6 +584,27 @@ func (*KubectlTargetProvider) GetValidationRule(ctx context.Context) model.Valid
 	}
 }
 
+// getWaitOptions returns if Apply waits for the objects of a component to become ready, and for how long
+func getWaitOptions(component model.ComponentSpec) (bool, time.Duration, error) {
+	wait := false
+	if v, ok := component.Properties["wait"]; ok {
+		var err error
+		wait, err = strconv.ParseBool(fmt.Sprintf("%v", v))
+		if err != nil {
+			return false, 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the 'wait' property of component '%s'", component.Name), v1alpha2.BadRequest)
+		}
+	}
+	timeout := DEFAULT_WAIT_TIMEOUT
+	if v, ok := component.Properties["waitTimeout"]; ok {
+		var err error
+		timeout, err = time.ParseDuration(fmt.Sprintf("%v", v))
+		if err != nil {
+			return false, 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid duration in the 'waitTimeout' property of component '%s'", component.Name), v1alpha2.BadRequest)
+		}
+	}
+	return wait, timeout, nil
+}
+
 // ReadYaml reads yaml from url
 func readYaml(yaml string) (<-chan []byte, <-chan error) {
 	var (
@@ -521,6 +640,67 @@ func readYaml(yaml string) (<-chan []byte, <-chan error) {
 	return chanBytes, chanErr
 }
 
+// readComponentObjects reads the objects of a component, from the documents of its yaml URL or from its resource
+func (i *KubectlTargetProvider) readComponentObjects(component model.ComponentSpec, scope string) ([]componentObject, error) {
+	documents, err := readComponentDocuments(component)
+	if err != nil {
+		return nil, err
+	}
+	return i.buildComponentObjects(documents, scope)
+}
+
+// readComponentDocuments reads the documents of the yaml URL of a component, or its resource
+func readComponentDocuments(component model.ComponentSpec) ([][]byte, error) {
+	documents := make([][]byte, 0)
+	if v, ok := component.Properties["yaml"].(string); ok {
+		chanMes, chanErr := readYaml(v)
+		stop := false
+		for !stop {
+			select {
+			case dataBytes, ok := <-chanMes:
+				if !ok {
+					return nil, errors.New("failed to receive from data channel")
+				}
+				if len(bytes.TrimSpace(dataBytes)) > 0 {
+					documents = append(documents, dataBytes)
+				}
+
+			case err, ok := <-chanErr:
+				if !ok {
+					return nil, errors.New("failed to receive from error channel")
+				}
+				if err != io.EOF {
+					return nil, err
+				}
+				stop = true
+			}
+		}
+	} else if component.Properties["resource"] != nil {
+		dataBytes, err := json.Marshal(component.Properties["resource"])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, dataBytes)
+	} else {
+		return nil, errors.New("component doesn't have yaml property or resource property")
+	}
+	return documents, nil
+}
+
+// buildComponentObjects decodes documents into objects, with the clients of their resources
+func (i *KubectlTargetProvider) buildComponentObjects(documents [][]byte, scope string) ([]componentObject, error) {
+	ret := make([]componentObject, 0, len(documents))
+	for _, document := range documents {
+		obj, dr, err := i.buildDynamicResourceClient(document, scope)
+		if err != nil {
+			sLog.Errorf("  P (Kubectl Target): failed to build a new dynamic client: %+v", err)
+			return nil, err
+		}
+		ret = append(ret, componentObject{obj: obj, dr: dr})
+	}
+	return ret, nil
+}
+
 // BuildDynamicResourceClient builds a new dynamic client
 func (i KubectlTargetProvider) buildDynamicResourceClient(data []byte, scope string) (obj *unstructured.Unstructured, dr dynamic.ResourceInterface, err error) {
 	// Decode YAML manifest into unstructured.Unstructured
@@ -536,11 +716,6 @@ func (i KubectlTargetProvider) buildDynamicResourceClient(data []byte, scope str
 		return obj, dr, err
 	}
 
-	i.DynamicClient, err = dynamic.NewForConfig(i.RESTConfig)
-	if err != nil {
-		return obj, dr, err
-	}
-
 	// Obtain REST interface for the GVR
 	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
 		// namespaced resources should specify the namespace
@@ -554,73 +729,276 @@ func (i KubectlTargetProvider) buildDynamicResourceClient(data []byte, scope str
 	return obj, dr, nil
 }
 
-// getCustomResource gets a custom resource from a byte array
-func (i *KubectlTargetProvider) getCustomResource(ctx context.Context, dataBytes []byte, scope string) (*unstructured.Unstructured, error) {
-	obj, dr, err := i.buildDynamicResourceClient(dataBytes, scope)
+// applyObject applies an object with server-side apply. Symphony takes the ownership of fields other managers
+// changed, as a controller does.
+func (i *KubectlTargetProvider) applyObject(ctx context.Context, object componentObject) error {
+	object.obj.SetResourceVersion("")
+	object.obj.SetManagedFields(nil)
+	_, err := object.dr.Apply(ctx, object.obj.GetName(), object.obj, metav1.ApplyOptions{
+		FieldManager: i.getFieldManager(),
+		Force:        true,
+	})
 	if err != nil {
-		sLog.Error("  P (Kubectl Target): failed to build a new dynamic client: +%v", err)
-		return nil, err
+		sLog.Errorf("  P (Kubectl Target): failed to apply object: %+v", err)
+		return err
+	}
+	return nil
+}
+
+func (i *KubectlTargetProvider) getFieldManager() string {
+	if i.Config.FieldManager != "" {
+		return i.Config.FieldManager
+	}
+	return DEFAULT_FIELD_MANAGER
+}
+
+// pruneObjects deletes the objects of an inventory that aren't kept, in reverse order
+func (i *KubectlTargetProvider) pruneObjects(ctx context.Context, entries []inventoryEntry, keep []inventoryEntry) error {
+	for idx := len(entries) - 1; idx >= 0; idx-- {
+		entry := entries[idx]
+		if containsInventoryEntry(keep, entry) {
+			continue
+		}
+		dr, err := i.getInventoryEntryResource(entry)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				// the type is gone, and so are its objects
+				continue
+			}
+			return err
+		}
+		sLog.Infof("  P (Kubectl Target): pruning %s %s/%s", entry.Kind, entry.Namespace, entry.Name)
+		err = dr.Delete(ctx, entry.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			sLog.Errorf("  P (Kubectl Target): failed to prune object: %+v", err)
+			return err
+		}
 	}
+	return nil
+}
 
-	obj, err = dr.Get(ctx, obj.GetName(), metav1.GetOptions{})
+// getInventoryEntryResource returns the client of the resource of an object in an inventory
+func (i *KubectlTargetProvider) getInventoryEntryResource(entry inventoryEntry) (dynamic.ResourceInterface, error) {
+	mapping, err := i.Mapper.RESTMapping(schema.GroupKind{Group: entry.Group, Kind: entry.Kind}, entry.Version)
 	if err != nil {
-		sLog.Error("  P (Kubectl Target): failed to read object: +%v", err)
 		return nil, err
 	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return i.DynamicClient.Resource(mapping.Resource).Namespace(entry.Namespace), nil
+	}
+	return i.DynamicClient.Resource(mapping.Resource), nil
+}
+
+// waitForObjects waits for Deployments, StatefulSets, DaemonSets and Jobs to become ready
+func (i *KubectlTargetProvider) waitForObjects(ctx context.Context, objects []componentObject, timeout time.Duration) error {
+	interval := i.pollInterval
+	if interval == 0 {
+		interval = DEFAULT_POLL_INTERVAL
+	}
+	deadline := time.Now().Add(timeout)
+	for _, object := range objects {
+		for {
+			current, err := object.dr.Get(ctx, object.obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			ready, err := isObjectReady(current)
+			if err != nil {
+				return err
+			}
+			if ready {
+				break
+			}
+			if time.Now().After(deadline) {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s '%s' isn't ready after %s", current.GetKind(), current.GetName(), timeout), v1alpha2.InternalError)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+	}
+	return nil
+}
+
+// isObjectReady checks if an object is ready, the way kubectl rollout status does. Objects other than Deployments,
+// StatefulSets, DaemonSets and Jobs are ready once they exist. A failed Job returns an error, as it won't become ready.
+func isObjectReady(obj *unstructured.Unstructured) (bool, error) {
+	gk := obj.GroupVersionKind().GroupKind()
+	if gk.Group == "batch" && gk.Kind == "Job" {
+		for _, condition := range getConditions(obj) {
+			if condition["status"] != "True" {
+				continue
+			}
+			switch condition["type"] {
+			case "Complete":
+				return true, nil
+			case "Failed":
+				return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("job '%s' failed: %v", obj.GetName(), condition["message"]), v1alpha2.InternalError)
+			}
+		}
+		return false, nil
+	}
+	if gk.Group != "apps" {
+		return true, nil
+	}
+
+	observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observedGeneration < obj.GetGeneration() {
+		return false, nil
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	status := func(field string) int64 {
+		v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		return v
+	}
+
+	switch gk.Kind {
+	case "Deployment":
+		for _, condition := range getConditions(obj) {
+			if condition["type"] == "Progressing" && condition["reason"] == "ProgressDeadlineExceeded" {
+				return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("deployment '%s' exceeded its progress deadline", obj.GetName()), v1alpha2.InternalError)
+			}
+		}
+		return status("updatedReplicas") >= replicas && status("replicas") <= status("updatedReplicas") && status("availableReplicas") >= status("updatedReplicas"), nil
+	case "StatefulSet":
+		strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+		if strategy == "OnDelete" {
+			return true, nil
+		}
+		currentRevision, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+		updateRevision, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+		return status("readyReplicas") >= replicas && status("updatedReplicas") >= replicas && currentRevision == updateRevision, nil
+	case "DaemonSet":
+		desired := status("desiredNumberScheduled")
+		return status("updatedNumberScheduled") >= desired && status("numberAvailable") >= desired, nil
+	}
+	return true, nil
+}
+
+func getConditions(obj *unstructured.Unstructured) []map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	ret := make([]map[string]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]interface{}); ok {
+			ret = append(ret, condition)
+		}
+	}
+	return ret
+}
+
+func newInventoryEntry(obj *unstructured.Unstructured) inventoryEntry {
+	gvk := obj.GroupVersionKind()
+	return inventoryEntry{
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+}
+
+// containsInventoryEntry checks if an inventory has an object. Versions aren't compared, as an object can be applied
+// with another version of its type.
+func containsInventoryEntry(entries []inventoryEntry, entry inventoryEntry) bool {
+	for _, e := range entries {
+		if e.Group == entry.Group && e.Kind == entry.Kind && e.Namespace == entry.Namespace && e.Name == entry.Name {
+			return true
+		}
+	}
+	return false
+}
 
-	return obj, nil
+// getInventoryName returns the name of the ConfigMap that records the objects of a component
+func getInventoryName(deployment model.DeploymentSpec, component string) string {
+	hash := sha256.Sum256([]byte(deployment.Instance.Name + "/" + component))
+	return INVENTORY_PREFIX + hex.EncodeToString(hash[:])[:16]
 }
 
-// deleteCustomResource deletes a custom resource from a byte array
-func (i *KubectlTargetProvider) deleteCustomResource(ctx context.Context, dataBytes []byte, scope string) error {
-	obj, dr, err := i.buildDynamicResourceClient(dataBytes, scope)
+func getInventoryNamespace(deployment model.DeploymentSpec) string {
+	if deployment.Instance.Scope == "" {
+		return "default"
+	}
+	return deployment.Instance.Scope
+}
+
+// readInventory reads the objects recorded for a component, and if there is an inventory
+func (i *KubectlTargetProvider) readInventory(ctx context.Context, deployment model.DeploymentSpec, component string) ([]inventoryEntry, bool, error) {
+	configMap, err := i.getInventory(ctx, deployment, component)
+	if err != nil || configMap == nil {
+		return nil, false, err
+	}
+	entries, err := parseInventory(configMap)
 	if err != nil {
-		sLog.Error("  P (Kubectl Target): failed to build a new dynamic client: +%v", err)
-		return err
+		return nil, false, err
 	}
+	return entries, true, nil
+}
 
-	err = dr.Delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+// getInventory gets the inventory ConfigMap of a component, or nil if there isn't one
+func (i *KubectlTargetProvider) getInventory(ctx context.Context, deployment model.DeploymentSpec, component string) (*corev1.ConfigMap, error) {
+	configMap, err := i.Client.CoreV1().ConfigMaps(getInventoryNamespace(deployment)).Get(ctx, getInventoryName(deployment, component), metav1.GetOptions{})
 	if err != nil {
-		if !kerrors.IsNotFound(err) {
-			sLog.Error("  P (Kubectl Target): failed to delete Yaml: +%v", err)
-			return err
+		if kerrors.IsNotFound(err) {
+			return nil, nil
 		}
+		sLog.Errorf("  P (Kubectl Target): failed to read inventory: %+v", err)
+		return nil, err
 	}
+	return configMap, nil
+}
 
-	return nil
+func parseInventory(configMap *corev1.ConfigMap) ([]inventoryEntry, error) {
+	entries := make([]inventoryEntry, 0)
+	if data, ok := configMap.Data[INVENTORY_OBJECTS_KEY]; ok {
+		if err := json.Unmarshal([]byte(data), &entries); err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("inventory '%s' is corrupted", configMap.Name), v1alpha2.InternalError)
+		}
+	}
+	return entries, nil
 }
 
-// applyCustomResource applies a custom resource from a byte array
-func (i *KubectlTargetProvider) applyCustomResource(ctx context.Context, dataBytes []byte, scope string) error {
-	obj, dr, err := i.buildDynamicResourceClient(dataBytes, scope)
+// writeInventory records the objects of a component, with extra annotations
+func (i *KubectlTargetProvider) writeInventory(ctx context.Context, deployment model.DeploymentSpec, component string, entries []inventoryEntry, annotations map[string]string) error {
+	data, err := json.Marshal(entries)
 	if err != nil {
-		sLog.Error("  P (Kubectl Target): failed to build a new dynamic client: +%v", err)
 		return err
 	}
-
-	// Check if the object exists
-	existing, err := dr.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	namespace := getInventoryNamespace(deployment)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        getInventoryName(deployment, component),
+			Namespace:   namespace,
+			Annotations: map[string]string{},
+		},
+		Data: map[string]string{
+			INVENTORY_OBJECTS_KEY: string(data),
+		},
+	}
+	for k, v := range annotations {
+		configMap.Annotations[k] = v
+	}
+	configMap.Annotations[INVENTORY_INSTANCE_ANNOTATION] = deployment.Instance.Name
+	configMap.Annotations[INVENTORY_COMPONENT_ANNOTATION] = component
+	existing, err := i.Client.CoreV1().ConfigMaps(namespace).Get(ctx, configMap.Name, metav1.GetOptions{})
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
-			sLog.Error("  P (Kubectl Target): failed to read object: +%v", err)
 			return err
 		}
-		// Create the object
-		_, err = dr.Create(ctx, obj, metav1.CreateOptions{})
-		if err != nil {
-			sLog.Error("  P (Kubectl Target): failed to create Yaml: +%v", err)
-			return err
+		err = i.ensureNamespace(ctx, namespace)
+		if err == nil {
+			_, err = i.Client.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
 		}
-		return nil
+	} else {
+		configMap.ResourceVersion = existing.ResourceVersion
+		_, err = i.Client.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
 	}
-
-	// Update the object
-	obj.SetResourceVersion(existing.GetResourceVersion())
-	_, err = dr.Update(ctx, obj, metav1.UpdateOptions{})
 	if err != nil {
-		sLog.Error("  P (Kubectl Target): failed to apply Yaml: +%v", err)
-		return err
+		sLog.Errorf("  P (Kubectl Target): failed to write inventory: %+v", err)
 	}
-
-	return nil
+	return err
 }
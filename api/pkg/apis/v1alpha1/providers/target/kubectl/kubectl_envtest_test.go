@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kubectl
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const (
+	envtestConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  greeting: hello
+`
+	envtestDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+`
+	// envtestInvalidDeployment has no selector, which the API server rejects
+	envtestInvalidDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: invalid
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+`
+)
+
+// newEnvtestKubectlProvider creates a provider for a local API server, which needs the envtest binaries. The fake
+// dynamic client doesn't implement server-side apply, so field ownership and pruning are tested against a real API
+// server. Set KUBEBUILDER_ASSETS to the directory of the binaries, for example with `setup-envtest use -p path`.
+func newEnvtestKubectlProvider(t *testing.T) *KubectlTargetProvider {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("Skipping envtest tests as KUBEBUILDER_ASSETS isn't set")
+	}
+	testEnv := &envtest.Environment{}
+	cfg, err := testEnv.Start()
+	require.Nil(t, err)
+	t.Cleanup(func() {
+		testEnv.Stop()
+	})
+	provider := &KubectlTargetProvider{}
+	require.Nil(t, provider.initClients(cfg))
+	return provider
+}
+
+func TestKubectlTargetProviderServerSideApplyEnvtest(t *testing.T) {
+	provider := newEnvtestKubectlProvider(t)
+	ctx := context.Background()
+	deployment, _ := conformance.Deployment("web-system", "web", "update")
+	configMaps := provider.DynamicClient.Resource(configMapGVR).Namespace("web-system")
+
+	err := provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestConfigMap)}, ApplyOptions{})
+	require.Nil(t, err)
+
+	// another manager changes a field Symphony owns, and adds one
+	other := &unstructured.Unstructured{}
+	other.SetAPIVersion("v1")
+	other.SetKind("ConfigMap")
+	other.SetName("settings")
+	other.SetNamespace("web-system")
+	unstructured.SetNestedStringMap(other.Object, map[string]string{"greeting": "hi", "extra": "kept"}, "data")
+	_, err = configMaps.Apply(ctx, "settings", other, metav1.ApplyOptions{FieldManager: "other", Force: true})
+	require.Nil(t, err)
+
+	// Symphony takes the field back, and leaves the field it doesn't manage alone
+	err = provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestConfigMap)}, ApplyOptions{})
+	require.Nil(t, err)
+	obj, err := configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	require.Nil(t, err)
+	data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+	assert.Equal(t, map[string]string{"greeting": "hello", "extra": "kept"}, data)
+	managers := make([]string, 0)
+	for _, field := range obj.GetManagedFields() {
+		if field.Operation == metav1.ManagedFieldsOperationApply {
+			managers = append(managers, field.Manager)
+		}
+	}
+	assert.ElementsMatch(t, []string{DEFAULT_FIELD_MANAGER, "other"}, managers)
+}
+
+func TestKubectlTargetProviderPruneEnvtest(t *testing.T) {
+	provider := newEnvtestKubectlProvider(t)
+	ctx := context.Background()
+	deployment, _ := conformance.Deployment("web-system", "web", "update")
+	configMaps := provider.DynamicClient.Resource(configMapGVR).Namespace("web-system")
+	deployments := provider.DynamicClient.Resource(deploymentGVR).Namespace("web-system")
+
+	err := provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestConfigMap), []byte(envtestDeployment)}, ApplyOptions{})
+	require.Nil(t, err)
+	_, err = deployments.Get(ctx, "web", metav1.GetOptions{})
+	require.Nil(t, err)
+
+	// objects removed from the documents are pruned
+	err = provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestConfigMap)}, ApplyOptions{})
+	require.Nil(t, err)
+	_, err = deployments.Get(ctx, "web", metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	// an object the API server rejects fails the apply, and the objects applied before it are recorded
+	err = provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestDeployment), []byte(envtestInvalidDeployment)}, ApplyOptions{})
+	require.NotNil(t, err)
+	assert.True(t, kerrors.IsInvalid(err))
+	entries, _, err := provider.readInventory(ctx, deployment, "web")
+	require.Nil(t, err)
+	assert.Equal(t, []inventoryEntry{
+		{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "web-system", Name: "web"},
+		{Version: "v1", Kind: "ConfigMap", Namespace: "web-system", Name: "settings"},
+	}, entries)
+
+	// and pruned once the apply succeeds without them
+	err = provider.ApplyDocuments(ctx, deployment, "web", [][]byte{[]byte(envtestConfigMap)}, ApplyOptions{})
+	require.Nil(t, err)
+	_, err = deployments.Get(ctx, "web", metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err))
+	_, err = configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// deleting the inventory deletes the objects
+	found, err := provider.DeleteInventory(ctx, deployment, "web")
+	require.Nil(t, err)
+	assert.True(t, found)
+	_, err = configMaps.Get(ctx, "settings", metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
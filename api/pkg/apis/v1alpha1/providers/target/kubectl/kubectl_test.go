@@ -9,15 +9,27 @@ package kubectl
 import (
 	"context"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	dfake "k8s.io/client-go/dynamic/fake"
 	kfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 // TestKubectlTargetProviderConfigFromMapNil tests that passing nil to KubectlTargetProviderConfigFromMap returns a valid config
@@ -704,3 +716,341 @@ func TestKubectlTargetProviderGet(t *testing.T) {
 	_, err = provider.Get(context.Background(), deployment, reference)
 	assert.Nil(t, err)
 }
+
+var (
+	configMapGVR  = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	deploymentGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	jobGVR        = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+)
+
+func TestKubectlTargetProviderApplyPrune(t *testing.T) {
+	var lock sync.Mutex
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  greeting: hello
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.Write([]byte(manifest))
+	}))
+	defer ts.Close()
+
+	provider, dynamicClient := newFakeKubectlProvider()
+	component := model.ComponentSpec{
+		Name: "web",
+		Type: "yaml.k8s",
+		Properties: map[string]interface{}{
+			"yaml": ts.URL,
+		},
+	}
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "settings")
+	assert.Nil(t, err)
+	_, err = dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+	assert.Nil(t, err)
+
+	entries, found, err := provider.readInventory(context.Background(), deployment, "web")
+	require.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []inventoryEntry{
+		{Version: "v1", Kind: "ConfigMap", Namespace: "web-system", Name: "settings"},
+		{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "web-system", Name: "web"},
+	}, entries)
+
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+
+	// a component is missing when any of its objects is
+	require.Nil(t, dynamicClient.Tracker().Delete(deploymentGVR, "web-system", "web"))
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+
+	// objects removed from the manifest are pruned
+	lock.Lock()
+	manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: more-settings
+`
+	lock.Unlock()
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	lock.Lock()
+	manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: more-settings
+`
+	lock.Unlock()
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "settings")
+	assert.True(t, kerrors.IsNotFound(err))
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "more-settings")
+	assert.Nil(t, err)
+
+	// removing the component deletes its objects and its inventory
+	deployment, step = conformance.Deployment("web-system", "web", "delete", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "more-settings")
+	assert.True(t, kerrors.IsNotFound(err))
+	_, found, err = provider.readInventory(context.Background(), deployment, "web")
+	require.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestKubectlTargetProviderApplyPartialInventory(t *testing.T) {
+	provider, dynamicClient := newFakeKubectlProvider()
+	deployment, _ := conformance.Deployment("web-system", "web", "update")
+	documents := func(names ...string) [][]byte {
+		ret := make([][]byte, 0)
+		for _, name := range names {
+			ret = append(ret, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: "+name+"\n"))
+		}
+		return ret
+	}
+	err := provider.ApplyDocuments(context.Background(), deployment, "web", documents("old"), ApplyOptions{Annotations: map[string]string{"hash": "1"}})
+	require.Nil(t, err)
+
+	// the apply fails on the third object
+	dynamicClient.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.PatchAction).GetName() == "third" {
+			return true, nil, kerrors.NewForbidden(configMapGVR.GroupResource(), "third", nil)
+		}
+		return false, nil, nil
+	})
+	err = provider.ApplyDocuments(context.Background(), deployment, "web", documents("first", "second", "third"), ApplyOptions{Annotations: map[string]string{"hash": "2"}})
+	require.NotNil(t, err)
+
+	// the objects applied so far are recorded along with the previous ones, which are pruned once the apply succeeds
+	entries, found, err := provider.readInventory(context.Background(), deployment, "web")
+	require.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []inventoryEntry{
+		{Version: "v1", Kind: "ConfigMap", Namespace: "web-system", Name: "first"},
+		{Version: "v1", Kind: "ConfigMap", Namespace: "web-system", Name: "second"},
+		{Version: "v1", Kind: "ConfigMap", Namespace: "web-system", Name: "old"},
+	}, entries)
+	annotations, _, err := provider.ReadInventoryStatus(context.Background(), deployment, "web")
+	require.Nil(t, err)
+	assert.Equal(t, "1", annotations["hash"])
+
+	// objects applied before the failure are pruned once they're removed from the documents
+	err = provider.ApplyDocuments(context.Background(), deployment, "web", documents("second"), ApplyOptions{Annotations: map[string]string{"hash": "3"}})
+	require.Nil(t, err)
+	for _, name := range []string{"old", "first"} {
+		_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", name)
+		assert.True(t, kerrors.IsNotFound(err), name)
+	}
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "second")
+	assert.Nil(t, err)
+}
+
+func TestKubectlTargetProviderApplyNamespaceFailed(t *testing.T) {
+	provider, dynamicClient := newFakeKubectlProvider()
+	provider.Client.(*kfake.Clientset).PrependReactor("create", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "web-system", nil)
+	})
+	deployment, _ := conformance.Deployment("web-system", "web", "update")
+	err := provider.ApplyDocuments(context.Background(), deployment, "web", [][]byte{[]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")}, ApplyOptions{})
+	assert.True(t, kerrors.IsForbidden(err))
+	_, err = dynamicClient.Tracker().Get(configMapGVR, "web-system", "config")
+	assert.True(t, kerrors.IsNotFound(err))
+
+	// the inventory isn't written without its namespace either
+	err = provider.writeInventory(context.Background(), deployment, "web", nil, nil)
+	assert.True(t, kerrors.IsForbidden(err))
+}
+
+func TestKubectlTargetProviderApplyWait(t *testing.T) {
+	provider, dynamicClient := newFakeKubectlProvider()
+	provider.pollInterval = 10 * time.Millisecond
+	component := model.ComponentSpec{
+		Name: "web",
+		Type: "yaml.k8s",
+		Properties: map[string]interface{}{
+			"resource": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "web"},
+				"spec":       map[string]interface{}{"replicas": 2},
+			},
+			"wait":        true,
+			"waitTimeout": "5s",
+		},
+	}
+
+	// the deployment becomes ready after a while
+	go func() {
+		for {
+			time.Sleep(50 * time.Millisecond)
+			obj, err := dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+			if err != nil {
+				continue
+			}
+			deployment := obj.(*unstructured.Unstructured).DeepCopy()
+			unstructured.SetNestedField(deployment.Object, map[string]interface{}{
+				"replicas":          int64(2),
+				"updatedReplicas":   int64(2),
+				"availableReplicas": int64(2),
+			}, "status")
+			dynamicClient.Tracker().Update(deploymentGVR, deployment, "web-system")
+			return
+		}
+	}()
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+	start := time.Now()
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// a deployment that isn't ready in time fails the component
+	component.Properties["resource"].(map[string]interface{})["spec"] = map[string]interface{}{"replicas": 3}
+	component.Properties["waitTimeout"] = "100ms"
+	deployment, step = conformance.Deployment("web-system", "web", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["web"].Status)
+
+	// a failed job fails the component without waiting for the timeout
+	job := model.ComponentSpec{
+		Name: "migrate",
+		Type: "yaml.k8s",
+		Properties: map[string]interface{}{
+			"resource": map[string]interface{}{
+				"apiVersion": "batch/v1",
+				"kind":       "Job",
+				"metadata":   map[string]interface{}{"name": "migrate"},
+			},
+			"wait": "true",
+		},
+	}
+	dynamicClient.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   map[string]interface{}{"name": "migrate", "namespace": "web-system"},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Failed", "status": "True", "message": "BackoffLimitExceeded"},
+				},
+			},
+		}}, nil
+	})
+	deployment, step = conformance.Deployment("web-system", "web", "update", job)
+	start = time.Now()
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "BackoffLimitExceeded")
+	assert.Less(t, time.Since(start), time.Minute)
+	_, err = dynamicClient.Tracker().Get(jobGVR, "web-system", "migrate")
+	assert.Nil(t, err)
+}
+
+func TestKubectlTargetProviderApplyInvalidWait(t *testing.T) {
+	provider, _ := newFakeKubectlProvider()
+	for _, properties := range []map[string]interface{}{
+		{"resource": map[string]interface{}{}, "wait": "sometimes"},
+		{"resource": map[string]interface{}{}, "waitTimeout": "soon"},
+	} {
+		deployment, step := conformance.Deployment("web-system", "web", "update", model.ComponentSpec{Name: "web", Type: "yaml.k8s", Properties: properties})
+		_, err := provider.Apply(context.Background(), deployment, step, true)
+		coaErr, ok := err.(v1alpha2.COAError)
+		require.True(t, ok, properties)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, properties)
+	}
+}
+
+func TestIsObjectReady(t *testing.T) {
+	object := func(apiVersion string, kind string, spec map[string]interface{}, status map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "test", "generation": int64(2)},
+			"spec":       spec,
+			"status":     status,
+		}}
+	}
+	for _, c := range []struct {
+		obj   *unstructured.Unstructured
+		ready bool
+		err   bool
+	}{
+		{obj: object("v1", "ConfigMap", nil, nil), ready: true},
+		{obj: object("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)}), ready: true},
+		{obj: object("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2)})},
+		{obj: object("apps/v1", "Deployment", map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{"observedGeneration": int64(2), "replicas": int64(3), "updatedReplicas": int64(2), "availableReplicas": int64(2)})},
+		{obj: object("apps/v1", "Deployment", map[string]interface{}{}, map[string]interface{}{"observedGeneration": int64(2), "conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"}}}), err: true},
+		{obj: object("apps/v1", "StatefulSet", map[string]interface{}{"replicas": int64(1)}, map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(1), "updatedReplicas": int64(1), "currentRevision": "a", "updateRevision": "a"}), ready: true},
+		{obj: object("apps/v1", "StatefulSet", map[string]interface{}{"replicas": int64(1)}, map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(1), "updatedReplicas": int64(1), "currentRevision": "a", "updateRevision": "b"})},
+		{obj: object("apps/v1", "DaemonSet", nil, map[string]interface{}{"observedGeneration": int64(2), "desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(3), "numberAvailable": int64(3)}), ready: true},
+		{obj: object("apps/v1", "DaemonSet", nil, map[string]interface{}{"observedGeneration": int64(2), "desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(3), "numberAvailable": int64(2)})},
+		{obj: object("batch/v1", "Job", nil, map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}}), ready: true},
+		{obj: object("batch/v1", "Job", nil, map[string]interface{}{})},
+	} {
+		ready, err := isObjectReady(c.obj)
+		assert.Equal(t, c.ready, ready, c.obj.Object)
+		assert.Equal(t, c.err, err != nil, c.obj.Object)
+	}
+}
+
+// newFakeKubectlProvider creates a provider with fake clients. Server-side apply requests create or replace objects.
+func newFakeKubectlProvider() (*KubectlTargetProvider, *dfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dynamicClient.Tracker()
+		existing, err := tracker.Get(action.GetResource(), action.GetNamespace(), patch.GetName())
+		if kerrors.IsNotFound(err) {
+			return true, obj, tracker.Create(action.GetResource(), obj, action.GetNamespace())
+		} else if err != nil {
+			return true, nil, err
+		}
+		if status, ok := existing.(*unstructured.Unstructured).Object["status"]; ok {
+			obj.Object["status"] = status
+		}
+		return true, obj, tracker.Update(action.GetResource(), obj, action.GetNamespace())
+	})
+
+	provider := &KubectlTargetProvider{
+		Client:        kfake.NewSimpleClientset(),
+		DynamicClient: dynamicClient,
+		Mapper:        mapper,
+	}
+	return provider, dynamicClient
+}
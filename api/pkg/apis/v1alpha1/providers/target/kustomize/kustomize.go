@@ -0,0 +1,655 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kustomize
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/kubectl"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	// FilesProperty holds an inline kustomization, as a map of file paths to file contents
+	FilesProperty = "kustomize.files"
+	// CatalogProperty names a catalog whose "kustomization" property holds the files of the kustomization
+	CatalogProperty = "kustomize.catalog"
+	// PathProperty is a local directory that holds the kustomization
+	PathProperty = "kustomize.path"
+	// ImagesProperty overrides images, as the images field of a kustomization
+	ImagesProperty = "kustomize.images"
+	// PatchesProperty adds inline patches, as the patches field of a kustomization
+	PatchesProperty = "kustomize.patches"
+	// WaitProperty waits for the objects of a component to become ready
+	WaitProperty = "kustomize.wait"
+	// WaitTimeoutProperty is how long to wait for the objects of a component to become ready
+	WaitTimeoutProperty = "kustomize.waitTimeout"
+	// HashProperty is reported by Get with the hash of the rendered output that is deployed
+	HashProperty = "kustomize.hash"
+
+	// DEFAULT_MAX_FILES is how many files a local kustomization can have by default
+	DEFAULT_MAX_FILES = 1000
+	// DEFAULT_MAX_BYTES is how large the files of a local kustomization can be in total by default
+	DEFAULT_MAX_BYTES = 16 * 1024 * 1024
+
+	catalogFilesProperty = "kustomization"
+	hashAnnotation       = "symphony/kustomize-hash"
+	baseDirectory        = "/base"
+	overlayDirectory     = "/overlay"
+)
+
+type (
+	// KustomizeTargetProviderConfig is the configuration of the kustomize target provider
+	KustomizeTargetProviderConfig struct {
+		Name         string `json:"name,omitempty"`
+		ConfigType   string `json:"configType,omitempty"`
+		ConfigData   string `json:"configData,omitempty"`
+		Context      string `json:"context,omitempty"`
+		InCluster    bool   `json:"inCluster"`
+		FieldManager string `json:"fieldManager,omitempty"`
+		// BaseDir is the directory that local kustomizations must be in. Local kustomizations are disabled without it.
+		BaseDir string `json:"baseDir,omitempty"`
+		// MaxFiles and MaxBytes limit how many files, and how many bytes in total, are read from a local kustomization
+		MaxFiles int   `json:"maxFiles,omitempty"`
+		MaxBytes int64 `json:"maxBytes,omitempty"`
+	}
+
+	// KustomizeTargetProvider builds kustomizations and applies the result with the kubectl target provider
+	KustomizeTargetProvider struct {
+		Config  KustomizeTargetProviderConfig
+		Context *contexts.ManagerContext
+		Kubectl *kubectl.KubectlTargetProvider
+	}
+
+	// componentOptions are the overrides and the wait options of a component
+	componentOptions struct {
+		images      []types.Image
+		patches     []types.Patch
+		wait        bool
+		waitTimeout time.Duration
+	}
+)
+
+// KustomizeTargetProviderConfigFromMap converts a map to a KustomizeTargetProviderConfig
+func KustomizeTargetProviderConfigFromMap(properties map[string]string) (KustomizeTargetProviderConfig, error) {
+	ret := KustomizeTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["configType"]; ok {
+		ret.ConfigType = v
+	}
+	if v, ok := properties["configData"]; ok {
+		ret.ConfigData = v
+	}
+	if v, ok := properties["context"]; ok {
+		ret.Context = v
+	}
+	if v, ok := properties["fieldManager"]; ok {
+		ret.FieldManager = v
+	}
+	if v, ok := properties["inCluster"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'inCluster' setting of kustomize provider", v1alpha2.BadConfig)
+		}
+		ret.InCluster = bVal
+	}
+	if v, ok := properties["baseDir"]; ok {
+		ret.BaseDir = v
+	}
+	if v, ok := properties["maxFiles"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxFiles' setting of kustomize provider", v1alpha2.BadConfig)
+		}
+		ret.MaxFiles = n
+	}
+	if v, ok := properties["maxBytes"]; ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxBytes' setting of kustomize provider", v1alpha2.BadConfig)
+		}
+		ret.MaxBytes = n
+	}
+	return ret, nil
+}
+
+// InitWithMap initializes the kustomize target provider with a map
+func (i *KustomizeTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := KustomizeTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (i *KustomizeTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	i.Context = ctx
+}
+
+// Init initializes the kustomize target provider, and the kubectl target provider it applies objects with
+func (i *KustomizeTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("Kustomize Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (Kustomize Target): Init()")
+
+	updateConfig, err := toKustomizeTargetProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (Kustomize Target): expected KustomizeTargetProviderConfig: %+v", err)
+		return err
+	}
+	i.Config = updateConfig
+
+	i.Kubectl = &kubectl.KubectlTargetProvider{}
+	err = i.Kubectl.Init(kubectl.KubectlTargetProviderConfig{
+		Name:         i.Config.Name,
+		ConfigType:   i.Config.ConfigType,
+		ConfigData:   i.Config.ConfigData,
+		Context:      i.Config.Context,
+		InCluster:    i.Config.InCluster,
+		FieldManager: i.Config.FieldManager,
+	})
+	if err != nil {
+		sLog.Errorf("  P (Kustomize Target): failed to initialize the kubectl provider: %+v", err)
+		return err
+	}
+	return nil
+}
+
+func toKustomizeTargetProviderConfig(config providers.IProviderConfig) (KustomizeTargetProviderConfig, error) {
+	ret := KustomizeTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// Get reports the components whose objects all exist, with the hash of their deployed output in kustomize.hash.
+// A component whose current output has the same hash reports its properties as in the reference. Otherwise its
+// kustomization properties report the deployed hash in their place, so that change detection deploys it again.
+func (i *KustomizeTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Kustomize Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Kustomize Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		var annotations map[string]string
+		var complete bool
+		annotations, complete, err = i.Kubectl.ReadInventoryStatus(ctx, deployment, reference.Component.Name)
+		if err != nil {
+			sLog.Errorf("  P (Kustomize Target): failed to read the inventory of %s: %+v", reference.Component.Name, err)
+			return nil, err
+		}
+		if !complete {
+			continue
+		}
+
+		deployed := annotations[hashAnnotation]
+		component := model.ComponentSpec{
+			Name:       reference.Component.Name,
+			Type:       reference.Component.Type,
+			Properties: map[string]interface{}{},
+		}
+		for k, v := range reference.Component.Properties {
+			component.Properties[k] = v
+		}
+		_, hash, renderErr := i.render(ctx, deployment, reference.Component)
+		if renderErr != nil {
+			// the component is reported as drifted, and deploying it reports the error
+			sLog.Errorf("  P (Kustomize Target): failed to build the kustomization of %s: %+v", reference.Component.Name, renderErr)
+		}
+		if renderErr != nil || hash != deployed {
+			for _, property := range []string{FilesProperty, CatalogProperty, PathProperty} {
+				if _, ok := component.Properties[property]; ok {
+					component.Properties[property] = deployed
+				}
+			}
+		}
+		component.Properties[HashProperty] = deployed
+		ret = append(ret, component)
+	}
+
+	return ret, nil
+}
+
+// Apply builds the kustomization of each component and applies the output in the scope of the instance, pruning
+// the objects that are no longer in the output. A component whose output and objects are unchanged is left alone.
+func (i *KustomizeTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Kustomize Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Kustomize Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range step.GetUpdatedComponents() {
+		if _, err = readComponentOptions(deployment, component); err != nil {
+			return nil, err
+		}
+	}
+	if isDryRun {
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+	for _, component := range step.GetUpdatedComponents() {
+		var result model.ComponentResultSpec
+		result, err = i.applyComponent(ctx, deployment, component)
+		ret[component.Name] = result
+		if err != nil {
+			sLog.Errorf("  P (Kustomize Target): failed to apply %s: %+v", component.Name, err)
+			return ret, err
+		}
+	}
+	for _, component := range step.GetDeletedComponents() {
+		var found bool
+		found, err = i.Kubectl.DeleteInventory(ctx, deployment, component.Name)
+		if err != nil {
+			sLog.Errorf("  P (Kustomize Target): failed to remove %s: %+v", component.Name, err)
+			ret[component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.DeleteFailed,
+				Message: err.Error(),
+			}
+			return ret, err
+		}
+		if !found {
+			sLog.Infof("  P (Kustomize Target): %s has no inventory, nothing to remove", component.Name)
+		}
+		ret[component.Name] = model.ComponentResultSpec{
+			Status:  v1alpha2.Deleted,
+			Message: "",
+		}
+	}
+	return ret, nil
+}
+
+func (i *KustomizeTargetProvider) applyComponent(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) (model.ComponentResultSpec, error) {
+	options, err := readComponentOptions(deployment, component)
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	documents, hash, err := i.render(ctx, deployment, component)
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+
+	annotations, complete, err := i.Kubectl.ReadInventoryStatus(ctx, deployment, component.Name)
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	if complete && annotations[hashAnnotation] == hash {
+		sLog.Infof("  P (Kustomize Target): %s is up to date", component.Name)
+		return model.ComponentResultSpec{Status: v1alpha2.Untouched, Message: "rendered output is up to date"}, nil
+	}
+
+	err = i.Kubectl.ApplyDocuments(ctx, deployment, component.Name, documents, kubectl.ApplyOptions{
+		Wait:        options.wait,
+		WaitTimeout: options.waitTimeout,
+		Annotations: map[string]string{hashAnnotation: hash},
+	})
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	return model.ComponentResultSpec{Status: v1alpha2.Updated, Message: ""}, nil
+}
+
+// GetValidationRule returns the validation rule of the provider
+func (*KustomizeTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		RequiredProperties:    []string{},
+		OptionalProperties:    []string{FilesProperty, CatalogProperty, PathProperty, ImagesProperty, PatchesProperty, WaitProperty, WaitTimeoutProperty},
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: FilesProperty, IgnoreCase: false, SkipIfMissing: true},
+			{Name: CatalogProperty, IgnoreCase: false, SkipIfMissing: true},
+			{Name: PathProperty, IgnoreCase: false, SkipIfMissing: true},
+			{Name: ImagesProperty, IgnoreCase: false, SkipIfMissing: true},
+			{Name: PatchesProperty, IgnoreCase: false, SkipIfMissing: true},
+		},
+	}
+}
+
+// render builds the kustomization of a component, in an overlay that sets the namespace to the scope of the
+// instance and adds the image overrides and the patches of the component. It returns the documents of the output
+// and its hash.
+func (i *KustomizeTargetProvider) render(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) ([][]byte, string, error) {
+	options, err := readComponentOptions(deployment, component)
+	if err != nil {
+		return nil, "", err
+	}
+	files, err := i.readFiles(ctx, deployment, component)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fSys := filesys.MakeFsInMemory()
+	hasKustomization := false
+	for name, content := range files {
+		cleaned := path.Clean("/" + filepath.ToSlash(name))
+		if filepath.IsAbs(name) || strings.HasPrefix(path.Clean(filepath.ToSlash(name)), "..") {
+			return nil, "", v1alpha2.NewCOAError(nil, fmt.Sprintf("file '%s' of component '%s' is outside of the kustomization", name, component.Name), v1alpha2.BadRequest)
+		}
+		for _, kustomization := range []string{"/kustomization.yaml", "/kustomization.yml", "/Kustomization"} {
+			if cleaned == kustomization {
+				hasKustomization = true
+			}
+		}
+		file := baseDirectory + cleaned
+		if err = fSys.MkdirAll(path.Dir(file)); err != nil {
+			return nil, "", err
+		}
+		if err = fSys.WriteFile(file, []byte(content)); err != nil {
+			return nil, "", err
+		}
+	}
+	if !hasKustomization {
+		return nil, "", v1alpha2.NewCOAError(nil, fmt.Sprintf("component '%s' doesn't have a kustomization.yaml file", component.Name), v1alpha2.BadRequest)
+	}
+
+	overlay := types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+		Namespace: deployment.Instance.Scope,
+		Resources: []string{"../" + strings.TrimPrefix(baseDirectory, "/")},
+		Images:    options.images,
+		Patches:   options.patches,
+	}
+	data, err := yaml.Marshal(overlay)
+	if err != nil {
+		return nil, "", err
+	}
+	if err = fSys.MkdirAll(overlayDirectory); err != nil {
+		return nil, "", err
+	}
+	if err = fSys.WriteFile(overlayDirectory+"/kustomization.yaml", data); err != nil {
+		return nil, "", err
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, overlayDirectory)
+	if err != nil {
+		return nil, "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to build the kustomization of component '%s'", component.Name), v1alpha2.BadRequest)
+	}
+	output, err := resMap.AsYaml()
+	if err != nil {
+		return nil, "", err
+	}
+	documents := make([][]byte, 0, resMap.Size())
+	for _, resource := range resMap.Resources() {
+		document, err := resource.AsYAML()
+		if err != nil {
+			return nil, "", err
+		}
+		documents = append(documents, document)
+	}
+	hash := sha256.Sum256(output)
+	return documents, hex.EncodeToString(hash[:]), nil
+}
+
+// readFiles reads the files of the kustomization of a component, inline, from a catalog or from a local directory,
+// and injects values such as ${{$instance()}} into them
+func (i *KustomizeTargetProvider) readFiles(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec) (map[string]string, error) {
+	injections := getValueInjections(deployment)
+	var source interface{}
+	if v, ok := component.Properties[FilesProperty]; ok {
+		source = v
+	} else if v, ok := component.Properties[CatalogProperty]; ok {
+		if i.Context == nil || i.Context.SiteInfo.CurrentSite.BaseUrl == "" {
+			return nil, v1alpha2.NewCOAError(nil, "catalog references require a Symphony API endpoint", v1alpha2.BadConfig)
+		}
+		name := model.ResolveString(fmt.Sprintf("%v", v), injections)
+		catalog, err := utils.GetCatalog(
+			ctx,
+			i.Context.SiteInfo.CurrentSite.BaseUrl,
+			name,
+			i.Context.SiteInfo.CurrentSite.Username,
+			i.Context.SiteInfo.CurrentSite.Password)
+		if err != nil {
+			return nil, err
+		}
+		if catalog.Spec == nil || catalog.Spec.Properties[catalogFilesProperty] == nil {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("catalog '%s' doesn't have a '%s' property", name, catalogFilesProperty), v1alpha2.BadRequest)
+		}
+		source = catalog.Spec.Properties[catalogFilesProperty]
+	} else if v, ok := component.Properties[PathProperty]; ok {
+		files, err := i.readDirectory(model.ResolveString(fmt.Sprintf("%v", v), injections))
+		if err != nil {
+			if _, ok := err.(v1alpha2.COAError); ok {
+				return nil, err
+			}
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read the kustomization of component '%s'", component.Name), v1alpha2.BadRequest)
+		}
+		source = files
+	} else {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("component '%s' has none of %s, %s and %s properties", component.Name, FilesProperty, CatalogProperty, PathProperty), v1alpha2.BadRequest)
+	}
+
+	ret := make(map[string]string)
+	switch files := source.(type) {
+	case map[string]string:
+		for name, content := range files {
+			ret[name] = model.ResolveString(content, injections)
+		}
+	case map[string]interface{}:
+		for name, content := range files {
+			text, ok := content.(string)
+			if !ok {
+				return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("file '%s' of component '%s' is not a string", name, component.Name), v1alpha2.BadRequest)
+			}
+			ret[name] = model.ResolveString(text, injections)
+		}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("the kustomization of component '%s' is not a map of file names to file contents", component.Name), v1alpha2.BadRequest)
+	}
+	return ret, nil
+}
+
+// readDirectory reads the files of a local kustomization, by their paths relative to its directory. The directory must
+// be in BaseDir, and relative paths are relative to BaseDir. Symbolic links are followed only if they stay in the
+// directory, and MaxFiles and MaxBytes limit how much is read.
+func (i *KustomizeTargetProvider) readDirectory(path string) (map[string]string, error) {
+	if i.Config.BaseDir == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s is disabled, as the baseDir setting of the kustomize provider is not set", PathProperty), v1alpha2.BadRequest)
+	}
+	base, err := filepath.Abs(i.Config.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	base, err = filepath.EvalSymlinks(base)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	root, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if !isWithin(base, root) {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s '%s' is outside of the base directory of the kustomize provider", PathProperty, path), v1alpha2.BadRequest)
+	}
+
+	maxFiles := i.Config.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = DEFAULT_MAX_FILES
+	}
+	maxBytes := i.Config.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DEFAULT_MAX_BYTES
+	}
+	ret := make(map[string]string)
+	var total int64
+	err = filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		file := name
+		if entry.Type()&fs.ModeSymlink != 0 {
+			file, err = filepath.EvalSymlinks(name)
+			if err != nil {
+				return err
+			}
+			if !isWithin(root, file) {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' links outside of the kustomization directory", name), v1alpha2.BadRequest)
+			}
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if len(ret) >= maxFiles {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("the kustomization in '%s' has more than %d files", path, maxFiles), v1alpha2.BadRequest)
+		}
+		if total+info.Size() > maxBytes {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("the kustomization in '%s' is larger than %d bytes", path, maxBytes), v1alpha2.BadRequest)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		// the file can grow after it's checked, so no more than the remaining bytes are read
+		data, err := io.ReadAll(io.LimitReader(f, maxBytes-total+1))
+		if err != nil {
+			return err
+		}
+		total += int64(len(data))
+		if total > maxBytes {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("the kustomization in '%s' is larger than %d bytes", path, maxBytes), v1alpha2.BadRequest)
+		}
+		relative, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		ret[filepath.ToSlash(relative)] = string(data)
+		return nil
+	})
+	return ret, err
+}
+
+// isWithin checks if a cleaned path is a directory or is in it
+func isWithin(directory string, path string) bool {
+	relative, err := filepath.Rel(directory, path)
+	if err != nil {
+		return false
+	}
+	return relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// readComponentOptions reads the image overrides, the patches and the wait options of a component
+func readComponentOptions(deployment model.DeploymentSpec, component model.ComponentSpec) (componentOptions, error) {
+	ret := componentOptions{waitTimeout: kubectl.DEFAULT_WAIT_TIMEOUT}
+	injections := getValueInjections(deployment)
+	if v, ok := component.Properties[ImagesProperty]; ok {
+		if err := parseList(v, &ret.images); err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid '%s' property of component '%s'", ImagesProperty, component.Name), v1alpha2.BadRequest)
+		}
+		for idx, image := range ret.images {
+			if image.Name == "" {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("an image override of component '%s' doesn't have a name", component.Name), v1alpha2.BadRequest)
+			}
+			ret.images[idx].NewName = model.ResolveString(image.NewName, injections)
+			ret.images[idx].NewTag = model.ResolveString(image.NewTag, injections)
+		}
+	}
+	if v, ok := component.Properties[PatchesProperty]; ok {
+		if err := parseList(v, &ret.patches); err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid '%s' property of component '%s'", PatchesProperty, component.Name), v1alpha2.BadRequest)
+		}
+		for idx, patch := range ret.patches {
+			if patch.Patch == "" || patch.Path != "" {
+				return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("a patch of component '%s' must be inline, in a 'patch' field", component.Name), v1alpha2.BadRequest)
+			}
+			ret.patches[idx].Patch = model.ResolveString(patch.Patch, injections)
+		}
+	}
+	if v, ok := component.Properties[WaitProperty]; ok {
+		wait, err := strconv.ParseBool(fmt.Sprintf("%v", v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the '%s' property of component '%s'", WaitProperty, component.Name), v1alpha2.BadRequest)
+		}
+		ret.wait = wait
+	}
+	if v, ok := component.Properties[WaitTimeoutProperty]; ok {
+		timeout, err := time.ParseDuration(fmt.Sprintf("%v", v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid duration in the '%s' property of component '%s'", WaitTimeoutProperty, component.Name), v1alpha2.BadRequest)
+		}
+		ret.waitTimeout = timeout
+	}
+	return ret, nil
+}
+
+// parseList reads a list given either as an array or as a YAML or JSON string
+func parseList(value interface{}, out interface{}) error {
+	if text, ok := value.(string); ok {
+		return yaml.Unmarshal([]byte(text), out)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func getValueInjections(deployment model.DeploymentSpec) *model.ValueInjections {
+	return &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+}
@@ -0,0 +1,493 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kustomize
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/kubectl"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dfake "k8s.io/client-go/dynamic/fake"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	deploymentGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	serviceGVR    = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+)
+
+const (
+	baseKustomization = `resources:
+- deployment.yaml
+- service.yaml
+`
+	baseDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+        env:
+        - name: INSTANCE
+          value: ${{$instance()}}
+`
+	baseService = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`
+)
+
+func TestKustomizeTargetProviderConfigFromMap(t *testing.T) {
+	config, err := KustomizeTargetProviderConfigFromMap(map[string]string{
+		"name":         "kustomize",
+		"configType":   "inline",
+		"configData":   "data",
+		"inCluster":    "true",
+		"fieldManager": "platform",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, KustomizeTargetProviderConfig{
+		Name:         "kustomize",
+		ConfigType:   "inline",
+		ConfigData:   "data",
+		InCluster:    true,
+		FieldManager: "platform",
+	}, config)
+
+	config, err = KustomizeTargetProviderConfigFromMap(map[string]string{
+		"baseDir":  "/srv/kustomizations",
+		"maxFiles": "10",
+		"maxBytes": "1024",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/srv/kustomizations", config.BaseDir)
+	assert.Equal(t, 10, config.MaxFiles)
+	assert.Equal(t, int64(1024), config.MaxBytes)
+
+	_, err = KustomizeTargetProviderConfigFromMap(map[string]string{"inCluster": "sure"})
+	assert.NotNil(t, err)
+	_, err = KustomizeTargetProviderConfigFromMap(map[string]string{"maxFiles": "many"})
+	assert.NotNil(t, err)
+}
+
+func TestKustomizeTargetProviderInitWithMapInvalidConfig(t *testing.T) {
+	provider := KustomizeTargetProvider{}
+	err := provider.InitWithMap(map[string]string{"configType": "bad"})
+	assert.NotNil(t, err)
+}
+
+func TestRender(t *testing.T) {
+	provider, _ := newFakeKustomizeProvider()
+	component := kustomizeComponent(baseFiles())
+	component.Properties[ImagesProperty] = []interface{}{
+		map[string]interface{}{"name": "nginx", "newTag": "1.26"},
+	}
+	component.Properties[PatchesProperty] = `
+- target:
+    kind: Deployment
+    name: web
+  patch: |-
+    - op: replace
+      path: /spec/replicas
+      value: 3
+`
+	deployment, _ := conformance.Deployment("web-system", "web", "update", component)
+
+	documents, hash, err := provider.render(context.Background(), deployment, component)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(documents))
+	assert.Equal(t, 64, len(hash))
+
+	output := string(documents[0]) + string(documents[1])
+	assert.Contains(t, output, "namespace: web-system")
+	assert.Contains(t, output, "image: nginx:1.26")
+	assert.Contains(t, output, "replicas: 3")
+	assert.Contains(t, output, "value: web")
+
+	// the same kustomization renders the same output
+	_, again, err := provider.render(context.Background(), deployment, component)
+	require.Nil(t, err)
+	assert.Equal(t, hash, again)
+
+	delete(component.Properties, ImagesProperty)
+	_, other, err := provider.render(context.Background(), deployment, component)
+	require.Nil(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestRenderInvalid(t *testing.T) {
+	provider, _ := newFakeKustomizeProvider()
+	cases := map[string]model.ComponentSpec{
+		"no kustomization": kustomizeComponent(map[string]interface{}{"deployment.yaml": baseDeployment}),
+		"outside file": kustomizeComponent(map[string]interface{}{
+			"kustomization.yaml": baseKustomization,
+			"../deployment.yaml": baseDeployment,
+		}),
+		"missing resource": kustomizeComponent(map[string]interface{}{"kustomization.yaml": baseKustomization}),
+		"not a string":     kustomizeComponent(map[string]interface{}{"kustomization.yaml": 1}),
+		"no source":        {Name: "web", Properties: map[string]interface{}{}},
+	}
+	for name, component := range cases {
+		deployment, _ := conformance.Deployment("web-system", "web", "update", component)
+		_, _, err := provider.render(context.Background(), deployment, component)
+		require.NotNil(t, err, name)
+		coaErr, ok := err.(v1alpha2.COAError)
+		require.True(t, ok, name)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, name)
+	}
+}
+
+func TestApplyInvalidProperties(t *testing.T) {
+	provider, _ := newFakeKustomizeProvider()
+	cases := map[string]map[string]interface{}{
+		"image without name": {ImagesProperty: []interface{}{map[string]interface{}{"newTag": "1.26"}}},
+		"patch file":         {PatchesProperty: []interface{}{map[string]interface{}{"path": "patch.yaml"}}},
+		"invalid patches":    {PatchesProperty: "patch: ["},
+		"invalid wait":       {WaitProperty: "sometimes"},
+		"invalid timeout":    {WaitTimeoutProperty: "soon"},
+	}
+	for name, properties := range cases {
+		component := kustomizeComponent(baseFiles())
+		for k, v := range properties {
+			component.Properties[k] = v
+		}
+		deployment, step := conformance.Deployment("web-system", "web", "update", component)
+		_, err := provider.Apply(context.Background(), deployment, step, true)
+		require.NotNil(t, err, name)
+		coaErr, ok := err.(v1alpha2.COAError)
+		require.True(t, ok, name)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, name)
+	}
+}
+
+func TestApply(t *testing.T) {
+	provider, dynamicClient := newFakeKustomizeProvider()
+	component := kustomizeComponent(baseFiles())
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+	assert.Nil(t, err)
+	_, err = dynamicClient.Tracker().Get(serviceGVR, "web-system", "web")
+	assert.Nil(t, err)
+
+	_, hash, err := provider.render(context.Background(), deployment, component)
+	require.Nil(t, err)
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(components))
+	assert.Equal(t, hash, components[0].Properties[HashProperty])
+	assert.Equal(t, component.Properties[FilesProperty], components[0].Properties[FilesProperty])
+
+	// an unchanged output is left alone
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Untouched, ret["web"].Status)
+
+	// objects removed from the kustomization are pruned
+	files := baseFiles()
+	files["kustomization.yaml"] = "resources:\n- deployment.yaml\n"
+	component = kustomizeComponent(files)
+	deployment, step = conformance.Deployment("web-system", "web", "update", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(serviceGVR, "web-system", "web")
+	assert.True(t, kerrors.IsNotFound(err))
+
+	deployment, step = conformance.Deployment("web-system", "web", "delete", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+	assert.True(t, kerrors.IsNotFound(err))
+
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+}
+
+func TestGetDrift(t *testing.T) {
+	provider, dynamicClient := newFakeKustomizeProvider()
+	component := kustomizeComponent(baseFiles())
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	_, deployed, err := provider.render(context.Background(), deployment, component)
+	require.Nil(t, err)
+
+	// a changed kustomization reports the deployed hash in place of the files
+	files := baseFiles()
+	files["service.yaml"] = strings.Replace(baseService, "port: 80", "port: 8080", 1)
+	changed := kustomizeComponent(files)
+	_, step = conformance.Deployment("web-system", "web", "update", changed)
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(components))
+	assert.Equal(t, deployed, components[0].Properties[FilesProperty])
+	assert.Equal(t, deployed, components[0].Properties[HashProperty])
+
+	// a missing object reports the component as missing, and applying it again restores the object
+	require.Nil(t, dynamicClient.Tracker().Delete(serviceGVR, "web-system", "web"))
+	_, step = conformance.Deployment("web-system", "web", "update", component)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(serviceGVR, "web-system", "web")
+	assert.Nil(t, err)
+}
+
+func TestApplyPath(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range baseFiles() {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content.(string)), 0644))
+	}
+
+	provider, dynamicClient := newFakeKustomizeProvider()
+	provider.Config.BaseDir = filepath.Dir(dir)
+	component := model.ComponentSpec{
+		Name:       "web",
+		Properties: map[string]interface{}{PathProperty: dir},
+	}
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	_, err = dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+	assert.Nil(t, err)
+
+	// relative to the base directory
+	component.Properties[PathProperty] = filepath.Base(dir)
+	deployment, step = conformance.Deployment("web-system", "web", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	component.Properties[PathProperty] = filepath.Join(dir, "missing")
+	deployment, step = conformance.Deployment("web-system", "web", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestReadDirectoryDisabled(t *testing.T) {
+	provider := &KustomizeTargetProvider{}
+	_, err := provider.readDirectory(t.TempDir())
+	require.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
+
+func TestReadDirectoryOutsideBaseDir(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(outside, "kustomization.yaml"), []byte(baseKustomization), 0644))
+	provider := &KustomizeTargetProvider{Config: KustomizeTargetProviderConfig{BaseDir: base}}
+
+	for _, path := range []string{outside, "/", filepath.Join(base, ".."), "../" + filepath.Base(outside)} {
+		_, err := provider.readDirectory(path)
+		require.NotNil(t, err, path)
+		assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State, path)
+	}
+
+	// a link in the base directory to a directory outside of it
+	require.Nil(t, os.Symlink(outside, filepath.Join(base, "link")))
+	_, err := provider.readDirectory(filepath.Join(base, "link"))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "outside of the base directory")
+}
+
+func TestReadDirectorySymlinks(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	dir := filepath.Join(base, "web")
+	require.Nil(t, os.Mkdir(dir, 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(baseKustomization), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+	provider := &KustomizeTargetProvider{Config: KustomizeTargetProviderConfig{BaseDir: base}}
+
+	// links that stay in the kustomization are followed
+	require.Nil(t, os.Symlink(filepath.Join(dir, "kustomization.yaml"), filepath.Join(dir, "copy.yaml")))
+	files, err := provider.readDirectory(dir)
+	require.Nil(t, err)
+	assert.Equal(t, baseKustomization, files["copy.yaml"])
+
+	require.Nil(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "secret.yaml")))
+	_, err = provider.readDirectory(dir)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "links outside of the kustomization directory")
+}
+
+func TestReadDirectoryLimits(t *testing.T) {
+	base := t.TempDir()
+	for name, content := range baseFiles() {
+		require.Nil(t, os.WriteFile(filepath.Join(base, name), []byte(content.(string)), 0644))
+	}
+	provider := &KustomizeTargetProvider{Config: KustomizeTargetProviderConfig{BaseDir: base}}
+	files, err := provider.readDirectory(base)
+	require.Nil(t, err)
+	assert.Equal(t, len(baseFiles()), len(files))
+
+	provider.Config.MaxFiles = len(baseFiles()) - 1
+	_, err = provider.readDirectory(base)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "files")
+
+	provider.Config.MaxFiles = 0
+	provider.Config.MaxBytes = int64(len(baseKustomization))
+	_, err = provider.readDirectory(base)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bytes")
+}
+
+func TestApplyCatalog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/users/auth":
+			response = map[string]string{"accessToken": "test-token"}
+		case "/catalogs/registry/web-base":
+			response = model.CatalogState{
+				Id: "web-base",
+				Spec: &model.CatalogSpec{
+					Properties: map[string]interface{}{
+						"kustomization": baseFiles(),
+					},
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+
+	provider, dynamicClient := newFakeKustomizeProvider()
+	provider.SetContext(&contexts.ManagerContext{
+		SiteInfo: v1alpha2.SiteInfo{
+			CurrentSite: v1alpha2.SiteConnection{
+				BaseUrl:  ts.URL + "/",
+				Username: "admin",
+				Password: "",
+			},
+		},
+	})
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			CatalogProperty: "web-base",
+			ImagesProperty:  `[{"name": "nginx", "newName": "registry.local/nginx"}]`,
+		},
+	}
+	deployment, step := conformance.Deployment("web-system", "web", "update", component)
+	ret, err := provider.Apply(context.Background(), deployment, step, false)
+	require.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, ret["web"].Status)
+	obj, err := dynamicClient.Tracker().Get(deploymentGVR, "web-system", "web")
+	require.Nil(t, err)
+	containers, _, _ := unstructured.NestedSlice(obj.(*unstructured.Unstructured).Object, "spec", "template", "spec", "containers")
+	require.Equal(t, 1, len(containers))
+	assert.Equal(t, "registry.local/nginx:1.25", containers[0].(map[string]interface{})["image"])
+
+	// a missing catalog fails the deployment
+	component.Properties[CatalogProperty] = "other"
+	deployment, step = conformance.Deployment("web-system", "web", "update", component)
+	ret, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, ret["web"].Status)
+}
+
+func baseFiles() map[string]interface{} {
+	return map[string]interface{}{
+		"kustomization.yaml": baseKustomization,
+		"deployment.yaml":    baseDeployment,
+		"service.yaml":       baseService,
+	}
+}
+
+func kustomizeComponent(files map[string]interface{}) model.ComponentSpec {
+	return model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			FilesProperty: files,
+		},
+	}
+}
+
+// newFakeKustomizeProvider creates a provider with fake clients. Server-side apply requests create or replace objects.
+func newFakeKustomizeProvider() (*KustomizeTargetProvider, *dfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dynamicClient.Tracker()
+		_, err := tracker.Get(action.GetResource(), action.GetNamespace(), patch.GetName())
+		if kerrors.IsNotFound(err) {
+			return true, obj, tracker.Create(action.GetResource(), obj, action.GetNamespace())
+		} else if err != nil {
+			return true, nil, err
+		}
+		return true, obj, tracker.Update(action.GetResource(), obj, action.GetNamespace())
+	})
+
+	provider := &KustomizeTargetProvider{
+		Kubectl: &kubectl.KubectlTargetProvider{
+			Client:        kfake.NewSimpleClientset(),
+			DynamicClient: dynamicClient,
+			Mapper:        mapper,
+		},
+	}
+	return provider, dynamicClient
+}
+
+// Conformance: you should call the conformance suite to ensure provider conformance
+func TestConformanceSuite(t *testing.T) {
+	provider, _ := newFakeKustomizeProvider()
+	conformance.ConformanceSuite(t, provider)
+}
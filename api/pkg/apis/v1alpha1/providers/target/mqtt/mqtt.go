@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,8 +37,18 @@ type MQTTTargetProviderConfig struct {
 	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`
 	KeepAliveSeconds   int    `json:"keepAliveSeconds,omitempty"`
 	PingTimeoutSeconds int    `json:"pingTimeoutSeconds,omitempty"`
+	// QoS is used for the request publications and the response subscription
+	QoS byte `json:"qos,omitempty"`
 }
 
+const (
+	// callContextMetadata tells the kind of call a response is for. It's echoed by responders that
+	// predate request IDs.
+	callContextMetadata = "call-context"
+	// requestIDMetadata correlates a response with its request. Responders copy it from the request.
+	requestIDMetadata = "request-id"
+)
+
 var lock sync.Mutex
 
 type ProxyResponse struct {
@@ -46,15 +57,22 @@ type ProxyResponse struct {
 	Payload interface{}
 }
 type MQTTTargetProvider struct {
-	Config          MQTTTargetProviderConfig
-	Context         *contexts.ManagerContext
-	MQTTClient      gmqtt.Client
-	GetChan         chan ProxyResponse
-	RemoveChan      chan ProxyResponse
-	NeedsUpdateChan chan ProxyResponse
-	NeedsRemoveChan chan ProxyResponse
-	ApplyChan       chan ProxyResponse
-	Initialized     bool
+	Config      MQTTTargetProviderConfig
+	Context     *contexts.ManagerContext
+	MQTTClient  gmqtt.Client
+	Initialized bool
+
+	pendingLock     sync.Mutex
+	pending         map[string]*pendingRequest
+	pendingSequence uint64
+}
+
+// pendingRequest is a request that waits for its response. Its response channel is buffered, so
+// delivering a response never blocks the subscriber.
+type pendingRequest struct {
+	callContext string
+	sequence    uint64
+	response    chan ProxyResponse
 }
 
 func MQTTTargetProviderConfigFromMap(properties map[string]string) (MQTTTargetProviderConfig, error) {
@@ -109,6 +127,13 @@ func MQTTTargetProviderConfigFromMap(properties map[string]string) (MQTTTargetPr
 	} else {
 		ret.PingTimeoutSeconds = 1
 	}
+	if v, ok := properties["qos"]; ok {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 && num <= 2 {
+			ret.QoS = byte(num)
+		} else {
+			return ret, v1alpha2.NewCOAError(nil, "'qos' is not 0, 1 or 2 in MQTT provider config", v1alpha2.BadConfig)
+		}
+	}
 	return ret, nil
 }
 
@@ -144,6 +169,10 @@ func (i *MQTTTargetProvider) Init(config providers.IProviderConfig) error {
 		sLog.Errorf("  P (MQTT Target): expected HttpTargetProviderConfig: %+v", err)
 		return err
 	}
+	if updateConfig.QoS > 2 {
+		err = v1alpha2.NewCOAError(nil, "'qos' is not 0, 1 or 2 in MQTT provider config", v1alpha2.BadConfig)
+		return err
+	}
 	i.Config = updateConfig
 	id := uuid.New()
 	opts := gmqtt.NewClientOptions().AddBroker(i.Config.BrokerAddress).SetClientID(id.String())
@@ -155,43 +184,10 @@ func (i *MQTTTargetProvider) Init(config providers.IProviderConfig) error {
 		sLog.Errorf("  P (MQTT Target): faild to connect to MQTT broker - %+v", err)
 		return v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
 	}
+	i.pending = make(map[string]*pendingRequest)
 
-	i.GetChan = make(chan ProxyResponse)
-	i.RemoveChan = make(chan ProxyResponse)
-	i.NeedsUpdateChan = make(chan ProxyResponse)
-	i.NeedsRemoveChan = make(chan ProxyResponse)
-	i.ApplyChan = make(chan ProxyResponse)
-
-	if token := i.MQTTClient.Subscribe(i.Config.ResponseTopic, 0, func(client gmqtt.Client, msg gmqtt.Message) {
-		var response v1alpha2.COAResponse
-		json.Unmarshal(msg.Payload(), &response)
-		proxyResponse := ProxyResponse{
-			IsOK:  response.State == v1alpha2.OK || response.State == v1alpha2.Accepted,
-			State: response.State,
-		}
-		if !proxyResponse.IsOK {
-			proxyResponse.Payload = string(response.Body)
-		}
-		switch response.Metadata["call-context"] {
-		case "TargetProvider-Get":
-			if proxyResponse.IsOK {
-				var ret []model.ComponentSpec
-				err = json.Unmarshal(response.Body, &ret)
-				if err != nil {
-					sLog.Errorf("  P (MQTT Target): faild to deserialize components from MQTT - %+v, %s", err.Error(), string(response.Body))
-				}
-				proxyResponse.Payload = ret
-			}
-			i.GetChan <- proxyResponse
-		case "TargetProvider-Remove":
-			i.RemoveChan <- proxyResponse
-		case "TargetProvider-NeedsUpdate":
-			i.NeedsUpdateChan <- proxyResponse
-		case "TargetProvider-NeedsRemove":
-			i.NeedsRemoveChan <- proxyResponse
-		case "TargetProvider-Apply":
-			i.ApplyChan <- proxyResponse
-		}
+	if token := i.MQTTClient.Subscribe(i.Config.ResponseTopic, i.Config.QoS, func(client gmqtt.Client, msg gmqtt.Message) {
+		i.handleResponse(msg.Payload())
 	}); token.Wait() && token.Error() != nil {
 		if token.Error().Error() != "subscription exists" {
 			sLog.Errorf("  P (MQTT Target): faild to connect to subscribe to the response topic - %+v", token.Error())
@@ -202,6 +198,114 @@ func (i *MQTTTargetProvider) Init(config providers.IProviderConfig) error {
 	i.Initialized = true
 	return nil
 }
+
+// handleResponse delivers a response to the request it correlates with. Responses to requests that
+// have timed out, or that belong to another provider sharing the response topic, are dropped.
+func (i *MQTTTargetProvider) handleResponse(payload []byte) {
+	var response v1alpha2.COAResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		sLog.Errorf("  P (MQTT Target): failed to deserialize response from MQTT - %+v", err)
+		return
+	}
+	request := i.takePendingRequest(response.Metadata)
+	if request == nil {
+		sLog.Infof("  P (MQTT Target): dropping response without a pending request - %s: %s", response.Metadata[requestIDMetadata], response.Metadata[callContextMetadata])
+		return
+	}
+	proxyResponse := ProxyResponse{
+		IsOK:  response.State == v1alpha2.OK || response.State == v1alpha2.Accepted,
+		State: response.State,
+	}
+	if proxyResponse.IsOK {
+		proxyResponse.Payload = response.Body
+	} else {
+		proxyResponse.Payload = string(response.Body)
+	}
+	request.response <- proxyResponse
+}
+
+// takePendingRequest removes the request a response correlates with from the pending requests. A
+// response without a request ID goes to the oldest pending request with the same call context.
+func (i *MQTTTargetProvider) takePendingRequest(metadata map[string]string) *pendingRequest {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	if id, ok := metadata[requestIDMetadata]; ok {
+		request := i.pending[id]
+		delete(i.pending, id)
+		return request
+	}
+	var oldestID string
+	var oldest *pendingRequest
+	for id, request := range i.pending {
+		if request.callContext == metadata[callContextMetadata] && (oldest == nil || request.sequence < oldest.sequence) {
+			oldestID = id
+			oldest = request
+		}
+	}
+	if oldest != nil {
+		delete(i.pending, oldestID)
+	}
+	return oldest
+}
+
+func (i *MQTTTargetProvider) addPendingRequest(id string, callContext string) *pendingRequest {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	i.pendingSequence++
+	request := &pendingRequest{
+		callContext: callContext,
+		sequence:    i.pendingSequence,
+		response:    make(chan ProxyResponse, 1),
+	}
+	i.pending[id] = request
+	return request
+}
+
+func (i *MQTTTargetProvider) removePendingRequest(id string) {
+	i.pendingLock.Lock()
+	defer i.pendingLock.Unlock()
+	delete(i.pending, id)
+}
+
+// call publishes a request with a new request ID and waits for its response until the configured
+// timeout, or until the context is done
+func (i *MQTTTargetProvider) call(ctx context.Context, method string, callContext string, body []byte) (ProxyResponse, error) {
+	id := uuid.New().String()
+	pending := i.addPendingRequest(id, callContext)
+	defer i.removePendingRequest(id)
+
+	request := v1alpha2.COARequest{
+		Route:  "instances",
+		Method: method,
+		Body:   body,
+		Metadata: map[string]string{
+			callContextMetadata: callContext,
+			requestIDMetadata:   id,
+		},
+	}
+	data, _ := json.Marshal(request)
+	// requests are never retained, as the broker would replay the last one to every responder that
+	// (re)connects, and an old Apply or Remove would run again
+	if token := i.MQTTClient.Publish(i.Config.RequestTopic, i.Config.QoS, false, data); token.Wait() && token.Error() != nil {
+		return ProxyResponse{}, v1alpha2.NewCOAError(token.Error(), "failed to publish request to MQTT broker", v1alpha2.InternalError)
+	}
+
+	call := strings.TrimPrefix(callContext, "TargetProvider-")
+	timer := time.NewTimer(time.Duration(i.Config.TimeoutSeconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case resp := <-pending.response:
+		if !resp.IsOK {
+			return resp, v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
+		}
+		return resp, nil
+	case <-timer.C:
+		return ProxyResponse{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("didn't get response to %s() call over MQTT", call), v1alpha2.InternalError)
+	case <-ctx.Done():
+		return ProxyResponse{}, v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("%s() call over MQTT is cancelled", call), v1alpha2.InternalError)
+	}
+}
+
 func toMQTTTargetProviderConfig(config providers.IProviderConfig) (MQTTTargetProviderConfig, error) {
 	ret := MQTTTargetProviderConfig{}
 	data, err := json.Marshal(config)
@@ -221,49 +325,24 @@ func (i *MQTTTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 	sLog.Infof("  P (MQTT Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
 	data, _ := json.Marshal(deployment)
-	request := v1alpha2.COARequest{
-		Route:  "instances",
-		Method: "GET",
-		Body:   data,
-		Metadata: map[string]string{
-			"call-context": "TargetProvider-Get",
-		},
-	}
-	data, _ = json.Marshal(request)
-
-	if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-		sLog.Infof("  P (MQTT Target): failed to getting artifacts - %s", token.Error())
-		err = token.Error()
+	var resp ProxyResponse
+	resp, err = i.call(ctx, "GET", "TargetProvider-Get", data)
+	if err != nil {
+		sLog.Infof("  P (MQTT Target): failed to get artifacts - %s", err.Error())
 		return nil, err
 	}
-
-	timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-	select {
-	case resp := <-i.GetChan:
-		if resp.IsOK {
-			var data []byte
-			data, err = json.Marshal(resp.Payload)
-			if err != nil {
-				sLog.Infof("  P (MQTT Target): failed to serialize payload - %s - %s", err.Error(), fmt.Sprint(resp.Payload))
-				err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
-				return nil, err
-			}
-			var ret []model.ComponentSpec
-			err = json.Unmarshal(data, &ret)
-			if err != nil {
-				sLog.Infof("  P (MQTT Target): failed to deserialize components - %s - %s", err.Error(), fmt.Sprint(data))
-				err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
-				return nil, err
-			}
-			return ret, nil
-		} else {
-			err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-			return nil, err
-		}
-	case <-timeout:
-		err = v1alpha2.NewCOAError(nil, "didn't get response to Get() call over MQTT", v1alpha2.InternalError)
+	var ret []model.ComponentSpec
+	body := resp.Payload.([]byte)
+	if len(body) == 0 {
+		return ret, nil
+	}
+	err = json.Unmarshal(body, &ret)
+	if err != nil {
+		sLog.Infof("  P (MQTT Target): failed to deserialize components - %s - %s", err.Error(), string(body))
+		err = v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.InternalError)
 		return nil, err
 	}
+	return ret, nil
 }
 func (i *MQTTTargetProvider) Remove(ctx context.Context, deployment model.DeploymentSpec, currentRef []model.ComponentSpec) error {
 	_, span := observability.StartSpan("MQTT Target Provider", ctx, &map[string]string{
@@ -275,35 +354,8 @@ func (i *MQTTTargetProvider) Remove(ctx context.Context, deployment model.Deploy
 	sLog.Infof("  P (MQTT Target): deleting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
 
 	data, _ := json.Marshal(deployment)
-	request := v1alpha2.COARequest{
-		Route:  "instances",
-		Method: "DELETE",
-		Body:   data,
-		Metadata: map[string]string{
-			"call-context": "TargetProvider-Remove",
-		},
-	}
-	data, _ = json.Marshal(request)
-
-	if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-		err = token.Error()
-		return err
-	}
-
-	timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-	select {
-	case resp := <-i.RemoveChan:
-		if resp.IsOK {
-			err = nil
-			return err
-		} else {
-			err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-			return err
-		}
-	case <-timeout:
-		err = v1alpha2.NewCOAError(nil, "didn't get response to Remove() call over MQTT", v1alpha2.InternalError)
-		return err
-	}
+	_, err = i.call(ctx, "DELETE", "TargetProvider-Remove", data)
+	return err
 }
 
 func (i *MQTTTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
@@ -330,68 +382,13 @@ func (i *MQTTTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 
 	components = step.GetUpdatedComponents()
 	if len(components) > 0 {
-
-		request := v1alpha2.COARequest{
-			Route:  "instances",
-			Method: "POST",
-			Body:   data,
-			Metadata: map[string]string{
-				"call-context": "TargetProvider-Apply",
-			},
-		}
-		data, _ = json.Marshal(request)
-
-		if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-			err = token.Error()
-			return ret, err
-		}
-
-		timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-		select {
-		case resp := <-i.ApplyChan:
-			if resp.IsOK {
-				err = nil
-				return ret, err
-			} else {
-				err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-				return ret, err
-			}
-		case <-timeout:
-			err = v1alpha2.NewCOAError(nil, "didn't get response to Apply() call over MQTT", v1alpha2.InternalError)
-			return ret, err
-		}
+		_, err = i.call(ctx, "POST", "TargetProvider-Apply", data)
+		return ret, err
 	}
 	components = step.GetDeletedComponents()
 	if len(components) > 0 {
-		request := v1alpha2.COARequest{
-			Route:  "instances",
-			Method: "DELETE",
-			Body:   data,
-			Metadata: map[string]string{
-				"call-context": "TargetProvider-Remove",
-			},
-		}
-		data, _ = json.Marshal(request)
-
-		if token := i.MQTTClient.Publish(i.Config.RequestTopic, 0, false, data); token.Wait() && token.Error() != nil {
-			err = token.Error()
-			return ret, err
-		}
-
-		timeout := time.After(time.Duration(i.Config.TimeoutSeconds) * time.Second)
-		select {
-		case resp := <-i.RemoveChan:
-			if resp.IsOK {
-				err = nil
-				return ret, err
-			} else {
-				err = v1alpha2.NewCOAError(nil, fmt.Sprint(resp.Payload), resp.State)
-				return ret, err
-			}
-		case <-timeout:
-			err = v1alpha2.NewCOAError(nil, "didn't get response to Remove() call over MQTT", v1alpha2.InternalError)
-			return ret, err
-		}
+		_, err = i.call(ctx, "DELETE", "TargetProvider-Remove", data)
+		return ret, err
 	}
 	//TODO: Should we remove empty namespaces?
 	err = nil
@@ -9,14 +9,19 @@ package mqtt
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	mqttbinding "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/mqtt"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -480,10 +485,418 @@ func TestInitFailed(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestInitWithMapQoS(t *testing.T) {
+	configMap := map[string]string{
+		"brokerAddress": "tcp://127.0.0.1:1883",
+		"clientID":      "coa-test2",
+		"requestTopic":  "coa-request",
+		"responseTopic": "coa-response",
+		"qos":           "2",
+	}
+	config, err := MQTTTargetProviderConfigFromMap(configMap)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(2), config.QoS)
+
+	configMap["qos"] = "3"
+	_, err = MQTTTargetProviderConfigFromMap(configMap)
+	assert.NotNil(t, err)
+}
+
+func testConfig(broker *testBroker) MQTTTargetProviderConfig {
+	return MQTTTargetProviderConfig{
+		Name:           "me",
+		BrokerAddress:  broker.address(),
+		ClientID:       "coa-test2",
+		RequestTopic:   "coa-request",
+		ResponseTopic:  "coa-response",
+		TimeoutSeconds: 5,
+	}
+}
+
+// startResponder passes the requests published by the provider to handler, which answers them through
+// respond. Handlers can hold back or reorder their responses.
+func startResponder(t *testing.T, broker *testBroker, config MQTTTargetProviderConfig, handler func(request v1alpha2.COARequest, respond func(v1alpha2.COAResponse))) {
+	c := gmqtt.NewClient(gmqtt.NewClientOptions().AddBroker(broker.address()).SetClientID("test-responder"))
+	token := c.Connect()
+	token.Wait()
+	assert.Nil(t, token.Error())
+	token = c.Subscribe(config.RequestTopic, 1, func(client gmqtt.Client, msg gmqtt.Message) {
+		var request v1alpha2.COARequest
+		json.Unmarshal(msg.Payload(), &request)
+		handler(request, func(response v1alpha2.COAResponse) {
+			data, _ := json.Marshal(response)
+			client.Publish(config.ResponseTopic, 1, false, data).Wait()
+		})
+	})
+	token.Wait()
+	assert.Nil(t, token.Error())
+	t.Cleanup(func() { c.Disconnect(0) })
+}
+
+func componentsResponse(request v1alpha2.COARequest, metadataKeys ...string) v1alpha2.COAResponse {
+	var deployment model.DeploymentSpec
+	json.Unmarshal(request.Body, &deployment)
+	data, _ := json.Marshal([]model.ComponentSpec{{Name: deployment.Instance.Name}})
+	response := v1alpha2.COAResponse{
+		State:    v1alpha2.OK,
+		Body:     data,
+		Metadata: make(map[string]string),
+	}
+	for _, key := range metadataKeys {
+		response.Metadata[key] = request.Metadata[key]
+	}
+	return response
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	broker := newTestBroker(t)
+	config := testConfig(broker)
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+
+	// requests are answered in reverse order, once all of them have arrived
+	const count = 5
+	var lock sync.Mutex
+	held := make([]func(), 0, count)
+	startResponder(t, broker, config, func(request v1alpha2.COARequest, respond func(v1alpha2.COAResponse)) {
+		lock.Lock()
+		held = append(held, func() {
+			respond(componentsResponse(request, callContextMetadata, requestIDMetadata))
+		})
+		release := len(held) == count
+		lock.Unlock()
+		if release {
+			for i := count - 1; i >= 0; i-- {
+				held[i]()
+			}
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: name}}, nil)
+			assert.Nil(t, err)
+			if assert.Equal(t, 1, len(components)) {
+				assert.Equal(t, name, components[0].Name)
+			}
+		}(fmt.Sprintf("instance-%d", i))
+	}
+	wg.Wait()
+	assert.Equal(t, 0, len(provider.pending))
+}
+
+func TestLateResponse(t *testing.T) {
+	broker := newTestBroker(t)
+	config := testConfig(broker)
+	config.TimeoutSeconds = 1
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+
+	late := make(chan func(), 1)
+	startResponder(t, broker, config, func(request v1alpha2.COARequest, respond func(v1alpha2.COAResponse)) {
+		var deployment model.DeploymentSpec
+		json.Unmarshal(request.Body, &deployment)
+		if deployment.Instance.Name == "late" {
+			late <- func() {
+				respond(componentsResponse(request, callContextMetadata, requestIDMetadata))
+			}
+			return
+		}
+		respond(componentsResponse(request, callContextMetadata, requestIDMetadata))
+	})
+
+	_, err = provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: "late"}}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "didn't get response to Get() call over MQTT", err.Error())
+	assert.Equal(t, 0, len(provider.pending))
+
+	// the late response is dropped, and doesn't block the responses that follow
+	(<-late)()
+	for i := 0; i < 2; i++ {
+		components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: "on-time"}}, nil)
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(components)) {
+			assert.Equal(t, "on-time", components[0].Name)
+		}
+	}
+}
+
+func TestCancelledCall(t *testing.T) {
+	broker := newTestBroker(t)
+	config := testConfig(broker)
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	startResponder(t, broker, config, func(request v1alpha2.COARequest, respond func(v1alpha2.COAResponse)) {
+		cancel()
+	})
+	err = provider.Remove(ctx, model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(provider.pending))
+}
+
+func TestResponderWithoutRequestID(t *testing.T) {
+	broker := newTestBroker(t)
+	config := testConfig(broker)
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+
+	startResponder(t, broker, config, func(request v1alpha2.COARequest, respond func(v1alpha2.COAResponse)) {
+		if request.Method == "GET" {
+			respond(componentsResponse(request, callContextMetadata))
+		} else {
+			respond(v1alpha2.COAResponse{
+				State:    v1alpha2.InternalError,
+				Body:     []byte("BAD!!"),
+				Metadata: map[string]string{callContextMetadata: request.Metadata[callContextMetadata]},
+			})
+		}
+	})
+
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1", components[0].Name)
+
+	err = provider.Remove(context.Background(), model.DeploymentSpec{}, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "BAD!!", err.Error())
+}
+
+func TestQoSWithBinding(t *testing.T) {
+	broker := newTestBroker(t)
+	config := testConfig(broker)
+	config.QoS = 1
+	provider := MQTTTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+
+	binding := mqttbinding.MQTTBinding{}
+	err = binding.Launch(mqttbinding.MQTTBindingConfig{
+		BrokerAddress: broker.address(),
+		ClientID:      "test-binding",
+		RequestTopic:  config.RequestTopic,
+		ResponseTopic: config.ResponseTopic,
+		QoS:           1,
+	}, []v1alpha2.Endpoint{
+		{
+			Methods: []string{"GET", "POST", "DELETE"},
+			Route:   "instances",
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				if request.Method == "GET" {
+					return componentsResponse(request)
+				}
+				return v1alpha2.COAResponse{State: v1alpha2.OK}
+			},
+		},
+	}, nil)
+	assert.Nil(t, err)
+	defer binding.MQTTClient.Disconnect(0)
+
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{Instance: model.InstanceSpec{Name: "instance1"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1", components[0].Name)
+
+	_, err = provider.Apply(context.Background(), model.DeploymentSpec{}, model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "component1"}}},
+	}, false)
+	assert.Nil(t, err)
+
+	requests := broker.publications(config.RequestTopic)
+	assert.Equal(t, 2, len(requests))
+	for _, request := range requests {
+		assert.Equal(t, byte(1), request.Qos)
+		// a retained request would be replayed to responders that connect later
+		assert.False(t, request.Retain)
+	}
+}
+
 // Conformance: you should call the conformance suite to ensure provider conformance
 func TestConformanceSuite(t *testing.T) {
+	broker := newTestBroker(t)
 	provider := &MQTTTargetProvider{}
-	_ = provider.Init(MQTTTargetProviderConfig{})
-	// assert.Nil(t, err) okay if provider is not fully initialized
+	err := provider.Init(testConfig(broker))
+	assert.Nil(t, err)
 	conformance.ConformanceSuite(t, provider)
 }
+
+// testBroker is an in-process MQTT broker stand-in. It supports what the provider and the responders
+// in these tests use: exact topic subscriptions, QoS 0 to 2 and retained messages.
+type testBroker struct {
+	listener  net.Listener
+	lock      sync.Mutex
+	clients   map[*testBrokerClient]bool
+	retained  map[string]*packets.PublishPacket
+	published []*packets.PublishPacket
+	messageID uint16
+}
+
+type testBrokerClient struct {
+	conn      net.Conn
+	writeLock sync.Mutex
+	topics    map[string]byte
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	broker := &testBroker{
+		listener: listener,
+		clients:  make(map[*testBrokerClient]bool),
+		retained: make(map[string]*packets.PublishPacket),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	t.Cleanup(broker.close)
+	return broker
+}
+
+func (b *testBroker) address() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *testBroker) close() {
+	b.listener.Close()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for c := range b.clients {
+		c.conn.Close()
+	}
+}
+
+// publications returns the messages published to a topic
+func (b *testBroker) publications(topic string) []*packets.PublishPacket {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ret := make([]*packets.PublishPacket, 0)
+	for _, p := range b.published {
+		if p.TopicName == topic {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	client := &testBrokerClient{conn: conn, topics: make(map[string]byte)}
+	b.lock.Lock()
+	b.clients[client] = true
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		delete(b.clients, client)
+		b.lock.Unlock()
+		conn.Close()
+	}()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			client.write(packets.NewControlPacket(packets.Connack))
+		case *packets.SubscribePacket:
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = p.Qoss
+			b.lock.Lock()
+			for i, topic := range p.Topics {
+				client.topics[topic] = p.Qoss[i]
+			}
+			b.lock.Unlock()
+			client.write(suback)
+			for i, topic := range p.Topics {
+				b.lock.Lock()
+				retained := b.retained[topic]
+				b.lock.Unlock()
+				if retained != nil {
+					b.deliver(client, retained, p.Qoss[i])
+				}
+			}
+		case *packets.PublishPacket:
+			switch p.Qos {
+			case 1:
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				client.write(puback)
+			case 2:
+				pubrec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				pubrec.MessageID = p.MessageID
+				client.write(pubrec)
+			}
+			b.publish(p)
+		case *packets.PubrecPacket:
+			pubrel := packets.NewControlPacket(packets.Pubrel).(*packets.PubrelPacket)
+			pubrel.MessageID = p.MessageID
+			client.write(pubrel)
+		case *packets.PubrelPacket:
+			pubcomp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			pubcomp.MessageID = p.MessageID
+			client.write(pubcomp)
+		case *packets.UnsubscribePacket:
+			unsuback := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+			unsuback.MessageID = p.MessageID
+			client.write(unsuback)
+		case *packets.PingreqPacket:
+			client.write(packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *testBroker) publish(p *packets.PublishPacket) {
+	b.lock.Lock()
+	b.published = append(b.published, p)
+	if p.Retain {
+		b.retained[p.TopicName] = p
+	}
+	subscribers := make(map[*testBrokerClient]byte)
+	for c := range b.clients {
+		if qos, ok := c.topics[p.TopicName]; ok {
+			subscribers[c] = qos
+		}
+	}
+	b.lock.Unlock()
+	for c, qos := range subscribers {
+		b.deliver(c, p, qos)
+	}
+}
+
+func (b *testBroker) deliver(c *testBrokerClient, p *packets.PublishPacket, qos byte) {
+	message := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	message.TopicName = p.TopicName
+	message.Payload = p.Payload
+	message.Qos = p.Qos
+	if qos < message.Qos {
+		message.Qos = qos
+	}
+	if message.Qos > 0 {
+		b.lock.Lock()
+		b.messageID++
+		message.MessageID = b.messageID
+		b.lock.Unlock()
+	}
+	c.write(message)
+}
+
+func (c *testBrokerClient) write(packet packets.ControlPacket) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	packet.Write(c.conn)
+}
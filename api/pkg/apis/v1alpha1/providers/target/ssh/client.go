@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// client is a connection to a device, with an SFTP session to upload files over
+type client struct {
+	conn *ssh.Client
+	sftp *sftp.Client
+	// workingDirectory is the absolute path of the working directory on the device
+	workingDirectory string
+}
+
+// commandResult is the outcome of a remote command
+type commandResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// connect opens a connection to the device, authenticating with the private key read from the secret
+// provider
+func (i *SshTargetProvider) connect(ctx context.Context) (*client, error) {
+	if i.SecretProvider == nil {
+		return nil, v1alpha2.NewCOAError(nil, "ssh provider needs a secret provider to read the private key from", v1alpha2.MissingConfig)
+	}
+	key, err := i.SecretProvider.Get(i.Config.PrivateKeySecret, i.Config.PrivateKeyField)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read private key from secret '%s'", i.Config.PrivateKeySecret), v1alpha2.MissingConfig)
+	}
+	var signer ssh.Signer
+	if i.Config.PassphraseField != "" {
+		var passphrase string
+		passphrase, err = i.SecretProvider.Get(i.Config.PrivateKeySecret, i.Config.PassphraseField)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read passphrase from secret '%s'", i.Config.PrivateKeySecret), v1alpha2.MissingConfig)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(key))
+	}
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("secret '%s' doesn't hold a valid private key", i.Config.PrivateKeySecret), v1alpha2.BadConfig)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !i.Config.InsecureIgnoreHostKey {
+		var hostKey ssh.PublicKey
+		hostKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(i.Config.HostKey))
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "invalid host key", v1alpha2.BadConfig)
+		}
+		hostKeyCallback = ssh.FixedHostKey(hostKey)
+	}
+
+	timeout, _ := utils.ParsePositiveDuration(i.Config.ConnectionTimeout, defaultConnectionTimeout)
+	address := net.JoinHostPort(i.Config.Host, strconv.Itoa(i.Config.Port))
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to connect to %s", address), v1alpha2.InternalError)
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            i.Config.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to open an ssh connection to %s", address), v1alpha2.Unauthorized)
+	}
+	ret := &client{conn: ssh.NewClient(sshConn, channels, requests)}
+	ret.sftp, err = sftp.NewClient(ret.conn)
+	if err != nil {
+		ret.conn.Close()
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to open an sftp session to %s", address), v1alpha2.InternalError)
+	}
+
+	// relative paths are relative to the directory the sftp server starts in, usually the home directory
+	ret.workingDirectory = i.Config.WorkingDirectory
+	if !path.IsAbs(ret.workingDirectory) {
+		var wd string
+		wd, err = ret.sftp.Getwd()
+		if err != nil {
+			ret.Close()
+			return nil, err
+		}
+		ret.workingDirectory = path.Join(wd, ret.workingDirectory)
+	}
+	return ret, nil
+}
+
+func (c *client) Close() error {
+	c.sftp.Close()
+	return c.conn.Close()
+}
+
+// run runs a command through the device's shell, feeding it stdin. A command that doesn't complete
+// before the context is done is killed.
+func (c *client) run(ctx context.Context, command string, stdin []byte) (commandResult, error) {
+	ret := commandResult{}
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return ret, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = bytes.NewReader(stdin)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// not every server supports signals, closing the session ends the command in any case
+		_ = session.Signal(ssh.SIGKILL)
+		session.Close()
+		return ret, v1alpha2.NewCOAError(ctx.Err(), "command timed out", v1alpha2.InternalError)
+	}
+	ret.stdout = stdout.String()
+	ret.stderr = stderr.String()
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			ret.exitCode = exitErr.ExitStatus()
+			return ret, nil
+		}
+		return ret, err
+	}
+	return ret, nil
+}
+
+// upload writes a file on the device through a temporary file, so that a file in use can be replaced
+func (c *client) upload(target string, data io.Reader, mode os.FileMode) error {
+	if err := c.sftp.MkdirAll(path.Dir(target)); err != nil {
+		return err
+	}
+	temp := path.Join(path.Dir(target), "."+path.Base(target)+".upload")
+	file, err := c.sftp.Create(temp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, data); err != nil {
+		file.Close()
+		c.sftp.Remove(temp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		c.sftp.Remove(temp)
+		return err
+	}
+	if err := c.sftp.Chmod(temp, mode); err != nil {
+		c.sftp.Remove(temp)
+		return err
+	}
+	if err := c.sftp.PosixRename(temp, target); err != nil {
+		// servers without the posix-rename extension can't rename over an existing file
+		c.sftp.Remove(target)
+		if err := c.sftp.Rename(temp, target); err != nil {
+			c.sftp.Remove(temp)
+			return err
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,706 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	ApplyCommandProperty  = "ssh.applyCommand"
+	ApplyScriptProperty   = "ssh.applyScript"
+	GetCommandProperty    = "ssh.getCommand"
+	GetScriptProperty     = "ssh.getScript"
+	RemoveCommandProperty = "ssh.removeCommand"
+	RemoveScriptProperty  = "ssh.removeScript"
+	TimeoutProperty       = "ssh.timeout"
+	ArtifactsProperty     = "ssh.artifacts"
+)
+
+const (
+	defaultPort              = 22
+	defaultPrivateKeyField   = "privateKey"
+	defaultWorkingDirectory  = ".symphony"
+	defaultConnectionTimeout = 30 * time.Second
+	defaultCommandTimeout    = 5 * time.Minute
+)
+
+var (
+	envNamePattern      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	invalidNamePattern  = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+	errNoCommandToRun   = errors.New("no command to run")
+	operationProperties = map[string][2]string{
+		"apply":  {ApplyCommandProperty, ApplyScriptProperty},
+		"get":    {GetCommandProperty, GetScriptProperty},
+		"remove": {RemoveCommandProperty, RemoveScriptProperty},
+	}
+)
+
+type SshTargetProviderConfig struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	// Port defaults to 22
+	Port int    `json:"port,omitempty"`
+	User string `json:"user"`
+	// PrivateKeySecret is the secret object the private key is read from, through the secret provider
+	PrivateKeySecret string `json:"privateKeySecret"`
+	// PrivateKeyField is the field of the secret that holds the private key. It defaults to privateKey.
+	PrivateKeyField string `json:"privateKeyField,omitempty"`
+	// PassphraseField is the field of the secret that holds the passphrase of an encrypted private key
+	PassphraseField string `json:"passphraseField,omitempty"`
+	// HostKey is the public key of the device, in authorized_keys format
+	HostKey string `json:"hostKey,omitempty"`
+	// InsecureIgnoreHostKey accepts any host key. It's meant for testing only.
+	InsecureIgnoreHostKey bool `json:"insecureIgnoreHostKey,omitempty"`
+	// WorkingDirectory is where scripts and artifacts are uploaded to, in a directory per component.
+	// A relative path is relative to the user's home directory. It defaults to .symphony.
+	WorkingDirectory string `json:"workingDirectory,omitempty"`
+	// ConnectionTimeout defaults to 30s
+	ConnectionTimeout string `json:"connectionTimeout,omitempty"`
+	// CommandTimeout is the timeout of commands of components that don't set ssh.timeout. It defaults to 5m.
+	CommandTimeout string `json:"commandTimeout,omitempty"`
+}
+
+type SshTargetProvider struct {
+	Config         SshTargetProviderConfig
+	Context        *contexts.ManagerContext
+	SecretProvider secret.ISecretProvider
+}
+
+// Artifact is a file uploaded to the device before the apply command runs
+type Artifact struct {
+	// Source is an HTTP(S) URL, or an absolute path on the machine Symphony runs on
+	Source string `json:"source"`
+	// Path is where the artifact is uploaded to. A relative path is relative to the directory of the component.
+	Path string `json:"path"`
+	// Mode is the octal file mode of the artifact. It defaults to 0644.
+	Mode   string `json:"mode,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+// commandOutput is the JSON object apply and remove commands can print as the last line of their output
+type commandOutput struct {
+	Status  json.RawMessage `json:"status"`
+	Message string          `json:"message"`
+}
+
+func SshTargetProviderConfigFromMap(properties map[string]string) (SshTargetProviderConfig, error) {
+	ret := SshTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["host"]; ok {
+		ret.Host = v
+	}
+	if v, ok := properties["port"]; ok && v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid ssh provider config, 'port' must be a number", v1alpha2.BadConfig)
+		}
+		ret.Port = port
+	}
+	if v, ok := properties["user"]; ok {
+		ret.User = v
+	}
+	if v, ok := properties["privateKeySecret"]; ok {
+		ret.PrivateKeySecret = v
+	}
+	if v, ok := properties["privateKeyField"]; ok {
+		ret.PrivateKeyField = v
+	}
+	if v, ok := properties["passphraseField"]; ok {
+		ret.PassphraseField = v
+	}
+	if v, ok := properties["hostKey"]; ok {
+		ret.HostKey = v
+	}
+	if v, ok := properties["insecureIgnoreHostKey"]; ok && v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid ssh provider config, 'insecureIgnoreHostKey' must be a boolean", v1alpha2.BadConfig)
+		}
+		ret.InsecureIgnoreHostKey = insecure
+	}
+	if v, ok := properties["workingDirectory"]; ok {
+		ret.WorkingDirectory = v
+	}
+	if v, ok := properties["connectionTimeout"]; ok {
+		ret.ConnectionTimeout = v
+	}
+	if v, ok := properties["commandTimeout"]; ok {
+		ret.CommandTimeout = v
+	}
+	return ret, nil
+}
+
+func (i *SshTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := SshTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (i *SshTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	i.Context = ctx
+}
+
+func (i *SshTargetProvider) SetSecretProvider(provider secret.ISecretProvider) {
+	i.SecretProvider = provider
+}
+
+func (i *SshTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("SSH Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (SSH Target): Init()")
+
+	updateConfig, err := toSshTargetProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (SSH Target): expected SshTargetProviderConfig: %+v", err)
+		err = v1alpha2.NewCOAError(err, "expected SshTargetProviderConfig", v1alpha2.BadConfig)
+		return err
+	}
+	if updateConfig.Host == "" || updateConfig.User == "" || updateConfig.PrivateKeySecret == "" {
+		err = v1alpha2.NewCOAError(nil, "invalid ssh provider config, expected 'host', 'user' and 'privateKeySecret'", v1alpha2.BadConfig)
+		return err
+	}
+	if updateConfig.HostKey == "" && !updateConfig.InsecureIgnoreHostKey {
+		err = v1alpha2.NewCOAError(nil, "invalid ssh provider config, expected 'hostKey'", v1alpha2.BadConfig)
+		return err
+	}
+	if updateConfig.Port == 0 {
+		updateConfig.Port = defaultPort
+	}
+	if updateConfig.PrivateKeyField == "" {
+		updateConfig.PrivateKeyField = defaultPrivateKeyField
+	}
+	if updateConfig.WorkingDirectory == "" {
+		updateConfig.WorkingDirectory = defaultWorkingDirectory
+	}
+	if _, err = utils.ParsePositiveDuration(updateConfig.ConnectionTimeout, defaultConnectionTimeout); err != nil {
+		err = v1alpha2.NewCOAError(err, "invalid ssh provider config, 'connectionTimeout' must be a duration", v1alpha2.BadConfig)
+		return err
+	}
+	if _, err = utils.ParsePositiveDuration(updateConfig.CommandTimeout, defaultCommandTimeout); err != nil {
+		err = v1alpha2.NewCOAError(err, "invalid ssh provider config, 'commandTimeout' must be a duration", v1alpha2.BadConfig)
+		return err
+	}
+	i.Config = updateConfig
+	return nil
+}
+
+func toSshTargetProviderConfig(config providers.IProviderConfig) (SshTargetProviderConfig, error) {
+	ret := SshTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// Get runs the get command of each component that has one. A command that prints nothing, or null, reports
+// the component as missing. Components without a get command aren't reported.
+func (i *SshTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("SSH Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (SSH Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	var c *client
+	for _, reference := range references {
+		if !hasOperation(reference.Component, "get") {
+			continue
+		}
+		if c == nil {
+			c, err = i.connect(ctx)
+			if err != nil {
+				sLog.Errorf("  P (SSH Target): failed to connect: %+v", err)
+				return nil, err
+			}
+			defer c.Close()
+		}
+		var output string
+		output, err = i.runOperation(ctx, c, deployment, reference.Component, "get", injections)
+		if err != nil {
+			sLog.Errorf("  P (SSH Target): failed to get %s: %+v", reference.Component.Name, err)
+			return nil, err
+		}
+		line := lastLine(output)
+		if line == "" || line == "null" {
+			continue
+		}
+		component := model.ComponentSpec{}
+		err = json.Unmarshal([]byte(line), &component)
+		if err != nil {
+			sLog.Errorf("  P (SSH Target): failed to parse get output of %s (expected ComponentSpec): %+v", reference.Component.Name, err)
+			err = v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse get output of %s (expected ComponentSpec)", reference.Component.Name), v1alpha2.InternalError)
+			return nil, err
+		}
+		if component.Name == "" {
+			component.Name = reference.Component.Name
+		}
+		if component.Type == "" {
+			component.Type = reference.Component.Type
+		}
+		// values that match the reference once values are injected are returned as in the reference
+		for k, v := range component.Properties {
+			if rv, ok := reference.Component.Properties[k]; ok {
+				if s, ok := v.(string); ok && model.ResolveString(fmt.Sprintf("%v", rv), injections) == s {
+					component.Properties[k] = rv
+				}
+			}
+		}
+		ret = append(ret, component)
+	}
+	return ret, nil
+}
+
+func (i *SshTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("SSH Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (SSH Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range components {
+		err = validateComponent(component)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if isDryRun {
+		err = nil
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+	if len(step.Components) == 0 {
+		return ret, nil
+	}
+
+	c, err := i.connect(ctx)
+	if err != nil {
+		sLog.Errorf("  P (SSH Target): failed to connect: %+v", err)
+		return ret, err
+	}
+	defer c.Close()
+
+	for _, component := range step.Components {
+		if component.Action == "update" {
+			var result model.ComponentResultSpec
+			result, err = i.deploy(ctx, c, deployment, component.Component, injections)
+			ret[component.Component.Name] = result
+			if err != nil {
+				sLog.Errorf("  P (SSH Target): failed to deploy %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+		} else {
+			var result model.ComponentResultSpec
+			result, err = i.remove(ctx, c, deployment, component.Component, injections)
+			ret[component.Component.Name] = result
+			if err != nil {
+				sLog.Errorf("  P (SSH Target): failed to remove %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (*SshTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		RequiredProperties: []string{},
+		OptionalProperties: []string{
+			ApplyCommandProperty,
+			ApplyScriptProperty,
+			GetCommandProperty,
+			GetScriptProperty,
+			RemoveCommandProperty,
+			RemoveScriptProperty,
+			TimeoutProperty,
+			ArtifactsProperty,
+			"env.*",
+		},
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ExclusiveProperties: [][]string{
+			{ApplyCommandProperty, ApplyScriptProperty},
+			{GetCommandProperty, GetScriptProperty},
+			{RemoveCommandProperty, RemoveScriptProperty},
+		},
+		DurationProperties: []string{TimeoutProperty},
+		// only the properties the get command reports are compared
+		ChangeDetectionProperties: []model.PropertyDesc{
+			{Name: "*", IgnoreCase: false, SkipIfMissing: true},
+		},
+		InstanceIsolation: true,
+	}
+}
+
+// deploy uploads the artifacts of a component and runs its apply command
+func (i *SshTargetProvider) deploy(ctx context.Context, c *client, deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) (model.ComponentResultSpec, error) {
+	artifacts, err := readArtifacts(component, injections)
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	directory := componentDirectory(c, deployment, component)
+	for _, artifact := range artifacts {
+		err = uploadArtifact(ctx, c, directory, artifact)
+		if err != nil {
+			return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+		}
+	}
+	output, err := i.runOperation(ctx, c, deployment, component, "apply", injections)
+	if errors.Is(err, errNoCommandToRun) {
+		return model.ComponentResultSpec{Status: v1alpha2.Updated, Message: ""}, nil
+	}
+	if err != nil {
+		return model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: err.Error()}, err
+	}
+	return parseResult(output, v1alpha2.Updated, v1alpha2.UpdateFailed)
+}
+
+// remove runs the remove command of a component, and removes its directory
+func (i *SshTargetProvider) remove(ctx context.Context, c *client, deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) (model.ComponentResultSpec, error) {
+	result := model.ComponentResultSpec{Status: v1alpha2.Deleted, Message: ""}
+	output, err := i.runOperation(ctx, c, deployment, component, "remove", injections)
+	if err != nil && !errors.Is(err, errNoCommandToRun) {
+		return model.ComponentResultSpec{Status: v1alpha2.DeleteFailed, Message: err.Error()}, err
+	}
+	if err == nil {
+		result, err = parseResult(output, v1alpha2.Deleted, v1alpha2.DeleteFailed)
+		if err != nil {
+			return result, err
+		}
+	}
+	directory := componentDirectory(c, deployment, component)
+	cleanup, err := c.run(ctx, "rm -rf "+shellQuote(directory), nil)
+	if err == nil && cleanup.exitCode != 0 {
+		err = fmt.Errorf("exit code %d: %s", cleanup.exitCode, strings.TrimSpace(cleanup.stderr))
+	}
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, fmt.Sprintf("failed to remove %s", directory), v1alpha2.InternalError)
+		return model.ComponentResultSpec{Status: v1alpha2.DeleteFailed, Message: err.Error()}, err
+	}
+	return result, nil
+}
+
+// runOperation runs the command or the script of an operation in the directory of the component, with
+// the component, its values injected, as JSON on stdin. It returns errNoCommandToRun for a component
+// without a command or a script for the operation.
+func (i *SshTargetProvider) runOperation(ctx context.Context, c *client, deployment model.DeploymentSpec, component model.ComponentSpec, operation string, injections *model.ValueInjections) (string, error) {
+	properties := operationProperties[operation]
+	command := model.ReadPropertyCompat(component.Properties, properties[0], injections)
+	script := model.ReadPropertyCompat(component.Properties, properties[1], injections)
+	if command == "" && script == "" {
+		return "", errNoCommandToRun
+	}
+
+	directory := componentDirectory(c, deployment, component)
+	if script != "" {
+		scriptPath := path.Join(directory, "."+operation)
+		if err := c.upload(scriptPath, strings.NewReader(script), 0700); err != nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to upload %s script", operation), v1alpha2.InternalError)
+		}
+		// a script without a shebang is run by sh
+		command = shellQuote(scriptPath)
+		if !strings.HasPrefix(script, "#!") {
+			command = "sh " + command
+		}
+	} else {
+		if err := c.sftp.MkdirAll(directory); err != nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to create %s", directory), v1alpha2.InternalError)
+		}
+		command = "sh -c " + shellQuote(command)
+	}
+
+	env, err := environment(deployment, component, directory, operation, injections)
+	if err != nil {
+		return "", err
+	}
+	stdin, err := json.Marshal(resolveComponent(component, injections))
+	if err != nil {
+		return "", err
+	}
+	timeout, _ := utils.ParsePositiveDuration(i.Config.CommandTimeout, defaultCommandTimeout)
+	if v := model.ReadPropertyCompat(component.Properties, TimeoutProperty, nil); v != "" {
+		timeout, err = utils.ParsePositiveDuration(v, timeout)
+		if err != nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("%s of component %s must be a duration", TimeoutProperty, component.Name), v1alpha2.BadRequest)
+		}
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sLog.Debugf("  P (SSH Target): running %s command of %s", operation, component.Name)
+	result, err := c.run(runCtx, fmt.Sprintf("cd %s && %s && %s", shellQuote(directory), env, command), stdin)
+	if err != nil {
+		if runCtx.Err() != nil && ctx.Err() == nil {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("%s command of %s timed out after %s", operation, component.Name, timeout), v1alpha2.InternalError)
+		}
+		return "", err
+	}
+	if result.exitCode != 0 {
+		message := strings.TrimSpace(result.stderr)
+		if message == "" {
+			message = lastLine(result.stdout)
+		}
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("%s command of %s exited with %d: %s", operation, component.Name, result.exitCode, message), v1alpha2.InternalError)
+	}
+	return result.stdout, nil
+}
+
+// environment builds the export statement of the variables commands run with
+func environment(deployment model.DeploymentSpec, component model.ComponentSpec, directory string, operation string, injections *model.ValueInjections) (string, error) {
+	variables := map[string]string{
+		"SYMPHONY_INSTANCE":  deployment.Instance.Name,
+		"SYMPHONY_SOLUTION":  deployment.Instance.Solution,
+		"SYMPHONY_TARGET":    deployment.ActiveTarget,
+		"SYMPHONY_COMPONENT": component.Name,
+		"SYMPHONY_OPERATION": operation,
+		"SYMPHONY_DIRECTORY": directory,
+	}
+	for k := range component.Properties {
+		if !strings.HasPrefix(k, "env.") {
+			continue
+		}
+		name := strings.TrimPrefix(k, "env.")
+		if !envNamePattern.MatchString(name) {
+			return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' isn't a valid environment variable name", name), v1alpha2.BadRequest)
+		}
+		variables[name] = model.ReadPropertyCompat(component.Properties, k, injections)
+	}
+	names := make([]string, 0, len(variables))
+	for k := range variables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	assignments := make([]string, 0, len(names))
+	for _, name := range names {
+		assignments = append(assignments, name+"="+shellQuote(variables[name]))
+	}
+	return "export " + strings.Join(assignments, " "), nil
+}
+
+// resolveComponent injects values into the string properties of a component
+func resolveComponent(component model.ComponentSpec, injections *model.ValueInjections) model.ComponentSpec {
+	ret := component
+	ret.Properties = make(map[string]interface{}, len(component.Properties))
+	for k, v := range component.Properties {
+		if s, ok := v.(string); ok {
+			ret.Properties[k] = model.ResolveString(s, injections)
+		} else {
+			ret.Properties[k] = v
+		}
+	}
+	return ret
+}
+
+// componentDirectory is the directory on the device the scripts and artifacts of a component are uploaded to
+func componentDirectory(c *client, deployment model.DeploymentSpec, component model.ComponentSpec) string {
+	return path.Join(c.workingDirectory, safeName(deployment.Instance.Name), safeName(component.Name))
+}
+
+func safeName(name string) string {
+	name = invalidNamePattern.ReplaceAllString(name, "-")
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+func hasOperation(component model.ComponentSpec, operation string) bool {
+	properties := operationProperties[operation]
+	return hasProperty(component, properties[0]) || hasProperty(component, properties[1])
+}
+
+func hasProperty(component model.ComponentSpec, name string) bool {
+	v, ok := component.Properties[name]
+	return ok && v != nil && fmt.Sprintf("%v", v) != ""
+}
+
+func validateComponent(component model.ComponentSpec) error {
+	if _, err := readArtifacts(component, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readArtifacts reads ssh.artifacts, given either as a list or as a JSON string
+func readArtifacts(component model.ComponentSpec, injections *model.ValueInjections) ([]Artifact, error) {
+	v, ok := component.Properties[ArtifactsProperty]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	var data []byte
+	if s, ok := v.(string); ok {
+		data = []byte(s)
+	} else {
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ret := make([]Artifact, 0)
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("%s of component %s must be a list of artifacts", ArtifactsProperty, component.Name), v1alpha2.BadRequest)
+	}
+	for idx, artifact := range ret {
+		artifact.Source = model.ResolveString(artifact.Source, injections)
+		artifact.Path = model.ResolveString(artifact.Path, injections)
+		if artifact.Source == "" || artifact.Path == "" {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifacts of component %s must have a source and a path", component.Name), v1alpha2.BadRequest)
+		}
+		if artifact.Mode != "" {
+			if _, err := strconv.ParseUint(artifact.Mode, 8, 32); err != nil {
+				return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("mode of artifact '%s' must be an octal number", artifact.Path), v1alpha2.BadRequest)
+			}
+		}
+		ret[idx] = artifact
+	}
+	return ret, nil
+}
+
+// uploadArtifact downloads an artifact, verifies its checksum, and uploads it to the device
+func uploadArtifact(ctx context.Context, c *client, directory string, artifact Artifact) error {
+	data, err := utils.ReadSource(ctx, artifact.Source)
+	if err != nil {
+		return err
+	}
+	if err := utils.VerifySHA256(artifact.Source, data, artifact.Sha256); err != nil {
+		return err
+	}
+	mode := uint64(0644)
+	if artifact.Mode != "" {
+		mode, _ = strconv.ParseUint(artifact.Mode, 8, 32)
+	}
+	target := artifact.Path
+	if !path.IsAbs(target) {
+		target = path.Join(directory, target)
+	}
+	sLog.Debugf("  P (SSH Target): uploading %s to %s", artifact.Source, target)
+	if err := c.upload(target, bytes.NewReader(data), os.FileMode(mode)); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to upload '%s' to %s", artifact.Source, target), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+// parseResult reads the result a command prints as the last line of its output. A command that prints
+// something else succeeded.
+func parseResult(output string, success v1alpha2.State, failure v1alpha2.State) (model.ComponentResultSpec, error) {
+	line := lastLine(output)
+	if !strings.HasPrefix(line, "{") {
+		return model.ComponentResultSpec{Status: success, Message: ""}, nil
+	}
+	result := commandOutput{}
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to parse command output (expected ComponentResultSpec)", v1alpha2.InternalError)
+		return model.ComponentResultSpec{Status: failure, Message: err.Error()}, err
+	}
+	status := success
+	if len(result.Status) > 0 {
+		var err error
+		status, err = parseState(result.Status)
+		if err != nil {
+			return model.ComponentResultSpec{Status: failure, Message: err.Error()}, err
+		}
+	}
+	ret := model.ComponentResultSpec{Status: status, Message: result.Message}
+	if status == v1alpha2.UpdateFailed || status == v1alpha2.DeleteFailed {
+		return ret, v1alpha2.NewCOAError(nil, result.Message, v1alpha2.InternalError)
+	}
+	return ret, nil
+}
+
+var resultStates = []v1alpha2.State{
+	v1alpha2.Updated,
+	v1alpha2.UpdateFailed,
+	v1alpha2.Deleted,
+	v1alpha2.DeleteFailed,
+	v1alpha2.Untouched,
+}
+
+// parseState reads a state given as a number, or as a name such as "Updated" or "UpdateFailed"
+func parseState(data json.RawMessage) (v1alpha2.State, error) {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		for _, state := range resultStates {
+			if int(state) == number {
+				return state, nil
+			}
+		}
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		name = strings.ReplaceAll(strings.ToLower(name), " ", "")
+		for _, state := range resultStates {
+			if strings.ReplaceAll(strings.ToLower(state.String()), " ", "") == name {
+				return state, nil
+			}
+		}
+	}
+	return v1alpha2.InternalError, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s is not a valid component status", string(data)), v1alpha2.InternalError)
+}
+
+// lastLine is the last non-empty line of a command's output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,640 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSshTargetProviderConfigFromMap(t *testing.T) {
+	config, err := SshTargetProviderConfigFromMap(map[string]string{
+		"name":                  "name",
+		"host":                  "device1",
+		"port":                  "2222",
+		"user":                  "symphony",
+		"privateKeySecret":      "device-keys",
+		"privateKeyField":       "id_ed25519",
+		"passphraseField":       "passphrase",
+		"insecureIgnoreHostKey": "true",
+		"workingDirectory":      "/var/lib/symphony",
+		"connectionTimeout":     "10s",
+		"commandTimeout":        "1m",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, SshTargetProviderConfig{
+		Name:                  "name",
+		Host:                  "device1",
+		Port:                  2222,
+		User:                  "symphony",
+		PrivateKeySecret:      "device-keys",
+		PrivateKeyField:       "id_ed25519",
+		PassphraseField:       "passphrase",
+		InsecureIgnoreHostKey: true,
+		WorkingDirectory:      "/var/lib/symphony",
+		ConnectionTimeout:     "10s",
+		CommandTimeout:        "1m",
+	}, config)
+
+	for _, properties := range []map[string]string{
+		{"port": "ssh"},
+		{"insecureIgnoreHostKey": "maybe"},
+	} {
+		_, err = SshTargetProviderConfigFromMap(properties)
+		coaErr, ok := err.(v1alpha2.COAError)
+		assert.True(t, ok)
+		assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+	}
+}
+
+func TestInitWithMap(t *testing.T) {
+	provider := SshTargetProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"host":             "device1",
+		"user":             "symphony",
+		"privateKeySecret": "device-keys",
+		"hostKey":          "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIJSPPr0k8kBDUXBDjq8SBKkxTGzMHbKJvUIqw7B5GYgF",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 22, provider.Config.Port)
+	assert.Equal(t, "privateKey", provider.Config.PrivateKeyField)
+	assert.Equal(t, ".symphony", provider.Config.WorkingDirectory)
+
+	for _, properties := range []map[string]string{
+		{"user": "symphony", "privateKeySecret": "device-keys", "insecureIgnoreHostKey": "true"},
+		{"host": "device1", "user": "symphony", "insecureIgnoreHostKey": "true"},
+		{"host": "device1", "user": "symphony", "privateKeySecret": "device-keys"},
+		{"host": "device1", "user": "symphony", "privateKeySecret": "device-keys", "insecureIgnoreHostKey": "true", "commandTimeout": "forever"},
+		{"host": "device1", "user": "symphony", "privateKeySecret": "device-keys", "insecureIgnoreHostKey": "true", "connectionTimeout": "-1s"},
+	} {
+		err = provider.InitWithMap(properties)
+		coaErr, ok := err.(v1alpha2.COAError)
+		assert.True(t, ok, properties)
+		assert.Equal(t, v1alpha2.BadConfig, coaErr.State, properties)
+	}
+}
+
+func TestApplyCommand(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ApplyCommandProperty: `cat > component.json; echo "$SYMPHONY_INSTANCE $SYMPHONY_OPERATION $GREETING" > env.txt; echo installing; echo '{"status":"Updated","message":"installed ${{$instance()}}"}'`,
+			"env.GREETING":       "it's ${{$target()}}",
+			"version":            "${{$solution()}}-1",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.Updated, Message: "installed instance1"}, result["web"])
+
+	directory := filepath.Join(server.root, "instance1", "web")
+	data, err := os.ReadFile(filepath.Join(directory, "env.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1 apply it's target1\n", string(data))
+
+	// the component is passed on stdin, with values injected
+	data, err = os.ReadFile(filepath.Join(directory, "component.json"))
+	assert.Nil(t, err)
+	stdin := model.ComponentSpec{}
+	assert.Nil(t, json.Unmarshal(data, &stdin))
+	assert.Equal(t, "solution1-1", stdin.Properties["version"])
+}
+
+func TestApplyScriptAndArtifacts(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	binary := []byte("#!/bin/sh\necho agent\n")
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/agent" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(binary)
+	}))
+	defer files.Close()
+	local := filepath.Join(t.TempDir(), "agent.conf")
+	assert.Nil(t, os.WriteFile(local, []byte("level=debug"), 0600))
+	absolute := filepath.Join(server.root, "etc", "agent.conf")
+
+	component := model.ComponentSpec{
+		Name: "agent",
+		Properties: map[string]interface{}{
+			ArtifactsProperty: []interface{}{
+				map[string]interface{}{"source": files.URL + "/agent", "path": "bin/agent", "mode": "0755", "sha256": utils.SHA256(binary)},
+				map[string]interface{}{"source": local, "path": absolute},
+			},
+			ApplyScriptProperty: "#!/bin/sh\nset -e\n./bin/agent > out.txt\ncat " + absolute + " >> out.txt\n",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, result["agent"].Status)
+
+	directory := filepath.Join(server.root, "instance1", "agent")
+	data, err := os.ReadFile(filepath.Join(directory, "out.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "agent\nlevel=debug", string(data))
+	info, err := os.Stat(filepath.Join(directory, "bin", "agent"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	info, err = os.Stat(absolute)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// artifacts are replaced when the component is deployed again
+	binary = []byte("#!/bin/sh\necho agent 2\n")
+	component.Properties[ArtifactsProperty] = `[{"source": "` + files.URL + `/agent", "path": "bin/agent", "mode": "755"}]`
+	component.Properties[ApplyScriptProperty] = "./bin/agent > out.txt"
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err = os.ReadFile(filepath.Join(directory, "out.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "agent 2\n", string(data))
+}
+
+func TestApplyArtifactChecksumMismatch(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	local := filepath.Join(t.TempDir(), "agent")
+	assert.Nil(t, os.WriteFile(local, []byte("agent"), 0600))
+	component := model.ComponentSpec{
+		Name: "agent",
+		Properties: map[string]interface{}{
+			ArtifactsProperty:    []interface{}{map[string]interface{}{"source": local, "path": "agent", "sha256": utils.SHA256([]byte("other"))}},
+			ApplyCommandProperty: "touch applied",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["agent"].Status)
+	assert.Contains(t, result["agent"].Message, "checksum")
+	_, err = os.Stat(filepath.Join(server.root, "instance1", "agent", "applied"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyFailure(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ApplyCommandProperty: "echo 'no space left' >&2; exit 3",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["web"].Status)
+	assert.Contains(t, result["web"].Message, "exited with 3: no space left")
+
+	// a command can report a failure as its output
+	component.Properties[ApplyCommandProperty] = `echo '{"status": 8001, "message": "port in use"}'`
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	result, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.UpdateFailed, Message: "port in use"}, result["web"])
+}
+
+func TestApplyTimeout(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ApplyCommandProperty: "sleep 10",
+			TimeoutProperty:      "200ms",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	start := time.Now()
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["web"].Status)
+	assert.Contains(t, result["web"].Message, "timed out after 200ms")
+
+	// the provider's timeout applies to components that don't set one
+	provider.Config.CommandTimeout = "200ms"
+	delete(component.Properties, TimeoutProperty)
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestApplyInvalidComponents(t *testing.T) {
+	provider := &SshTargetProvider{}
+	for _, properties := range []map[string]interface{}{
+		{ApplyCommandProperty: "true", ApplyScriptProperty: "true"},
+		{GetCommandProperty: "true", GetScriptProperty: "true"},
+		{TimeoutProperty: "soon"},
+		{ArtifactsProperty: "agent"},
+		{ArtifactsProperty: []interface{}{map[string]interface{}{"source": "/tmp/agent"}}},
+		{ArtifactsProperty: []interface{}{map[string]interface{}{"source": "/tmp/agent", "path": "agent", "mode": "rwx"}}},
+	} {
+		deployment, step := conformance.Deployment("default", "instance1", "update", model.ComponentSpec{Name: "web", Properties: properties})
+		_, err := provider.Apply(context.Background(), deployment, step, true)
+		coaErr, ok := err.(v1alpha2.COAError)
+		assert.True(t, ok, properties)
+		assert.Equal(t, v1alpha2.BadRequest, coaErr.State, properties)
+	}
+}
+
+func TestGet(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+
+	components := []model.ComponentSpec{
+		{
+			Name: "web",
+			Type: "service",
+			Properties: map[string]interface{}{
+				GetCommandProperty: `cat > /dev/null; echo checking; echo '{"properties": {"version": "1.0", "site": "target1"}}'`,
+				"version":          "1.0",
+				"site":             "${{$target()}}",
+			},
+		},
+		{
+			Name: "missing",
+			Properties: map[string]interface{}{
+				GetScriptProperty: "if [ -f installed ]; then echo '{}'; fi",
+			},
+		},
+		{
+			Name: "unknown",
+			Properties: map[string]interface{}{
+				ApplyCommandProperty: "true",
+			},
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", components...)
+	ret, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ret))
+	assert.Equal(t, "web", ret[0].Name)
+	assert.Equal(t, "service", ret[0].Type)
+	assert.Equal(t, "1.0", ret[0].Properties["version"])
+	assert.Equal(t, "${{$target()}}", ret[0].Properties["site"])
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(ret[0], components[0]))
+
+	changed := model.ComponentSpec{Name: "web", Properties: map[string]interface{}{"version": "2.0"}}
+	assert.True(t, rule.IsComponentChanged(ret[0], changed))
+
+	components[0].Properties[GetCommandProperty] = "echo '{not json'"
+	deployment, step = conformance.Deployment("default", "instance1", "update", components[0])
+	_, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.NotNil(t, err)
+
+	components[0].Properties[GetCommandProperty] = "exit 1"
+	deployment, step = conformance.Deployment("default", "instance1", "update", components[0])
+	_, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.NotNil(t, err)
+}
+
+func TestRemove(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+	marker := filepath.Join(server.root, "removed")
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ApplyCommandProperty:  "touch installed",
+			RemoveCommandProperty: "test -f installed && echo $SYMPHONY_COMPONENT > " + marker,
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	deployment, step = conformance.Deployment("default", "instance1", "delete", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, result["web"].Status)
+	data, err := os.ReadFile(marker)
+	assert.Nil(t, err)
+	assert.Equal(t, "web\n", string(data))
+	_, err = os.Stat(filepath.Join(server.root, "instance1", "web"))
+	assert.True(t, os.IsNotExist(err))
+
+	// a component without a remove command only has its directory removed
+	delete(component.Properties, RemoveCommandProperty)
+	deployment, step = conformance.Deployment("default", "instance1", "delete", component)
+	result, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, result["web"].Status)
+}
+
+func TestHostKeyMismatch(t *testing.T) {
+	server := newTestServer(t)
+	provider := server.provider(t)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(other)
+	provider.Config.HostKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	component := model.ComponentSpec{Name: "web", Properties: map[string]interface{}{ApplyCommandProperty: "true"}}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Unauthorized, coaErr.State)
+	assert.Empty(t, server.commands())
+}
+
+func TestPrivateKeySecret(t *testing.T) {
+	server := newTestServer(t)
+	component := model.ComponentSpec{Name: "web", Properties: map[string]interface{}{ApplyCommandProperty: "true"}}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+
+	// without a secret provider
+	provider := server.provider(t)
+	provider.SecretProvider = nil
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.MissingConfig, coaErr.State)
+
+	// with a key that isn't authorized
+	provider = server.provider(t)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	provider.SecretProvider.(*conformance.FakeSecretProvider).Secrets["device-keys"]["privateKey"] = pemKey(t, other)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+
+	// with an encrypted key
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	// legacy PEM encryption, as ssh-keygen -m PEM produces
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), x509.PEMCipherAES256)
+	assert.Nil(t, err)
+	server.authorize(t, rsaKey)
+	provider = server.provider(t)
+	provider.Config.PassphraseField = "passphrase"
+	provider.SecretProvider.(*conformance.FakeSecretProvider).Secrets["device-keys"]["privateKey"] = string(pem.EncodeToMemory(block))
+	provider.SecretProvider.(*conformance.FakeSecretProvider).Secrets["device-keys"]["passphrase"] = "secret"
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+}
+
+func TestParseResult(t *testing.T) {
+	result, err := parseResult("", v1alpha2.Updated, v1alpha2.UpdateFailed)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, result.Status)
+
+	result, err = parseResult("{\"message\": \"removed\"}\n\n", v1alpha2.Deleted, v1alpha2.DeleteFailed)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.Deleted, Message: "removed"}, result)
+
+	result, err = parseResult(`{"status": "Untouched"}`, v1alpha2.Updated, v1alpha2.UpdateFailed)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Untouched, result.Status)
+
+	result, err = parseResult(`{"status": "delete failed", "message": "busy"}`, v1alpha2.Deleted, v1alpha2.DeleteFailed)
+	assert.NotNil(t, err)
+	assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.DeleteFailed, Message: "busy"}, result)
+
+	result, err = parseResult(`{"status": "installed"}`, v1alpha2.Updated, v1alpha2.UpdateFailed)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, result.Status)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+	out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(`$HOME "quoted" 'single' \n`)).Output()
+	assert.Nil(t, err)
+	assert.Equal(t, `$HOME "quoted" 'single' \n`, string(out))
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &SshTargetProvider{}
+	err := provider.Init(SshTargetProviderConfig{Host: "device1", User: "symphony", PrivateKeySecret: "device-keys", InsecureIgnoreHostKey: true})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
+
+func pemKey(t *testing.T, key interface{}) string {
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}))
+}
+
+// testServer is an in-process SSH server that runs commands with the local shell, and serves SFTP
+type testServer struct {
+	listener   net.Listener
+	hostKey    ssh.PublicKey
+	clientKey  string
+	root       string
+	lock       sync.Mutex
+	authorized [][]byte
+	executed   []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	assert.Nil(t, err)
+	_, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	server := &testServer{
+		hostKey:   hostSigner.PublicKey(),
+		clientKey: pemKey(t, clientKey),
+		root:      t.TempDir(),
+	}
+	server.authorize(t, clientKey)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			server.lock.Lock()
+			defer server.lock.Unlock()
+			for _, authorized := range server.authorized {
+				if conn.User() == "symphony" && bytes.Equal(authorized, key.Marshal()) {
+					return &ssh.Permissions{}, nil
+				}
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { server.listener.Close() })
+	go func() {
+		for {
+			conn, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testServer) authorize(t *testing.T, key interface{}) {
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.Nil(t, err)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.authorized = append(s.authorized, signer.PublicKey().Marshal())
+}
+
+func (s *testServer) provider(t *testing.T) *SshTargetProvider {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	provider := &SshTargetProvider{
+		SecretProvider: &conformance.FakeSecretProvider{
+			Secrets: map[string]map[string]string{
+				"device-keys": {"privateKey": s.clientKey},
+			},
+		},
+	}
+	err := provider.InitWithMap(map[string]string{
+		"host":             "127.0.0.1",
+		"port":             port,
+		"user":             "symphony",
+		"privateKeySecret": "device-keys",
+		"hostKey":          string(ssh.MarshalAuthorizedKey(s.hostKey)),
+		"workingDirectory": s.root,
+	})
+	assert.Nil(t, err)
+	return provider
+}
+
+func (s *testServer) commands() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.executed...)
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(channel, requests)
+	}
+}
+
+func (s *testServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	var cmd *exec.Cmd
+	for request := range requests {
+		switch request.Type {
+		case "exec":
+			payload := struct{ Command string }{}
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil || cmd != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			s.lock.Lock()
+			s.executed = append(s.executed, payload.Command)
+			s.lock.Unlock()
+			cmd = exec.Command("sh", "-c", payload.Command)
+			cmd.Dir = s.root
+			cmd.Stdin = channel
+			cmd.Stdout = channel
+			cmd.Stderr = channel.Stderr()
+			if err := cmd.Start(); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			go func(cmd *exec.Cmd) {
+				status := uint32(0)
+				if err := cmd.Wait(); err != nil {
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						status = uint32(exitErr.ExitCode())
+					} else {
+						status = 255
+					}
+				}
+				channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				channel.Close()
+			}(cmd)
+		case "signal":
+			if cmd != nil && cmd.Process != nil {
+				cmd.Process.Signal(syscall.SIGKILL)
+			}
+			request.Reply(true, nil)
+		case "subsystem":
+			payload := struct{ Name string }{}
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil || payload.Name != "sftp" {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			go func() {
+				server, err := sftp.NewServer(channel)
+				if err != nil {
+					channel.Close()
+					return
+				}
+				if err := server.Serve(); err == io.EOF {
+					server.Close()
+				}
+				channel.Close()
+			}()
+		default:
+			request.Reply(false, nil)
+		}
+	}
+	// the client closed the session, such as when a command timed out
+	if cmd != nil && cmd.Process != nil {
+		cmd.Process.Signal(syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// sourceName is the file name of a binary, given as a URL or as a local path
+func sourceName(source string) string {
+	name := source
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		name = u.Path
+	}
+	name = path.Base(name)
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// download writes a binary to target once its checksum is verified
+func download(ctx context.Context, source string, checksum string, target string, mode os.FileMode) error {
+	data, err := utils.ReadSource(ctx, source)
+	if err != nil {
+		return err
+	}
+	if err := utils.VerifySHA256(source, data, checksum); err != nil {
+		return err
+	}
+	return writeFile(target, data, mode)
+}
+
+// installArtifact extracts a .tar.gz, .tgz or .zip artifact into a directory
+func installArtifact(ctx context.Context, source string, checksum string, directory string) error {
+	data, err := utils.ReadSource(ctx, source)
+	if err != nil {
+		return err
+	}
+	if err := utils.VerifySHA256(source, data, checksum); err != nil {
+		return err
+	}
+	name := strings.ToLower(sourceName(source))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return extractTarGz(data, directory)
+	case strings.HasSuffix(name, ".zip"):
+		return extractZip(data, directory)
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact '%s' is neither a .tar.gz, a .tgz nor a .zip archive", source), v1alpha2.BadRequest)
+	}
+}
+
+// archivePath resolves the path of an archive entry, which must stay within the directory
+func archivePath(directory string, name string) (string, error) {
+	target := filepath.Join(directory, filepath.FromSlash(name))
+	if target != filepath.Clean(directory) && !strings.HasPrefix(target, filepath.Clean(directory)+string(os.PathSeparator)) {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("archive entry '%s' is outside of the install directory", name), v1alpha2.BadRequest)
+	}
+	return target, nil
+}
+
+func extractTarGz(data []byte, directory string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "artifact is not a valid gzip archive", v1alpha2.BadRequest)
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "artifact is not a valid tar archive", v1alpha2.BadRequest)
+		}
+		target, err := archivePath(directory, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(target, content, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		default:
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("archive entry '%s' is neither a file nor a directory", header.Name), v1alpha2.BadRequest)
+		}
+	}
+}
+
+func extractZip(data []byte, directory string) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "artifact is not a valid zip archive", v1alpha2.BadRequest)
+	}
+	for _, file := range reader.File {
+		target, err := archivePath(directory, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !file.FileInfo().Mode().IsRegular() {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("archive entry '%s' is neither a file nor a directory", file.Name), v1alpha2.BadRequest)
+		}
+		entry, err := file.Open()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(entry)
+		entry.Close()
+		if err != nil {
+			return err
+		}
+		if err := writeFile(target, content, file.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile replaces a file through a rename, so that a running binary can be replaced
+func writeFile(target string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), target)
+}
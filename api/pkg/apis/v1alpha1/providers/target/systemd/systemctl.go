@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// UnitStatus is the state of a unit, as reported by systemctl show
+type UnitStatus struct {
+	// LoadState is "loaded", or "not-found" for a unit without a unit file
+	LoadState string
+	// ActiveState is "active", "inactive", "activating", "deactivating" or "failed"
+	ActiveState string
+	SubState    string
+	// Result is "success", or the reason the unit failed, such as "exit-code"
+	Result string
+}
+
+// Systemctl manages units. The provider uses systemctl, and tests replace it with a fake.
+type Systemctl interface {
+	DaemonReload(ctx context.Context) error
+	Enable(ctx context.Context, unit string) error
+	Disable(ctx context.Context, unit string) error
+	Restart(ctx context.Context, unit string) error
+	Stop(ctx context.Context, unit string) error
+	ResetFailed(ctx context.Context, unit string) error
+	Show(ctx context.Context, unit string) (UnitStatus, error)
+}
+
+// NewSystemctl creates a Systemctl that runs systemctl, against the user's service manager in user mode
+func NewSystemctl(userMode bool) Systemctl {
+	return &execSystemctl{userMode: userMode}
+}
+
+type execSystemctl struct {
+	userMode bool
+}
+
+func (s *execSystemctl) DaemonReload(ctx context.Context) error {
+	_, err := s.run(ctx, "daemon-reload")
+	return err
+}
+func (s *execSystemctl) Enable(ctx context.Context, unit string) error {
+	_, err := s.run(ctx, "enable", unit)
+	return err
+}
+func (s *execSystemctl) Disable(ctx context.Context, unit string) error {
+	_, err := s.run(ctx, "disable", unit)
+	return err
+}
+func (s *execSystemctl) Restart(ctx context.Context, unit string) error {
+	_, err := s.run(ctx, "restart", unit)
+	return err
+}
+func (s *execSystemctl) Stop(ctx context.Context, unit string) error {
+	_, err := s.run(ctx, "stop", unit)
+	return err
+}
+func (s *execSystemctl) ResetFailed(ctx context.Context, unit string) error {
+	_, err := s.run(ctx, "reset-failed", unit)
+	return err
+}
+func (s *execSystemctl) Show(ctx context.Context, unit string) (UnitStatus, error) {
+	out, err := s.run(ctx, "show", "--property=LoadState,ActiveState,SubState,Result", unit)
+	if err != nil {
+		return UnitStatus{}, err
+	}
+	return parseShow(out), nil
+}
+
+func (s *execSystemctl) run(ctx context.Context, args ...string) (string, error) {
+	if s.userMode {
+		args = append([]string{"--user"}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("systemctl %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseShow parses the key=value lines systemctl show prints
+func parseShow(out string) UnitStatus {
+	ret := UnitStatus{}
+	for _, line := range strings.Split(out, "\n") {
+		pair := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		switch pair[0] {
+		case "LoadState":
+			ret.LoadState = pair[1]
+		case "ActiveState":
+			ret.ActiveState = pair[1]
+		case "SubState":
+			ret.SubState = pair[1]
+		case "Result":
+			ret.Result = pair[1]
+		}
+	}
+	return ret
+}
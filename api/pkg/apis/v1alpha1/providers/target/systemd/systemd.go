@@ -0,0 +1,817 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"golang.org/x/exp/slices"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+// component properties
+const (
+	UnitNameProperty         = "systemd.unitName"
+	DescriptionProperty      = "systemd.description"
+	BinaryProperty           = "systemd.binary"
+	BinarySha256Property     = "systemd.binarySha256"
+	ArtifactProperty         = "systemd.artifact"
+	ArtifactSha256Property   = "systemd.artifactSha256"
+	ExecStartProperty        = "systemd.execStart"
+	ArgsProperty             = "systemd.args"
+	TypeProperty             = "systemd.type"
+	UserProperty             = "systemd.user"
+	GroupProperty            = "systemd.group"
+	WorkingDirectoryProperty = "systemd.workingDirectory"
+	RestartProperty          = "systemd.restart"
+	RestartSecProperty       = "systemd.restartSec"
+	AfterProperty            = "systemd.after"
+	WantedByProperty         = "systemd.wantedBy"
+	UnitTemplateProperty     = "systemd.unitTemplate"
+	// StateProperty is reported by Get with the active state of the unit
+	StateProperty = "systemd.state"
+)
+
+const (
+	// symphonySection is the unit file section that records who owns a unit and how it was deployed.
+	// systemd ignores sections whose name starts with X-.
+	symphonySection = "X-Symphony"
+	environmentFile = "environment"
+)
+
+var (
+	unitNamePattern    = regexp.MustCompile(`^[A-Za-z0-9:_.@-]+\.service$`)
+	invalidUnitPattern = regexp.MustCompile(`[^A-Za-z0-9:_.@-]`)
+	envNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	serviceTypes       = []string{"simple", "exec", "forking", "oneshot", "notify", "idle"}
+	restartPolicies    = []string{"no", "on-success", "on-failure", "on-abnormal", "on-watchdog", "on-abort", "always"}
+)
+
+const defaultUnitTemplate = `[Unit]
+Description={{.Description}}
+{{- if .After}}
+After={{.After}}
+{{- end}}
+
+[Service]
+Type={{.Type}}
+ExecStart={{.ExecStart}}
+WorkingDirectory={{.WorkingDirectory}}
+EnvironmentFile={{.EnvironmentFile}}
+{{- if .User}}
+User={{.User}}
+{{- end}}
+{{- if .Group}}
+Group={{.Group}}
+{{- end}}
+Restart={{.Restart}}
+{{- if .RestartSec}}
+RestartSec={{.RestartSec}}
+{{- end}}
+
+[Install]
+WantedBy={{.WantedBy}}
+`
+
+type SystemdTargetProviderConfig struct {
+	Name string `json:"name"`
+	// UnitDirectory is where unit files are written. It defaults to /etc/systemd/system, or to
+	// ~/.config/systemd/user in user mode.
+	UnitDirectory string `json:"unitDirectory,omitempty"`
+	// InstallDirectory is where binaries and artifacts are installed, in a directory per unit. It
+	// defaults to /opt/symphony, or to ~/.local/share/symphony in user mode.
+	InstallDirectory string `json:"installDirectory,omitempty"`
+	// UserMode manages units of the user's service manager, with systemctl --user
+	UserMode bool `json:"userMode,omitempty"`
+}
+
+type SystemdTargetProvider struct {
+	Config    SystemdTargetProviderConfig
+	Context   *contexts.ManagerContext
+	Systemctl Systemctl
+}
+
+// UnitTemplateData is the data unit templates are rendered with
+type UnitTemplateData struct {
+	Instance         string
+	Solution         string
+	Component        string
+	Unit             string
+	Description      string
+	Type             string
+	ExecStart        string
+	WorkingDirectory string
+	EnvironmentFile  string
+	User             string
+	Group            string
+	Restart          string
+	RestartSec       string
+	After            string
+	WantedBy         string
+	// InstallDirectory is the directory the binary and the artifact of the unit are installed in
+	InstallDirectory string
+	// Binary is the path of the installed binary, if any
+	Binary string
+	// Properties are the component properties, with values injected
+	Properties map[string]string
+}
+
+func SystemdTargetProviderConfigFromMap(properties map[string]string) (SystemdTargetProviderConfig, error) {
+	ret := SystemdTargetProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = v
+	}
+	if v, ok := properties["unitDirectory"]; ok {
+		ret.UnitDirectory = v
+	}
+	if v, ok := properties["installDirectory"]; ok {
+		ret.InstallDirectory = v
+	}
+	if v, ok := properties["userMode"]; ok && v != "" {
+		userMode, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid systemd provider config, 'userMode' must be a boolean", v1alpha2.BadConfig)
+		}
+		ret.UserMode = userMode
+	}
+	return ret, nil
+}
+func (i *SystemdTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := SystemdTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func (i *SystemdTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	i.Context = ctx
+}
+
+func (i *SystemdTargetProvider) Init(config providers.IProviderConfig) error {
+	_, span := observability.StartSpan("Systemd Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (Systemd Target): Init()")
+
+	systemdConfig, err := toSystemdTargetProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (Systemd Target): expected SystemdTargetProviderConfig: %+v", err)
+		return err
+	}
+	if systemdConfig.UnitDirectory == "" || systemdConfig.InstallDirectory == "" {
+		unitDirectory, installDirectory := "/etc/systemd/system", "/opt/symphony"
+		if systemdConfig.UserMode {
+			var home string
+			home, err = os.UserHomeDir()
+			if err != nil {
+				sLog.Errorf("  P (Systemd Target): failed to find the home directory: %+v", err)
+				return v1alpha2.NewCOAError(err, "failed to find the home directory for user mode", v1alpha2.BadConfig)
+			}
+			unitDirectory = filepath.Join(home, ".config", "systemd", "user")
+			installDirectory = filepath.Join(home, ".local", "share", "symphony")
+		}
+		if systemdConfig.UnitDirectory == "" {
+			systemdConfig.UnitDirectory = unitDirectory
+		}
+		if systemdConfig.InstallDirectory == "" {
+			systemdConfig.InstallDirectory = installDirectory
+		}
+	}
+
+	i.Config = systemdConfig
+	if i.Systemctl == nil {
+		i.Systemctl = NewSystemctl(systemdConfig.UserMode)
+	}
+	return nil
+}
+func toSystemdTargetProviderConfig(config providers.IProviderConfig) (SystemdTargetProviderConfig, error) {
+	ret := SystemdTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// UnitName is the name of the unit of an instance's component, unless the component sets
+// systemd.unitName. Characters unit names can't contain are replaced with dashes.
+func UnitName(instance string, component string) string {
+	name := component
+	if instance != "" {
+		name = instance + "-" + component
+	}
+	return invalidUnitPattern.ReplaceAllString(name, "-") + ".service"
+}
+
+// Get reports the properties each unit was deployed with, along with its state in systemd.state. A unit
+// that isn't running, or a oneshot unit whose last run failed, is reported without systemd.unitName, so
+// that change detection deploys it again.
+func (i *SystemdTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Systemd Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Systemd Target): getting artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		unit := i.unitName(deployment, reference.Component, injections)
+		var recorded recordedUnit
+		recorded, err = i.readUnit(unit)
+		if err != nil {
+			sLog.Errorf("  P (Systemd Target): failed to read unit %s: %+v", unit, err)
+			return nil, err
+		}
+		if !recorded.exists || !recorded.ownedBy(deployment, reference.Component.Name) {
+			continue
+		}
+
+		var status UnitStatus
+		status, err = i.Systemctl.Show(ctx, unit)
+		if err != nil {
+			sLog.Errorf("  P (Systemd Target): failed to get the state of unit %s: %+v", unit, err)
+			return nil, err
+		}
+
+		component := model.ComponentSpec{
+			Name:       reference.Component.Name,
+			Properties: make(map[string]interface{}),
+		}
+		for k, v := range recorded.properties {
+			component.Properties[k] = v
+		}
+		component.Properties[StateProperty] = status.ActiveState
+		if isHealthy(status, recorded.serviceType) {
+			if _, ok := component.Properties[UnitNameProperty]; !ok {
+				component.Properties[UnitNameProperty] = unit
+			}
+		} else {
+			delete(component.Properties, UnitNameProperty)
+		}
+
+		// environment variables that match the reference once values are injected are returned as in
+		// the reference
+		var env map[string]string
+		env, err = readEnvironmentFile(filepath.Join(i.unitDirectory(unit), environmentFile))
+		if err != nil {
+			sLog.Errorf("  P (Systemd Target): failed to read the environment of unit %s: %+v", unit, err)
+			return nil, err
+		}
+		for k, v := range env {
+			if rv, ok := reference.Component.Properties["env."+k]; ok && model.ResolveString(fmt.Sprintf("%v", rv), injections) == v {
+				component.Properties["env."+k] = rv
+			} else {
+				component.Properties["env."+k] = v
+			}
+		}
+		ret = append(ret, component)
+	}
+
+	return ret, nil
+}
+
+func (i *SystemdTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Systemd Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Infof("  P (Systemd Target): applying artifacts: %s - %s", deployment.Instance.Scope, deployment.Instance.Name)
+
+	injections := &model.ValueInjections{
+		InstanceId: deployment.Instance.Name,
+		SolutionId: deployment.Instance.Solution,
+		TargetId:   deployment.ActiveTarget,
+	}
+
+	components := step.GetComponents()
+	err = i.GetValidationRule(ctx).Validate(components)
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun {
+		err = nil
+		return nil, nil
+	}
+
+	ret := step.PrepareResultMap()
+
+	for _, component := range step.Components {
+		if component.Action == "update" {
+			err = i.deploy(ctx, deployment, component.Component, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Systemd Target): failed to deploy %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Updated,
+				Message: "",
+			}
+		} else {
+			err = i.remove(ctx, deployment, component.Component, injections)
+			if err != nil {
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.DeleteFailed,
+					Message: err.Error(),
+				}
+				sLog.Errorf("  P (Systemd Target): failed to remove %s: %+v", component.Component.Name, err)
+				return ret, err
+			}
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Deleted,
+				Message: "",
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (*SystemdTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		RequiredProperties: []string{},
+		OptionalProperties: []string{
+			UnitNameProperty,
+			DescriptionProperty,
+			BinaryProperty,
+			BinarySha256Property,
+			ArtifactProperty,
+			ArtifactSha256Property,
+			ExecStartProperty,
+			ArgsProperty,
+			TypeProperty,
+			UserProperty,
+			GroupProperty,
+			WorkingDirectoryProperty,
+			RestartProperty,
+			RestartSecProperty,
+			AfterProperty,
+			WantedByProperty,
+			UnitTemplateProperty,
+			"env.*",
+		},
+		RequiredComponentType: "",
+		RequiredMetadata:      []string{},
+		OptionalMetadata:      []string{},
+		ChangeDetectionProperties: []model.PropertyDesc{
+			// reported only for units that run, so that a stopped or failed unit is deployed again
+			{Name: UnitNameProperty, IgnoreCase: false, SkipIfMissing: false},
+			{Name: "systemd.*", IgnoreCase: false, SkipIfMissing: true},
+			{Name: "env.*", IgnoreCase: false, SkipIfMissing: true},
+		},
+		InstanceIsolation: true,
+	}
+}
+
+// deploy installs a unit and restarts it. A unit that runs with the desired configuration is left alone.
+func (i *SystemdTargetProvider) deploy(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) error {
+	spec, err := i.buildUnit(deployment, component, injections)
+	if err != nil {
+		return err
+	}
+	recorded, err := i.readUnit(spec.Unit)
+	if err != nil {
+		return err
+	}
+	if recorded.exists {
+		if !recorded.ownedBy(deployment, component.Name) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("unit %s exists and isn't managed by instance '%s' in scope '%s'", spec.Unit, deployment.Instance.Name, deployment.Instance.Scope), v1alpha2.BadRequest)
+		}
+		if recorded.hash == spec.Hash {
+			status, err := i.Systemctl.Show(ctx, spec.Unit)
+			if err != nil {
+				return err
+			}
+			if isHealthy(status, spec.Data.Type) {
+				sLog.Infof("  P (Systemd Target): unit %s is up to date", spec.Unit)
+				return nil
+			}
+		}
+	}
+
+	if err := i.install(ctx, spec); err != nil {
+		return err
+	}
+	if err := i.Systemctl.DaemonReload(ctx); err != nil {
+		return err
+	}
+	if err := i.Systemctl.Enable(ctx, spec.Unit); err != nil {
+		return err
+	}
+	if err := i.Systemctl.Restart(ctx, spec.Unit); err != nil {
+		return err
+	}
+	status, err := i.Systemctl.Show(ctx, spec.Unit)
+	if err != nil {
+		return err
+	}
+	if status.ActiveState == "failed" {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("unit %s failed to start: %s", spec.Unit, status.Result), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+// install downloads the binary and the artifact of a unit, and writes its environment and unit files
+func (i *SystemdTargetProvider) install(ctx context.Context, spec unitSpec) error {
+	if err := os.MkdirAll(spec.Data.InstallDirectory, 0755); err != nil {
+		return err
+	}
+	if spec.Artifact != "" {
+		if err := installArtifact(ctx, spec.Artifact, spec.ArtifactSha256, spec.Data.InstallDirectory); err != nil {
+			return err
+		}
+	}
+	if spec.BinarySource != "" {
+		if err := download(ctx, spec.BinarySource, spec.BinarySha256, spec.Data.Binary, 0755); err != nil {
+			return err
+		}
+	}
+	// environment variables may carry secrets, so unlike the unit file, only the owner can read them
+	if err := writeFile(spec.Data.EnvironmentFile, []byte(spec.Environment), 0600); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(i.Config.UnitDirectory, 0755); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(i.Config.UnitDirectory, spec.Unit), []byte(spec.Content), 0644)
+}
+
+// remove stops and disables a unit, and removes its unit file and install directory
+func (i *SystemdTargetProvider) remove(ctx context.Context, deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) error {
+	unit := i.unitName(deployment, component, injections)
+	recorded, err := i.readUnit(unit)
+	if err != nil {
+		return err
+	}
+	if !recorded.exists {
+		return nil
+	}
+	if !recorded.ownedBy(deployment, component.Name) {
+		sLog.Infof("  P (Systemd Target): unit %s isn't managed by instance %s in scope %s and is left alone", unit, deployment.Instance.Name, deployment.Instance.Scope)
+		return nil
+	}
+	if err := i.Systemctl.Stop(ctx, unit); err != nil {
+		return err
+	}
+	if err := i.Systemctl.Disable(ctx, unit); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(i.Config.UnitDirectory, unit)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := i.Systemctl.DaemonReload(ctx); err != nil {
+		return err
+	}
+	// a unit that failed stays listed until its failed state is reset
+	if err := i.Systemctl.ResetFailed(ctx, unit); err != nil {
+		sLog.Infof("  P (Systemd Target): failed to reset the failed state of unit %s: %+v", unit, err)
+	}
+	return os.RemoveAll(i.unitDirectory(unit))
+}
+
+func (i *SystemdTargetProvider) unitName(deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) string {
+	if name := model.ReadPropertyCompat(component.Properties, UnitNameProperty, injections); name != "" {
+		if !strings.HasSuffix(name, ".service") {
+			name = name + ".service"
+		}
+		return name
+	}
+	return UnitName(deployment.Instance.Name, component.Name)
+}
+
+// unitDirectory is the install directory of a unit
+func (i *SystemdTargetProvider) unitDirectory(unit string) string {
+	return filepath.Join(i.Config.InstallDirectory, strings.TrimSuffix(unit, ".service"))
+}
+
+// isHealthy tells whether a unit runs, or, for a oneshot unit, whether its last run succeeded
+func isHealthy(status UnitStatus, serviceType string) bool {
+	if status.ActiveState == "active" || status.ActiveState == "activating" || status.ActiveState == "reloading" {
+		return true
+	}
+	return serviceType == "oneshot" && status.ActiveState == "inactive" && status.Result == "success"
+}
+
+type unitSpec struct {
+	Unit           string
+	Data           UnitTemplateData
+	BinarySource   string
+	BinarySha256   string
+	Artifact       string
+	ArtifactSha256 string
+	Environment    string
+	// Content is the unit file, which ends with the X-Symphony section
+	Content string
+	Hash    string
+}
+
+// buildUnit renders the unit file and the environment file of a component
+func (i *SystemdTargetProvider) buildUnit(deployment model.DeploymentSpec, component model.ComponentSpec, injections *model.ValueInjections) (unitSpec, error) {
+	spec := unitSpec{
+		Unit: i.unitName(deployment, component, injections),
+	}
+	if !unitNamePattern.MatchString(spec.Unit) || strings.HasPrefix(spec.Unit, ".") {
+		return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is not a valid unit name", spec.Unit), v1alpha2.BadRequest)
+	}
+
+	properties := make(map[string]string)
+	for k := range component.Properties {
+		properties[k] = model.ReadPropertyCompat(component.Properties, k, injections)
+		// only the unit template spans lines, other values would add directives to the unit
+		if k != UnitTemplateProperty && strings.ContainsAny(properties[k], "\r\n") {
+			return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("property '%s' can't span lines", k), v1alpha2.BadRequest)
+		}
+	}
+	directory := i.unitDirectory(spec.Unit)
+	spec.Data = UnitTemplateData{
+		Instance:         deployment.Instance.Name,
+		Solution:         deployment.Instance.Solution,
+		Component:        component.Name,
+		Unit:             spec.Unit,
+		Description:      properties[DescriptionProperty],
+		Type:             properties[TypeProperty],
+		WorkingDirectory: properties[WorkingDirectoryProperty],
+		EnvironmentFile:  filepath.Join(directory, environmentFile),
+		User:             properties[UserProperty],
+		Group:            properties[GroupProperty],
+		Restart:          properties[RestartProperty],
+		RestartSec:       properties[RestartSecProperty],
+		After:            properties[AfterProperty],
+		WantedBy:         properties[WantedByProperty],
+		InstallDirectory: directory,
+		Properties:       properties,
+	}
+	if spec.Data.Description == "" {
+		spec.Data.Description = fmt.Sprintf("Symphony component %s of instance %s", component.Name, deployment.Instance.Name)
+	}
+	if spec.Data.Type == "" {
+		spec.Data.Type = "simple"
+	}
+	if !slices.Contains(serviceTypes, spec.Data.Type) {
+		return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("service type '%s' is not supported", spec.Data.Type), v1alpha2.BadRequest)
+	}
+	if spec.Data.Restart == "" {
+		spec.Data.Restart = "on-failure"
+	}
+	if !slices.Contains(restartPolicies, spec.Data.Restart) {
+		return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("restart policy '%s' is not supported", spec.Data.Restart), v1alpha2.BadRequest)
+	}
+	if spec.Data.WorkingDirectory == "" {
+		spec.Data.WorkingDirectory = directory
+	}
+	if spec.Data.WantedBy == "" {
+		spec.Data.WantedBy = "multi-user.target"
+		if i.Config.UserMode {
+			spec.Data.WantedBy = "default.target"
+		}
+	}
+
+	spec.BinarySource = properties[BinaryProperty]
+	spec.BinarySha256 = strings.ToLower(properties[BinarySha256Property])
+	spec.Artifact = properties[ArtifactProperty]
+	spec.ArtifactSha256 = strings.ToLower(properties[ArtifactSha256Property])
+	if spec.BinarySource != "" {
+		name := sourceName(spec.BinarySource)
+		if name == "" {
+			return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("binary '%s' doesn't name a file", spec.BinarySource), v1alpha2.BadRequest)
+		}
+		spec.Data.Binary = filepath.Join(directory, name)
+	}
+
+	// the start command may refer to the install directory and to the binary
+	execStart := properties[ExecStartProperty]
+	if execStart != "" {
+		rendered, err := render("execStart", execStart, spec.Data)
+		if err != nil {
+			return spec, err
+		}
+		spec.Data.ExecStart = rendered
+	} else if spec.Data.Binary != "" {
+		spec.Data.ExecStart = strconv.Quote(spec.Data.Binary)
+		if args := properties[ArgsProperty]; args != "" {
+			spec.Data.ExecStart += " " + args
+		}
+	} else {
+		return spec, v1alpha2.NewCOAError(nil, fmt.Sprintf("component '%s' has neither %s nor %s property", component.Name, ExecStartProperty, BinaryProperty), v1alpha2.BadRequest)
+	}
+
+	env, err := buildEnvironment(properties)
+	if err != nil {
+		return spec, err
+	}
+	spec.Environment = env
+
+	unitTemplate := defaultUnitTemplate
+	if v, ok := properties[UnitTemplateProperty]; ok && v != "" {
+		unitTemplate = v
+	}
+	content, err := render("unit", unitTemplate, spec.Data)
+	if err != nil {
+		return spec, err
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{content, spec.Environment, spec.BinarySource, spec.BinarySha256, spec.Artifact, spec.ArtifactSha256} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	spec.Hash = hex.EncodeToString(hash.Sum(nil))
+
+	// the raw property values are recorded, so that Get returns them as they're in the solution
+	recorded := make([]string, 0)
+	for k, v := range component.Properties {
+		if strings.HasPrefix(k, "systemd.") {
+			value, _ := json.Marshal(fmt.Sprintf("%v", v))
+			recorded = append(recorded, fmt.Sprintf("Property=%s=%s", k, string(value)))
+		}
+	}
+	sort.Strings(recorded)
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(content, "\n"))
+	fmt.Fprintf(&b, "\n\n[%s]\n", symphonySection)
+	fmt.Fprintf(&b, "Scope=%s\n", deployment.Instance.Scope)
+	fmt.Fprintf(&b, "Instance=%s\n", deployment.Instance.Name)
+	fmt.Fprintf(&b, "Solution=%s\n", deployment.Instance.Solution)
+	fmt.Fprintf(&b, "Component=%s\n", component.Name)
+	fmt.Fprintf(&b, "Type=%s\n", spec.Data.Type)
+	fmt.Fprintf(&b, "ConfigHash=%s\n", spec.Hash)
+	for _, line := range recorded {
+		b.WriteString(line + "\n")
+	}
+	spec.Content = b.String()
+	return spec, nil
+}
+
+func render(name string, text string, data UnitTemplateData) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("%s template is not valid", name), v1alpha2.BadRequest)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to render %s template", name), v1alpha2.BadRequest)
+	}
+	return out.String(), nil
+}
+
+// buildEnvironment renders the env.* properties as an environment file, with values quoted
+func buildEnvironment(properties map[string]string) (string, error) {
+	lines := make([]string, 0)
+	for k, v := range properties {
+		if !strings.HasPrefix(k, "env.") {
+			continue
+		}
+		name := strings.TrimPrefix(k, "env.")
+		if !envNamePattern.MatchString(name) {
+			return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is not a valid environment variable name", name), v1alpha2.BadRequest)
+		}
+		lines = append(lines, name+"="+quoteEnvironmentValue(v))
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+var environmentEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+func quoteEnvironmentValue(value string) string {
+	return `"` + environmentEscaper.Replace(value) + `"`
+}
+
+func unquoteEnvironmentValue(value string) string {
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value
+	}
+	value = value[1 : len(value)-1]
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+func readEnvironmentFile(path string) (map[string]string, error) {
+	ret := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		pair := strings.SplitN(line, "=", 2)
+		if len(pair) == 2 {
+			ret[pair[0]] = unquoteEnvironmentValue(pair[1])
+		}
+	}
+	return ret, nil
+}
+
+// recordedUnit is what the X-Symphony section of a unit file records
+type recordedUnit struct {
+	exists      bool
+	scope       string
+	instance    string
+	component   string
+	serviceType string
+	hash        string
+	properties  map[string]string
+}
+
+func (r recordedUnit) ownedBy(deployment model.DeploymentSpec, component string) bool {
+	return r.scope == deployment.Instance.Scope && r.instance == deployment.Instance.Name && r.component == component
+}
+
+func (i *SystemdTargetProvider) readUnit(unit string) (recordedUnit, error) {
+	ret := recordedUnit{properties: make(map[string]string)}
+	data, err := os.ReadFile(filepath.Join(i.Config.UnitDirectory, unit))
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return ret, err
+	}
+	ret.exists = true
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		if section != symphonySection {
+			continue
+		}
+		pair := strings.SplitN(line, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		switch pair[0] {
+		case "Scope":
+			ret.scope = pair[1]
+		case "Instance":
+			ret.instance = pair[1]
+		case "Component":
+			ret.component = pair[1]
+		case "Type":
+			ret.serviceType = pair[1]
+		case "ConfigHash":
+			ret.hash = pair[1]
+		case "Property":
+			property := strings.SplitN(pair[1], "=", 2)
+			if len(property) == 2 {
+				var value string
+				if json.Unmarshal([]byte(property[1]), &value) == nil {
+					ret.properties[property[0]] = value
+				}
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,639 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package systemd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemdTargetProviderConfigFromMap(t *testing.T) {
+	config, err := SystemdTargetProviderConfigFromMap(map[string]string{
+		"name":             "name",
+		"unitDirectory":    "/run/units",
+		"installDirectory": "/srv/apps",
+		"userMode":         "true",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, SystemdTargetProviderConfig{Name: "name", UnitDirectory: "/run/units", InstallDirectory: "/srv/apps", UserMode: true}, config)
+
+	_, err = SystemdTargetProviderConfigFromMap(map[string]string{"userMode": "sometimes"})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+func TestInitWithMap(t *testing.T) {
+	provider := SystemdTargetProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name": "name",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/etc/systemd/system", provider.Config.UnitDirectory)
+	assert.Equal(t, "/opt/symphony", provider.Config.InstallDirectory)
+	assert.NotNil(t, provider.Systemctl)
+}
+func TestInitUserMode(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	provider := SystemdTargetProvider{}
+	err := provider.Init(SystemdTargetProviderConfig{UserMode: true})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "systemd", "user"), provider.Config.UnitDirectory)
+	assert.Equal(t, filepath.Join(home, ".local", "share", "symphony"), provider.Config.InstallDirectory)
+}
+
+func TestUnitName(t *testing.T) {
+	assert.Equal(t, "instance1-web.service", UnitName("instance1", "web"))
+	assert.Equal(t, "web.service", UnitName("", "web"))
+	assert.Equal(t, "instance-1-my-app.service", UnitName("instance 1", "my/app"))
+}
+
+func TestParseShow(t *testing.T) {
+	status := parseShow("LoadState=loaded\nActiveState=failed\nSubState=failed\nResult=exit-code\n")
+	assert.Equal(t, UnitStatus{LoadState: "loaded", ActiveState: "failed", SubState: "failed", Result: "exit-code"}, status)
+}
+
+func TestApplyBinary(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho hello\n")
+	server := serveFiles(t, map[string][]byte{"/releases/agent": binary})
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "agent",
+		Properties: map[string]interface{}{
+			BinaryProperty:       server.URL + "/releases/agent",
+			BinarySha256Property: utils.SHA256(binary),
+			ArgsProperty:         "--verbose",
+			RestartProperty:      "always",
+			RestartSecProperty:   "5",
+			UserProperty:         "symphony",
+			"env.INSTANCE":       "${{$instance()}}",
+			"env.GREETING":       `say "hi" for $5`,
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, result["agent"].Status)
+	assert.Equal(t, []string{"daemon-reload", "enable instance1-agent.service", "restart instance1-agent.service"}, systemctl.changes())
+
+	directory := filepath.Join(provider.Config.InstallDirectory, "instance1-agent")
+	data, err := os.ReadFile(filepath.Join(directory, "agent"))
+	assert.Nil(t, err)
+	assert.Equal(t, binary, data)
+	info, err := os.Stat(filepath.Join(directory, "agent"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	unit, err := os.ReadFile(filepath.Join(provider.Config.UnitDirectory, "instance1-agent.service"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(unit), fmt.Sprintf("ExecStart=%q --verbose\n", filepath.Join(directory, "agent")))
+	assert.Contains(t, string(unit), "Restart=always\nRestartSec=5\n")
+	assert.Contains(t, string(unit), "User=symphony\n")
+	assert.Contains(t, string(unit), "WorkingDirectory="+directory+"\n")
+	assert.Contains(t, string(unit), "EnvironmentFile="+filepath.Join(directory, "environment")+"\n")
+	assert.Contains(t, string(unit), "WantedBy=multi-user.target\n")
+	assert.Contains(t, string(unit), "\n[X-Symphony]\nScope=default\nInstance=instance1\n")
+
+	env, err := os.ReadFile(filepath.Join(directory, "environment"))
+	assert.Nil(t, err)
+	assert.Equal(t, "GREETING=\"say \\\"hi\\\" for \\$5\"\nINSTANCE=\"instance1\"\n", string(env))
+	info, err = os.Stat(filepath.Join(directory, "environment"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestApplyBinaryChecksumMismatch(t *testing.T) {
+	server := serveFiles(t, map[string][]byte{"/agent": []byte("binary")})
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "agent",
+		Properties: map[string]interface{}{
+			BinaryProperty:       server.URL + "/agent",
+			BinarySha256Property: utils.SHA256([]byte("other")),
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["agent"].Status)
+	assert.Contains(t, result["agent"].Message, "checksum")
+	assert.Empty(t, systemctl.changes())
+	_, err = os.Stat(filepath.Join(provider.Config.UnitDirectory, "instance1-agent.service"))
+	assert.True(t, os.IsNotExist(err))
+
+	// so does a binary that can't be downloaded
+	component.Properties[BinaryProperty] = server.URL + "/missing"
+	delete(component.Properties, BinarySha256Property)
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestApplyArtifact(t *testing.T) {
+	tarball := tarGz(t, map[string]string{"bin/app": "app", "config/app.yaml": "port: 80"})
+	archive := zipArchive(t, map[string]string{"lib/plugin.so": "plugin"})
+	server := serveFiles(t, map[string][]byte{"/app.tar.gz": tarball, "/plugins.zip": archive})
+	provider, _ := newTestProvider(t)
+
+	for _, artifact := range []string{"/app.tar.gz", "/plugins.zip"} {
+		component := model.ComponentSpec{
+			Name: "app",
+			Properties: map[string]interface{}{
+				ArtifactProperty:       server.URL + artifact,
+				ArtifactSha256Property: strings.ToUpper(utils.SHA256(map[string][]byte{"/app.tar.gz": tarball, "/plugins.zip": archive}[artifact])),
+				ExecStartProperty:      "{{.InstallDirectory}}/bin/app --config {{.InstallDirectory}}/config/app.yaml",
+			},
+		}
+		deployment, step := conformance.Deployment("default", "instance1", "update", component)
+		_, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.Nil(t, err)
+	}
+
+	directory := filepath.Join(provider.Config.InstallDirectory, "instance1-app")
+	data, err := os.ReadFile(filepath.Join(directory, "config", "app.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "port: 80", string(data))
+	info, err := os.Stat(filepath.Join(directory, "bin", "app"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	data, err = os.ReadFile(filepath.Join(directory, "lib", "plugin.so"))
+	assert.Nil(t, err)
+	assert.Equal(t, "plugin", string(data))
+
+	unit, err := os.ReadFile(filepath.Join(provider.Config.UnitDirectory, "instance1-app.service"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(unit), fmt.Sprintf("ExecStart=%s/bin/app --config %s/config/app.yaml\n", directory, directory))
+}
+
+func TestApplyArtifactOutsideInstallDirectory(t *testing.T) {
+	server := serveFiles(t, map[string][]byte{"/evil.tar.gz": tarGz(t, map[string]string{"../../escaped": "evil"})})
+	provider, _ := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "app",
+		Properties: map[string]interface{}{
+			ArtifactProperty:  server.URL + "/evil.tar.gz",
+			ExecStartProperty: "/bin/true",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "outside of the install directory")
+	_, err = os.Stat(filepath.Join(provider.Config.InstallDirectory, "..", "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplyUnitTemplate(t *testing.T) {
+	provider, _ := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "job",
+		Properties: map[string]interface{}{
+			UnitNameProperty:  "nightly-job",
+			TypeProperty:      "oneshot",
+			ExecStartProperty: "/usr/bin/backup --target {{.Properties.target}}",
+			"target":          "${{$target()}}",
+			UnitTemplateProperty: "[Unit]\nDescription=Backup of {{.Instance}}\n\n[Service]\nType={{.Type}}\nExecStart={{.ExecStart}}\n" +
+				"RemainAfterExit=yes\n\n[Install]\nWantedBy={{.WantedBy}}\n",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	deployment.ActiveTarget = "nas"
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	unit, err := os.ReadFile(filepath.Join(provider.Config.UnitDirectory, "nightly-job.service"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(unit), "[Unit]\nDescription=Backup of instance1\n\n[Service]\nType=oneshot\nExecStart=/usr/bin/backup --target nas\nRemainAfterExit=yes\n"))
+
+	component.Properties[UnitTemplateProperty] = "ExecStart={{.Missing}}"
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+}
+
+func TestApplyRestartsOnlyChangedUnits(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ExecStartProperty: "/usr/bin/python3 -m http.server",
+			"env.PORT":        "8080",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, systemctl.count("restart"))
+
+	// nothing changed
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, systemctl.count("restart"))
+
+	// an environment variable changed
+	component.Properties["env.PORT"] = "8081"
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, systemctl.count("restart"))
+
+	// the unit stopped
+	systemctl.setState("instance1-web.service", "inactive", "success")
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, systemctl.count("restart"))
+}
+
+func TestApplyFailedUnit(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+	systemctl.failing["instance1-web.service"] = true
+
+	component := model.ComponentSpec{
+		Name:       "web",
+		Properties: map[string]interface{}{ExecStartProperty: "/usr/bin/false"},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.UpdateFailed, result["web"].Status)
+	assert.Contains(t, result["web"].Message, "failed to start: exit-code")
+}
+
+func TestApplyInvalidProperties(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+
+	properties := []map[string]interface{}{
+		{},
+		{ExecStartProperty: "/bin/true", RestartProperty: "sometimes"},
+		{ExecStartProperty: "/bin/true", TypeProperty: "daemon"},
+		{ExecStartProperty: "/bin/true", DescriptionProperty: "web\nExecStartPre=/bin/evil"},
+		{ExecStartProperty: "/bin/true", "env.NOT-VALID": "1"},
+		{ExecStartProperty: "/bin/true", UnitNameProperty: "../escape"},
+		{ExecStartProperty: "{{.Undefined"},
+		{BinaryProperty: "relative/agent"},
+	}
+	for _, p := range properties {
+		deployment, step := conformance.Deployment("default", "instance1", "update", model.ComponentSpec{Name: "web", Properties: p})
+		result, err := provider.Apply(context.Background(), deployment, step, false)
+		assert.NotNil(t, err, p)
+		assert.Equal(t, v1alpha2.UpdateFailed, result["web"].Status, p)
+	}
+	assert.Empty(t, systemctl.changes())
+}
+
+func TestApplyForeignUnit(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+	unitFile := filepath.Join(provider.Config.UnitDirectory, "sshd.service")
+	assert.Nil(t, os.MkdirAll(provider.Config.UnitDirectory, 0755))
+	assert.Nil(t, os.WriteFile(unitFile, []byte("[Service]\nExecStart=/usr/sbin/sshd\n"), 0644))
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			UnitNameProperty:  "sshd",
+			ExecStartProperty: "/bin/true",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+
+	// nor is it removed
+	deployment, step = conformance.Deployment("default", "instance1", "delete", component)
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	data, err := os.ReadFile(unitFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "[Service]\nExecStart=/usr/sbin/sshd\n", string(data))
+	assert.Empty(t, systemctl.changes())
+}
+
+func TestApplyUnitOfOtherScope(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ExecStartProperty: "/bin/true",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	unitFile := filepath.Join(provider.Config.UnitDirectory, "instance1-web.service")
+	data, err := os.ReadFile(unitFile)
+	assert.Nil(t, err)
+	changes := len(systemctl.changes())
+
+	// an instance of the same name in another scope doesn't own the unit
+	deployment, step = conformance.Deployment("default", "instance1", "update", component)
+	deployment.Instance.Scope = "other"
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.NotNil(t, err)
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Empty(t, components)
+	deployment, step = conformance.Deployment("default", "instance1", "delete", component)
+	deployment.Instance.Scope = "other"
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	current, err := os.ReadFile(unitFile)
+	assert.Nil(t, err)
+	assert.Equal(t, string(data), string(current))
+	assert.Equal(t, changes, len(systemctl.changes()))
+}
+
+func TestGet(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "web",
+		Properties: map[string]interface{}{
+			ExecStartProperty: "/usr/bin/python3 -m http.server",
+			RestartProperty:   "always",
+			"env.INSTANCE":    "${{$instance()}}",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+
+	// a component that isn't deployed isn't returned
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "web", components[0].Name)
+	assert.Equal(t, "/usr/bin/python3 -m http.server", components[0].Properties[ExecStartProperty])
+	assert.Equal(t, "always", components[0].Properties[RestartProperty])
+	assert.Equal(t, "${{$instance()}}", components[0].Properties["env.INSTANCE"])
+	assert.Equal(t, "active", components[0].Properties[StateProperty])
+	assert.Equal(t, "instance1-web.service", components[0].Properties[UnitNameProperty])
+	rule := provider.GetValidationRule(context.Background())
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+
+	// a changed property is detected
+	changed := model.ComponentSpec{Name: "web", Properties: map[string]interface{}{}}
+	for k, v := range component.Properties {
+		changed.Properties[k] = v
+	}
+	changed.Properties[RestartProperty] = "on-failure"
+	assert.True(t, rule.IsComponentChanged(components[0], changed))
+
+	// a failed unit is deployed again
+	systemctl.setState("instance1-web.service", "failed", "exit-code")
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, "failed", components[0].Properties[StateProperty])
+	assert.True(t, rule.IsComponentChanged(components[0], component))
+
+	// another instance's unit isn't returned
+	other, otherStep := conformance.Deployment("default", "instance2", "update", model.ComponentSpec{
+		Name:       "web",
+		Properties: map[string]interface{}{UnitNameProperty: "instance1-web"},
+	})
+	components, err = provider.Get(context.Background(), other, otherStep.Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(components))
+}
+
+func TestGetOneshot(t *testing.T) {
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name: "migrate",
+		Properties: map[string]interface{}{
+			ExecStartProperty: "/usr/bin/migrate",
+			TypeProperty:      "oneshot",
+		},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	rule := provider.GetValidationRule(context.Background())
+
+	// a oneshot unit that ran successfully isn't run again
+	systemctl.setState("instance1-migrate.service", "inactive", "success")
+	components, err := provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.False(t, rule.IsComponentChanged(components[0], component))
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, systemctl.count("restart"))
+
+	systemctl.setState("instance1-migrate.service", "failed", "exit-code")
+	components, err = provider.Get(context.Background(), deployment, step.Components)
+	assert.Nil(t, err)
+	assert.True(t, rule.IsComponentChanged(components[0], component))
+}
+
+func TestRemove(t *testing.T) {
+	binary := []byte("agent")
+	server := serveFiles(t, map[string][]byte{"/agent": binary})
+	provider, systemctl := newTestProvider(t)
+
+	component := model.ComponentSpec{
+		Name:       "agent",
+		Properties: map[string]interface{}{BinaryProperty: server.URL + "/agent"},
+	}
+	deployment, step := conformance.Deployment("default", "instance1", "update", component)
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+
+	deployment, step = conformance.Deployment("default", "instance1", "delete", component)
+	result, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, result["agent"].Status)
+	assert.Equal(t, []string{
+		"daemon-reload", "enable instance1-agent.service", "restart instance1-agent.service",
+		"stop instance1-agent.service", "disable instance1-agent.service", "daemon-reload", "reset-failed instance1-agent.service",
+	}, systemctl.changes())
+	_, err = os.Stat(filepath.Join(provider.Config.UnitDirectory, "instance1-agent.service"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(provider.Config.InstallDirectory, "instance1-agent"))
+	assert.True(t, os.IsNotExist(err))
+
+	// removing a component that isn't deployed succeeds
+	_, err = provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(systemctl.changes()))
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider, _ := newTestProvider(t)
+	conformance.ConformanceSuite(t, provider)
+}
+
+func newTestProvider(t *testing.T) (*SystemdTargetProvider, *fakeSystemctl) {
+	root := t.TempDir()
+	systemctl := &fakeSystemctl{
+		units:         make(map[string]UnitStatus),
+		failing:       make(map[string]bool),
+		unitDirectory: filepath.Join(root, "units"),
+	}
+	provider := &SystemdTargetProvider{Systemctl: systemctl}
+	err := provider.Init(SystemdTargetProviderConfig{
+		UnitDirectory:    systemctl.unitDirectory,
+		InstallDirectory: filepath.Join(root, "apps"),
+	})
+	assert.Nil(t, err)
+	return provider, systemctl
+}
+
+func serveFiles(t *testing.T, files map[string][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gz := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.Nil(t, writer.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := writer.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, gz.Close())
+	return buffer.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0644)
+		w, err := writer.CreateHeader(header)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return buffer.Bytes()
+}
+
+// fakeSystemctl keeps the state of units in memory. Units start, unless they're set to fail, once their
+// unit file is written.
+type fakeSystemctl struct {
+	lock          sync.Mutex
+	units         map[string]UnitStatus
+	failing       map[string]bool
+	unitDirectory string
+	calls         []string
+}
+
+func (f *fakeSystemctl) record(call string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, call)
+}
+
+// changes lists the calls other than show
+func (f *fakeSystemctl) changes() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ret := make([]string, 0)
+	for _, call := range f.calls {
+		if !strings.HasPrefix(call, "show ") {
+			ret = append(ret, call)
+		}
+	}
+	return ret
+}
+
+func (f *fakeSystemctl) count(command string) int {
+	ret := 0
+	for _, call := range f.changes() {
+		if strings.HasPrefix(call, command+" ") {
+			ret++
+		}
+	}
+	return ret
+}
+
+func (f *fakeSystemctl) setState(unit string, activeState string, result string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.units[unit] = UnitStatus{LoadState: "loaded", ActiveState: activeState, Result: result}
+}
+
+func (f *fakeSystemctl) DaemonReload(ctx context.Context) error {
+	f.record("daemon-reload")
+	return nil
+}
+func (f *fakeSystemctl) Enable(ctx context.Context, unit string) error {
+	f.record("enable " + unit)
+	return nil
+}
+func (f *fakeSystemctl) Disable(ctx context.Context, unit string) error {
+	f.record("disable " + unit)
+	return nil
+}
+func (f *fakeSystemctl) Restart(ctx context.Context, unit string) error {
+	f.record("restart " + unit)
+	if _, err := os.Stat(filepath.Join(f.unitDirectory, unit)); err != nil {
+		return fmt.Errorf("Unit %s not found.", unit)
+	}
+	if f.failing[unit] {
+		f.setState(unit, "failed", "exit-code")
+	} else {
+		f.setState(unit, "active", "success")
+	}
+	return nil
+}
+func (f *fakeSystemctl) Stop(ctx context.Context, unit string) error {
+	f.record("stop " + unit)
+	f.setState(unit, "inactive", "success")
+	return nil
+}
+func (f *fakeSystemctl) ResetFailed(ctx context.Context, unit string) error {
+	f.record("reset-failed " + unit)
+	return nil
+}
+func (f *fakeSystemctl) Show(ctx context.Context, unit string) (UnitStatus, error) {
+	f.record("show " + unit)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if status, ok := f.units[unit]; ok {
+		return status, nil
+	}
+	return UnitStatus{LoadState: "not-found", ActiveState: "inactive", Result: "success"}, nil
+}
@@ -7,10 +7,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 )
 
@@ -34,6 +38,36 @@ type SchemaResult struct {
 	Errors map[string]RuleResult `json:"errors,omitempty"`
 }
 
+// ErrorMessage lists the properties that failed validation with their errors, sorted by property
+func (r SchemaResult) ErrorMessage() string {
+	keys := make([]string, 0, len(r.Errors))
+	for k := range r.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	messages := make([]string, 0, len(keys))
+	for _, k := range keys {
+		messages = append(messages, fmt.Sprintf("%s: %s", k, r.Errors[k].Error))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// CheckCatalogSchema checks catalog properties against a schema catalog, whose properties hold the
+// schema under "spec"
+func CheckCatalogSchema(schemaProperties map[string]interface{}, properties map[string]interface{}) (SchemaResult, error) {
+	s, ok := schemaProperties["spec"]
+	if !ok {
+		return SchemaResult{Valid: false}, v1alpha2.NewCOAError(fmt.Errorf("schema not found"), "schema validation error", v1alpha2.ValidateFailed)
+	}
+	var schemaObj Schema
+	jData, _ := json.Marshal(s)
+	err := json.Unmarshal(jData, &schemaObj)
+	if err != nil {
+		return SchemaResult{Valid: false}, v1alpha2.NewCOAError(err, "invalid schema", v1alpha2.ValidateFailed)
+	}
+	return schemaObj.CheckProperties(properties, nil)
+}
+
 func (s *Schema) CheckProperties(properties map[string]interface{}, evaluationContext *coa_utils.EvaluationContext) (SchemaResult, error) {
 	context := evaluationContext
 	if context == nil {
@@ -44,22 +78,22 @@ func (s *Schema) CheckProperties(properties map[string]interface{}, evaluationCo
 		if v.Type != "" {
 			if val, ok := properties[k]; ok {
 				if v.Type == "int" {
-					if _, err := strconv.Atoi(val.(string)); err != nil {
+					if _, err := strconv.Atoi(FormatAsString(val)); err != nil {
 						ret.Valid = false
 						ret.Errors[k] = RuleResult{Valid: false, Error: "property is not an int"}
 					}
 				} else if v.Type == "float" {
-					if _, err := strconv.ParseFloat(val.(string), 64); err != nil {
+					if _, err := strconv.ParseFloat(FormatAsString(val), 64); err != nil {
 						ret.Valid = false
 						ret.Errors[k] = RuleResult{Valid: false, Error: "property is not a float"}
 					}
 				} else if v.Type == "bool" {
-					if _, err := strconv.ParseBool(val.(string)); err != nil {
+					if _, err := strconv.ParseBool(FormatAsString(val)); err != nil {
 						ret.Valid = false
 						ret.Errors[k] = RuleResult{Valid: false, Error: "property is not a bool"}
 					}
 				} else if v.Type == "uint" {
-					if _, err := strconv.ParseUint(val.(string), 10, 64); err != nil {
+					if _, err := strconv.ParseUint(FormatAsString(val), 10, 64); err != nil {
 						ret.Valid = false
 						ret.Errors[k] = RuleResult{Valid: false, Error: "property is not a uint"}
 					}
@@ -79,7 +113,7 @@ func (s *Schema) CheckProperties(properties map[string]interface{}, evaluationCo
 		}
 		if v.Pattern != "" {
 			if val, ok := properties[k]; ok {
-				match, err := s.matchPattern(val.(string), v.Pattern)
+				match, err := s.matchPattern(FormatAsString(val), v.Pattern)
 				if err != nil {
 					ret.Valid = false
 					ret.Errors[k] = RuleResult{Valid: false, Error: "error matching pattern: " + err.Error()}
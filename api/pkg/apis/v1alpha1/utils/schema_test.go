@@ -311,3 +311,50 @@ func TestInExpressionMiss(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, result.Valid)
 }
+func TestTypeNonStringValue(t *testing.T) {
+	schema := Schema{
+		Rules: map[string]Rule{
+			"port": Rule{
+				Type:    "int",
+				Pattern: "<port>",
+			},
+		},
+	}
+	properties := map[string]interface{}{
+		"port": float64(8080),
+	}
+	result, err := schema.CheckProperties(properties, nil)
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+}
+func TestCheckCatalogSchema(t *testing.T) {
+	schemaProperties := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"rules": map[string]interface{}{
+				"email": map[string]interface{}{
+					"pattern": "<email>",
+				},
+				"name": map[string]interface{}{
+					"required": true,
+				},
+			},
+		},
+	}
+	result, err := CheckCatalogSchema(schemaProperties, map[string]interface{}{
+		"name":  "sample",
+		"email": "sample@contoso.com",
+	})
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+
+	result, err = CheckCatalogSchema(schemaProperties, map[string]interface{}{
+		"email": "sample",
+	})
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "email: property does not match pattern: <email>; name: missing required property", result.ErrorMessage())
+}
+func TestCheckCatalogSchemaWithoutSpec(t *testing.T) {
+	_, err := CheckCatalogSchema(map[string]interface{}{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+}
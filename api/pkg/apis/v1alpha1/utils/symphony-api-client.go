@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// CredentialsProvider returns the user name and password used to sign in to the Symphony API. It's
+// called whenever a new access token is needed, so rotated credentials are picked up.
+type CredentialsProvider func(context context.Context) (string, string, error)
+
+// SymphonyAPIClient is a Symphony API client that can be shared by concurrent callers. It caches the
+// access token until shortly before it expires, and signs in again when the API rejects it.
+type SymphonyAPIClient struct {
+	baseUrl     string
+	credentials CredentialsProvider
+	client      *http.Client
+	lock        sync.Mutex
+	token       string
+	expiresAt   time.Time
+}
+
+// NewSymphonyAPIClient creates a client for the API at baseUrl. A nil tlsConfig uses the default
+// TLS settings.
+func NewSymphonyAPIClient(baseUrl string, credentials CredentialsProvider, tlsConfig *tls.Config) *SymphonyAPIClient {
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl += "/"
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &SymphonyAPIClient{
+		baseUrl:     baseUrl,
+		credentials: credentials,
+		client:      &http.Client{Transport: transport},
+	}
+}
+
+func (c *SymphonyAPIClient) GetSummary(context context.Context, id string, scope string) (model.SummaryResult, error) {
+	result := model.SummaryResult{}
+	ret, err := c.callRestAPI(context, "solution/queue?instance="+id+"&scope="+scope, "GET", nil)
+	if err != nil {
+		return result, err
+	}
+	if ret != nil {
+		err = json.Unmarshal(ret, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (c *SymphonyAPIClient) QueueJob(context context.Context, id string, scope string, isDelete bool, isTarget bool) error {
+	path := "solution/queue?instance=" + id
+	if isDelete {
+		path += "&delete=true"
+	}
+	if isTarget {
+		path += "&target=true"
+	}
+	path = path + "&scope=" + scope
+	_, err := c.callRestAPI(context, path, "POST", nil)
+	return err
+}
+
+func (c *SymphonyAPIClient) CatalogHook(context context.Context, payload []byte) error {
+	_, err := c.callRestAPI(context, "federation/k8shook?objectType=catalog", "POST", payload)
+	return err
+}
+
+func (c *SymphonyAPIClient) PublishActivationEvent(context context.Context, event v1alpha2.ActivationData) error {
+	jData, _ := json.Marshal(event)
+	_, err := c.callRestAPI(context, "jobs", "POST", jData)
+	return err
+}
+
+// WatchEvents streams events from the API's events endpoint and calls handler for each event, until
+// the stream ends or ctx is cancelled. parameters, such as topic and kind, filter the stream.
+func (c *SymphonyAPIClient) WatchEvents(ctx context.Context, parameters map[string]string, handler func(model.WatchEvent)) error {
+	query := url.Values{}
+	for k, v := range parameters {
+		if v != "" {
+			query.Add(k, v)
+		}
+	}
+	resp, err := c.openEventStream(ctx, query.Encode())
+	if isUnauthorized(err) {
+		resp, err = c.openEventStream(ctx, query.Encode())
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	err = ReadEventStream(resp.Body, handler)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return v1alpha2.NewCOAError(errors.New("event stream closed by the server"), "failed to watch Symphony API events", v1alpha2.InternalError)
+}
+
+// openEventStream opens an event stream with the cached access token, which is dropped when the API
+// rejects it
+func (c *SymphonyAPIClient) openEventStream(ctx context.Context, query string) (*http.Response, error) {
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseUrl+"events?"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		err = v1alpha2.FromHTTPResponseCode(resp.StatusCode, bodyBytes)
+		if isUnauthorized(err) {
+			c.invalidateToken(token)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// callRestAPI calls the API with the cached access token. A request rejected as unauthorized is
+// retried once with a new token, as the API may have been restarted or revoked the token.
+func (c *SymphonyAPIClient) callRestAPI(context context.Context, route string, method string, payload []byte) ([]byte, error) {
+	token, err := c.getToken(context)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := callRestAPIWithClient(context, c.client, c.baseUrl, route, method, payload, token)
+	if isUnauthorized(err) {
+		c.invalidateToken(token)
+		token, err = c.getToken(context)
+		if err != nil {
+			return nil, err
+		}
+		ret, err = callRestAPIWithClient(context, c.client, c.baseUrl, route, method, payload, token)
+	}
+	return ret, err
+}
+
+func (c *SymphonyAPIClient) getToken(context context.Context) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.token != "" && (c.expiresAt.IsZero() || time.Until(c.expiresAt) > coa_utils.TokenExpiryMargin) {
+		return c.token, nil
+	}
+	user, password, err := c.credentials(context)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to get Symphony API credentials", v1alpha2.Unauthorized)
+	}
+	requestData, _ := json.Marshal(authRequest{Username: user, Password: password})
+	ret, err := callRestAPIWithClient(context, c.client, c.baseUrl, "users/auth", "POST", requestData, "")
+	if err != nil {
+		return "", err
+	}
+	var response authResponse
+	err = json.Unmarshal(ret, &response)
+	if err != nil {
+		return "", err
+	}
+	if response.AccessToken == "" {
+		return "", v1alpha2.NewCOAError(nil, "Symphony API didn't return an access token", v1alpha2.Unauthorized)
+	}
+	c.token = response.AccessToken
+	// tokens without a known expiry are kept until the API rejects them
+	c.expiresAt = coa_utils.TokenExpiry(response.AccessToken)
+	if response.ExpiresIn > 0 {
+		c.expiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return c.token, nil
+}
+
+// invalidateToken drops the cached token, unless another caller has already replaced it
+func (c *SymphonyAPIClient) invalidateToken(token string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.token == token {
+		c.token = ""
+		c.expiresAt = time.Time{}
+	}
+}
+
+func isUnauthorized(err error) bool {
+	coaE, ok := err.(v1alpha2.COAError)
+	return ok && coaE.State == v1alpha2.Unauthorized
+}
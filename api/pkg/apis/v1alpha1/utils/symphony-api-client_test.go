@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAPI struct {
+	server    *httptest.Server
+	auths     int32
+	expiresIn int64
+	token     string
+	user      string
+}
+
+func newFakeAPI(expiresIn int64) *fakeAPI {
+	f := &fakeAPI{expiresIn: expiresIn, token: "token-0"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1alpha2/users/auth", func(w http.ResponseWriter, r *http.Request) {
+		var request authRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.user = request.Username
+		n := atomic.AddInt32(&f.auths, 1)
+		f.token = fmt.Sprintf("token-%d", n)
+		json.NewEncoder(w).Encode(authResponse{AccessToken: f.token, TokenType: "Bearer", ExpiresIn: f.expiresIn})
+	})
+	mux.HandleFunc("/v1alpha2/solution/queue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(model.SummaryResult{Generation: r.URL.Query().Get("instance")})
+	})
+	mux.HandleFunc("/v1alpha2/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		for i, name := range []string{"instance1", "instance2"} {
+			data, _ := json.Marshal(model.WatchEvent{Id: uint64(i + 1), Topic: r.URL.Query().Get("topic"), Kind: "instance", Name: name})
+			fmt.Fprintf(w, "id: %d\nevent: summary\ndata: %s\n\n", i+1, data)
+		}
+	})
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+func staticCredentials(context.Context) (string, string, error) {
+	return "admin", "", nil
+}
+
+func TestSymphonyAPIClientCachesToken(t *testing.T) {
+	api := newFakeAPI(3600)
+	defer api.server.Close()
+
+	client := NewSymphonyAPIClient(api.server.URL+"/v1alpha2", staticCredentials, nil)
+	for i := 0; i < 3; i++ {
+		summary, err := client.GetSummary(context.Background(), "instance1", "default")
+		assert.Nil(t, err)
+		assert.Equal(t, "instance1", summary.Generation)
+	}
+	assert.Equal(t, int32(1), api.auths)
+	assert.Equal(t, "admin", api.user)
+}
+
+func TestSymphonyAPIClientRenewsExpiringToken(t *testing.T) {
+	// tokens expiring within the renewal margin are never reused
+	api := newFakeAPI(10)
+	defer api.server.Close()
+
+	client := NewSymphonyAPIClient(api.server.URL+"/v1alpha2/", staticCredentials, nil)
+	for i := 0; i < 2; i++ {
+		_, err := client.GetSummary(context.Background(), "instance1", "default")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(2), api.auths)
+}
+
+func TestSymphonyAPIClientRetriesRejectedToken(t *testing.T) {
+	api := newFakeAPI(3600)
+	defer api.server.Close()
+
+	client := NewSymphonyAPIClient(api.server.URL+"/v1alpha2/", staticCredentials, nil)
+	_, err := client.GetSummary(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+
+	// the API forgets the issued token, as it does when restarted
+	api.token = "revoked"
+	_, err = client.GetSummary(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), api.auths)
+}
+
+func TestSymphonyAPIClientCredentialsError(t *testing.T) {
+	api := newFakeAPI(3600)
+	defer api.server.Close()
+
+	client := NewSymphonyAPIClient(api.server.URL+"/v1alpha2/", func(context.Context) (string, string, error) {
+		return "", "", errors.New("secret not found")
+	}, nil)
+	_, err := client.GetSummary(context.Background(), "instance1", "default")
+	assert.True(t, isUnauthorized(err))
+	assert.Equal(t, int32(0), api.auths)
+}
+
+func TestSymphonyAPIClientWatchEvents(t *testing.T) {
+	api := newFakeAPI(3600)
+	defer api.server.Close()
+
+	client := NewSymphonyAPIClient(api.server.URL+"/v1alpha2/", staticCredentials, nil)
+	_, err := client.GetSummary(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	api.token = "revoked"
+
+	events := make([]model.WatchEvent, 0)
+	err = client.WatchEvents(context.Background(), map[string]string{"topic": "summary"}, func(event model.WatchEvent) {
+		events = append(events, event)
+	})
+	// the stream ends when the fake API returns
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), api.auths)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "summary", events[0].Topic)
+	assert.Equal(t, "instance1", events[0].Name)
+	assert.Equal(t, "instance2", events[1].Name)
+}
@@ -34,6 +34,7 @@ type authRequest struct {
 type authResponse struct {
 	AccessToken string   `json:"accessToken"`
 	TokenType   string   `json:"tokenType"`
+	ExpiresIn   int64    `json:"expiresIn,omitempty"`
 	Username    string   `json:"username"`
 	Roles       []string `json:"roles"`
 }
@@ -533,26 +534,9 @@ func CreateTarget(context context.Context, baseUrl string, target string, user s
 
 func MatchTargets(instance model.InstanceState, targets []model.TargetState) []model.TargetState {
 	ret := make(map[string]model.TargetState)
-	if instance.Spec.Target.Name != "" {
-		for _, t := range targets {
-			if matchString(instance.Spec.Target.Name, t.Id) {
-				ret[t.Id] = t
-			}
-		}
-	}
-
-	if len(instance.Spec.Target.Selector) > 0 {
-		for _, t := range targets {
-			fullMatch := true
-			for k, v := range instance.Spec.Target.Selector {
-				if tv, ok := t.Spec.Properties[k]; !ok || !matchString(v, tv) {
-					fullMatch = false
-				}
-			}
-
-			if fullMatch {
-				ret[t.Id] = t
-			}
+	for _, t := range targets {
+		if MatchTarget(instance, t) {
+			ret[t.Id] = t
 		}
 	}
 
@@ -564,6 +548,22 @@ func MatchTargets(instance model.InstanceState, targets []model.TargetState) []m
 	return slice
 }
 
+// MatchTarget checks if an instance is deployed to a target, either by target name or by selector
+func MatchTarget(instance model.InstanceState, target model.TargetState) bool {
+	if instance.Spec.Target.Name != "" && matchString(instance.Spec.Target.Name, target.Id) {
+		return true
+	}
+	if len(instance.Spec.Target.Selector) > 0 {
+		for k, v := range instance.Spec.Target.Selector {
+			if tv, ok := target.Spec.Properties[k]; !ok || !matchString(v, tv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func CreateSymphonyDeploymentFromTarget(target model.TargetState) (model.DeploymentSpec, error) {
 	key := fmt.Sprintf("%s-%s", "target-runtime", target.Id)
 	scope := target.Spec.Scope
@@ -783,6 +783,9 @@ func auth(context context.Context, baseUrl string, user string, password string)
 	return response.AccessToken, nil
 }
 func callRestAPI(context context.Context, baseUrl string, route string, method string, payload []byte, token string) ([]byte, error) {
+	return callRestAPIWithClient(context, &http.Client{}, baseUrl, route, method, payload, token)
+}
+func callRestAPIWithClient(context context.Context, client *http.Client, baseUrl string, route string, method string, payload []byte, token string) ([]byte, error) {
 	context, span := observability.StartSpan("Symphony-API-Client", context, &map[string]string{
 		"method":      "callRestAPI",
 		"http.method": method,
@@ -793,7 +796,6 @@ func callRestAPI(context context.Context, baseUrl string, route string, method s
 
 	log.Infof("Calling Symphony API: %s %s, spanId: %s, traceId: %s", method, baseUrl+route, span.SpanContext().SpanID().String(), span.SpanContext().TraceID().String())
 
-	client := &http.Client{}
 	rUrl := baseUrl + route
 	var req *http.Request
 	if payload != nil {
@@ -0,0 +1,334 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/valyala/fasthttp"
+)
+
+var evLog = logger.NewLogger("coa.runtime")
+
+const (
+	defaultEventTopics      = "job,job-report,activation,trigger,summary"
+	defaultEventHeartbeat   = 15 * time.Second
+	defaultEventBufferSize  = 100
+	eventStreamContentType  = "text/event-stream"
+	eventStreamKeepAlive    = ": keep-alive\n\n"
+	eventStreamConnected    = ": connected\n\n"
+	eventFilterKindCampaign = "campaign"
+)
+
+// EventsVendor relays pub/sub events to clients as Server-Sent Events
+type EventsVendor struct {
+	vendors.Vendor
+	Topics     []string
+	Heartbeat  time.Duration
+	BufferSize int
+	lock       sync.RWMutex
+	watchers   map[*eventWatcher]struct{}
+	sequence   uint64
+}
+
+type eventWatcher struct {
+	filter eventFilter
+	events chan model.WatchEvent
+}
+
+type eventFilter struct {
+	topics map[string]bool
+	kind   string
+	name   string
+	scope  string
+}
+
+func (o *EventsVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "Events",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *EventsVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	err := e.Vendor.Init(config, factories, providers, pubsubProvider)
+	if err != nil {
+		return err
+	}
+	topics := defaultEventTopics
+	e.Heartbeat = defaultEventHeartbeat
+	e.BufferSize = defaultEventBufferSize
+	if config.Properties != nil {
+		if v, ok := config.Properties["topics"]; ok && v != "" {
+			topics = v
+		}
+		if v, ok := config.Properties["heartbeatSeconds"]; ok {
+			if i, err := strconv.Atoi(v); err == nil && i > 0 {
+				e.Heartbeat = time.Duration(i) * time.Second
+			}
+		}
+		if v, ok := config.Properties["bufferSize"]; ok {
+			if i, err := strconv.Atoi(v); err == nil && i > 0 {
+				e.BufferSize = i
+			}
+		}
+	}
+	e.watchers = make(map[*eventWatcher]struct{})
+	e.Topics = make([]string, 0)
+	for _, t := range strings.Split(topics, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		e.Topics = append(e.Topics, t)
+		err = e.Vendor.Context.Subscribe(t, e.relay)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *EventsVendor) GetEndpoints() []v1alpha2.Endpoint {
+	route := "events"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   route,
+			Version: o.Version,
+			Handler: o.onEvents,
+		},
+	}
+}
+
+func (c *EventsVendor) onEvents(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	_, span := observability.StartSpan("Events Vendor", request.Context, &map[string]string{
+		"method": "onEvents",
+	})
+	evLog.Info("V (Events): onEvents")
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		filter, err := c.parseFilter(request.Parameters)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State:       v1alpha2.BadRequest,
+				Body:        []byte(err.Error()),
+				ContentType: "text/plain",
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			ContentType: eventStreamContentType,
+			Stream:      c.stream(filter),
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	span.End()
+	return resp
+}
+
+// parseFilter reads the topic, kind, name and scope query parameters. Topics are comma-separated and
+// must be relayed by this vendor.
+func (c *EventsVendor) parseFilter(parameters map[string]string) (eventFilter, error) {
+	filter := eventFilter{
+		kind:  normalizeEventKind(parameters["kind"]),
+		name:  parameters["name"],
+		scope: parameters["scope"],
+	}
+	if v := parameters["topic"]; v != "" {
+		filter.topics = make(map[string]bool)
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !c.relays(t) {
+				return filter, v1alpha2.NewCOAError(nil, fmt.Sprintf("topic '%s' is not relayed", t), v1alpha2.BadRequest)
+			}
+			filter.topics[t] = true
+		}
+	}
+	return filter, nil
+}
+
+func (c *EventsVendor) relays(topic string) bool {
+	for _, t := range c.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *EventsVendor) addWatcher(filter eventFilter) *eventWatcher {
+	watcher := &eventWatcher{
+		filter: filter,
+		events: make(chan model.WatchEvent, c.BufferSize),
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.watchers[watcher] = struct{}{}
+	return watcher
+}
+
+func (c *EventsVendor) removeWatcher(watcher *eventWatcher) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.watchers, watcher)
+}
+
+// relay hands an event to all matching watchers. A watcher whose buffer is full misses the event
+// instead of holding up the publisher.
+func (c *EventsVendor) relay(topic string, event v1alpha2.Event) error {
+	watchEvent := toWatchEvent(topic, event)
+	watchEvent.Id = atomic.AddUint64(&c.sequence, 1)
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for w := range c.watchers {
+		if !w.filter.matches(watchEvent) {
+			continue
+		}
+		select {
+		case w.events <- watchEvent:
+		default:
+			evLog.Debugf("V (Events): watcher buffer is full, dropping %s event %d", topic, watchEvent.Id)
+		}
+	}
+	return nil
+}
+
+// stream writes the matching events until the client disconnects. The watcher only exists while the
+// stream is written, so bindings that don't stream the response never register one. Comments are sent
+// on connect and every heartbeat to keep proxies from closing idle streams, and to detect disconnected
+// clients.
+func (c *EventsVendor) stream(filter eventFilter) v1alpha2.StreamWriter {
+	return func(w io.Writer, flush func() error) {
+		watcher := c.addWatcher(filter)
+		defer c.removeWatcher(watcher)
+		ticker := time.NewTicker(c.Heartbeat)
+		defer ticker.Stop()
+		_, err := io.WriteString(w, eventStreamConnected)
+		for err == nil {
+			err = flush()
+			if err != nil {
+				break
+			}
+			select {
+			case event := <-watcher.events:
+				err = writeWatchEvent(w, event)
+			case <-ticker.C:
+				_, err = io.WriteString(w, eventStreamKeepAlive)
+			}
+		}
+		evLog.Debugf("V (Events): watcher disconnected: %v", err)
+	}
+}
+
+func writeWatchEvent(w io.Writer, event model.WatchEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Topic, data)
+	return err
+}
+
+// toWatchEvent works out which object an event is about. Event bodies are normalized to JSON maps, as
+// in-memory pub/sub passes the published structs while Redis passes decoded JSON.
+func toWatchEvent(topic string, event v1alpha2.Event) model.WatchEvent {
+	ret := model.WatchEvent{
+		Topic:    topic,
+		Time:     time.Now().UTC(),
+		Metadata: event.Metadata,
+		Body:     event.Body,
+		Scope:    event.Metadata["scope"],
+	}
+	var body map[string]interface{}
+	if data, err := json.Marshal(event.Body); err == nil {
+		if json.Unmarshal(data, &body) == nil {
+			ret.Body = body
+		}
+	}
+	switch topic {
+	case "job":
+		ret.Kind = event.Metadata["objectType"]
+		ret.Name = stringField(body, "id")
+	case "activation", "trigger":
+		ret.Kind = "activation"
+		ret.Name = stringField(body, "activation")
+		ret.Campaign = stringField(body, "campaign")
+	case "job-report":
+		ret.Kind = "activation"
+		outputs, _ := body["outputs"].(map[string]interface{})
+		ret.Name = stringField(outputs, "__activation")
+		ret.Campaign = stringField(outputs, "__campaign")
+	case "summary":
+		ret.Kind = "instance"
+		ret.Name = event.Metadata["instance"]
+	default:
+		ret.Kind = event.Metadata["objectType"]
+	}
+	return ret
+}
+
+func stringField(values map[string]interface{}, key string) string {
+	if v, ok := values[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func normalizeEventKind(kind string) string {
+	return strings.TrimSuffix(strings.ToLower(kind), "s")
+}
+
+// matches checks an event against the filter. Filtering by the campaign kind matches the events of the
+// campaign's activations. Events that carry no scope, such as activation events, match any scope.
+func (f eventFilter) matches(event model.WatchEvent) bool {
+	if f.topics != nil && !f.topics[event.Topic] {
+		return false
+	}
+	name := event.Name
+	if f.kind == eventFilterKindCampaign {
+		if event.Campaign == "" {
+			return false
+		}
+		name = event.Campaign
+	} else if f.kind != "" && normalizeEventKind(event.Kind) != f.kind {
+		return false
+	}
+	if f.name != "" && name != f.name {
+		return false
+	}
+	if f.scope != "" && event.Scope != "" && event.Scope != f.scope {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func createEventsVendor(t *testing.T) (*EventsVendor, *memory.InMemoryPubSubProvider) {
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor := EventsVendor{}
+	err := vendor.Init(vendors.VendorConfig{
+		Properties: map[string]string{
+			"heartbeatSeconds": "1",
+		},
+	}, []managers.IManagerFactroy{}, map[string]map[string]providers.IProvider{}, &pubSubProvider)
+	assert.Nil(t, err)
+	return &vendor, &pubSubProvider
+}
+
+func TestEventsVendorInit(t *testing.T) {
+	vendor, _ := createEventsVendor(t)
+	assert.Equal(t, []string{"job", "job-report", "activation", "trigger", "summary"}, vendor.Topics)
+	assert.Equal(t, time.Second, vendor.Heartbeat)
+	assert.Equal(t, defaultEventBufferSize, vendor.BufferSize)
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, "events", endpoints[0].Route)
+}
+
+func TestEventsVendorRejectsUnknownTopic(t *testing.T) {
+	vendor, _ := createEventsVendor(t)
+	resp := vendor.onEvents(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "job,secrets"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	assert.Nil(t, resp.Stream)
+}
+
+func TestEventsVendorFilter(t *testing.T) {
+	job := toWatchEvent("job", v1alpha2.Event{
+		Metadata: map[string]string{"objectType": "instance", "scope": "default"},
+		Body:     v1alpha2.JobData{Id: "instance-1", Action: "UPDATE"},
+	})
+	assert.Equal(t, "instance", job.Kind)
+	assert.Equal(t, "instance-1", job.Name)
+	report := toWatchEvent("job-report", v1alpha2.Event{
+		Body: model.ActivationStatus{
+			Stage:   "deploy",
+			Outputs: map[string]interface{}{"__campaign": "campaign-1", "__activation": "activation-1"},
+		},
+	})
+	assert.Equal(t, "activation-1", report.Name)
+	assert.Equal(t, "campaign-1", report.Campaign)
+
+	assert.True(t, eventFilter{kind: "instance", name: "instance-1", scope: "default"}.matches(job))
+	assert.False(t, eventFilter{kind: "instance", name: "instance-2"}.matches(job))
+	assert.False(t, eventFilter{kind: "target"}.matches(job))
+	assert.False(t, eventFilter{scope: "other"}.matches(job))
+	assert.False(t, eventFilter{topics: map[string]bool{"summary": true}}.matches(job))
+	assert.True(t, eventFilter{kind: "campaign", name: "campaign-1", scope: "other"}.matches(report))
+	assert.False(t, eventFilter{kind: "campaign", name: "campaign-2"}.matches(report))
+	assert.False(t, eventFilter{kind: "campaign"}.matches(job))
+}
+
+func TestEventsVendorStream(t *testing.T) {
+	vendor, pubSubProvider := createEventsVendor(t)
+	resp := vendor.onEvents(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"kind": "instances", "name": "instance-1"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, "text/event-stream", resp.ContentType)
+	assert.NotNil(t, resp.Stream)
+	// the watcher is registered by the stream, so a response that's never streamed doesn't leak one
+	vendor.lock.RLock()
+	assert.Equal(t, 0, len(vendor.watchers))
+	vendor.lock.RUnlock()
+
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		resp.Stream(writer, func() error { return nil })
+		close(done)
+	}()
+
+	lines := bufio.NewReader(reader)
+	line, err := lines.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	pubSubProvider.Publish("summary", v1alpha2.Event{
+		Metadata: map[string]string{"objectType": "instance", "instance": "instance-2"},
+		Body:     model.SummaryResult{Generation: "1"},
+	})
+	time.Sleep(100 * time.Millisecond)
+	pubSubProvider.Publish("summary", v1alpha2.Event{
+		Metadata: map[string]string{"objectType": "instance", "instance": "instance-1"},
+		Body:     model.SummaryResult{Generation: "2"},
+	})
+
+	var event model.WatchEvent
+	for event.Topic == "" {
+		line, err = lines.ReadString('\n')
+		assert.Nil(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		}
+	}
+	assert.Equal(t, "summary", event.Topic)
+	assert.Equal(t, "instance-1", event.Name)
+	assert.Equal(t, "2", event.Body.(map[string]interface{})["generation"])
+
+	reader.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stream didn't stop after the client disconnected")
+	}
+	vendor.lock.RLock()
+	defer vendor.lock.RUnlock()
+	assert.Equal(t, 0, len(vendor.watchers))
+}
@@ -9,7 +9,7 @@ package vendors
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/users"
@@ -22,14 +22,62 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
 var rLog = logger.NewLogger("coa.runtime")
 
+const (
+	defaultSigningKey           = "SymphonyKey"
+	defaultAccessTokenLifetime  = 24 * time.Hour
+	defaultRefreshTokenLifetime = 7 * 24 * time.Hour
+)
+
 type UsersVendor struct {
 	vendors.Vendor
-	UsersManager *users.UsersManager
+	UsersManager         *users.UsersManager
+	SigningKey           []byte
+	AccessTokenLifetime  time.Duration
+	RefreshTokenLifetime time.Duration
+}
+
+type UserClaims struct {
+	User  string   `json:"user"`
+	Roles []string `json:"roles,omitempty"`
+	// IssuedAtMillis is when the token was issued in unix milliseconds, as iat only has whole seconds
+	IssuedAtMillis int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
+type AuthResponse struct {
+	AccessToken  string   `json:"accessToken"`
+	TokenType    string   `json:"tokenType"`
+	ExpiresIn    int64    `json:"expiresIn"`
+	RefreshToken string   `json:"refreshToken,omitempty"`
+	UserName     string   `json:"username"`
+	Roles        []string `json:"roles"`
+}
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+type RevokeRequest struct {
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+type ChangePasswordRequest struct {
+	UserName    string `json:"username"`
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+type UserRequest struct {
+	UserName string   `json:"username"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles,omitempty"`
+}
+type UserInfo struct {
+	UserName string   `json:"username"`
+	Roles    []string `json:"roles"`
+	Locked   bool     `json:"locked,omitempty"`
 }
 
 func (o *UsersVendor) GetInfo() vendors.VendorInfo {
@@ -53,6 +101,24 @@ func (e *UsersVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	if e.UsersManager == nil {
 		return v1alpha2.NewCOAError(nil, "users manager is not supplied", v1alpha2.MissingConfig)
 	}
+	e.SigningKey = []byte(defaultSigningKey)
+	e.AccessTokenLifetime = defaultAccessTokenLifetime
+	e.RefreshTokenLifetime = defaultRefreshTokenLifetime
+	if config.Properties != nil {
+		if v, ok := config.Properties["signingKey"]; ok && v != "" {
+			e.SigningKey = []byte(v)
+		}
+		if v, ok := config.Properties["accessTokenLifetime"]; ok {
+			if i, err := strconv.Atoi(v); err == nil {
+				e.AccessTokenLifetime = time.Duration(i) * time.Second
+			}
+		}
+		if v, ok := config.Properties["refreshTokenLifetime"]; ok {
+			if i, err := strconv.Atoi(v); err == nil {
+				e.RefreshTokenLifetime = time.Duration(i) * time.Second
+			}
+		}
+	}
 	if config.Properties != nil && config.Properties["test-users"] == "true" {
 		e.UsersManager.UpsertUser(context.Background(), "admin", "", nil)
 		e.UsersManager.UpsertUser(context.Background(), "reader", "", nil)
@@ -70,6 +136,38 @@ func (o *UsersVendor) GetEndpoints() []v1alpha2.Endpoint {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route + "/registry",
+			Version:    o.Version,
+			Handler:    o.onRegistry,
+			Parameters: []string{"name?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost},
+			Route:      route + "/roles",
+			Version:    o.Version,
+			Handler:    o.onRoles,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/password",
+			Version: o.Version,
+			Handler: o.onPassword,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/revoke",
+			Version: o.Version,
+			Handler: o.onRevoke,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/refresh",
+			Version: o.Version,
+			Handler: o.onRefresh,
+		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/auth",
@@ -101,31 +199,337 @@ func (c *UsersVendor) onAuth(request v1alpha2.COARequest) v1alpha2.COAResponse {
 			Body:  []byte("login failed"),
 		})
 	}
+	return observ_utils.CloseSpanWithCOAResponse(span, c.issueTokens(ctx, authRequest.UserName, roles))
+}
 
-	mySigningKey := []byte("SymphonyKey")
-	claims := MyCustomClaims{
-		authRequest.UserName,
-		jwt.RegisteredClaims{
-			// A usual scenario is to set the expiration time relative to the current time
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "symphony",
-			Subject:   "symphony",
-			ID:        "1",
-			Audience:  []string{"*"},
-		},
+func (c *UsersVendor) onRefresh(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRefresh",
+	})
+	defer span.End()
+	log.Debug("V (Users): refresh token")
+
+	var refreshRequest RefreshRequest
+	err := json.Unmarshal(request.Body, &refreshRequest)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte(err.Error()),
+		})
+	}
+	name, roles, err := c.UsersManager.RedeemRefreshToken(ctx, refreshRequest.RefreshToken)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("refresh failed"),
+		})
 	}
+	return observ_utils.CloseSpanWithCOAResponse(span, c.issueTokens(ctx, name, roles))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, _ := token.SignedString(mySigningKey)
+func (c *UsersVendor) onRevoke(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRevoke",
+	})
+	defer span.End()
+	log.Debug("V (Users): revoke token")
+
+	var revokeRequest RevokeRequest
+	err := json.Unmarshal(request.Body, &revokeRequest)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte(err.Error()),
+		})
+	}
+	if revokeRequest.RefreshToken != "" {
+		// revoking an unknown refresh token is not an error, so callers can't probe for valid tokens
+		c.UsersManager.RevokeRefreshToken(ctx, revokeRequest.RefreshToken)
+	}
+	if revokeRequest.AccessToken != "" {
+		claims := UserClaims{}
+		_, err := jwt.ParseWithClaims(revokeRequest.AccessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+			return c.SigningKey, nil
+		})
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("access token is invalid"),
+			})
+		}
+		data := v1alpha2.TokenRevocationData{
+			TokenId: claims.ID,
+		}
+		if claims.ExpiresAt != nil {
+			data.ExpiresAt = claims.ExpiresAt.Unix()
+		}
+		c.publishRevocation(data)
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
+
+func (c *UsersVendor) onPassword(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onPassword",
+	})
+	defer span.End()
+	log.Debug("V (Users): change password")
+
+	var passwordRequest ChangePasswordRequest
+	err := json.Unmarshal(request.Body, &passwordRequest)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte(err.Error()),
+		})
+	}
+	err = c.UsersManager.ChangePassword(ctx, passwordRequest.UserName, passwordRequest.OldPassword, passwordRequest.NewPassword)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("password change failed"),
+		})
+	}
+	c.revokeUserTokens(passwordRequest.UserName)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
+
+func (c *UsersVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRegistry",
+	})
+	defer span.End()
+	log.Debug("V (Users): onRegistry")
 
-	rolesJSON, _ := json.Marshal(roles)
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onRegistry-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		var state interface{}
+		if id == "" {
+			list, err := c.UsersManager.ListUsers(ctx)
+			if err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.InternalError,
+					Body:  []byte(err.Error()),
+				})
+			}
+			infos := make([]UserInfo, 0, len(list))
+			for _, u := range list {
+				infos = append(infos, toUserInfo(u))
+			}
+			state = infos
+		} else {
+			user, err := c.UsersManager.GetUser(ctx, id)
+			if err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.NotFound,
+					Body:  []byte(err.Error()),
+				})
+			}
+			state = toUserInfo(user)
+		}
+		jData, _ := json.Marshal(state)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onRegistry-POST", pCtx, nil)
+		var userRequest UserRequest
+		err := json.Unmarshal(request.Body, &userRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		if id := request.Parameters["__name"]; id != "" {
+			userRequest.UserName = id
+		}
+		if userRequest.UserName == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("username is required"),
+			})
+		}
+		err = c.UsersManager.UpsertUser(ctx, userRequest.UserName, userRequest.Password, userRequest.Roles)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		c.revokeUserTokens(userRequest.UserName)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	case fasthttp.MethodDelete:
+		ctx, span := observability.StartSpan("onRegistry-DELETE", pCtx, nil)
+		id := request.Parameters["__name"]
+		err := c.UsersManager.DeleteUser(ctx, id)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		c.revokeUserTokens(id)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
 	resp := v1alpha2.COAResponse{
-		State:       v1alpha2.OK,
-		Body:        []byte(fmt.Sprintf(`{"accessToken":"%s", "tokenType": "Bearer", "username": "%s", "roles": %s}`, ss, authRequest.UserName, rolesJSON)),
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *UsersVendor) onRoles(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRoles",
+	})
+	defer span.End()
+	log.Debug("V (Users): onRoles")
+
+	id := request.Parameters["__name"]
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onRoles-GET", pCtx, nil)
+		user, err := c.UsersManager.GetUser(ctx, id)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.NotFound,
+				Body:  []byte(err.Error()),
+			})
+		}
+		roles := user.Roles
+		if roles == nil {
+			roles = []string{}
+		}
+		jData, _ := json.Marshal(roles)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onRoles-POST", pCtx, nil)
+		var roles []string
+		err := json.Unmarshal(request.Body, &roles)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		err = c.UsersManager.SetRoles(ctx, id, roles)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.NotFound,
+				Body:  []byte(err.Error()),
+			})
+		}
+		// tokens carry roles, so tokens issued with the old roles are revoked
+		c.revokeUserTokens(id)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
 		ContentType: "application/json",
 	}
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *UsersVendor) issueTokens(ctx context.Context, name string, roles []string) v1alpha2.COAResponse {
+	now := time.Now()
+	claims := UserClaims{
+		User:           name,
+		Roles:          roles,
+		IssuedAtMillis: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(c.AccessTokenLifetime)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "symphony",
+			Subject:   name,
+			ID:        uuid.New().String(),
+			Audience:  []string{"*"},
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	ss, err := token.SignedString(c.SigningKey)
+	if err != nil {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		}
+	}
+	refreshToken, err := c.UsersManager.IssueRefreshToken(ctx, name, c.RefreshTokenLifetime)
+	if err != nil {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		}
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	jData, _ := json.Marshal(AuthResponse{
+		AccessToken:  ss,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(c.AccessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		UserName:     name,
+		Roles:        roles,
+	})
+	return v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        jData,
+		ContentType: "application/json",
+	}
+}
+
+// revokeUserTokens revokes all access tokens issued to a user so far
+func (c *UsersVendor) revokeUserTokens(name string) {
+	c.publishRevocation(v1alpha2.TokenRevocationData{
+		User:      name,
+		RevokedAt: time.Now().UnixMilli(),
+	})
+}
+
+func (c *UsersVendor) publishRevocation(data v1alpha2.TokenRevocationData) {
+	if c.Context == nil {
+		return
+	}
+	err := c.Context.Publish(v1alpha2.TokenRevocationTopic, v1alpha2.Event{
+		Body: data,
+	})
+	if err != nil {
+		rLog.Errorf("V (Users): failed to publish token revocation - %s", err.Error())
+	}
+}
+
+func toUserInfo(user users.UserState) UserInfo {
+	roles := user.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return UserInfo{
+		UserName: user.Id,
+		Roles:    roles,
+		Locked:   user.LockedUntil > time.Now().Unix(),
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/stretchr/testify/assert"
@@ -95,3 +96,103 @@ func TestUsersEndpoints(t *testing.T) {
 	assert.NotNil(t, endpoints)
 	assert.Equal(t, "user/auth", endpoints[len(endpoints)-1].Route)
 }
+
+func TestAuthIssuesRefreshToken(t *testing.T) {
+	vendor := initVendor(t)
+	data, _ := json.Marshal(AuthRequest{UserName: "admin", Password: ""})
+	response := vendor.onAuth(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var auth AuthResponse
+	err := json.Unmarshal(response.Body, &auth)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, auth.AccessToken)
+	assert.NotEmpty(t, auth.RefreshToken)
+
+	data, _ = json.Marshal(RefreshRequest{RefreshToken: auth.RefreshToken})
+	response = vendor.onRefresh(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var refreshed AuthResponse
+	err = json.Unmarshal(response.Body, &refreshed)
+	assert.Nil(t, err)
+	assert.NotEqual(t, auth.RefreshToken, refreshed.RefreshToken)
+
+	// the original refresh token has been rotated out
+	response = vendor.onRefresh(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+}
+
+func TestRevokePublishesEvent(t *testing.T) {
+	pubSub := &memory.InMemoryPubSubProvider{}
+	pubSub.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor := initVendor(t)
+	vendor.Context.PubsubProvider = pubSub
+	sig := make(chan v1alpha2.TokenRevocationData)
+	pubSub.Subscribe(v1alpha2.TokenRevocationTopic, func(topic string, event v1alpha2.Event) error {
+		sig <- event.Body.(v1alpha2.TokenRevocationData)
+		return nil
+	})
+
+	data, _ := json.Marshal(AuthRequest{UserName: "admin", Password: ""})
+	response := vendor.onAuth(v1alpha2.COARequest{Context: context.Background(), Method: "POST", Body: data})
+	var auth AuthResponse
+	json.Unmarshal(response.Body, &auth)
+
+	data, _ = json.Marshal(RevokeRequest{AccessToken: auth.AccessToken, RefreshToken: auth.RefreshToken})
+	response = vendor.onRevoke(v1alpha2.COARequest{Context: context.Background(), Method: "POST", Body: data})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	revocation := <-sig
+	assert.NotEmpty(t, revocation.TokenId)
+
+	data, _ = json.Marshal(RefreshRequest{RefreshToken: auth.RefreshToken})
+	response = vendor.onRefresh(v1alpha2.COARequest{Context: context.Background(), Method: "POST", Body: data})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+}
+
+func TestChangePasswordAndRoles(t *testing.T) {
+	vendor := initVendor(t)
+	data, _ := json.Marshal(ChangePasswordRequest{UserName: "admin", OldPassword: "", NewPassword: "secret"})
+	response := vendor.onPassword(v1alpha2.COARequest{Context: context.Background(), Method: "POST", Body: data})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	data, _ = json.Marshal(AuthRequest{UserName: "admin", Password: "secret"})
+	response = vendor.onAuth(v1alpha2.COARequest{Context: context.Background(), Method: "POST", Body: data})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	data, _ = json.Marshal([]string{"administrator"})
+	response = vendor.onRoles(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "POST",
+		Body:       data,
+		Parameters: map[string]string{"__name": "admin"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	response = vendor.onRegistry(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "GET",
+		Parameters: map[string]string{},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var list []UserInfo
+	err := json.Unmarshal(response.Body, &list)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(list))
+	for _, u := range list {
+		if u.UserName == "admin" {
+			assert.Equal(t, []string{"administrator"}, u.Roles)
+		}
+	}
+	assert.NotContains(t, string(response.Body), "argon2id")
+}
@@ -17,6 +17,8 @@ func (c SymphonyVendorFactory) CreateVendor(config vendors.VendorConfig) (vendor
 	switch config.Type {
 	case "vendors.echo":
 		return &EchoVendor{}, nil
+	case "vendors.events":
+		return &EventsVendor{}, nil
 	case "vendors.solution":
 		return &SolutionVendor{}, nil
 	case "vendors.agent":
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestPath string
+)
+
+var ApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update Symphony objects from YAML or JSON manifests",
+	Example: `  maestro apply -f solution.yaml
+  maestro apply -f ./manifests
+  cat instance.yaml | maestro apply -f -`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runManifests(false)
+	},
+}
+
+var DeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete Symphony objects described by YAML or JSON manifests",
+	Example: `  maestro delete -f solution.yaml
+  maestro delete -f ./manifests`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runManifests(true)
+	},
+}
+
+func runManifests(remove bool) {
+	manifests, err := utils.ReadManifests(manifestPath)
+	if err != nil {
+		fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+		os.Exit(1)
+	}
+	if len(manifests) == 0 {
+		fmt.Printf("\n%s  no objects found in %s%s\n\n", utils.ColorYellow(), manifestPath, utils.ColorReset())
+		return
+	}
+	utils.SortManifests(manifests, remove)
+
+	ctx, token := connect()
+
+	failed := 0
+	for _, m := range manifests {
+		verb := "configured"
+		if remove {
+			verb = "deleted"
+			err = utils.DeleteManifest(ctx.Url, token, m)
+		} else {
+			err = utils.ApplyManifest(ctx.Url, token, m)
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%s  %s/%s failed: %s%s\n", utils.ColorRed(), m.Kind, m.Name, err.Error(), utils.ColorReset())
+		} else {
+			fmt.Printf("%s  %s/%s %s%s\n", utils.ColorGreen(), m.Kind, m.Name, verb, utils.ColorReset())
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("\n%s  %d of %d objects failed%s\n\n", utils.ColorRed(), failed, len(manifests), utils.ColorReset())
+		os.Exit(1)
+	}
+}
+
+func init() {
+	for _, c := range []*cobra.Command{ApplyCmd, DeleteCmd} {
+		c.Flags().StringVarP(&manifestPath, "filename", "f", "", "Manifest file, directory, or - for stdin")
+		c.MarkFlagRequired("filename")
+		RootCmd.AddCommand(c)
+	}
+}
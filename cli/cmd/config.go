@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	contextUrl      string
+	contextUser     string
+	contextSecret   string
+	contextCACert   string
+	contextInsecure bool
+)
+
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage Maestro CLI contexts",
+}
+
+var GetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List contexts, with the state of their cached tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := config.GetMaestroConfig(configFile)
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Current", "Name", "Url", "User", "TLS", "Token"})
+		for _, name := range c.ContextNames() {
+			ctx := c.Contexts[name]
+			current := ""
+			if name == c.DefaultContext {
+				current = "*"
+			}
+			tls := ""
+			if ctx.Insecure {
+				tls = "insecure"
+			} else if ctx.CACert != "" {
+				tls = "ca: " + ctx.CACert
+			}
+			t.AppendRow(table.Row{current, name, ctx.Url, ctx.User, tls, tokenState(name, ctx)})
+		}
+		t.SetStyle(table.StyleColoredBright)
+		t.Render()
+	},
+}
+
+var UseContextCmd = &cobra.Command{
+	Use:   "use-context <name>",
+	Short: "Set the default context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := config.GetMaestroConfig(configFile)
+		if _, ok := c.Contexts[args[0]]; !ok {
+			exitWithError(fmt.Errorf("context '%s' is not defined", args[0]))
+		}
+		c.DefaultContext = args[0]
+		if err := config.SaveMaestroConfigFile(configFile, c); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("\n%s  Switched to context '%s'%s\n\n", utils.ColorGreen(), args[0], utils.ColorReset())
+	},
+}
+
+var SetContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create or update a context",
+	Example: `  maestro config set-context prod --url https://symphony.contoso.com/v1alpha2 --user admin --ca-cert ./ca.pem
+  maestro config set-context dev --insecure=false`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := config.GetMaestroConfig(configFile)
+		ctx, exists := c.Contexts[args[0]]
+		if !exists && contextUrl == "" {
+			exitWithError(fmt.Errorf("context '%s' is not defined, use --url to create it", args[0]))
+		}
+		if cmd.Flags().Changed("url") {
+			ctx.Url = contextUrl
+		}
+		if cmd.Flags().Changed("user") {
+			ctx.User = contextUser
+		}
+		if cmd.Flags().Changed("secret") {
+			secret, err := config.EncryptSecret(contextSecret)
+			if err != nil {
+				exitWithError(err)
+			}
+			ctx.Secret = secret
+		}
+		if cmd.Flags().Changed("ca-cert") {
+			ctx.CACert = contextCACert
+		}
+		if cmd.Flags().Changed("insecure") {
+			ctx.Insecure = contextInsecure
+		}
+		c.Contexts[args[0]] = ctx
+		if err := config.SaveMaestroConfigFile(configFile, c); err != nil {
+			exitWithError(err)
+		}
+		verb := "modified"
+		if !exists {
+			verb = "created"
+		}
+		fmt.Printf("\n%s  Context '%s' %s%s\n\n", utils.ColorGreen(), args[0], verb, utils.ColorReset())
+	},
+}
+
+func tokenState(name string, ctx config.MaestroContext) string {
+	token, ok := config.GetCachedToken(name)
+	if !ok || !token.IssuedTo(ctx) {
+		return "none"
+	}
+	if time.Now().Before(token.ExpiresAt) {
+		return "valid until " + token.ExpiresAt.Local().Format("2006-01-02 15:04:05")
+	}
+	if token.RefreshToken != "" {
+		return "expired, refreshable"
+	}
+	return "expired"
+}
+
+func init() {
+	SetContextCmd.Flags().StringVarP(&contextUrl, "url", "", "", "Symphony API URL, such as http://localhost:8080/v1alpha2")
+	SetContextCmd.Flags().StringVarP(&contextUser, "user", "u", "", "User name")
+	SetContextCmd.Flags().StringVarP(&contextSecret, "secret", "", "", "Password, encrypted when "+config.PassphraseEnv+" is set")
+	SetContextCmd.Flags().StringVarP(&contextCACert, "ca-cert", "", "", "PEM file with the CA certificates trusted for the API's TLS certificate")
+	SetContextCmd.Flags().BoolVar(&contextInsecure, "insecure", false, "Skip verification of the API's TLS certificate")
+	ConfigCmd.AddCommand(GetContextsCmd)
+	ConfigCmd.AddCommand(UseContextCmd)
+	ConfigCmd.AddCommand(SetContextCmd)
+	RootCmd.AddCommand(ConfigCmd)
+}
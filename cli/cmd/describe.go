@@ -0,0 +1,256 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	objectScope    string
+	maxActivations int
+)
+
+var DescribeObjectCmd = &cobra.Command{
+	Use:   "describe <kind> <name>",
+	Short: "Show details of a Symphony object, including its latest deployment summary",
+	Example: `  maestro describe instance my-instance
+  maestro describe campaign my-campaign --activations 10`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind := utils.NormalizeKind(args[0])
+		name := args[1]
+		ctx, token := connect()
+
+		obj, err := utils.GetObject(ctx.Url, token, kind, name, objectScope)
+		if err != nil {
+			exitWithError(err)
+		}
+		if obj == nil {
+			exitWithError(fmt.Errorf("%s '%s' is not found", kind, name))
+		}
+
+		fmt.Printf("\n%sKind:%s   %s\n", utils.ColorBlue(), utils.ColorReset(), kind)
+		fmt.Printf("%sName:%s   %s\n", utils.ColorBlue(), utils.ColorReset(), name)
+		if objectScope != "" {
+			fmt.Printf("%sScope:%s  %s\n", utils.ColorBlue(), utils.ColorReset(), objectScope)
+		}
+		if spec, ok := obj["spec"]; ok {
+			fmt.Printf("\n%sSpec:%s\n", utils.ColorBlue(), utils.ColorReset())
+			printYaml(spec)
+		}
+		if status, ok := obj["status"].(map[string]interface{}); ok && len(status) > 0 {
+			fmt.Printf("\n%sStatus:%s\n", utils.ColorBlue(), utils.ColorReset())
+			printStatus(status)
+		}
+
+		switch kind {
+		case "instance":
+			summary, err := utils.GetSummary(ctx.Url, token, name, objectScope)
+			if err != nil {
+				exitWithError(err)
+			}
+			printSummary(summary)
+		case "campaign":
+			activations, err := utils.ListObjects(ctx.Url, token, "activation", objectScope)
+			if err != nil {
+				exitWithError(err)
+			}
+			printActivations(name, activations)
+		}
+		fmt.Println()
+	},
+}
+
+func exitWithError(err error) {
+	fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+	os.Exit(1)
+}
+
+func printYaml(obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		exitWithError(err)
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+func printStatus(status map[string]interface{}) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Key", "Value"})
+	t.AppendRows(statusRows(status))
+	t.SetStyle(table.StyleColoredBright)
+	t.Render()
+}
+
+// statusRows lists the status of an object by key
+func statusRows(status map[string]interface{}) []table.Row {
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := make([]table.Row, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, table.Row{k, status[k]})
+	}
+	return rows
+}
+
+func printSummary(summary *model.SummaryResult) {
+	fmt.Printf("\n%sLatest deployment:%s\n", utils.ColorBlue(), utils.ColorReset())
+	if summary == nil {
+		fmt.Println("  no deployment summary is available")
+		return
+	}
+	s := summary.Summary
+	fmt.Printf("  Generation: %s\n", summary.Generation)
+	fmt.Printf("  Time:       %s\n", summary.Time.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("  Targets:    %d of %d succeeded\n", s.SuccessCount, s.TargetCount)
+	if s.IsRemoval {
+		fmt.Println("  Removal:    true")
+	}
+	if s.Skipped {
+		fmt.Println("  Skipped:    true")
+	}
+	if s.SummaryMessage != "" {
+		fmt.Printf("  Message:    %s\n", s.SummaryMessage)
+	}
+	if len(s.TargetResults) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Target", "Component", "Status", "Message"})
+	t.AppendRows(summaryRows(s))
+	t.SetStyle(table.StyleColoredBright)
+	t.Render()
+}
+
+// summaryRows lists the result of each target of a deployment, followed by the results of its components
+func summaryRows(summary model.SummarySpec) []table.Row {
+	targets := make([]string, 0, len(summary.TargetResults))
+	for k := range summary.TargetResults {
+		targets = append(targets, k)
+	}
+	sort.Strings(targets)
+	rows := make([]table.Row, 0)
+	for _, target := range targets {
+		result := summary.TargetResults[target]
+		rows = append(rows, table.Row{target, "", result.Status, result.Message})
+		components := make([]string, 0, len(result.ComponentResults))
+		for k := range result.ComponentResults {
+			components = append(components, k)
+		}
+		sort.Strings(components)
+		for _, component := range components {
+			c := result.ComponentResults[component]
+			rows = append(rows, table.Row{"", component, c.Status.String(), c.Message})
+		}
+	}
+	return rows
+}
+
+func printActivations(campaign string, list []map[string]interface{}) {
+	activations := recentActivations(campaign, list, maxActivations)
+	fmt.Printf("\n%sRecent activations:%s\n", utils.ColorBlue(), utils.ColorReset())
+	if len(activations) == 0 {
+		fmt.Println("  no activations found")
+		return
+	}
+	for _, a := range activations {
+		fmt.Printf("\n  %s\n", a.Id)
+		if a.Status == nil {
+			fmt.Println("    not started")
+			continue
+		}
+		fmt.Printf("    Stage:   %s\n", a.Status.Stage)
+		if a.Status.NextStage != "" {
+			fmt.Printf("    Next:    %s\n", a.Status.NextStage)
+		}
+		fmt.Printf("    Status:  %s\n", a.Status.Status.String())
+		fmt.Printf("    Updated: %s\n", a.Status.UpdateTime)
+		if a.Status.ErrorMessage != "" {
+			fmt.Printf("    %sError:   %s%s\n", utils.ColorRed(), a.Status.ErrorMessage, utils.ColorReset())
+		}
+		if len(a.Status.Outputs) > 0 {
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"Output", "Value"})
+			t.AppendRows(outputRows(a.Status.Outputs))
+			t.SetStyle(table.StyleColoredBright)
+			t.Render()
+		}
+	}
+}
+
+// recentActivations returns the activations of a campaign, most recently updated first, up to max
+// activations when max is positive
+func recentActivations(campaign string, list []map[string]interface{}, max int) []model.ActivationState {
+	activations := make([]model.ActivationState, 0)
+	for _, item := range list {
+		data, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		var activation model.ActivationState
+		if json.Unmarshal(data, &activation) != nil || activation.Spec == nil || activation.Spec.Campaign != campaign {
+			continue
+		}
+		activations = append(activations, activation)
+	}
+	sort.SliceStable(activations, func(i, j int) bool {
+		return activationTime(activations[i]).After(activationTime(activations[j]))
+	})
+	if max > 0 && len(activations) > max {
+		activations = activations[:max]
+	}
+	return activations
+}
+
+// outputRows lists the outputs of an activation by name
+func outputRows(outputs map[string]interface{}) []table.Row {
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	rows := make([]table.Row, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, table.Row{k, fmt.Sprintf("%v", outputs[k])})
+	}
+	return rows
+}
+
+func activationTime(activation model.ActivationState) time.Time {
+	if activation.Status == nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, activation.Status.UpdateTime)
+	return t
+}
+
+func init() {
+	DescribeObjectCmd.Flags().StringVarP(&objectScope, "scope", "s", "", "Object scope")
+	DescribeObjectCmd.Flags().IntVarP(&maxActivations, "activations", "", 5, "Maximum number of recent activations to show for a campaign")
+	RootCmd.AddCommand(DescribeObjectCmd)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestStatusRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		status map[string]interface{}
+		want   []table.Row
+	}{
+		{
+			name:   "empty",
+			status: map[string]interface{}{},
+			want:   []table.Row{},
+		},
+		{
+			name:   "sorted by key",
+			status: map[string]interface{}{"status": "Succeeded", "deployed": 2, "properties": nil},
+			want: []table.Row{
+				{"deployed", 2},
+				{"properties", nil},
+				{"status", "Succeeded"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusRows(tt.status); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statusRows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary model.SummarySpec
+		want    []table.Row
+	}{
+		{
+			name:    "no targets",
+			summary: model.SummarySpec{TargetCount: 0},
+			want:    []table.Row{},
+		},
+		{
+			name: "targets followed by their components",
+			summary: model.SummarySpec{
+				TargetCount:  2,
+				SuccessCount: 1,
+				TargetResults: map[string]model.TargetResultSpec{
+					"target-b": {
+						Status:  "Failed",
+						Message: "timed out",
+						ComponentResults: map[string]model.ComponentResultSpec{
+							"web": {Status: v1alpha2.UpdateFailed, Message: "timed out"},
+							"api": {Status: v1alpha2.Updated},
+						},
+					},
+					"target-a": {Status: "OK"},
+				},
+			},
+			want: []table.Row{
+				{"target-a", "", "OK", ""},
+				{"target-b", "", "Failed", "timed out"},
+				{"", "api", v1alpha2.Updated.String(), ""},
+				{"", "web", v1alpha2.UpdateFailed.String(), "timed out"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summaryRows(tt.summary); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("summaryRows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecentActivations(t *testing.T) {
+	activation := func(id string, campaign string, updated string) map[string]interface{} {
+		ret := map[string]interface{}{
+			"id":   id,
+			"spec": map[string]interface{}{"campaign": campaign},
+		}
+		if updated != "" {
+			ret["status"] = map[string]interface{}{"stage": "deploy", "updateTime": updated}
+		}
+		return ret
+	}
+	list := []map[string]interface{}{
+		activation("first", "rollout", "2024-01-01T10:00:00Z"),
+		activation("other", "backup", "2024-01-03T10:00:00Z"),
+		activation("pending", "rollout", ""),
+		activation("latest", "rollout", "2024-01-02T10:00:00Z"),
+		{"id": "no-spec"},
+	}
+	tests := []struct {
+		name     string
+		campaign string
+		max      int
+		want     []string
+	}{
+		{name: "most recent first", campaign: "rollout", want: []string{"latest", "first", "pending"}},
+		{name: "limited", campaign: "rollout", max: 2, want: []string{"latest", "first"}},
+		{name: "other campaign", campaign: "backup", max: 5, want: []string{"other"}},
+		{name: "unknown campaign", campaign: "missing", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, a := range recentActivations(tt.campaign, list, tt.max) {
+				got = append(got, a.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recentActivations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs map[string]interface{}
+		want    []table.Row
+	}{
+		{
+			name:    "values are formatted",
+			outputs: map[string]interface{}{"status": 200, "body": map[string]interface{}{"ok": true}},
+			want: []table.Row{
+				{"body", "map[ok:true]"},
+				{"status", "200"},
+			},
+		},
+		{
+			name:    "no outputs",
+			outputs: nil,
+			want:    []table.Row{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outputRows(tt.outputs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("outputRows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"sort"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
-	"github.com/eclipse-symphony/symphony/cli/config"
 	"github.com/eclipse-symphony/symphony/cli/utils"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
@@ -32,21 +31,11 @@ var GetCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Query Symphony objects",
 	Run: func(cmd *cobra.Command, args []string) {
-		c := config.GetMaestroConfig(configFile)
-		ctx := c.DefaultContext
-		if configContext != "" {
-			ctx = configContext
-		}
-
-		if ctx == "" {
-			ctx = "default"
-		}
-
+		ctx, token := connect()
 		for _, a := range args {
 			list, err := utils.Get(
-				c.Contexts[ctx].Url,
-				c.Contexts[ctx].User,
-				c.Contexts[ctx].Secret,
+				ctx.Url,
+				token,
 				a,
 				jsonPath,
 				docType,
@@ -256,10 +245,8 @@ func outputListItem(t table.Writer, item interface{}, objType string, path strin
 
 func init() {
 	GetCmd.Flags().StringVarP(&objectName, "name", "n", "", "Symphony object name")
-	GetCmd.Flags().StringVarP(&configFile, "config", "c", "", "Maestro CLI config file")
 	GetCmd.Flags().StringVarP(&jsonPath, "json-path", "", "", "Jason Path query to be applied on results")
 	GetCmd.Flags().StringVarP(&docType, "doc-type", "", "", "Result type (Json or Yaml)")
-	GetCmd.Flags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
 	RootCmd.AddCommand(GetCmd)
 }
 
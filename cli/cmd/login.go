@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	loginUrl           string
+	loginUser          string
+	loginPassword      string
+	loginPasswordStdin bool
+	loginSaveSecret    bool
+)
+
+var LoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Sign in to a Symphony API and cache the issued tokens",
+	Example: `  maestro login
+  maestro login --context prod --url https://symphony.contoso.com/v1alpha2 --user admin
+  echo $PASSWORD | maestro login --password-stdin`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := config.GetMaestroConfig(configFile)
+		name, ctx, err := config.ResolveContext(c, configContext)
+		if err != nil && loginUrl == "" {
+			exitWithError(fmt.Errorf("%s, use --url to create it", err.Error()))
+		}
+		changed := err != nil
+		if loginUrl != "" && loginUrl != ctx.Url {
+			ctx.Url = loginUrl
+			changed = true
+		}
+		if loginUser != "" && loginUser != ctx.User {
+			ctx.User = loginUser
+			changed = true
+		}
+
+		password, err := readPassword(ctx)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := utils.ConfigureTLS(ctx.CACert, ctx.Insecure); err != nil {
+			exitWithError(err)
+		}
+		if _, err := utils.LoginContext(name, ctx, password); err != nil {
+			exitWithError(err)
+		}
+		if loginSaveSecret {
+			ctx.Secret, err = config.EncryptSecret(password)
+			if err != nil {
+				exitWithError(err)
+			}
+			changed = true
+		}
+		if changed {
+			c.Contexts[name] = ctx
+			if err := config.SaveMaestroConfigFile(configFile, c); err != nil {
+				exitWithError(err)
+			}
+		}
+		token, _ := config.GetCachedToken(name)
+		fmt.Printf("\n%s  Logged in to %s as %s (context '%s'), token expires at %s%s\n\n",
+			utils.ColorGreen(), ctx.Url, ctx.User, name, token.ExpiresAt.Local().Format("2006-01-02 15:04:05"), utils.ColorReset())
+	},
+}
+
+var LogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke and remove the cached tokens of a context",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, ctx, err := resolveContext()
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := utils.Logout(name, ctx); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("\n%s  Logged out of context '%s'%s\n\n", utils.ColorGreen(), name, utils.ColorReset())
+	},
+}
+
+// readPassword reads the password from --password, stdin, the context's stored secret, or a prompt,
+// in that order
+func readPassword(ctx config.MaestroContext) (string, error) {
+	if loginPassword != "" {
+		return loginPassword, nil
+	}
+	if loginPasswordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password from stdin: %s", err.Error())
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	if ctx.Secret != "" {
+		return config.DecryptSecret(ctx.Secret)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+	fmt.Printf("Password for %s: ", ctx.User)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// resolveContext resolves the context selected by --config and --context and applies its TLS settings
+func resolveContext() (string, config.MaestroContext, error) {
+	c := config.GetMaestroConfig(configFile)
+	name, ctx, err := config.ResolveContext(c, configContext)
+	if err != nil {
+		return name, ctx, err
+	}
+	return name, ctx, utils.ConfigureTLS(ctx.CACert, ctx.Insecure)
+}
+
+// connectContext resolves the selected context and returns it with a bearer token, signing in only
+// when there is no usable cached token
+func connectContext() (config.MaestroContext, string, error) {
+	name, ctx, err := resolveContext()
+	if err != nil {
+		return ctx, "", err
+	}
+	token, err := utils.Authenticate(name, ctx)
+	return ctx, token, err
+}
+
+// connect is connectContext for commands that exit on errors
+func connect() (config.MaestroContext, string) {
+	ctx, token, err := connectContext()
+	if err != nil {
+		exitWithError(err)
+	}
+	return ctx, token
+}
+
+func init() {
+	LoginCmd.Flags().StringVarP(&loginUrl, "url", "", "", "Symphony API URL, such as http://localhost:8080/v1alpha2")
+	LoginCmd.Flags().StringVarP(&loginUser, "user", "u", "", "User name")
+	LoginCmd.Flags().StringVarP(&loginPassword, "password", "p", "", "Password")
+	LoginCmd.Flags().BoolVar(&loginPasswordStdin, "password-stdin", false, "Read the password from stdin")
+	LoginCmd.Flags().BoolVar(&loginSaveSecret, "save-secret", false, "Store the password in the context, encrypted when "+config.PassphraseEnv+" is set")
+	RootCmd.AddCommand(LoginCmd)
+	RootCmd.AddCommand(LogoutCmd)
+}
@@ -41,4 +41,6 @@ func Execute(versiong string) {
 
 func init() {
 	RootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Display verbose tracing info.")
+	RootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Maestro CLI config file")
+	RootCmd.PersistentFlags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
 }
@@ -17,16 +17,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/eclipse-symphony/symphony/cli/config"
 	"github.com/eclipse-symphony/symphony/cli/utils"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 )
 
 var (
-	setSwitches         []string
-	sampleConfigFile    string
-	sampleConfigContext string
+	setSwitches []string
 )
 var SamplesCmd = &cobra.Command{
 	Use:   "samples",
@@ -220,21 +217,15 @@ func setSwitchToMap() (map[string]string, error) {
 	return ret, nil
 }
 func removeArtifact(artifact ArtifactSpec) error {
-	c := config.GetMaestroConfig(sampleConfigFile)
-	ctx := c.DefaultContext
-	if sampleConfigContext != "" {
-		ctx = sampleConfigContext
-	}
-
-	if ctx == "" {
-		ctx = "default"
+	ctx, token, err := connectContext()
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("%sRemoving %s %s%s ...", utils.ColorCyan(), artifact.Type, utils.ColorReset(), artifact.Name)
-	err := utils.Remove(
-		c.Contexts[ctx].Url,
-		c.Contexts[ctx].User,
-		c.Contexts[ctx].Secret,
+	err = utils.Remove(
+		ctx.Url,
+		token,
 		artifact.Type,
 		artifact.Name)
 	if err != nil {
@@ -270,21 +261,15 @@ func runArtifact(samplePath string, artifact ArtifactSpec, paramMap map[string]s
 			strStr = strings.ReplaceAll(strStr, p.Replace, p.Value)
 		}
 	}
-	c := config.GetMaestroConfig(sampleConfigFile)
-	ctx := c.DefaultContext
-	if sampleConfigContext != "" {
-		ctx = sampleConfigContext
-	}
-
-	if ctx == "" {
-		ctx = "default"
+	ctx, token, err := connectContext()
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("%sCreating %s %s%s ... ", utils.ColorCyan(), artifact.Type, utils.ColorReset(), artifact.Name)
 	err = utils.Upsert(
-		c.Contexts[ctx].Url,
-		c.Contexts[ctx].User,
-		c.Contexts[ctx].Secret,
+		ctx.Url,
+		token,
 		artifact.Type,
 		artifact.Name,
 		[]byte(strStr))
@@ -335,12 +320,6 @@ var RemoveCmd = &cobra.Command{
 
 func init() {
 	RunCmd.Flags().StringArrayVarP(&setSwitches, "set", "s", nil, "set sample parameter as key=value")
-	RunCmd.Flags().StringVarP(&sampleConfigFile, "config", "c", "", "Maestro CLI config file")
-	RunCmd.Flags().StringVarP(&sampleConfigContext, "context", "", "", "Maestro CLI configuration context")
-	RemoveCmd.Flags().StringVarP(&sampleConfigFile, "config", "c", "", "Maestro CLI config file")
-	RemoveCmd.Flags().StringVarP(&sampleConfigContext, "context", "", "", "Maestro CLI configuration context")
-	DescribeCmd.Flags().StringVarP(&sampleConfigFile, "config", "c", "", "Maestro CLI config file")
-	DescribeCmd.Flags().StringVarP(&sampleConfigContext, "context", "", "", "Maestro CLI configuration context")
 	SamplesCmd.AddCommand(RunCmd)
 	SamplesCmd.AddCommand(RemoveCmd)
 	SamplesCmd.AddCommand(DescribeCmd)
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+// health counts objects by their reported status
+type health struct {
+	succeeded   int
+	failed      int
+	reconciling int
+	unknown     int
+}
+
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show a fleet-wide overview of instance and target health",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, token := connect()
+
+		instances, err := utils.ListObjects(ctx.Url, token, "instance", objectScope)
+		if err != nil {
+			exitWithError(err)
+		}
+		targets, err := utils.ListObjects(ctx.Url, token, "target", objectScope)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Printf("\n%sInstances:%s\n", utils.ColorBlue(), utils.ColorReset())
+		instanceHealth := printHealthTable(instances, "Deployed")
+		fmt.Printf("\n%sTargets:%s\n", utils.ColorBlue(), utils.ColorReset())
+		targetHealth := printHealthTable(targets, "")
+
+		fmt.Println()
+		printHealth("Instances", instanceHealth)
+		printHealth("Targets", targetHealth)
+		fmt.Println()
+	},
+}
+
+func printHealthTable(list []map[string]interface{}, deployedColumn string) health {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{"Name", "Scope", "Status"}
+	if deployedColumn != "" {
+		header = append(header, deployedColumn)
+	}
+	t.AppendHeader(append(header, "Details"))
+	rows, ret := healthRows(list, deployedColumn != "")
+	t.AppendRows(rows)
+	t.SetStyle(table.StyleColoredBright)
+	t.Render()
+	return ret
+}
+
+// healthRows lists objects by name with their status, and counts them by status. With deployed, the
+// number of targets an instance is deployed to is listed as well.
+func healthRows(list []map[string]interface{}, deployed bool) ([]table.Row, health) {
+	ret := health{}
+	sort.Slice(list, func(i, j int) bool {
+		return fmt.Sprintf("%v", list[i]["id"]) < fmt.Sprintf("%v", list[j]["id"])
+	})
+	rows := make([]table.Row, 0, len(list))
+	for _, item := range list {
+		status, _ := item["status"].(map[string]interface{})
+		state := statusValue(status, "status")
+		switch state {
+		case "Succeeded", "OK":
+			ret.succeeded++
+		case "Failed":
+			ret.failed++
+		case "Reconciling":
+			ret.reconciling++
+		default:
+			ret.unknown++
+		}
+		row := table.Row{item["id"], statusValue(item, "scope"), state}
+		if deployed {
+			count := statusValue(status, "deployed")
+			if targets := statusValue(status, "targets"); targets != "" {
+				count = fmt.Sprintf("%s/%s", count, targets)
+			}
+			row = append(row, count)
+		}
+		rows = append(rows, append(row, statusValue(status, "status-details")))
+	}
+	return rows, ret
+}
+
+func statusValue(values map[string]interface{}, key string) string {
+	if v, ok := values[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+func printHealth(title string, h health) {
+	fmt.Printf("  %-10s %s%d succeeded%s, %s%d failed%s, %s%d reconciling%s, %d unknown\n",
+		title+":",
+		utils.ColorGreen(), h.succeeded, utils.ColorReset(),
+		utils.ColorRed(), h.failed, utils.ColorReset(),
+		utils.ColorYellow(), h.reconciling, utils.ColorReset(),
+		h.unknown)
+}
+
+func init() {
+	StatusCmd.Flags().StringVarP(&objectScope, "scope", "s", "", "Object scope")
+	RootCmd.AddCommand(StatusCmd)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func TestHealthRows(t *testing.T) {
+	list := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"id": "web", "scope": "default", "status": map[string]interface{}{"status": "Succeeded", "deployed": 2, "targets": 2}},
+			{"id": "api", "scope": "default", "status": map[string]interface{}{"status": "Failed", "deployed": 1, "targets": 2, "status-details": "target-b: timed out"}},
+			{"id": "db", "status": map[string]interface{}{"status": "Reconciling", "deployed": 0}},
+			{"id": "cache"},
+		}
+	}
+	tests := []struct {
+		name     string
+		deployed bool
+		want     []table.Row
+	}{
+		{
+			name: "without the deployed column",
+			want: []table.Row{
+				{"api", "default", "Failed", "target-b: timed out"},
+				{"cache", "", "", ""},
+				{"db", "", "Reconciling", ""},
+				{"web", "default", "Succeeded", ""},
+			},
+		},
+		{
+			name:     "with the deployed column",
+			deployed: true,
+			want: []table.Row{
+				{"api", "default", "Failed", "1/2", "target-b: timed out"},
+				{"cache", "", "", "", ""},
+				{"db", "", "Reconciling", "0", ""},
+				{"web", "default", "Succeeded", "2/2", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, h := healthRows(list(), tt.deployed)
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("healthRows() rows = %v, want %v", rows, tt.want)
+			}
+			if want := (health{succeeded: 1, failed: 1, reconciling: 1, unknown: 1}); h != want {
+				t.Errorf("healthRows() health = %+v, want %+v", h, want)
+			}
+		})
+	}
+}
+
+func TestStatusValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		key    string
+		want   string
+	}{
+		{name: "string", values: map[string]interface{}{"status": "OK"}, key: "status", want: "OK"},
+		{name: "number", values: map[string]interface{}{"deployed": 3}, key: "deployed", want: "3"},
+		{name: "nil", values: map[string]interface{}{"deployed": nil}, key: "deployed", want: ""},
+		{name: "missing", values: map[string]interface{}{}, key: "deployed", want: ""},
+		{name: "nil map", values: nil, key: "deployed", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusValue(tt.values, tt.key); got != tt.want {
+				t.Errorf("statusValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchTopics []string
+	watchOutput string
+)
+
+const watchRetryInterval = 3 * time.Second
+
+var WatchCmd = &cobra.Command{
+	Use:   "watch [kind [name]]",
+	Short: "Follow reconciles and campaign progress as they happen",
+	Example: `  maestro watch
+  maestro watch instance my-instance
+  maestro watch campaign my-campaign --topic activation,job-report
+  maestro watch --output json`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		parameters := map[string]string{
+			"topic": strings.Join(watchTopics, ","),
+			"scope": objectScope,
+		}
+		if len(args) > 0 {
+			parameters["kind"] = utils.NormalizeKind(args[0])
+		}
+		if len(args) > 1 {
+			parameters["name"] = args[1]
+		}
+
+		name, mctx, err := resolveContext()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		for {
+			token, err := utils.Authenticate(name, mctx)
+			if err == nil {
+				err = utils.WatchEvents(sigCtx, mctx.Url, token, parameters, printWatchEvent)
+			}
+			if sigCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				fmt.Printf("%s  %s, reconnecting...%s\n", utils.ColorYellow(), err.Error(), utils.ColorReset())
+			}
+			select {
+			case <-sigCtx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+			}
+		}
+	},
+}
+
+func printWatchEvent(event model.WatchEvent) {
+	if watchOutput == "json" {
+		data, _ := json.Marshal(event)
+		fmt.Println(string(data))
+		return
+	}
+	object := event.Kind
+	if event.Name != "" {
+		object += "/" + event.Name
+	}
+	if event.Scope != "" && event.Scope != "default" {
+		object = event.Scope + ":" + object
+	}
+	fmt.Printf("%s  %s%-11s%s %-40s %s\n",
+		event.Time.Local().Format("15:04:05"),
+		utils.ColorCyan(), event.Topic, utils.ColorReset(),
+		object,
+		describeWatchEvent(event))
+}
+
+// describeWatchEvent renders the interesting parts of an event body on one line
+func describeWatchEvent(event model.WatchEvent) string {
+	body, _ := event.Body.(map[string]interface{})
+	switch event.Topic {
+	case "job":
+		return fmt.Sprintf("%v", body["action"])
+	case "activation", "trigger":
+		ret := "campaign " + event.Campaign
+		if stage, ok := body["stage"].(string); ok && stage != "" {
+			ret += ", stage " + stage
+		}
+		return ret
+	case "job-report":
+		ret := fmt.Sprintf("campaign %s, stage %v", event.Campaign, body["stage"])
+		if status, ok := body["status"].(float64); ok {
+			ret += " " + colorState(v1alpha2.State(int(status)))
+		}
+		if msg, ok := body["errorMessage"].(string); ok && msg != "" {
+			ret += fmt.Sprintf(" %s%s%s", utils.ColorRed(), msg, utils.ColorReset())
+		}
+		return ret
+	case "summary":
+		var result model.SummaryResult
+		data, _ := json.Marshal(event.Body)
+		if json.Unmarshal(data, &result) != nil {
+			return ""
+		}
+		s := result.Summary
+		color := utils.ColorGreen()
+		if s.SuccessCount < s.TargetCount {
+			color = utils.ColorRed()
+		}
+		ret := fmt.Sprintf("%s%d/%d targets succeeded%s, generation %s", color, s.SuccessCount, s.TargetCount, utils.ColorReset(), result.Generation)
+		if s.IsRemoval {
+			ret += " (removal)"
+		}
+		if s.SummaryMessage != "" {
+			ret += ": " + s.SummaryMessage
+		}
+		return ret
+	}
+	return ""
+}
+
+func colorState(state v1alpha2.State) string {
+	color := utils.ColorYellow()
+	switch state {
+	case v1alpha2.OK, v1alpha2.Done, v1alpha2.Updated, v1alpha2.Deleted:
+		color = utils.ColorGreen()
+	case v1alpha2.Running, v1alpha2.Paused, v1alpha2.Delayed, v1alpha2.Accepted, v1alpha2.Untouched:
+	default:
+		color = utils.ColorRed()
+	}
+	return color + state.String() + utils.ColorReset()
+}
+
+func init() {
+	WatchCmd.Flags().StringVarP(&objectScope, "scope", "s", "", "Object scope")
+	WatchCmd.Flags().StringSliceVarP(&watchTopics, "topic", "t", nil, "Topics to watch: job, job-report, activation, trigger, summary (default all)")
+	WatchCmd.Flags().StringVarP(&watchOutput, "output", "o", "", "Output format: text or json")
+	RootCmd.AddCommand(WatchCmd)
+}
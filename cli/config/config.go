@@ -8,10 +8,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -19,6 +21,10 @@ type MaestroContext struct {
 	Url    string `json:"url"`
 	User   string `json:"user"`
 	Secret string `json:"secret,omitempty"`
+	// CACert is a PEM file with the CA certificates trusted for the API's TLS certificate
+	CACert string `json:"caCert,omitempty"`
+	// Insecure skips verification of the API's TLS certificate
+	Insecure bool `json:"insecure,omitempty"`
 }
 type MaestroConfig struct {
 	DefaultContext string                    `json:"default,omitempty"`
@@ -39,46 +45,68 @@ func UpdateMaestroConfig(context string, address string) error {
 	return SaveMaestroConfig(config)
 }
 func SaveMaestroConfig(config MaestroConfig) error {
-	dirname, err := os.UserHomeDir()
-	if err != nil {
-		return err
+	return SaveMaestroConfigFile("", config)
+}
+
+// SaveMaestroConfigFile saves the config to a file, or to the default config file when path is empty.
+// The file is only readable by the current user, as it may contain secrets.
+func SaveMaestroConfigFile(path string, config MaestroConfig) error {
+	if path == "" {
+		folderName, err := configFolder()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(folderName, ".config.json")
 	}
-	configFile := filepath.Join(dirname, ".symphony", ".config.json")
-	file, err := os.OpenFile(configFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 064)
-	if err != nil {
-		return err
+	if strings.Contains(path, ":") && filepath.VolumeName(path) == "" {
+		return fmt.Errorf("config can only be saved to a single file, got '%s'", path)
 	}
-	defer file.Close()
-
 	b, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
-	if _, err := file.Write(b); err != nil {
-		return err
+	return writePrivateFile(path, b)
+}
+
+// ResolveContext returns the name and settings of the context selected by override, or of the default
+// context when override is empty
+func ResolveContext(config MaestroConfig, override string) (string, MaestroContext, error) {
+	name := config.DefaultContext
+	if override != "" {
+		name = override
+	}
+	if name == "" {
+		name = "default"
+	}
+	ctx, ok := config.Contexts[name]
+	if !ok {
+		return name, MaestroContext{}, fmt.Errorf("context '%s' is not defined", name)
+	}
+	return name, ctx, nil
+}
+
+// ContextNames returns the sorted names of all contexts
+func (c MaestroConfig) ContextNames() []string {
+	ret := make([]string, 0, len(c.Contexts))
+	for k := range c.Contexts {
+		ret = append(ret, k)
 	}
-	return nil
+	sort.Strings(ret)
+	return ret
 }
+
 func GetMaestroConfig(path string) MaestroConfig {
 	var files []string
-	dirname, err := os.UserHomeDir()
+	folderName, err := configFolder()
 	if err != nil {
 		log.Fatal(err)
 	}
-	folderName := filepath.Join(dirname, ".symphony")
 	fileName := filepath.Join(folderName, ".config.json")
-	if _, err := os.Stat(folderName); os.IsNotExist(err) {
-		err = os.MkdirAll(folderName, 0755)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
 	if _, err := os.Stat(fileName); os.IsNotExist(err) {
-		file, err := os.Create(fileName)
+		err = writePrivateFile(fileName, []byte{})
 		if err != nil {
 			log.Fatal(err)
 		}
-		file.Close()
 	}
 	if path == "" {
 		files = []string{fileName}
@@ -115,3 +143,33 @@ func GetMaestroConfig(path string) MaestroConfig {
 	}
 	return ret
 }
+
+func configFolder() (string, error) {
+	dirname, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	folderName := filepath.Join(dirname, ".symphony")
+	if _, err := os.Stat(folderName); os.IsNotExist(err) {
+		err = os.MkdirAll(folderName, 0755)
+		if err != nil {
+			return "", err
+		}
+	}
+	return folderName, nil
+}
+
+// writePrivateFile writes a file that only the current user can read and write. Permissions of an
+// existing file are tightened as well.
+func writePrivateFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
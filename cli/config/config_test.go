@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveContext(t *testing.T) {
+	contexts := map[string]MaestroContext{
+		"default": {Url: "http://localhost:8082/v1alpha2", User: "admin"},
+		"local":   {Url: "http://localhost:8080/v1alpha2", User: "admin"},
+		"prod":    {Url: "https://symphony.contoso.com/v1alpha2", User: "operator"},
+	}
+	tests := []struct {
+		name     string
+		config   MaestroConfig
+		override string
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name:     "override wins over the default context",
+			config:   MaestroConfig{DefaultContext: "local", Contexts: contexts},
+			override: "prod",
+			wantName: "prod",
+		},
+		{
+			name:     "default context",
+			config:   MaestroConfig{DefaultContext: "local", Contexts: contexts},
+			wantName: "local",
+		},
+		{
+			name:     "context named default",
+			config:   MaestroConfig{Contexts: contexts},
+			wantName: "default",
+		},
+		{
+			name:     "undefined override",
+			config:   MaestroConfig{DefaultContext: "local", Contexts: contexts},
+			override: "staging",
+			wantName: "staging",
+			wantErr:  true,
+		},
+		{
+			name:     "no contexts",
+			config:   MaestroConfig{},
+			wantName: "default",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, ctx, err := ResolveContext(tt.config, tt.override)
+			if name != tt.wantName {
+				t.Errorf("ResolveContext() name = %q, want %q", name, tt.wantName)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(ctx, tt.config.Contexts[tt.wantName]) {
+				t.Errorf("ResolveContext() context = %+v, want %+v", ctx, tt.config.Contexts[tt.wantName])
+			}
+		})
+	}
+}
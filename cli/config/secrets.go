@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PassphraseEnv names the environment variable with the passphrase that encrypts stored secrets and tokens
+	PassphraseEnv    = "MAESTRO_PASSPHRASE"
+	encryptedPrefix  = "enc:v1:"
+	saltSize         = 16
+	encryptionKeyLen = 32
+)
+
+// EncryptSecret encrypts a value with AES-GCM under a key derived from the MAESTRO_PASSPHRASE
+// environment variable. The value is returned unchanged when no passphrase is set.
+func EncryptSecret(value string) (string, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if value == "" || passphrase == "" {
+		return value, nil
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	data := append(salt, nonce...)
+	data = gcm.Seal(data, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptSecret decrypts a value written by EncryptSecret. Values that aren't encrypted are returned
+// unchanged.
+func DecryptSecret(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return "", fmt.Errorf("secret is encrypted, set %s to decrypt it", PassphraseEnv)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(data) < saltSize {
+		return "", fmt.Errorf("encrypted secret is malformed")
+	}
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted secret is malformed")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret, check %s", PassphraseEnv)
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted checks if a value was written by EncryptSecret
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, encryptionKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
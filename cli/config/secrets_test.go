@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSecretEncryption(t *testing.T) {
+	tamper := func(value string) string {
+		data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+		data[len(data)-1] ^= 0xff
+		return encryptedPrefix + base64.StdEncoding.EncodeToString(data)
+	}
+	tests := []struct {
+		name          string
+		value         string
+		encryptWith   string
+		decryptWith   string
+		modify        func(string) string
+		wantEncrypted bool
+		wantErr       string
+	}{
+		{
+			name:          "round trip",
+			value:         "s3cret",
+			encryptWith:   "passphrase",
+			decryptWith:   "passphrase",
+			wantEncrypted: true,
+		},
+		{
+			name:  "no passphrase",
+			value: "s3cret",
+		},
+		{
+			name:        "empty value",
+			value:       "",
+			encryptWith: "passphrase",
+			decryptWith: "passphrase",
+		},
+		{
+			name:          "wrong passphrase",
+			value:         "s3cret",
+			encryptWith:   "passphrase",
+			decryptWith:   "other",
+			wantEncrypted: true,
+			wantErr:       "failed to decrypt secret",
+		},
+		{
+			name:          "passphrase missing",
+			value:         "s3cret",
+			encryptWith:   "passphrase",
+			wantEncrypted: true,
+			wantErr:       "set " + PassphraseEnv,
+		},
+		{
+			name:          "tampered ciphertext",
+			value:         "s3cret",
+			encryptWith:   "passphrase",
+			decryptWith:   "passphrase",
+			modify:        tamper,
+			wantEncrypted: true,
+			wantErr:       "failed to decrypt secret",
+		},
+		{
+			name:          "truncated ciphertext",
+			value:         "s3cret",
+			encryptWith:   "passphrase",
+			decryptWith:   "passphrase",
+			modify:        func(v string) string { return encryptedPrefix + base64.StdEncoding.EncodeToString([]byte("short")) },
+			wantEncrypted: true,
+			wantErr:       "malformed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(PassphraseEnv, tt.encryptWith)
+			encrypted, err := EncryptSecret(tt.value)
+			if err != nil {
+				t.Fatalf("EncryptSecret() error = %v", err)
+			}
+			if IsEncrypted(encrypted) != tt.wantEncrypted {
+				t.Fatalf("EncryptSecret() = %q, encrypted = %v, want %v", encrypted, IsEncrypted(encrypted), tt.wantEncrypted)
+			}
+			if tt.wantEncrypted && strings.Contains(encrypted, tt.value) {
+				t.Errorf("EncryptSecret() = %q contains the value", encrypted)
+			}
+			if tt.modify != nil {
+				encrypted = tt.modify(encrypted)
+			}
+			t.Setenv(PassphraseEnv, tt.decryptWith)
+			got, err := DecryptSecret(encrypted)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecryptSecret() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecryptSecret() error = %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("DecryptSecret() = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestEncryptSecretIsSalted(t *testing.T) {
+	t.Setenv(PassphraseEnv, "passphrase")
+	first, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	second, err := EncryptSecret("s3cret")
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	if first == second {
+		t.Errorf("EncryptSecret() returned %q twice", first)
+	}
+}
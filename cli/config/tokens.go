@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CachedToken is an access token issued to a context, with the refresh token that renews it. Url and
+// User record who the token was issued to, so that a token is not reused after a context changes.
+type CachedToken struct {
+	Url          string    `json:"url"`
+	User         string    `json:"user"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// IssuedTo checks if the token was issued for the context's current API and user
+func (t CachedToken) IssuedTo(ctx MaestroContext) bool {
+	return t.Url == ctx.Url && t.User == ctx.User
+}
+
+// GetCachedToken returns the cached token of a context, with its tokens decrypted
+func GetCachedToken(context string) (CachedToken, bool) {
+	tokens, err := readTokens()
+	if err != nil {
+		return CachedToken{}, false
+	}
+	token, ok := tokens[context]
+	if !ok {
+		return CachedToken{}, false
+	}
+	if token.AccessToken, err = DecryptSecret(token.AccessToken); err != nil {
+		return CachedToken{}, false
+	}
+	if token.RefreshToken, err = DecryptSecret(token.RefreshToken); err != nil {
+		return CachedToken{}, false
+	}
+	return token, true
+}
+
+// SaveCachedToken caches the token of a context. Tokens are encrypted when MAESTRO_PASSPHRASE is set.
+func SaveCachedToken(context string, token CachedToken) error {
+	tokens, err := readTokens()
+	if err != nil {
+		return err
+	}
+	if token.AccessToken, err = EncryptSecret(token.AccessToken); err != nil {
+		return err
+	}
+	if token.RefreshToken, err = EncryptSecret(token.RefreshToken); err != nil {
+		return err
+	}
+	tokens[context] = token
+	return writeTokens(tokens)
+}
+
+// DeleteCachedToken removes the cached token of a context
+func DeleteCachedToken(context string) error {
+	tokens, err := readTokens()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[context]; !ok {
+		return nil
+	}
+	delete(tokens, context)
+	return writeTokens(tokens)
+}
+
+func tokensFile() (string, error) {
+	folderName, err := configFolder()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(folderName, ".tokens.json"), nil
+}
+
+func readTokens() (map[string]CachedToken, error) {
+	ret := make(map[string]CachedToken)
+	fileName, err := tokensFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > 0 {
+		// a corrupted cache is discarded, tokens are simply issued again
+		json.Unmarshal(content, &ret)
+	}
+	return ret, nil
+}
+
+func writeTokens(tokens map[string]CachedToken) error {
+	fileName, err := tokensFile()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(fileName, b)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssuedTo(t *testing.T) {
+	token := CachedToken{Url: "http://localhost:8082/v1alpha2", User: "admin"}
+	tests := []struct {
+		name string
+		ctx  MaestroContext
+		want bool
+	}{
+		{
+			name: "same API and user",
+			ctx:  MaestroContext{Url: "http://localhost:8082/v1alpha2", User: "admin", Secret: "changed"},
+			want: true,
+		},
+		{
+			name: "other user",
+			ctx:  MaestroContext{Url: "http://localhost:8082/v1alpha2", User: "reader"},
+		},
+		{
+			name: "other API",
+			ctx:  MaestroContext{Url: "https://symphony.contoso.com/v1alpha2", User: "admin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := token.IssuedTo(tt.ctx); got != tt.want {
+				t.Errorf("IssuedTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{name: "plain"},
+		{name: "encrypted", passphrase: "passphrase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv(PassphraseEnv, tt.passphrase)
+			token := CachedToken{
+				Url:          "http://localhost:8082/v1alpha2",
+				User:         "admin",
+				AccessToken:  "access-token",
+				RefreshToken: "refresh-token",
+				ExpiresAt:    time.Now().Add(time.Hour).UTC().Round(time.Second),
+			}
+			if _, ok := GetCachedToken("local"); ok {
+				t.Fatalf("GetCachedToken() found a token in an empty cache")
+			}
+			if err := SaveCachedToken("local", token); err != nil {
+				t.Fatalf("SaveCachedToken() error = %v", err)
+			}
+			got, ok := GetCachedToken("local")
+			if !ok || got != token {
+				t.Errorf("GetCachedToken() = %+v, %v, want %+v", got, ok, token)
+			}
+
+			content, err := os.ReadFile(filepath.Join(home, ".symphony", ".tokens.json"))
+			if err != nil {
+				t.Fatalf("failed to read the token cache: %v", err)
+			}
+			if encrypted := !strings.Contains(string(content), token.AccessToken); encrypted != (tt.passphrase != "") {
+				t.Errorf("cached tokens encrypted = %v, want %v", encrypted, tt.passphrase != "")
+			}
+			// a token that can't be decrypted is ignored, so that a new one is issued
+			if tt.passphrase != "" {
+				t.Setenv(PassphraseEnv, "other")
+				if _, ok := GetCachedToken("local"); ok {
+					t.Errorf("GetCachedToken() returned a token with the wrong passphrase")
+				}
+				t.Setenv(PassphraseEnv, tt.passphrase)
+			}
+
+			if err := DeleteCachedToken("local"); err != nil {
+				t.Fatalf("DeleteCachedToken() error = %v", err)
+			}
+			if _, ok := GetCachedToken("local"); ok {
+				t.Errorf("GetCachedToken() found a deleted token")
+			}
+		})
+	}
+}
@@ -9,7 +9,6 @@ replace github.com/eclipse-symphony/symphony/coa => ../coa
 require github.com/spf13/cobra v1.6.1
 
 require (
-	github.com/eclipse-symphony/symphony/coa v0.0.0 // indirect
 	github.com/fatih/color v1.13.0 // indirect
 	github.com/mattn/go-colorable v0.1.12 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
@@ -19,7 +18,12 @@ require (
 	helm.sh/helm/v3 v3.10.0 // indirect
 )
 
-require github.com/princjef/mageutil v1.0.0
+require (
+	github.com/eclipse-symphony/symphony/coa v0.0.0
+	github.com/princjef/mageutil v1.0.0
+	golang.org/x/crypto v0.8.0
+	golang.org/x/term v0.7.0
+)
 
 require (
 	github.com/eclipse-symphony/symphony/api v0.0.0
@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"sigs.k8s.io/yaml"
 )
 
@@ -23,24 +24,26 @@ type authRequest struct {
 }
 
 type authResponse struct {
-	AccessToken string `json:"accessToken"`
-	TokenType   string `json:"tokenType"`
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
 
-func Remove(url string, username string, password string, objType string, objName string) error {
-	token, err := Login(url, username, password)
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type revokeRequest struct {
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+func Remove(url string, token string, objType string, objName string) error {
+	route, err := KindRoute(objType)
 	if err != nil {
 		return err
 	}
-	route := ""
-	switch objType {
-	case "target", "targets":
-		route = "/targets/registry"
-	case "solution", "solutions":
-		route = "/solutions"
-	case "instance", "instances":
-		route = "/instances"
-	}
 	if objName == "" {
 		return errors.New("object name is missing")
 	}
@@ -51,20 +54,11 @@ func Remove(url string, username string, password string, objType string, objNam
 	}
 	return nil
 }
-func Upsert(url string, username string, password string, objType string, objName string, payload []byte) error {
-	token, err := Login(url, username, password)
+func Upsert(url string, token string, objType string, objName string, payload []byte) error {
+	route, err := KindRoute(objType)
 	if err != nil {
 		return err
 	}
-	route := ""
-	switch objType {
-	case "target", "targets":
-		route = "/targets/registry"
-	case "solution", "solutions":
-		route = "/solutions"
-	case "instance", "instances":
-		route = "/instances"
-	}
 	if objName == "" {
 		return errors.New("object name is missing")
 	}
@@ -96,22 +90,11 @@ func yamlToJson(payload []byte) ([]byte, error) {
 	return json.Marshal(o.Spec)
 }
 
-func Get(url string, username string, password string, objType string, path string, docType string, objName string) ([]interface{}, error) {
-	token, err := Login(url, username, password)
+func Get(url string, token string, objType string, path string, docType string, objName string) ([]interface{}, error) {
+	route, err := KindRoute(objType)
 	if err != nil {
 		return nil, err
 	}
-	route := ""
-	switch objType {
-	case "target", "targets":
-		route = "/targets/registry"
-	case "device", "devices":
-		route = "/devices"
-	case "solution", "solutions":
-		route = "/solutions"
-	case "instance", "instances":
-		route = "/instances"
-	}
 	if objName != "" {
 		route += "/" + objName
 	}
@@ -143,25 +126,20 @@ func Get(url string, username string, password string, objType string, path stri
 	return ret, nil
 }
 
+// Login signs in to the API with a user name and password, and returns a bearer token
 func Login(url string, username string, password string) (string, error) {
 	data, _ := json.Marshal(authRequest{
 		UserName: username,
 		Password: password,
 	})
-	resp, err := callRestAPI(url, "/users/auth", "POST", data, "", nil)
-	if err != nil {
-		return "", err
-	}
-	var authResp authResponse
-	err = json.Unmarshal(resp, &authResp)
+	resp, err := requestToken(url, "/users/auth", data)
 	if err != nil {
 		return "", err
 	}
-	return "Bearer " + authResp.AccessToken, nil
+	return "Bearer " + resp.AccessToken, nil
 }
 
 func callRestAPI(url string, route string, method string, payload []byte, token string, parameters map[string]string) ([]byte, error) {
-	client := &http.Client{}
 	rUrl := url + route
 	req, err := http.NewRequest(method, rUrl, bytes.NewBuffer(payload))
 	if err != nil {
@@ -181,7 +159,7 @@ func callRestAPI(url string, route string, method string, payload []byte, token
 		req.URL.RawQuery = query.Encode()
 	}
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -198,3 +176,58 @@ func callRestAPI(url string, route string, method string, payload []byte, token
 	}
 	return bodyBytes, nil
 }
+
+// GetObject gets a Symphony object by kind and name. It returns nil when the object doesn't exist.
+func GetObject(url string, token string, objType string, objName string, scope string) (map[string]interface{}, error) {
+	route, err := KindRoute(objType)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := callRestAPI(url, route+"/"+objName, "GET", nil, token, scopeParameters(scope))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	var ret map[string]interface{}
+	err = json.Unmarshal(resp, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListObjects lists Symphony objects of a kind
+func ListObjects(url string, token string, objType string, scope string) ([]map[string]interface{}, error) {
+	route, err := KindRoute(objType)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := callRestAPI(url, route, "GET", nil, token, scopeParameters(scope))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	var ret []map[string]interface{}
+	err = json.Unmarshal(resp, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetSummary gets the latest deployment summary of an instance. It returns nil when the instance
+// hasn't been deployed yet.
+func GetSummary(url string, token string, instance string, scope string) (*model.SummaryResult, error) {
+	params := map[string]string{"instance": instance}
+	if scope != "" {
+		params["scope"] = scope
+	}
+	resp, err := callRestAPI(url, "/solution/queue", "GET", nil, token, params)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	var ret model.SummaryResult
+	err = json.Unmarshal(resp, &ret)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+var httpClient = &http.Client{}
+
+// ConfigureTLS sets the CA certificates trusted for API calls, and whether certificate verification
+// is skipped
+func ConfigureTLS(caCert string, insecure bool) error {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificate: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in '%s'", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient = &http.Client{Transport: transport}
+	return nil
+}
+
+// Authenticate returns a bearer token for a context. The cached access token is used until shortly
+// before it expires, then renewed with the cached refresh token. The context's user and secret are
+// only used when there is no usable cached token.
+func Authenticate(name string, ctx config.MaestroContext) (string, error) {
+	if token, ok := config.GetCachedToken(name); ok && token.IssuedTo(ctx) {
+		if time.Until(token.ExpiresAt) > coa_utils.TokenExpiryMargin {
+			return "Bearer " + token.AccessToken, nil
+		}
+		if token.RefreshToken != "" {
+			data, _ := json.Marshal(refreshRequest{RefreshToken: token.RefreshToken})
+			if resp, err := requestToken(ctx.Url, "/users/refresh", data); err == nil {
+				return cacheToken(name, ctx, resp), nil
+			}
+		}
+	}
+	secret, err := config.DecryptSecret(ctx.Secret)
+	if err != nil {
+		return "", err
+	}
+	return LoginContext(name, ctx, secret)
+}
+
+// LoginContext signs in to a context's API with a password, and caches the issued tokens
+func LoginContext(name string, ctx config.MaestroContext, password string) (string, error) {
+	data, _ := json.Marshal(authRequest{
+		UserName: ctx.User,
+		Password: password,
+	})
+	resp, err := requestToken(ctx.Url, "/users/auth", data)
+	if err != nil {
+		return "", err
+	}
+	return cacheToken(name, ctx, resp), nil
+}
+
+// Logout revokes the cached tokens of a context and removes them from the cache. Revocation is best
+// effort; the tokens are removed even when the API can't be reached.
+func Logout(name string, ctx config.MaestroContext) error {
+	if token, ok := config.GetCachedToken(name); ok && token.IssuedTo(ctx) {
+		data, _ := json.Marshal(revokeRequest{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+		})
+		callRestAPI(ctx.Url, "/users/revoke", "POST", data, "Bearer "+token.AccessToken, nil)
+	}
+	return config.DeleteCachedToken(name)
+}
+
+func requestToken(url string, route string, payload []byte) (authResponse, error) {
+	resp, err := callRestAPI(url, route, "POST", payload, "", nil)
+	if err != nil {
+		return authResponse{}, err
+	}
+	var authResp authResponse
+	err = json.Unmarshal(resp, &authResp)
+	if err != nil {
+		return authResponse{}, err
+	}
+	if authResp.AccessToken == "" {
+		return authResponse{}, fmt.Errorf("Symphony API didn't return an access token")
+	}
+	return authResp, nil
+}
+
+// cacheToken caches issued tokens and returns the bearer token. A token that can't be cached is still
+// returned, as it is valid for the current command.
+func cacheToken(name string, ctx config.MaestroContext, resp authResponse) string {
+	expiresAt := coa_utils.TokenExpiry(resp.AccessToken)
+	if resp.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	config.SaveCachedToken(name, config.CachedToken{
+		Url:          ctx.Url,
+		User:         ctx.User,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    expiresAt,
+	})
+	return "Bearer " + resp.AccessToken
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// newAuthServer serves the token routes of the users API. It accepts the password "secret" and the
+// refresh token "valid-refresh", and records the routes that were called.
+func newAuthServer(t *testing.T, calls *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls = append(*calls, r.URL.Path)
+		var resp authResponse
+		switch r.URL.Path {
+		case "/users/auth":
+			var req authRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.UserName != "admin" || req.Password != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			resp = authResponse{AccessToken: "login-token", TokenType: "Bearer", ExpiresIn: 3600, RefreshToken: "login-refresh"}
+		case "/users/refresh":
+			var req refreshRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.RefreshToken != "valid-refresh" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			resp = authResponse{AccessToken: "refreshed-token", TokenType: "Bearer", ExpiresIn: 3600, RefreshToken: "next-refresh"}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name        string
+		cached      *config.CachedToken
+		user        string
+		secret      string
+		want        string
+		wantCalls   []string
+		wantRefresh string
+		wantErr     bool
+	}{
+		{
+			name:      "no cached token",
+			want:      "Bearer login-token",
+			wantCalls: []string{"/users/auth"},
+		},
+		{
+			name:   "valid cached token",
+			cached: &config.CachedToken{AccessToken: "cached-token", RefreshToken: "valid-refresh", ExpiresAt: time.Now().Add(time.Hour)},
+			want:   "Bearer cached-token",
+		},
+		{
+			name:        "cached token within the expiry margin",
+			cached:      &config.CachedToken{AccessToken: "cached-token", RefreshToken: "valid-refresh", ExpiresAt: time.Now().Add(coa_utils.TokenExpiryMargin / 2)},
+			want:        "Bearer refreshed-token",
+			wantCalls:   []string{"/users/refresh"},
+			wantRefresh: "next-refresh",
+		},
+		{
+			name:      "expired token without a refresh token",
+			cached:    &config.CachedToken{AccessToken: "cached-token", ExpiresAt: time.Now().Add(-time.Minute)},
+			want:      "Bearer login-token",
+			wantCalls: []string{"/users/auth"},
+		},
+		{
+			name:      "rejected refresh token",
+			cached:    &config.CachedToken{AccessToken: "cached-token", RefreshToken: "revoked-refresh", ExpiresAt: time.Now().Add(-time.Minute)},
+			want:      "Bearer login-token",
+			wantCalls: []string{"/users/refresh", "/users/auth"},
+		},
+		{
+			name:      "token issued to another user",
+			cached:    &config.CachedToken{User: "reader", AccessToken: "cached-token", ExpiresAt: time.Now().Add(time.Hour)},
+			want:      "Bearer login-token",
+			wantCalls: []string{"/users/auth"},
+		},
+		{
+			name:      "wrong password",
+			secret:    "wrong",
+			wantCalls: []string{"/users/auth"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv(config.PassphraseEnv, "")
+			var calls []string
+			server := newAuthServer(t, &calls)
+			ctx := config.MaestroContext{Url: server.URL, User: "admin", Secret: "secret"}
+			if tt.secret != "" {
+				ctx.Secret = tt.secret
+			}
+			if tt.cached != nil {
+				cached := *tt.cached
+				cached.Url = ctx.Url
+				if cached.User == "" {
+					cached.User = ctx.User
+				}
+				if err := config.SaveCachedToken("test", cached); err != nil {
+					t.Fatalf("SaveCachedToken() error = %v", err)
+				}
+			}
+
+			got, err := Authenticate("test", ctx)
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("Authenticate() called %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Authenticate() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authenticate() = %q, want %q", got, tt.want)
+			}
+
+			// issued tokens are cached for the next command
+			token, ok := config.GetCachedToken("test")
+			if !ok || !token.IssuedTo(ctx) || "Bearer "+token.AccessToken != tt.want {
+				t.Errorf("cached token = %+v, want the access token of %q", token, tt.want)
+			}
+			if tt.wantRefresh != "" && token.RefreshToken != tt.wantRefresh {
+				t.Errorf("cached refresh token = %q, want %q", token.RefreshToken, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(config.PassphraseEnv, "")
+	var calls []string
+	server := newAuthServer(t, &calls)
+	ctx := config.MaestroContext{Url: server.URL, User: "admin", Secret: "secret"}
+	if _, err := LoginContext("test", ctx, "secret"); err != nil {
+		t.Fatalf("LoginContext() error = %v", err)
+	}
+	if err := Logout("test", ctx); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"/users/auth", "/users/revoke"}) {
+		t.Errorf("LoginContext() and Logout() called %v", calls)
+	}
+	if _, ok := config.GetCachedToken("test"); ok {
+		t.Errorf("Logout() kept the cached token")
+	}
+
+	// tokens are removed even when the API can't be reached
+	if _, err := LoginContext("test", ctx, "secret"); err != nil {
+		t.Fatalf("LoginContext() error = %v", err)
+	}
+	server.Close()
+	if err := Logout("test", ctx); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, ok := config.GetCachedToken("test"); ok {
+		t.Errorf("Logout() kept the cached token")
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest is a Symphony object read from a YAML or JSON document
+type Manifest struct {
+	Kind   string
+	Name   string
+	Scope  string
+	Spec   json.RawMessage
+	Source string
+}
+
+type manifestDocument struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"metadata"`
+	Spec  json.RawMessage `json:"spec"`
+	Scope string          `json:"scope,omitempty"`
+}
+
+type kindInfo struct {
+	route string
+	// order is the apply order of the kind. Objects are deleted in the reverse order.
+	order int
+}
+
+var kinds = map[string]kindInfo{
+	"site":       {route: "/federation/registry", order: 0},
+	"catalog":    {route: "/catalogs/registry", order: 1},
+	"model":      {route: "/models", order: 1},
+	"skill":      {route: "/skills", order: 1},
+	"device":     {route: "/devices", order: 1},
+	"target":     {route: "/targets/registry", order: 2},
+	"solution":   {route: "/solutions", order: 2},
+	"instance":   {route: "/instances", order: 3},
+	"campaign":   {route: "/campaigns", order: 3},
+	"activation": {route: "/activations/registry", order: 4},
+}
+
+// NormalizeKind turns a kind such as "Solution" or "solutions" into its singular, lower-case form
+func NormalizeKind(kind string) string {
+	kind = strings.ToLower(kind)
+	if _, ok := kinds[kind]; ok {
+		return kind
+	}
+	if _, ok := kinds[strings.TrimSuffix(kind, "s")]; ok {
+		return strings.TrimSuffix(kind, "s")
+	}
+	return kind
+}
+
+// KindRoute returns the vendor route of a Symphony kind, such as "/targets/registry" for targets
+func KindRoute(kind string) (string, error) {
+	if info, ok := kinds[NormalizeKind(kind)]; ok {
+		return info.route, nil
+	}
+	return "", fmt.Errorf("kind '%s' is not supported", kind)
+}
+
+// ReadManifests reads the Symphony objects in a file, in all .yaml, .yml and .json files of a directory,
+// or from stdin when path is "-". Files may contain multiple YAML documents separated by "---", or a
+// JSON array of objects.
+func ReadManifests(path string) ([]Manifest, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return parseManifests(data, "stdin")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = make([]string, 0)
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if !e.IsDir() && (ext == ".yaml" || ext == ".yml" || ext == ".json") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	ret := make([]Manifest, 0)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		manifests, err := parseManifests(data, f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, manifests...)
+	}
+	return ret, nil
+}
+
+func parseManifests(data []byte, source string) ([]Manifest, error) {
+	ret := make([]Manifest, 0)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var docs []json.RawMessage
+		if err := json.Unmarshal(trimmed, &docs); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err.Error())
+		}
+		for i, d := range docs {
+			m, err := parseManifest(d, fmt.Sprintf("%s[%d]", source, i))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, m)
+		}
+		return ret, nil
+	}
+	for i, d := range splitYamlDocuments(data) {
+		if isEmptyDocument(d) {
+			continue
+		}
+		m, err := parseManifest(d, fmt.Sprintf("%s#%d", source, i+1))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+func splitYamlDocuments(data []byte) [][]byte {
+	docs := make([][]byte, 0)
+	var current bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimRight(line, " \t\r") == "---" {
+			docs = append(docs, append([]byte{}, current.Bytes()...))
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	return append(docs, current.Bytes())
+}
+
+// isEmptyDocument tells whether a YAML document has nothing but blank lines and comments
+func isEmptyDocument(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+func parseManifest(data []byte, source string) (Manifest, error) {
+	var doc manifestDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Manifest{}, fmt.Errorf("%s: %s", source, err.Error())
+	}
+	kind := NormalizeKind(doc.Kind)
+	if _, ok := kinds[kind]; !ok {
+		return Manifest{}, fmt.Errorf("%s: kind '%s' is not supported", source, doc.Kind)
+	}
+	if doc.Metadata.Name == "" {
+		return Manifest{}, fmt.Errorf("%s: metadata.name is missing", source)
+	}
+	scope := doc.Scope
+	if scope == "" {
+		scope = doc.Metadata.Namespace
+	}
+	return Manifest{
+		Kind:   kind,
+		Name:   doc.Metadata.Name,
+		Scope:  scope,
+		Spec:   doc.Spec,
+		Source: source,
+	}, nil
+}
+
+// SortManifests orders manifests so that dependencies are applied first, such as targets and solutions
+// before instances, and campaigns before activations. Deletion uses the reverse order. The relative
+// order of objects of the same rank is preserved.
+func SortManifests(manifests []Manifest, forDeletion bool) {
+	sort.SliceStable(manifests, func(i, j int) bool {
+		a := kinds[manifests[i].Kind].order
+		b := kinds[manifests[j].Kind].order
+		if forDeletion {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+// ApplyManifest creates or updates the object described by a manifest
+func ApplyManifest(url string, token string, manifest Manifest) error {
+	route, err := KindRoute(manifest.Kind)
+	if err != nil {
+		return err
+	}
+	spec := manifest.Spec
+	if len(spec) == 0 {
+		spec = []byte("{}")
+	}
+	_, err = callRestAPI(url, route+"/"+manifest.Name, "POST", spec, token, scopeParameters(manifest.Scope))
+	return err
+}
+
+// DeleteManifest deletes the object described by a manifest
+func DeleteManifest(url string, token string, manifest Manifest) error {
+	route, err := KindRoute(manifest.Kind)
+	if err != nil {
+		return err
+	}
+	_, err = callRestAPI(url, route+"/"+manifest.Name, "DELETE", nil, token, scopeParameters(manifest.Scope))
+	return err
+}
+
+func scopeParameters(scope string) map[string]string {
+	if scope == "" {
+		return nil
+	}
+	return map[string]string{"scope": scope}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitYamlDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "single document",
+			data: "kind: Solution\n",
+			want: []string{"kind: Solution\n"},
+		},
+		{
+			name: "separated documents",
+			data: "kind: Target\n---\nkind: Solution\n",
+			want: []string{"kind: Target\n", "kind: Solution\n"},
+		},
+		{
+			name: "leading and trailing separators",
+			data: "---\nkind: Target\n---\n",
+			want: []string{"", "kind: Target\n", ""},
+		},
+		{
+			name: "separator with trailing whitespace",
+			data: "kind: Target\n--- \t\r\nkind: Solution\n",
+			want: []string{"kind: Target\n", "kind: Solution\n"},
+		},
+		{
+			name: "separator inside a value",
+			data: "kind: Target\ndescription: a --- b\n",
+			want: []string{"kind: Target\ndescription: a --- b\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := splitYamlDocuments([]byte(tt.data))
+			got := make([]string, len(docs))
+			for i, d := range docs {
+				got[i] = string(d)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitYamlDocuments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseManifests(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Manifest
+		wantErr string
+	}{
+		{
+			name: "separated documents",
+			data: "kind: Target\nmetadata:\n  name: t1\n---\nkind: solutions\nmetadata:\n  name: s1\n  namespace: dev\n",
+			want: []Manifest{
+				{Kind: "target", Name: "t1", Source: "file#1"},
+				{Kind: "solution", Name: "s1", Scope: "dev", Source: "file#2"},
+			},
+		},
+		{
+			name: "empty documents",
+			data: "---\n\n---\nkind: Target\nmetadata:\n  name: t1\n---\n  \n",
+			want: []Manifest{
+				{Kind: "target", Name: "t1", Source: "file#3"},
+			},
+		},
+		{
+			name: "comment-only documents",
+			data: "# targets\n---\nkind: Target\nmetadata:\n  name: t1\n---\n# nothing here\n  # indented\n",
+			want: []Manifest{
+				{Kind: "target", Name: "t1", Source: "file#2"},
+			},
+		},
+		{
+			name: "json array",
+			data: `[{"kind":"Campaign","metadata":{"name":"c1"},"scope":"prod"}]`,
+			want: []Manifest{
+				{Kind: "campaign", Name: "c1", Scope: "prod", Source: "file[0]"},
+			},
+		},
+		{
+			name:    "unsupported kind",
+			data:    "kind: Pod\nmetadata:\n  name: p1\n",
+			wantErr: "file#1: kind 'Pod' is not supported",
+		},
+		{
+			name:    "missing name",
+			data:    "kind: Target\n---\nkind: Target\nmetadata: {}\n",
+			wantErr: "file#1: metadata.name is missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseManifests([]byte(tt.data), "file")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseManifests() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseManifests() error = %v", err)
+			}
+			for i := range got {
+				got[i].Spec = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseManifests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortManifests(t *testing.T) {
+	manifests := func(names ...string) []Manifest {
+		ret := make([]Manifest, len(names))
+		for i, n := range names {
+			kind, name, _ := strings.Cut(n, "/")
+			ret[i] = Manifest{Kind: kind, Name: name}
+		}
+		return ret
+	}
+	tests := []struct {
+		name        string
+		manifests   []Manifest
+		forDeletion bool
+		want        []Manifest
+	}{
+		{
+			name:      "dependencies first",
+			manifests: manifests("activation/a1", "instance/i1", "solution/s1", "campaign/c1", "target/t1", "site/hq", "catalog/cfg"),
+			want:      manifests("site/hq", "catalog/cfg", "solution/s1", "target/t1", "instance/i1", "campaign/c1", "activation/a1"),
+		},
+		{
+			name:        "dependents first for deletion",
+			manifests:   manifests("site/hq", "target/t1", "solution/s1", "instance/i1", "activation/a1", "campaign/c1"),
+			forDeletion: true,
+			want:        manifests("activation/a1", "instance/i1", "campaign/c1", "target/t1", "solution/s1", "site/hq"),
+		},
+		{
+			name:      "same rank keeps its order",
+			manifests: manifests("solution/s2", "target/t1", "solution/s1", "target/t2"),
+			want:      manifests("solution/s2", "target/t1", "solution/s1", "target/t2"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SortManifests(tt.manifests, tt.forDeletion)
+			if !reflect.DeepEqual(tt.manifests, tt.want) {
+				t.Errorf("SortManifests() = %+v, want %+v", tt.manifests, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+)
+
+// newEventServer streams chunks as text/event-stream, flushing each one, and then closes the stream
+// unless hold is set, in which case the stream stays open until the client goes away
+func newEventServer(t *testing.T, chunks []string, hold bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" || r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, chunk := range chunks {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		if hold {
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWatchEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []string
+		want    []model.WatchEvent
+		wantErr string
+	}{
+		{
+			name:    "single events",
+			chunks:  []string{"data: {\"id\":1,\"topic\":\"job\"}\n\n", "data: {\"id\":2,\"topic\":\"trace\"}\n\n"},
+			want:    []model.WatchEvent{{Id: 1, Topic: "job"}, {Id: 2, Topic: "trace"}},
+			wantErr: "closed by the server",
+		},
+		{
+			name:    "event split across data lines",
+			chunks:  []string{"data: {\"id\":1,\ndata: \"topic\":\"job\"}\n\n"},
+			want:    []model.WatchEvent{{Id: 1, Topic: "job"}},
+			wantErr: "closed by the server",
+		},
+		{
+			name:    "event split across writes",
+			chunks:  []string{"data: {\"id\":1,", "\"topic\":\"job\"}\n", "\n"},
+			want:    []model.WatchEvent{{Id: 1, Topic: "job"}},
+			wantErr: "closed by the server",
+		},
+		{
+			name:    "comments, keepalives and other fields",
+			chunks:  []string{": connected\n\n", "id: 1\nevent: job\ndata:{\"id\":1,\"topic\":\"job\"}\nretry: 1000\n\n", ":\n\n", ": keepalive\n\n"},
+			want:    []model.WatchEvent{{Id: 1, Topic: "job"}},
+			wantErr: "closed by the server",
+		},
+		{
+			name:    "malformed event",
+			chunks:  []string{"data: {not json\n\n", "data: {\"id\":2,\"topic\":\"job\"}\n\n"},
+			want:    []model.WatchEvent{{Id: 2, Topic: "job"}},
+			wantErr: "closed by the server",
+		},
+		{
+			name:    "unterminated event",
+			chunks:  []string{"data: {\"id\":1,\"topic\":\"job\"}\n\n", "data: {\"id\":2,\"topic\":\"job\"}\n"},
+			want:    []model.WatchEvent{{Id: 1, Topic: "job"}},
+			wantErr: "closed by the server",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newEventServer(t, tt.chunks, false)
+			var got []model.WatchEvent
+			err := WatchEvents(context.Background(), server.URL, "Bearer token", nil, func(event model.WatchEvent) {
+				got = append(got, event)
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("WatchEvents() error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WatchEvents() events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchEventsCancelled(t *testing.T) {
+	server := newEventServer(t, []string{": keepalive\n\n", "data: {\"id\":1,\"topic\":\"job\"}\n\n"}, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []model.WatchEvent
+	// the stream stays open, so the watch only ends when the caller stops it
+	err := WatchEvents(ctx, server.URL, "Bearer token", nil, func(event model.WatchEvent) {
+		got = append(got, event)
+		cancel()
+	})
+	if err != nil {
+		t.Errorf("WatchEvents() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []model.WatchEvent{{Id: 1, Topic: "job"}}) {
+		t.Errorf("WatchEvents() events = %+v", got)
+	}
+}
+
+func TestWatchEventsParameters(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer server.Close()
+	WatchEvents(context.Background(), server.URL, "", map[string]string{"topic": "job", "scope": "", "name": "app"}, func(model.WatchEvent) {})
+	if query != "name=app&topic=job" {
+		t.Errorf("WatchEvents() query = %q, want %q", query, "name=app&topic=job")
+	}
+}
+
+func TestWatchEventsRejected(t *testing.T) {
+	server := newEventServer(t, nil, false)
+	err := WatchEvents(context.Background(), server.URL, "Bearer expired", nil, func(model.WatchEvent) {
+		t.Errorf("WatchEvents() called the handler of a rejected request")
+	})
+	if err == nil || !strings.Contains(err.Error(), "[401]") {
+		t.Errorf("WatchEvents() error = %v, want a 401 error", err)
+	}
+}
@@ -7,6 +7,7 @@
 package http
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -86,6 +87,8 @@ func (h *HttpBinding) useRouter(endpoints []v1alpha2.Endpoint) fasthttp.RequestH
 }
 func (h *HttpBinding) getRouter(endpoints []v1alpha2.Endpoint) *routing.Router {
 	router := routing.New()
+	// the matched route template is used as a low-cardinality metrics label
+	router.SaveMatchedRoutePath = true
 	for _, e := range endpoints {
 		path := fmt.Sprintf("/%s/%s", e.Version, e.Route)
 		for _, p := range e.Parameters {
@@ -136,6 +139,14 @@ func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler)
 
 		if resp.State == v1alpha2.APIRedirect {
 			reqCtx.Redirect(resp.RedirectUri, 308)
+		} else if resp.Stream != nil {
+			reqCtx.SetContentType(resp.ContentType)
+			reqCtx.Response.Header.Set("Cache-Control", "no-cache")
+			reqCtx.SetStatusCode(int(resp.State))
+			stream := resp.Stream
+			reqCtx.SetBodyStreamWriter(func(w *bufio.Writer) {
+				stream(w, w.Flush)
+			})
 		} else {
 			if len(resp.Metadata) != 0 {
 				data, _ := json.Marshal(resp.Metadata)
@@ -9,12 +9,10 @@ package http
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
-	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
@@ -31,15 +29,18 @@ func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvid
 		case "middleware.http.cors":
 			cors := CORS{Properties: c.Properties}
 			ret.Handlers = append(ret.Handlers, cors.CORS)
+		case "middleware.http.metrics":
+			metrics := NewMetrics(c.Properties)
+			ret.Handlers = append(ret.Handlers, metrics.Metrics)
 		case "middleware.http.trail":
 			trail := Trail{}
 			trail.SetPubSubProvider(pubsubProvider)
 			ret.Handlers = append(ret.Handlers, trail.Trail)
 		case "middleware.http.telemetry":
-			enableAppInsight := os.Getenv("ENABLE_APP_INSIGHT")
-			c.Properties["enabled"] = enableAppInsight == "true"
-			c.Properties["client"] = uuid.New().String()
-			telemetry := Telemetry{Properties: c.Properties}
+			telemetry, err := NewTelemetry(c.Properties)
+			if err != nil {
+				return ret, err
+			}
 			ret.Handlers = append(ret.Handlers, telemetry.Telemetry)
 		case "middleware.http.jwt":
 			jwts := JWT{}
@@ -48,8 +49,15 @@ func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvid
 			if err != nil {
 				return ret, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
 			}
-			if jwts.AuthHeader == "" {
-				jwts.AuthHeader = "Authorization"
+			err = jwts.Init()
+			if err != nil {
+				return ret, err
+			}
+			if pubsubProvider != nil {
+				err = jwts.SetPubSubProvider(pubsubProvider)
+				if err != nil {
+					return ret, err
+				}
 			}
 			ret.Handlers = append(ret.Handlers, jwts.JWT)
 		case "middleware.http.tracing":
@@ -66,6 +74,15 @@ func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvid
 				}
 				config.Pipelines = pipelines
 			}
+			if v, ok := c.Properties["serviceName"].(string); ok {
+				config.ServiceName = v
+			}
+			if v, ok := c.Properties["serviceVersion"].(string); ok {
+				config.ServiceVersion = v
+			}
+			if v, ok := c.Properties["siteId"].(string); ok {
+				config.SiteId = v
+			}
 			err := tracing.Observability.Init(config)
 			if err != nil {
 				return ret, v1alpha2.NewCOAError(nil, "failed to initialize tracing middleware", v1alpha2.InternalError)
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"io"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestStreamedResponse(t *testing.T) {
+	binding := HttpBinding{}
+	handler := binding.useRouter([]v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   "events",
+			Version: "v1alpha2",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					State:       v1alpha2.OK,
+					ContentType: "text/event-stream",
+					Body:        []byte("ignored"),
+					Stream: func(w io.Writer, flush func() error) {
+						for _, e := range []string{"first", "second"} {
+							io.WriteString(w, "data: "+e+"\n\n")
+							assert.Nil(t, flush())
+						}
+					},
+				}
+			},
+		},
+	})
+	ctx := serve(handler, fasthttp.MethodGet, "/v1alpha2/events")
+	assert.Equal(t, 200, ctx.Response.StatusCode())
+	assert.Equal(t, "text/event-stream", string(ctx.Response.Header.ContentType()))
+	assert.Equal(t, "no-cache", string(ctx.Response.Header.Peek("Cache-Control")))
+	assert.Equal(t, "data: first\n\ndata: second\n\n", string(ctx.Response.Body()))
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+const (
+	defaultJWKSRefreshInterval = 3600 * time.Second
+	minJWKSRefreshInterval     = 10 * time.Second
+)
+
+// JSONWebKey is a single key in a JSON Web Key Set (RFC 7517)
+type JSONWebKey struct {
+	Kid string `json:"kid,omitempty"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// JSONWebKeySet is a JSON Web Key Set document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type oidcConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JWKSUri string `json:"jwks_uri"`
+}
+
+// keySet caches verification keys loaded from a JWKS url or file, indexed by key id.
+// Keys are reloaded when the refresh interval expires, or when a token carries an
+// unknown key id (rate-limited to minJWKSRefreshInterval) so that rotated keys are
+// picked up without restarting the server.
+type keySet struct {
+	url             string
+	file            string
+	issuer          string
+	refreshInterval time.Duration
+	client          *http.Client
+	lock            sync.RWMutex
+	reloadLock      sync.Mutex
+	keys            map[string]interface{}
+	lastLoaded      time.Time
+}
+
+func newKeySet(url string, file string, issuer string, refreshInterval time.Duration) *keySet {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &keySet{
+		url:             url,
+		file:            file,
+		issuer:          issuer,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		keys:            make(map[string]interface{}),
+	}
+}
+
+func (s *keySet) getKey(kid string) (interface{}, error) {
+	s.lock.RLock()
+	key, ok := s.lookup(kid)
+	stale := time.Since(s.lastLoaded) > s.refreshInterval
+	canReload := time.Since(s.lastLoaded) > minJWKSRefreshInterval
+	s.lock.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && !canReload {
+		return nil, fmt.Errorf("key '%s' is not found in key set", kid)
+	}
+	if err := s.reload(); err != nil {
+		if ok {
+			// keep using the cached key if the key set can't be refreshed
+			return key, nil
+		}
+		return nil, err
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key '%s' is not found in key set", kid)
+}
+
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" {
+		// tokens without a key id can only be verified when the key set has exactly one key
+		if len(s.keys) == 1 {
+			for _, v := range s.keys {
+				return v, true
+			}
+		}
+		return nil, false
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *keySet) reload() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+	var data []byte
+	var err error
+	if s.file != "" {
+		data, err = os.ReadFile(s.file)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to read JWKS file", v1alpha2.BadConfig)
+		}
+	} else {
+		if s.url == "" {
+			s.url, err = discoverJWKSUrl(s.client, s.issuer)
+			if err != nil {
+				return err
+			}
+		}
+		data, err = utils.Download(context.Background(), s.client, s.url)
+		if err != nil {
+			return err
+		}
+	}
+	set := JSONWebKeySet{}
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to parse JWKS document", v1alpha2.BadConfig)
+	}
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			// skip keys of unsupported types instead of failing the whole set
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys = keys
+	s.lastLoaded = time.Now()
+	return nil
+}
+
+// discoverJWKSUrl reads the jwks_uri from the OpenID Connect discovery document of an issuer
+func discoverJWKSUrl(client *http.Client, issuer string) (string, error) {
+	data, err := utils.Download(context.Background(), client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	config := oidcConfiguration{}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to parse OpenID configuration", v1alpha2.BadConfig)
+	}
+	if config.JWKSUri == "" {
+		return "", v1alpha2.NewCOAError(nil, "OpenID configuration doesn't contain a jwks_uri", v1alpha2.BadConfig)
+	}
+	return config.JWKSUri, nil
+}
+
+// PublicKey converts the JWK into a key that can be used to verify token signatures
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve '%s' is not supported", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("key type '%s' is not supported", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
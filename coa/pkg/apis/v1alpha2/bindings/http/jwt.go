@@ -10,13 +10,18 @@ import (
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/valyala/fasthttp"
 )
 
+const defaultScope = "default"
+
 type JWT struct {
 	AuthHeader  string                 `json:"authHeader"`
 	VerifyKey   string                 `json:"verifyKey"`
@@ -27,6 +32,19 @@ type JWT struct {
 	Roles       []ClaimRoleMap    `json:"roles,omitempty"`
 	EnableRBAC  bool              `json:"enableRBAC,omitempty"`
 	Policy      map[string]Policy `json:"policy,omitempty"`
+	// JWKSUrl and JWKSFile point to a JSON Web Key Set used to verify tokens by key id
+	JWKSUrl  string `json:"jwksUrl,omitempty"`
+	JWKSFile string `json:"jwksFile,omitempty"`
+	// JWKSRefreshInterval is how often (in seconds) the key set is reloaded
+	JWKSRefreshInterval int `json:"jwksRefreshInterval,omitempty"`
+	// OIDCDiscovery resolves the JWKS url from the issuer's OpenID configuration
+	OIDCDiscovery bool     `json:"oidcDiscovery,omitempty"`
+	Issuer        string   `json:"issuer,omitempty"`
+	Audiences     []string `json:"audiences,omitempty"`
+	// Leeway is the clock skew (in seconds) tolerated when checking exp, nbf and iat
+	Leeway      int `json:"leeway,omitempty"`
+	keys        *keySet
+	revocations *revocationList
 }
 type ClaimRoleMap struct {
 	Role  string `json:"role"`
@@ -35,95 +53,245 @@ type ClaimRoleMap struct {
 }
 type Policy struct {
 	Items map[string]string `json:"items"`
+	Rules []PolicyRule      `json:"rules,omitempty"`
+}
+
+// PolicyRule grants verbs on resource types, optionally limited to a set of scopes.
+// Resources are matched against the route segments following the API version, such
+// as "instances" or "solution/queue". Verbs are "read", "write", "delete", HTTP
+// methods, or "*". An empty Scopes list allows all scopes.
+type PolicyRule struct {
+	Resources []string `json:"resources"`
+	Verbs     []string `json:"verbs"`
+	Scopes    []string `json:"scopes,omitempty"`
+}
+
+// Init prepares the key set when the middleware is configured with JWKS or OIDC discovery
+func (j *JWT) Init() error {
+	if j.AuthHeader == "" {
+		j.AuthHeader = "Authorization"
+	}
+	if j.OIDCDiscovery && j.Issuer == "" {
+		return v1alpha2.NewCOAError(nil, "issuer is required when OIDC discovery is enabled", v1alpha2.BadConfig)
+	}
+	if j.JWKSUrl != "" || j.JWKSFile != "" || j.OIDCDiscovery {
+		j.keys = newKeySet(j.JWKSUrl, j.JWKSFile, j.Issuer, time.Duration(j.JWKSRefreshInterval)*time.Second)
+	}
+	return nil
+}
+
+// SetPubSubProvider subscribes the middleware to token revocation events
+func (j *JWT) SetPubSubProvider(provider pubsub.IPubSubProvider) error {
+	j.revocations = newRevocationList()
+	return provider.Subscribe(v1alpha2.TokenRevocationTopic, j.revocations.onEvent)
 }
 
 func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		if j.IgnorePaths != nil {
-			for _, p := range j.IgnorePaths {
-				if p == string(ctx.Path()) {
-					next(ctx)
-					return
-				}
-			}
-		}
 		if ctx.IsOptions() {
 			next(ctx)
 			return
 		}
-		tokenStr := j.readAuthHeader(ctx)
-		if tokenStr == "" {
-			ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
-		} else {
-			_, roles, err := j.validateToken(tokenStr)
-			if err != nil {
-				ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
-			} else {
-				if j.EnableRBAC {
-					path := string(ctx.Path())
-					method := string(ctx.Method())
-					for _, role := range roles {
-						if v, ok := j.Policy[role]; ok {
-							for key, val := range v.Items {
-								if key == "*" || strings.HasPrefix(path, key) {
-									if val == "*" || strings.Contains(val, method) {
-										next(ctx)
-										return
-									}
-								}
-							}
-						}
+		path := string(ctx.Path())
+		method := string(ctx.Method())
+		scope := string(ctx.QueryArgs().Peek("scope"))
+		err := j.Authorize(j.readAuthHeader(ctx), path, method, scope)
+		if err != nil {
+			forbid(ctx, err.Error())
+			return
+		}
+		next(ctx)
+	}
+}
+
+// Authorize validates a bearer token and, when RBAC is enabled, checks that the token's roles
+// allow the method on the path and scope. It's shared by bindings other than HTTP, which carry
+// the token in request metadata. The returned error is the reason for the denial.
+func (j *JWT) Authorize(tokenStr string, path string, method string, scope string) error {
+	for _, p := range j.IgnorePaths {
+		if p == path {
+			return nil
+		}
+	}
+	if tokenStr == "" {
+		return v1alpha2.NewCOAError(nil, "missing bearer token", v1alpha2.Unauthorized)
+	}
+	claims, roles, err := j.validateToken(tokenStr)
+	if err != nil {
+		return v1alpha2.NewCOAError(nil, err.Error(), v1alpha2.Unauthorized)
+	}
+	if j.revocations != nil && j.revocations.isRevoked(claims) {
+		return v1alpha2.NewCOAError(nil, "token has been revoked", v1alpha2.Unauthorized)
+	}
+	if j.EnableRBAC {
+		if ok, reason := j.authorize(roles, path, method, scope); !ok {
+			return v1alpha2.NewCOAError(nil, reason, v1alpha2.Unauthorized)
+		}
+	}
+	return nil
+}
+func forbid(ctx *fasthttp.RequestCtx, reason string) {
+	ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
+	ctx.SetContentType("text/plain")
+	ctx.SetBodyString(reason)
+}
+func (j JWT) authorize(roles []string, path string, method string, scope string) (bool, string) {
+	if len(roles) == 0 {
+		return false, "no roles are assigned to the caller"
+	}
+	if scope == "" {
+		scope = defaultScope
+	}
+	resource := resourceFromPath(path)
+	for _, role := range roles {
+		if v, ok := j.Policy[role]; ok {
+			for key, val := range v.Items {
+				if key == "*" || strings.HasPrefix(path, key) {
+					if val == "*" || strings.Contains(val, method) {
+						return true, ""
 					}
-					ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
-					return
 				}
-				next(ctx)
 			}
+			for _, rule := range v.Rules {
+				if rule.allows(resource, method, scope) {
+					return true, ""
+				}
+			}
+		}
+	}
+	sorted := append([]string{}, roles...)
+	sort.Strings(sorted)
+	return false, fmt.Sprintf("roles [%s] are not allowed to %s '%s' in scope '%s'", strings.Join(sorted, ", "), verbFromMethod(method), resource, scope)
+}
+func (r PolicyRule) allows(resource string, method string, scope string) bool {
+	return matchResource(r.Resources, resource) && matchVerb(r.Verbs, method) && matchScope(r.Scopes, scope)
+}
+func matchResource(resources []string, resource string) bool {
+	for _, r := range resources {
+		r = strings.Trim(r, "/")
+		if r == "*" || r == resource || strings.HasPrefix(resource, r+"/") {
+			return true
+		}
+	}
+	return false
+}
+func matchVerb(verbs []string, method string) bool {
+	verb := verbFromMethod(method)
+	for _, v := range verbs {
+		if v == "*" || strings.EqualFold(v, verb) || strings.EqualFold(v, method) {
+			return true
+		}
+	}
+	return false
+}
+func matchScope(scopes []string, scope string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s == "*" || s == scope {
+			return true
 		}
 	}
+	return false
+}
+func verbFromMethod(method string) string {
+	switch strings.ToUpper(method) {
+	case fasthttp.MethodGet, fasthttp.MethodHead:
+		return "read"
+	case fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch:
+		return "write"
+	case fasthttp.MethodDelete:
+		return "delete"
+	default:
+		return strings.ToLower(method)
+	}
+}
+
+// resourceFromPath strips the API version from a request path, e.g. "/v1alpha2/solution/queue" becomes "solution/queue"
+func resourceFromPath(path string) string {
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
 }
 func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 	v := ctx.Request.Header.Peek(j.AuthHeader)
 	if v != nil {
-		tokenStr := string(v)
-		token := strings.Split(tokenStr, "Bearer ")
-		if len(token) == 2 {
-			return strings.TrimSpace(token[1])
+		return ParseBearerToken(string(v))
+	}
+	return ""
+}
+
+// ParseBearerToken extracts the token from a "Bearer <token>" authorization value
+func ParseBearerToken(value string) string {
+	token := strings.Split(value, "Bearer ")
+	if len(token) == 2 {
+		return strings.TrimSpace(token[1])
+	}
+	return ""
+}
+func (j *JWT) getVerifyKey(token *jwt.Token) (interface{}, error) {
+	if j.keys != nil {
+		kid, _ := token.Header["kid"].(string)
+		return j.keys.getKey(kid)
+	}
+	if j.verifyKey != nil {
+		return j.verifyKey, nil
+	} else {
+		if strings.HasPrefix(j.VerifyKey, "-----BEGIN PUBLIC KEY-----") {
+			verifyKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(j.VerifyKey))
+			if err != nil {
+				return nil, v1alpha2.NewCOAError(nil, "failed to parse public key", v1alpha2.BadConfig)
+			}
+			j.verifyKey = verifyKey
+			return j.verifyKey, nil
 		} else {
-			return ""
+			return []byte(j.VerifyKey), nil
 		}
 	}
-	return ""
+}
+func (j *JWT) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now().Unix()
+	leeway := int64(j.Leeway)
+	if !claims.VerifyExpiresAt(now-leeway, false) {
+		return errors.New("token is expired")
+	}
+	if !claims.VerifyNotBefore(now+leeway, false) {
+		return errors.New("token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(now+leeway, false) {
+		return errors.New("token used before issued")
+	}
+	if j.Issuer != "" && !claims.VerifyIssuer(j.Issuer, true) {
+		return errors.New("token issuer is not accepted")
+	}
+	if len(j.Audiences) > 0 {
+		for _, aud := range j.Audiences {
+			if claims.VerifyAudience(aud, true) {
+				return nil
+			}
+		}
+		return errors.New("token audience is not accepted")
+	}
+	return nil
 }
 func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string, error) {
 	ret := make(map[string]interface{})
 	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		claims,
-		func(token *jwt.Token) (interface{}, error) {
-			if j.verifyKey != nil {
-				return j.verifyKey, nil
-			} else {
-				if strings.HasPrefix(j.VerifyKey, "-----BEGIN PUBLIC KEY-----") {
-					verifyKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(j.VerifyKey))
-					if err != nil {
-						return ret, v1alpha2.NewCOAError(nil, "failed to parse public key", v1alpha2.BadConfig)
-					}
-					j.verifyKey = verifyKey
-					return j.verifyKey, nil
-				} else {
-					return []byte(j.VerifyKey), nil
-				}
-			}
-		},
-	)
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.ParseWithClaims(tokenStr, claims, j.getVerifyKey)
 	if err != nil {
 		return ret, nil, err
 	}
 	if !token.Valid {
 		return ret, nil, errors.New("invalid token")
 	}
+	err = j.validateClaims(claims)
+	if err != nil {
+		return ret, nil, err
+	}
 	for k, v := range claims {
 		ret[k] = v
 	}
@@ -150,7 +318,7 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 		roles = make([]string, 0)
 		for _, m := range j.Roles {
 			if v, ok := ret[m.Claim]; ok {
-				if m.Value == "*" || v == m.Value {
+				if claimMatches(v, m.Value) {
 					roles = append(roles, m.Role)
 				}
 			}
@@ -159,3 +327,20 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 	}
 	return ret, roles, nil
 }
+
+// claimMatches checks a claim value against a role mapping value. Array claims, such as
+// "roles" or "groups" issued by OIDC providers, match when any of their elements match.
+func claimMatches(claim interface{}, value string) bool {
+	if value == "*" {
+		return true
+	}
+	if arr, ok := claim.([]interface{}); ok {
+		for _, v := range arr {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	return claim == value
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	str, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return str
+}
+
+func toJWK(kid string, key *rsa.PrivateKey) JSONWebKey {
+	return JSONWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func callJWT(j JWT, token string, method string, uri string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	if token != "" {
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	j.JWT(func(c *fasthttp.RequestCtx) {
+		c.SetStatusCode(fasthttp.StatusOK)
+	})(ctx)
+	return ctx
+}
+
+func TestJWTSharedSecret(t *testing.T) {
+	j := JWT{VerifyKey: "SymphonyKey"}
+	assert.Nil(t, j.Init())
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin"}).SignedString([]byte("SymphonyKey"))
+	ctx := callJWT(j, token, "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = callJWT(j, "", "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "missing bearer token", string(ctx.Response.Body()))
+}
+
+func TestJWTJWKSFileRotation(t *testing.T) {
+	key1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	data, _ := json.Marshal(JSONWebKeySet{Keys: []JSONWebKey{toJWK("k1", key1)}})
+	assert.Nil(t, os.WriteFile(file, data, 0600))
+
+	j := JWT{JWKSFile: file}
+	assert.Nil(t, j.Init())
+	ctx := callJWT(j, signRS256(t, key1, "k1", jwt.MapClaims{"user": "admin"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	// rotate keys, then force the cache to be considered reloadable
+	data, _ = json.Marshal(JSONWebKeySet{Keys: []JSONWebKey{toJWK("k1", key1), toJWK("k2", key2)}})
+	assert.Nil(t, os.WriteFile(file, data, 0600))
+	j.keys.lastLoaded = time.Now().Add(-time.Minute)
+	ctx = callJWT(j, signRS256(t, key2, "k2", jwt.MapClaims{"user": "admin"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = callJWT(j, signRS256(t, key2, "k3", jwt.MapClaims{"user": "admin"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+}
+
+func TestJWTOIDCDiscovery(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(oidcConfiguration{Issuer: server.URL, JWKSUri: server.URL + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(JSONWebKeySet{Keys: []JSONWebKey{toJWK("k1", key)}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	j := JWT{OIDCDiscovery: true, Issuer: server.URL, Audiences: []string{"symphony"}}
+	assert.Nil(t, j.Init())
+	ctx := callJWT(j, signRS256(t, key, "k1", jwt.MapClaims{"iss": server.URL, "aud": "symphony"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = callJWT(j, signRS256(t, key, "k1", jwt.MapClaims{"iss": "https://elsewhere", "aud": "symphony"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "token issuer is not accepted", string(ctx.Response.Body()))
+
+	ctx = callJWT(j, signRS256(t, key, "k1", jwt.MapClaims{"iss": server.URL, "aud": "someone-else"}), "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "token audience is not accepted", string(ctx.Response.Body()))
+}
+
+func TestJWTOIDCDiscoveryRequiresIssuer(t *testing.T) {
+	j := JWT{OIDCDiscovery: true}
+	assert.NotNil(t, j.Init())
+}
+
+func TestJWTLeeway(t *testing.T) {
+	expired := jwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix()}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, expired).SignedString([]byte("SymphonyKey"))
+
+	j := JWT{VerifyKey: "SymphonyKey"}
+	assert.Nil(t, j.Init())
+	ctx := callJWT(j, token, "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "token is expired", string(ctx.Response.Body()))
+
+	j.Leeway = 60
+	ctx = callJWT(j, token, "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+}
+
+func TestJWTRBACRules(t *testing.T) {
+	j := JWT{
+		VerifyKey:  "SymphonyKey",
+		EnableRBAC: true,
+		Roles: []ClaimRoleMap{
+			{Role: "reader", Claim: "user", Value: "*"},
+			{Role: "team-a-operator", Claim: "groups", Value: "team-a"},
+		},
+		Policy: map[string]Policy{
+			"reader": {
+				Items: map[string]string{"*": "GET"},
+			},
+			"team-a-operator": {
+				Rules: []PolicyRule{
+					{Resources: []string{"instances"}, Verbs: []string{"write", "delete"}, Scopes: []string{"team-a"}},
+				},
+			},
+		},
+	}
+	assert.Nil(t, j.Init())
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "bob", "groups": []string{"team-a"}}).SignedString([]byte("SymphonyKey"))
+
+	ctx := callJWT(j, token, "GET", "/v1alpha2/instances")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = callJWT(j, token, "POST", "/v1alpha2/instances/instance1?scope=team-a")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	ctx = callJWT(j, token, "DELETE", "/v1alpha2/instances/instance1?scope=team-b")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "roles [reader, team-a-operator] are not allowed to delete 'instances/instance1' in scope 'team-b'", string(ctx.Response.Body()))
+
+	ctx = callJWT(j, token, "POST", "/v1alpha2/solutions/solution1?scope=team-a")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+}
+
+func TestJWTRBACNoRoles(t *testing.T) {
+	j := JWT{
+		VerifyKey:  "SymphonyKey",
+		EnableRBAC: true,
+		Roles:      []ClaimRoleMap{{Role: "administrator", Claim: "user", Value: "admin"}},
+		Policy:     map[string]Policy{"administrator": {Items: map[string]string{"*": "*"}}},
+	}
+	assert.Nil(t, j.Init())
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "bob"}).SignedString([]byte("SymphonyKey"))
+	ctx := callJWT(j, token, "GET", "/v1alpha2/instances")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "no roles are assigned to the caller", string(ctx.Response.Body()))
+}
+
+func TestResourceFromPath(t *testing.T) {
+	assert.Equal(t, "solution/queue", resourceFromPath("/v1alpha2/solution/queue"))
+	assert.Equal(t, "instances", resourceFromPath("/v1alpha2/instances/"))
+	assert.Equal(t, "", resourceFromPath("/v1alpha2"))
+}
+
+func TestJWTRevocation(t *testing.T) {
+	provider := &memory.InMemoryPubSubProvider{}
+	provider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	j := JWT{VerifyKey: "SymphonyKey"}
+	assert.Nil(t, j.Init())
+	assert.Nil(t, j.SetPubSubProvider(provider))
+
+	issued := time.Now().Add(-time.Minute).Unix()
+	token1, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin", "jti": "t1", "iat": issued}).SignedString([]byte("SymphonyKey"))
+	token2, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "bob", "jti": "t2", "iat": issued}).SignedString([]byte("SymphonyKey"))
+	assert.Equal(t, fasthttp.StatusOK, callJWT(j, token1, "GET", "/v1alpha2/solutions").Response.StatusCode())
+
+	j.revocations.onEvent(v1alpha2.TokenRevocationTopic, v1alpha2.Event{Body: v1alpha2.TokenRevocationData{TokenId: "t1"}})
+	ctx := callJWT(j, token1, "GET", "/v1alpha2/solutions")
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+	assert.Equal(t, "token has been revoked", string(ctx.Response.Body()))
+	assert.Equal(t, fasthttp.StatusOK, callJWT(j, token2, "GET", "/v1alpha2/solutions").Response.StatusCode())
+
+	j.revocations.onEvent(v1alpha2.TokenRevocationTopic, v1alpha2.Event{Body: v1alpha2.TokenRevocationData{User: "bob", RevokedAt: time.Now().UnixMilli()}})
+	assert.Equal(t, fasthttp.StatusForbidden, callJWT(j, token2, "GET", "/v1alpha2/solutions").Response.StatusCode())
+}
+
+func TestJWTRevocationSameSecond(t *testing.T) {
+	j := JWT{VerifyKey: "SymphonyKey"}
+	assert.Nil(t, j.Init())
+	j.revocations = newRevocationList()
+
+	revokedAt := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	j.revocations.revoke(v1alpha2.TokenRevocationData{User: "bob", RevokedAt: revokedAt.UnixMilli()})
+	sign := func(claims jwt.MapClaims) string {
+		claims["user"] = "bob"
+		claims["iat"] = revokedAt.Unix()
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("SymphonyKey"))
+		return token
+	}
+	// issued earlier in the same second
+	before := sign(jwt.MapClaims{"iat_ms": revokedAt.Add(-100 * time.Millisecond).UnixMilli()})
+	assert.Equal(t, fasthttp.StatusForbidden, callJWT(j, before, "GET", "/v1alpha2/solutions").Response.StatusCode())
+	// issued at the time of the revocation
+	at := sign(jwt.MapClaims{"iat_ms": revokedAt.UnixMilli()})
+	assert.Equal(t, fasthttp.StatusForbidden, callJWT(j, at, "GET", "/v1alpha2/solutions").Response.StatusCode())
+	// without iat_ms, the whole second of the revocation is revoked
+	legacy := sign(jwt.MapClaims{})
+	assert.Equal(t, fasthttp.StatusForbidden, callJWT(j, legacy, "GET", "/v1alpha2/solutions").Response.StatusCode())
+	// issued later in the same second
+	after := sign(jwt.MapClaims{"iat_ms": revokedAt.Add(100 * time.Millisecond).UnixMilli()})
+	assert.Equal(t, fasthttp.StatusOK, callJWT(j, after, "GET", "/v1alpha2/solutions").Response.StatusCode())
+}
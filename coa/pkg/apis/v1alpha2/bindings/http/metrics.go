@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
+	routing "github.com/fasthttp/router"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+const defaultMetricsPath = "/metrics"
+
+// Metrics middleware serves Prometheus metrics and records request counts and durations. Requests are
+// labeled with the matched route template (such as "/v1alpha2/solutions/{name?}"), never the request path.
+type Metrics struct {
+	Path    string
+	handler fasthttp.RequestHandler
+}
+
+func NewMetrics(properties map[string]interface{}) Metrics {
+	path := defaultMetricsPath
+	if v, ok := properties["path"].(string); ok && v != "" {
+		path = v
+	}
+	return Metrics{
+		Path:    path,
+		handler: fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})),
+	}
+}
+
+func (m Metrics) Metrics(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == m.Path && ctx.IsGet() {
+			m.handler(ctx)
+			return
+		}
+		start := time.Now()
+		next(ctx)
+		route := "unmatched"
+		if v, ok := ctx.UserValue(routing.MatchedRoutePathParam).(string); ok {
+			route = v
+		}
+		metrics.ObserveHTTPRequest(string(ctx.Method()), route, strconv.Itoa(ctx.Response.StatusCode()), time.Since(start))
+	}
+}
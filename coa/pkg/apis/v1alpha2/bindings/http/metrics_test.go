@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func serve(handler fasthttp.RequestHandler, method string, path string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	handler(ctx)
+	return ctx
+}
+
+func TestMetricsRouteLabels(t *testing.T) {
+	binding := HttpBinding{}
+	handler := binding.useRouter([]v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      "solutions",
+			Version:    "v1alpha2",
+			Parameters: []string{"name?"},
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{State: v1alpha2.OK}
+			},
+		},
+	})
+	metrics := NewMetrics(map[string]interface{}{})
+	handler = metrics.Metrics(handler)
+
+	serve(handler, fasthttp.MethodGet, "/v1alpha2/solutions/my-solution-1")
+	serve(handler, fasthttp.MethodGet, "/v1alpha2/solutions/my-solution-2")
+	serve(handler, fasthttp.MethodGet, "/v1alpha2/unknown/my-object-3")
+
+	ctx := serve(handler, fasthttp.MethodGet, "/metrics")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	body := string(ctx.Response.Body())
+	assert.Contains(t, body, `symphony_http_requests_total{method="GET",route="/v1alpha2/solutions/{name?}",status="200"} 2`)
+	assert.Contains(t, body, `symphony_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "my-solution")
+	assert.NotContains(t, body, "my-object")
+}
+
+func TestMetricsCustomPath(t *testing.T) {
+	metrics := NewMetrics(map[string]interface{}{"path": "/custom-metrics"})
+	handler := metrics.Metrics(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusTeapot)
+	})
+	assert.Equal(t, fasthttp.StatusTeapot, serve(handler, fasthttp.MethodGet, "/metrics").Response.StatusCode())
+	assert.Equal(t, fasthttp.StatusOK, serve(handler, fasthttp.MethodGet, "/custom-metrics").Response.StatusCode())
+}
@@ -9,60 +9,112 @@ package http
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	routing "github.com/fasthttp/router"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
+const (
+	TelemetrySinkNone        = "none"
+	TelemetrySinkAppInsights = "appinsights"
+	TelemetrySinkOTLP        = "otlp"
+	TelemetrySinkFile        = "file"
+)
+
+// TelemetryEvent is a usage event. It only carries the route template (such as
+// "/v1alpha2/solutions/{name?}"), never the request path, so object names don't leave the site.
+type TelemetryEvent struct {
+	Name       string    `json:"name"`
+	Route      string    `json:"route"`
+	Method     string    `json:"method"`
+	Status     int       `json:"status"`
+	Client     string    `json:"client,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// TelemetrySink delivers usage events to a telemetry backend
+type TelemetrySink interface {
+	Track(event TelemetryEvent)
+}
+
 type Telemetry struct {
 	Properties map[string]interface{}
+	Sink       TelemetrySink
 }
 
-var client appinsights.TelemetryClient
-var initialized bool
-
-func initClient(properties map[string]interface{}) {
-	instrumentationKey := os.Getenv("APP_INSIGHT_KEY")
-	if instrumentationKey == "" {
-		instrumentationKey = "0be0a36e-6e0a-4544-a453-a237fd25cf64"
+// NewTelemetry creates a telemetry middleware with the sink selected by the "sink" property. Telemetry is
+// opt-in: without a "sink" property, events are only sent to Application Insights when the
+// ENABLE_APP_INSIGHT environment variable is "true". Without an instrumentation key, the environment
+// variable only logs a warning, while an explicit "appinsights" sink is a configuration error.
+func NewTelemetry(properties map[string]interface{}) (Telemetry, error) {
+	if properties == nil {
+		properties = make(map[string]interface{})
 	}
-	telemetryConfig := appinsights.NewTelemetryConfiguration(instrumentationKey)
-	if batchSize, ok := properties["maxBatchSize"]; ok {
-		telemetryConfig.MaxBatchSize = int(batchSize.(float64))
-	} else {
-		telemetryConfig.MaxBatchSize = 8192
+	if _, ok := properties["client"]; !ok {
+		properties["client"] = uuid.New().String()
 	}
-	if batchInterval, ok := properties["maxBatchInterval"]; ok {
-		telemetryConfig.MaxBatchInterval = time.Duration(int(batchInterval.(float64))) * time.Second
-	} else {
-		telemetryConfig.MaxBatchInterval = 2 * time.Second
+	sinkType := TelemetrySinkNone
+	fromEnv := false
+	if v, ok := properties["sink"].(string); ok && v != "" {
+		sinkType = strings.ToLower(v)
+	} else if os.Getenv("ENABLE_APP_INSIGHT") == "true" {
+		sinkType = TelemetrySinkAppInsights
+		fromEnv = true
 	}
-	client = appinsights.NewTelemetryClientFromConfig(telemetryConfig)
-	initialized = true
+	ret := Telemetry{Properties: properties}
+	var err error
+	switch sinkType {
+	case TelemetrySinkNone:
+	case TelemetrySinkAppInsights:
+		ret.Sink, err = newAppInsightsSink(properties)
+		if err != nil && fromEnv {
+			log.Warnf("ENABLE_APP_INSIGHT is set, but no telemetry is sent: %s", err.Error())
+			ret.Sink, err = nil, nil
+		}
+	case TelemetrySinkOTLP:
+		ret.Sink, err = newOTLPLogSink(properties)
+	case TelemetrySinkFile:
+		ret.Sink, err = newFileSink(properties)
+	default:
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("telemetry sink '%s' is not supported", sinkType), v1alpha2.BadConfig)
+	}
+	return ret, err
 }
 
-// CORS middleware to allow CORS. The middleware doesn't override existing headers in incoming requests
+// Telemetry middleware records a usage event per request
 func (c Telemetry) Telemetry(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		if v, ok := c.Properties["enabled"]; !ok || v != true {
+		if c.Sink == nil {
 			next(ctx)
 			return
 		}
-		if !initialized {
-			initClient(c.Properties)
-		}
-		if initialized {
-			path := string(ctx.Path())
-			method := string(ctx.Method())
-			eventId := fmt.Sprintf("%s-%s", path, method)
-			event := appinsights.NewEventTelemetry(eventId)
-			event.Properties["client"] = fmt.Sprintf("%v", c.Properties["client"])
-			next(ctx)
-			event.Properties["status"] = fmt.Sprintf("%v", ctx.Response.StatusCode())
-			client.Track(event)
-		} else {
-			next(ctx)
+		start := time.Now()
+		next(ctx)
+		route := "unmatched"
+		if v, ok := ctx.UserValue(routing.MatchedRoutePathParam).(string); ok {
+			route = v
 		}
+		method := string(ctx.Method())
+		c.Sink.Track(TelemetryEvent{
+			Name:       fmt.Sprintf("%s-%s", route, method),
+			Route:      route,
+			Method:     method,
+			Status:     ctx.Response.StatusCode(),
+			Client:     fmt.Sprintf("%v", c.Properties["client"]),
+			DurationMs: time.Since(start).Milliseconds(),
+			Timestamp:  start.UTC(),
+		})
+	}
+}
+
+func readIntProperty(properties map[string]interface{}, key string, defaultValue int) int {
+	if v, ok := properties[key].(float64); ok {
+		return int(v)
 	}
+	return defaultValue
 }
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/microsoft/ApplicationInsights-Go/appinsights"
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// appInsightsSink sends events to Application Insights. The instrumentation key comes from the
+// "instrumentationKey" property or the APP_INSIGHT_KEY environment variable.
+type appInsightsSink struct {
+	client appinsights.TelemetryClient
+}
+
+func newAppInsightsSink(properties map[string]interface{}) (TelemetrySink, error) {
+	instrumentationKey, _ := properties["instrumentationKey"].(string)
+	if instrumentationKey == "" {
+		instrumentationKey = os.Getenv("APP_INSIGHT_KEY")
+	}
+	if instrumentationKey == "" {
+		return nil, v1alpha2.NewCOAError(nil, "Application Insights telemetry requires an instrumentation key", v1alpha2.BadConfig)
+	}
+	telemetryConfig := appinsights.NewTelemetryConfiguration(instrumentationKey)
+	telemetryConfig.MaxBatchSize = readIntProperty(properties, "maxBatchSize", 8192)
+	interval := readIntProperty(properties, "maxBatchInterval", 2)
+	telemetryConfig.MaxBatchInterval = time.Duration(readIntProperty(properties, "maxBatchIntervalSeconds", interval)) * time.Second
+	return &appInsightsSink{
+		client: appinsights.NewTelemetryClientFromConfig(telemetryConfig),
+	}, nil
+}
+
+func (s *appInsightsSink) Track(event TelemetryEvent) {
+	e := appinsights.NewEventTelemetry(event.Name)
+	e.Timestamp = event.Timestamp
+	e.Properties["client"] = event.Client
+	e.Properties["route"] = event.Route
+	e.Properties["method"] = event.Method
+	e.Properties["status"] = fmt.Sprintf("%v", event.Status)
+	e.Measurements["durationMs"] = float64(event.DurationMs)
+	s.client.Track(e)
+}
+
+// fileSink appends events as JSON lines to a local file, for sites that collect usage offline
+type fileSink struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+func newFileSink(properties map[string]interface{}) (TelemetrySink, error) {
+	path, _ := properties["path"].(string)
+	if path == "" {
+		return nil, v1alpha2.NewCOAError(nil, "file telemetry requires a path", v1alpha2.BadConfig)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to open telemetry file", v1alpha2.BadConfig)
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (s *fileSink) Track(event TelemetryEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		log.Errorf("failed to write telemetry event: %+v", err)
+	}
+}
+
+// otlpLogSink sends events as OTLP log records to a collector's OTLP/HTTP logs endpoint, such as
+// "http://localhost:4318/v1/logs". Events are batched up to maxBatchSize or maxBatchIntervalSeconds.
+type otlpLogSink struct {
+	endpoint  string
+	headers   map[string]string
+	client    *http.Client
+	events    chan TelemetryEvent
+	batchSize int
+	interval  time.Duration
+}
+
+func newOTLPLogSink(properties map[string]interface{}) (TelemetrySink, error) {
+	endpoint, _ := properties["endpoint"].(string)
+	if endpoint == "" {
+		return nil, v1alpha2.NewCOAError(nil, "OTLP telemetry requires an endpoint", v1alpha2.BadConfig)
+	}
+	headers := make(map[string]string)
+	if v, ok := properties["headers"].(map[string]interface{}); ok {
+		for k, h := range v {
+			headers[k] = fmt.Sprintf("%v", h)
+		}
+	}
+	sink := &otlpLogSink{
+		endpoint:  endpoint,
+		headers:   headers,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		batchSize: readIntProperty(properties, "maxBatchSize", 512),
+		interval:  time.Duration(readIntProperty(properties, "maxBatchIntervalSeconds", 2)) * time.Second,
+	}
+	sink.events = make(chan TelemetryEvent, sink.batchSize*2)
+	go sink.run()
+	return sink, nil
+}
+
+// Track queues an event without blocking the request. Events are dropped when the queue is full.
+func (s *otlpLogSink) Track(event TelemetryEvent) {
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+func (s *otlpLogSink) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	batch := make([]TelemetryEvent, 0, s.batchSize)
+	for {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+			if len(batch) < s.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := s.export(batch); err != nil {
+			log.Errorf("failed to export telemetry events: %+v", err)
+		}
+		batch = batch[:0]
+	}
+}
+
+func (s *otlpLogSink) export(events []TelemetryEvent) error {
+	records := make([]*logspb.LogRecord, 0, len(events))
+	for _, e := range events {
+		records = append(records, &logspb.LogRecord{
+			TimeUnixNano:   uint64(e.Timestamp.UnixNano()),
+			SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+			SeverityText:   "INFO",
+			Body:           stringValue(e.Name),
+			Attributes: []*commonpb.KeyValue{
+				{Key: "symphony.client", Value: stringValue(e.Client)},
+				{Key: "http.route", Value: stringValue(e.Route)},
+				{Key: "http.method", Value: stringValue(e.Method)},
+				{Key: "http.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(e.Status)}}},
+				{Key: "duration_ms", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: e.DurationMs}}},
+			},
+		})
+	}
+	request := &collectorlogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						{Key: "service.name", Value: stringValue("Symphony API")},
+					},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						Scope:      &commonpb.InstrumentationScope{Name: "middleware.http.telemetry"},
+						LogRecords: records,
+					},
+				},
+			},
+		},
+	}
+	data, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func telemetryHandler(telemetry Telemetry) fasthttp.RequestHandler {
+	binding := HttpBinding{}
+	handler := binding.useRouter([]v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      "targets/registry",
+			Version:    "v1alpha2",
+			Parameters: []string{"name"},
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{State: v1alpha2.OK}
+			},
+		},
+	})
+	return telemetry.Telemetry(handler)
+}
+
+func TestTelemetryIsOptIn(t *testing.T) {
+	t.Setenv("ENABLE_APP_INSIGHT", "")
+	telemetry, err := NewTelemetry(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Nil(t, telemetry.Sink)
+}
+
+func TestTelemetryAppInsightsRequiresKey(t *testing.T) {
+	t.Setenv("ENABLE_APP_INSIGHT", "true")
+	t.Setenv("APP_INSIGHT_KEY", "")
+	// the environment variable alone doesn't fail the binding
+	telemetry, err := NewTelemetry(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Nil(t, telemetry.Sink)
+
+	_, err = NewTelemetry(map[string]interface{}{"sink": "appInsights"})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+
+	telemetry, err = NewTelemetry(map[string]interface{}{"sink": "appinsights", "instrumentationKey": "00000000-0000-0000-0000-000000000000"})
+	assert.Nil(t, err)
+	assert.NotNil(t, telemetry.Sink)
+
+	t.Setenv("APP_INSIGHT_KEY", "00000000-0000-0000-0000-000000000000")
+	telemetry, err = NewTelemetry(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.NotNil(t, telemetry.Sink)
+}
+
+func TestTelemetryUnsupportedSink(t *testing.T) {
+	_, err := NewTelemetry(map[string]interface{}{"sink": "carrier-pigeon"})
+	assert.NotNil(t, err)
+}
+
+func TestTelemetryFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	telemetry, err := NewTelemetry(map[string]interface{}{"sink": "file", "path": path, "client": "site-a"})
+	assert.Nil(t, err)
+	handler := telemetryHandler(telemetry)
+	serve(handler, fasthttp.MethodPost, "/v1alpha2/targets/registry/secret-target-name")
+	serve(handler, fasthttp.MethodPost, "/v1alpha2/other/secret-object-name")
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "secret")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, 2, len(lines))
+	var event TelemetryEvent
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, "/v1alpha2/targets/registry/{name}", event.Route)
+	assert.Equal(t, "POST", event.Method)
+	assert.Equal(t, 200, event.Status)
+	assert.Equal(t, "site-a", event.Client)
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, "unmatched", event.Route)
+	assert.Equal(t, 404, event.Status)
+}
+
+func TestTelemetryOTLPSink(t *testing.T) {
+	received := make(chan *collectorlogs.ExportLogsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("api-key"))
+		data, _ := io.ReadAll(r.Body)
+		request := collectorlogs.ExportLogsServiceRequest{}
+		assert.Nil(t, proto.Unmarshal(data, &request))
+		received <- &request
+	}))
+	defer server.Close()
+
+	telemetry, err := NewTelemetry(map[string]interface{}{
+		"sink":         "otlp",
+		"endpoint":     server.URL + "/v1/logs",
+		"headers":      map[string]interface{}{"api-key": "secret"},
+		"maxBatchSize": float64(2),
+		"client":       "site-b",
+	})
+	assert.Nil(t, err)
+	handler := telemetryHandler(telemetry)
+	serve(handler, fasthttp.MethodPost, "/v1alpha2/targets/registry/t1")
+	serve(handler, fasthttp.MethodPost, "/v1alpha2/targets/registry/t2")
+
+	select {
+	case request := <-received:
+		records := request.ResourceLogs[0].ScopeLogs[0].LogRecords
+		assert.Equal(t, 2, len(records))
+		attrs := make(map[string]string)
+		for _, a := range records[0].Attributes {
+			attrs[a.Key] = a.Value.GetStringValue()
+		}
+		assert.Equal(t, "/v1alpha2/targets/registry/{name}", attrs["http.route"])
+		assert.Equal(t, "site-b", attrs["symphony.client"])
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "OTLP collector didn't receive telemetry events")
+	}
+}
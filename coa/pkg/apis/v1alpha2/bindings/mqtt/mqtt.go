@@ -8,11 +8,17 @@ package mqtt
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
 )
@@ -24,70 +30,304 @@ type MQTTBindingConfig struct {
 	ClientID      string `json:"clientID"`
 	RequestTopic  string `json:"requestTopic"`
 	ResponseTopic string `json:"responseTopic"`
+	UserName      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	// QoS is used for both the request subscription and response publications
+	QoS byte `json:"qos,omitempty"`
+	// CACert, ClientCert and ClientKey are paths to PEM files used for TLS connections
+	CACert             string `json:"caCert,omitempty"`
+	ClientCert         string `json:"clientCert,omitempty"`
+	ClientKey          string `json:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	// JWT validates the bearer token carried in request metadata, using the same
+	// properties as the HTTP binding's middleware.http.jwt middleware
+	JWT *http.JWT `json:"jwt,omitempty"`
 }
 
 type MQTTBinding struct {
 	MQTTClient gmqtt.Client
+	JWT        *http.JWT
+	routes     []route
 }
 
-var routeTable map[string]v1alpha2.Endpoint
+type route struct {
+	segments []string
+	endpoint v1alpha2.Endpoint
+}
 
-func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endpoint) error {
-	routeTable = make(map[string]v1alpha2.Endpoint)
-	for _, endpoint := range endpoints {
-		route := endpoint.Route
-		lastSlash := strings.LastIndex(endpoint.Route, "/")
-		if lastSlash > 0 {
-			route = strings.TrimPrefix(route, route[:lastSlash+1])
+func (m *MQTTBinding) Launch(config MQTTBindingConfig, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) error {
+	m.buildRoutes(endpoints)
+
+	if config.JWT != nil {
+		m.JWT = config.JWT
+		err := m.JWT.Init()
+		if err != nil {
+			return err
+		}
+		if pubsubProvider != nil {
+			err = m.JWT.SetPubSubProvider(pubsubProvider)
+			if err != nil {
+				return err
+			}
 		}
-		routeTable[route] = endpoint
 	}
 
 	opts := gmqtt.NewClientOptions().AddBroker(config.BrokerAddress).SetClientID(config.ClientID)
 	opts.SetKeepAlive(2 * time.Second)
 	opts.SetPingTimeout(1 * time.Second)
 	opts.CleanSession = false
+	if config.UserName != "" {
+		opts.SetUsername(config.UserName)
+		opts.SetPassword(config.Password)
+	}
+	tlsConfig, err := createTLSConfig(config)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
 	m.MQTTClient = gmqtt.NewClient(opts)
 	if token := m.MQTTClient.Connect(); token.Wait() && token.Error() != nil {
 		return v1alpha2.NewCOAError(token.Error(), "failed to connect to MQTT broker", v1alpha2.InternalError)
 	}
 
-	if token := m.MQTTClient.Subscribe(config.RequestTopic, 0, func(client gmqtt.Client, msg gmqtt.Message) {
-		var request v1alpha2.COARequest
-		var response v1alpha2.COAResponse
+	if token := m.MQTTClient.Subscribe(config.RequestTopic, config.QoS, func(client gmqtt.Client, msg gmqtt.Message) {
+		data := m.handle(msg.Payload())
+		if token := client.Publish(config.ResponseTopic, config.QoS, false, data); token.Wait() && token.Error() != nil {
+			log.Errorf("failed to handle request from MOTT: %s", token.Error())
+		}
+	}); token.Wait() && token.Error() != nil {
+		if token.Error().Error() != "subscription exists" {
+			log.Errorf("  P (MQTT Target): faild to connect to subscribe to request topic - %+v", token.Error())
+			return v1alpha2.NewCOAError(token.Error(), "failed to subscribe to request topic", v1alpha2.InternalError)
+		}
+	}
+
+	return nil
+}
+
+func createTLSConfig(config MQTTBindingConfig) (*tls.Config, error) {
+	if config.CACert == "" && config.ClientCert == "" && !config.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CACert != "" {
+		caCert, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to read MQTT CA certificate", v1alpha2.BadConfig)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, v1alpha2.NewCOAError(nil, "MQTT CA certificate is not a valid PEM certificate", v1alpha2.BadConfig)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to load MQTT client certificate", v1alpha2.BadConfig)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (m *MQTTBinding) buildRoutes(endpoints []v1alpha2.Endpoint) {
+	m.routes = make([]route, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		m.routes = append(m.routes, route{
+			segments: splitRoute(endpoint.Route),
+			endpoint: endpoint,
+		})
+	}
+}
+
+func (m *MQTTBinding) handle(payload []byte) []byte {
+	var request v1alpha2.COARequest
+	var response v1alpha2.COAResponse
+	err := json.Unmarshal(payload, &request)
+	if err != nil {
+		response = v1alpha2.COAResponse{
+			State:       v1alpha2.BadRequest,
+			ContentType: "application/text",
+			Body:        []byte(err.Error()),
+		}
+	} else {
 		request.Context = context.TODO()
-		err := json.Unmarshal(msg.Payload(), &request)
+		response = m.dispatch(request)
+	}
+
+	// needs to carry call-context and request-id from request into response, so that callers can
+	// correlate responses with their requests
+	for _, key := range []string{"call-context", "request-id"} {
+		if v, ok := request.Metadata[key]; ok {
+			if response.Metadata == nil {
+				response.Metadata = make(map[string]string)
+			}
+			response.Metadata[key] = v
+		}
+	}
+
+	data, _ := json.Marshal(response)
+	return data
+}
+
+func (m *MQTTBinding) dispatch(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	endpoint, path, parameters, err := m.resolve(request.Route)
+	if err != nil {
+		return v1alpha2.COAResponse{
+			State:       err.(v1alpha2.COAError).State,
+			ContentType: "application/text",
+			Body:        []byte(err.Error()),
+		}
+	}
+	if request.Method != "" && !containsMethod(endpoint.Methods, request.Method) {
+		return v1alpha2.COAResponse{
+			State:       v1alpha2.MethodNotAllowed,
+			ContentType: "application/text",
+			Body:        []byte(fmt.Sprintf("method '%s' is not allowed on route '%s'", request.Method, request.Route)),
+		}
+	}
+	if request.Parameters == nil {
+		request.Parameters = make(map[string]string)
+	}
+	for k, v := range parameters {
+		request.Parameters[k] = v
+	}
+	if m.JWT != nil {
+		err = m.JWT.Authorize(m.readAuthMetadata(request.Metadata), path, request.Method, request.Parameters["scope"])
 		if err != nil {
-			response = v1alpha2.COAResponse{
-				State:       v1alpha2.BadRequest,
-				ContentType: "application/text",
+			return v1alpha2.COAResponse{
+				State:       v1alpha2.Unauthorized,
+				ContentType: "text/plain",
 				Body:        []byte(err.Error()),
 			}
-		} else {
-			response = routeTable[request.Route].Handler(request)
 		}
+	}
+	return endpoint.Handler(request)
+}
 
-		// needs to carry call-context from request into response
-		if request.Metadata != nil {
-			if v, ok := request.Metadata["call-context"]; ok {
-				if response.Metadata == nil {
-					response.Metadata = make(map[string]string)
+func (m *MQTTBinding) readAuthMetadata(metadata map[string]string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, m.JWT.AuthHeader) {
+			return http.ParseBearerToken(v)
+		}
+	}
+	return ""
+}
+
+// resolve finds the endpoint for a request route. Routes are matched on all segments, with trailing
+// segments bound to the endpoint's parameters, e.g. "targets/registry/t1" binds "__name" to "t1". For
+// compatibility with clients that only send the last route segment (such as "instances"), a route
+// that doesn't match fully is resolved by its last segment when that's unambiguous.
+//
+// Besides the endpoint and its parameters, resolve returns the HTTP-equivalent request path (such as
+// "/v1alpha2/solution/instances"), so that the same JWT ignore paths and RBAC policies apply.
+func (m *MQTTBinding) resolve(requestRoute string) (v1alpha2.Endpoint, string, map[string]string, error) {
+	segments := splitRoute(requestRoute)
+	if len(segments) == 0 {
+		return v1alpha2.Endpoint{}, "", nil, v1alpha2.NewCOAError(nil, "route is not specified", v1alpha2.BadRequest)
+	}
+	var best *route
+	var bestValues []string
+	for i := range m.routes {
+		r := &m.routes[i]
+		candidate := segments
+		if r.endpoint.Version != "" && candidate[0] == r.endpoint.Version {
+			candidate = candidate[1:]
+		}
+		if values, ok := r.match(candidate); ok {
+			if best == nil || len(r.segments) > len(best.segments) {
+				best = r
+				bestValues = values
+			}
+		}
+	}
+	if best != nil {
+		return best.endpoint, best.path(bestValues), best.parameters(bestValues), nil
+	}
+	if len(segments) == 1 {
+		var found *route
+		for i := range m.routes {
+			r := &m.routes[i]
+			if len(r.segments) > 0 && r.segments[len(r.segments)-1] == segments[0] {
+				if found != nil {
+					return v1alpha2.Endpoint{}, "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("route '%s' is ambiguous", requestRoute), v1alpha2.BadRequest)
 				}
-				response.Metadata["call-context"] = v
+				found = r
 			}
 		}
+		if found != nil {
+			return found.endpoint, found.path(nil), found.parameters(nil), nil
+		}
+	}
+	return v1alpha2.Endpoint{}, "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("route '%s' is not found", requestRoute), v1alpha2.NotFound)
+}
 
-		data, _ := json.Marshal(response)
+// match checks whether the segments start with the route, and returns the remaining segments as
+// parameter values when their count fits the endpoint's required and optional parameters
+func (r route) match(segments []string) ([]string, bool) {
+	if len(segments) < len(r.segments) {
+		return nil, false
+	}
+	for i, s := range r.segments {
+		if segments[i] != s {
+			return nil, false
+		}
+	}
+	values := segments[len(r.segments):]
+	if len(values) > len(r.endpoint.Parameters) {
+		return nil, false
+	}
+	for i := len(values); i < len(r.endpoint.Parameters); i++ {
+		if !strings.HasSuffix(r.endpoint.Parameters[i], "?") {
+			return nil, false
+		}
+	}
+	return values, true
+}
 
-		if token := client.Publish(config.ResponseTopic, 0, false, data); token.Wait() && token.Error() != nil {
-			log.Errorf("failed to handle request from MOTT: %s", token.Error())
+func (r route) parameters(values []string) map[string]string {
+	parameters := make(map[string]string)
+	for i, p := range r.endpoint.Parameters {
+		k := "__" + strings.TrimSuffix(p, "?")
+		if i < len(values) {
+			parameters[k] = values[i]
+		} else {
+			parameters[k] = ""
 		}
-	}); token.Wait() && token.Error() != nil {
-		if token.Error().Error() != "subscription exists" {
-			log.Errorf("  P (MQTT Target): faild to connect to subscribe to request topic - %+v", token.Error())
-			return v1alpha2.NewCOAError(token.Error(), "failed to subscribe to request topic", v1alpha2.InternalError)
+	}
+	return parameters
+}
+
+func (r route) path(values []string) string {
+	parts := []string{""}
+	if r.endpoint.Version != "" {
+		parts = append(parts, r.endpoint.Version)
+	}
+	parts = append(parts, r.segments...)
+	parts = append(parts, values...)
+	return strings.Join(parts, "/")
+}
+
+func splitRoute(route string) []string {
+	ret := make([]string, 0)
+	for _, s := range strings.Split(route, "/") {
+		if s != "" {
+			ret = append(ret, s)
 		}
 	}
+	return ret
+}
 
-	return nil
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
 }
@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	gmqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,7 +43,7 @@ func TestMQTTEcho(t *testing.T) {
 			},
 		},
 	}
-	err := binding.Launch(config, endpoints)
+	err := binding.Launch(config, endpoints, nil)
 	assert.Nil(t, err)
 
 	opts := gmqtt.NewClientOptions().AddBroker(config.BrokerAddress).SetClientID("test-sender")
@@ -72,3 +74,95 @@ func TestMQTTEcho(t *testing.T) {
 	token.Wait()
 	<-sig
 }
+
+func testEndpoints() []v1alpha2.Endpoint {
+	handler := func(route string) v1alpha2.COAHandler {
+		return func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+			return v1alpha2.COAResponse{
+				State: v1alpha2.OK,
+				Body:  []byte(route + ":" + c.Parameters["__name"]),
+			}
+		}
+	}
+	return []v1alpha2.Endpoint{
+		{Methods: []string{"GET", "POST", "DELETE"}, Route: "solution/instances", Version: "v1alpha2", Handler: handler("solution/instances")},
+		{Methods: []string{"GET", "POST", "DELETE"}, Route: "targets/instances", Version: "v1alpha2", Handler: handler("targets/instances")},
+		{Methods: []string{"GET", "POST", "DELETE"}, Route: "targets/registry", Version: "v1alpha2", Handler: handler("targets/registry"), Parameters: []string{"name?"}},
+		{Methods: []string{"GET"}, Route: "greetings", Version: "v1alpha2", Handler: handler("greetings")},
+	}
+}
+
+func dispatch(binding *MQTTBinding, request v1alpha2.COARequest) v1alpha2.COAResponse {
+	data, _ := json.Marshal(request)
+	var response v1alpha2.COAResponse
+	json.Unmarshal(binding.handle(data), &response)
+	return response
+}
+
+func TestMQTTFullRouteMatching(t *testing.T) {
+	binding := MQTTBinding{}
+	binding.buildRoutes(testEndpoints())
+
+	response := dispatch(&binding, v1alpha2.COARequest{Route: "solution/instances", Method: "GET"})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, "solution/instances:", string(response.Body))
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "/v1alpha2/targets/instances", Method: "GET"})
+	assert.Equal(t, "targets/instances:", string(response.Body))
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "targets/registry/target1", Method: "GET"})
+	assert.Equal(t, "targets/registry:target1", string(response.Body))
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "targets/registry/target1/extra", Method: "GET"})
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "greetings", Method: "POST"})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, response.State)
+}
+
+func TestMQTTLastSegmentFallback(t *testing.T) {
+	binding := MQTTBinding{}
+	binding.buildRoutes(testEndpoints())
+	// "instances" matches both solution/instances and targets/instances
+	response := dispatch(&binding, v1alpha2.COARequest{Route: "instances", Method: "GET"})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+
+	binding.buildRoutes(testEndpoints()[:1])
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "instances", Method: "GET", Metadata: map[string]string{"call-context": "c1", "request-id": "r1"}})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, "solution/instances:", string(response.Body))
+	assert.Equal(t, "c1", response.Metadata["call-context"])
+	assert.Equal(t, "r1", response.Metadata["request-id"])
+}
+
+func TestMQTTJWT(t *testing.T) {
+	binding := MQTTBinding{
+		JWT: &http.JWT{
+			VerifyKey:   "SymphonyKey",
+			IgnorePaths: []string{"/v1alpha2/greetings"},
+			EnableRBAC:  true,
+			Roles:       []http.ClaimRoleMap{{Role: "operator", Claim: "user", Value: "operator"}},
+			Policy: map[string]http.Policy{
+				"operator": {Rules: []http.PolicyRule{{Resources: []string{"solution/instances"}, Verbs: []string{"*"}}}},
+			},
+		},
+	}
+	assert.Nil(t, binding.JWT.Init())
+	binding.buildRoutes(testEndpoints())
+
+	response := dispatch(&binding, v1alpha2.COARequest{Route: "greetings", Method: "GET"})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "solution/instances", Method: "GET"})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	assert.Equal(t, "missing bearer token", string(response.Body))
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "operator"}).SignedString([]byte("SymphonyKey"))
+	metadata := map[string]string{"Authorization": "Bearer " + token}
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "solution/instances", Method: "POST", Metadata: metadata})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	response = dispatch(&binding, v1alpha2.COARequest{Route: "targets/registry/target1", Method: "DELETE", Metadata: metadata})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	assert.Equal(t, "roles [operator] are not allowed to delete 'targets/registry/target1' in scope 'default'", string(response.Body))
+}
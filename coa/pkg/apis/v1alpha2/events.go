@@ -45,6 +45,15 @@ type HeartBeatData struct {
 	Action string    `json:"action"`
 	Time   time.Time `json:"time"`
 }
+
+// TokenRevocationData revokes a single access token by its id, or all tokens of a user
+// issued at or before a given time (unix milliseconds).
+type TokenRevocationData struct {
+	TokenId   string `json:"tokenId,omitempty"`
+	User      string `json:"user,omitempty"`
+	RevokedAt int64  `json:"revokedAt,omitempty"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"`
+}
 type ScheduleSpec struct {
 	Date string `json:"date"`
 	Time string `json:"time"`
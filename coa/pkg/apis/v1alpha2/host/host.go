@@ -176,7 +176,7 @@ func (h *APIHost) Launch(config HostConfig,
 					var binding bindings.IBinding
 					var err error
 					if h.SharedPubSubProvider != nil {
-						binding, err = h.launchHTTP(b.Config, endpoints, h.SharedPubSubProvider.(pubsub.IPubSubProvider))
+						binding, err = h.launchHTTP(b.Config, config.SiteInfo, endpoints, h.SharedPubSubProvider.(pubsub.IPubSubProvider))
 					} else {
 						var bindingPubsub pv.IProvider
 						for _, providerFactory := range providerFactories {
@@ -189,7 +189,7 @@ func (h *APIHost) Launch(config HostConfig,
 							bindingPubsub = mProvider
 							break
 						}
-						binding, err = h.launchHTTP(b.Config, endpoints, bindingPubsub.(pubsub.IPubSubProvider))
+						binding, err = h.launchHTTP(b.Config, config.SiteInfo, endpoints, bindingPubsub.(pubsub.IPubSubProvider))
 					}
 					if err != nil {
 						return err
@@ -199,7 +199,11 @@ func (h *APIHost) Launch(config HostConfig,
 					if wait {
 						wg.Add(1)
 					}
-					binding, err := h.launchMQTT(b.Config, endpoints)
+					var bindingPubsub pubsub.IPubSubProvider
+					if h.SharedPubSubProvider != nil {
+						bindingPubsub = h.SharedPubSubProvider.(pubsub.IPubSubProvider)
+					}
+					binding, err := h.launchMQTT(b.Config, endpoints, bindingPubsub)
 					if err != nil {
 						return err
 					}
@@ -216,7 +220,7 @@ func (h *APIHost) Launch(config HostConfig,
 	}
 }
 
-func (h *APIHost) launchHTTP(config interface{}, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) (bindings.IBinding, error) {
+func (h *APIHost) launchHTTP(config interface{}, siteInfo v1alpha2.SiteInfo, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) (bindings.IBinding, error) {
 	data, err := json.Marshal(config)
 	if err != nil {
 		return nil, err
@@ -226,10 +230,18 @@ func (h *APIHost) launchHTTP(config interface{}, endpoints []v1alpha2.Endpoint,
 	if err != nil {
 		return nil, err
 	}
+	// tag traces with the site id unless the tracing middleware overrides it
+	for _, m := range httpConfig.Pipeline {
+		if m.Type == "middleware.http.tracing" && m.Properties != nil {
+			if _, ok := m.Properties["siteId"]; !ok {
+				m.Properties["siteId"] = siteInfo.SiteId
+			}
+		}
+	}
 	binding := http.HttpBinding{}
 	return binding, binding.Launch(httpConfig, endpoints, pubsubProvider)
 }
-func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint) (bindings.IBinding, error) {
+func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) (bindings.IBinding, error) {
 	data, err := json.Marshal(config)
 	if err != nil {
 		return nil, err
@@ -240,5 +252,5 @@ func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint)
 		return nil, err
 	}
 	binding := mqtt.MQTTBinding{}
-	return binding, binding.Launch(mqttConfig, endpoints)
+	return binding, binding.Launch(mqttConfig, endpoints, pubsubProvider)
 }
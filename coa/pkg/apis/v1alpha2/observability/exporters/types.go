@@ -7,10 +7,16 @@
 package exporters
 
 import (
+	"context"
 	"io"
+	"net/url"
+	"strings"
+
 	// "log"
 	// "os"
 
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	stdout "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/exporters/zipkin"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -31,7 +37,50 @@ func NewConsoleExporter(writer io.Writer) (sdktrace.SpanExporter, error) {
 	}
 }
 
-func NewZipkinExporter(url string, sampleRate string) (sdktrace.SpanExporter, error) {
+func NewZipkinExporter(url string) (sdktrace.SpanExporter, error) {
 	// return zipkin.New(url, zipkin.WithLogger(logger))
 	return zipkin.New(url)
 }
+
+// NewOTLPgRPCExporter creates an OTLP exporter that sends spans to a collector's gRPC endpoint, such as
+// "localhost:4317". An "http://" endpoint, or insecure set to true, disables transport security.
+func NewOTLPgRPCExporter(endpoint string, insecure bool, headers map[string]string) (sdktrace.SpanExporter, error) {
+	host, _, plain := parseEndpoint(endpoint)
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(host)}
+	if insecure || plain {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	if len(headers) > 0 {
+		options = append(options, otlptracegrpc.WithHeaders(headers))
+	}
+	return otlptracegrpc.New(context.Background(), options...)
+}
+
+// NewOTLPHTTPExporter creates an OTLP exporter that posts spans to a collector's HTTP endpoint, such as
+// "http://localhost:4318/v1/traces". The path defaults to "/v1/traces" when the endpoint doesn't have one.
+func NewOTLPHTTPExporter(endpoint string, insecure bool, headers map[string]string) (sdktrace.SpanExporter, error) {
+	host, path, plain := parseEndpoint(endpoint)
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(host)}
+	if path != "" && path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(path))
+	}
+	if insecure || plain {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if len(headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+// parseEndpoint splits an endpoint into its host:port and path, and reports whether it uses plain HTTP
+func parseEndpoint(endpoint string) (string, string, bool) {
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, "", false
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint, "", false
+	}
+	return u.Host, u.Path, u.Scheme == "http"
+}
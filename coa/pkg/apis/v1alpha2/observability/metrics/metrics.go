@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Outcome label values. Labels only carry values from small, fixed sets (route templates, provider
+// types, topics and outcomes) so that the number of series doesn't grow with the number of objects.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomePaused  = "paused"
+)
+
+// Registry holds all Symphony metrics, along with the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "symphony_http_requests_total",
+		Help: "Number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_http_request_duration_seconds",
+		Help:    "HTTP request duration by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_reconcile_duration_seconds",
+		Help:    "Duration of deployment steps applied by the solution manager, by target provider and outcome.",
+		Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"provider", "outcome"})
+	planSteps = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "symphony_plan_steps",
+		Help:    "Number of steps in deployment plans.",
+		Buckets: []float64{0, 1, 2, 5, 10, 20, 50},
+	})
+	stageExecutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "symphony_stage_executions_total",
+		Help: "Number of campaign stage executions by stage provider and outcome.",
+	}, []string{"provider", "outcome"})
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_stage_duration_seconds",
+		Help:    "Campaign stage execution duration by stage provider.",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"provider"})
+	activationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_activation_duration_seconds",
+		Help:    "Campaign activation duration, from creation to completion, by outcome.",
+		Buckets: []float64{1, 5, 30, 60, 300, 900, 1800, 3600, 14400, 86400},
+	}, []string{"outcome"})
+	publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_pubsub_publish_duration_seconds",
+		Help:    "Pub/sub publish latency by provider and topic.",
+		Buckets: []float64{0.0001, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+	}, []string{"provider", "topic"})
+	handleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "symphony_pubsub_handle_duration_seconds",
+		Help:    "Pub/sub event handling latency by provider, topic and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider", "topic", "outcome"})
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "symphony_pubsub_queue_depth",
+		Help: "Pub/sub events waiting to be handled or being handled, by provider and topic.",
+	}, []string{"provider", "topic"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		reconcileDuration,
+		planSteps,
+		stageExecutions,
+		stageDuration,
+		activationDuration,
+		publishDuration,
+		handleDuration,
+		queueDepth,
+	)
+}
+
+// Outcome maps an error to the success or failure outcome label
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+func ObserveHTTPRequest(method string, route string, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, status).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func ObserveReconcile(provider string, outcome string, duration time.Duration) {
+	reconcileDuration.WithLabelValues(provider, outcome).Observe(duration.Seconds())
+}
+
+func ObservePlanSteps(steps int) {
+	planSteps.Observe(float64(steps))
+}
+
+func ObserveStageExecution(provider string, outcome string, duration time.Duration) {
+	stageExecutions.WithLabelValues(provider, outcome).Inc()
+	stageDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
+func ObserveActivation(outcome string, duration time.Duration) {
+	activationDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+func ObservePublish(provider string, topic string, duration time.Duration) {
+	publishDuration.WithLabelValues(provider, topic).Observe(duration.Seconds())
+}
+
+func ObserveHandle(provider string, topic string, outcome string, duration time.Duration) {
+	handleDuration.WithLabelValues(provider, topic, outcome).Observe(duration.Seconds())
+}
+
+// AddQueueDepth adjusts the number of queued events of a topic by delta
+func AddQueueDepth(provider string, topic string, delta int) {
+	queueDepth.WithLabelValues(provider, topic).Add(float64(delta))
+}
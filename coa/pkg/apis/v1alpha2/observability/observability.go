@@ -27,6 +27,10 @@ import (
 
 type ObservabilityConfig struct {
 	Pipelines []PipelineConfig `json:"pipelines"`
+	// ServiceName, ServiceVersion and SiteId are added as resource attributes to all spans
+	ServiceName    string `json:"serviceName,omitempty"`
+	ServiceVersion string `json:"serviceVersion,omitempty"`
+	SiteId         string `json:"siteId,omitempty"`
 }
 
 type PipelineConfig struct {
@@ -36,6 +40,9 @@ type ExporterConfig struct {
 	Type       string        `json:"type"`
 	BackendUrl string        `json:"backendUrl"`
 	Sampler    SamplerConfig `json:"sampler"`
+	// Insecure and Headers are used by OTLP exporters
+	Insecure bool              `json:"insecure,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
 }
 type ProcessorConfig struct {
 }
@@ -43,6 +50,9 @@ type ProviderConfig struct {
 }
 type SamplerConfig struct {
 	SampleRate string `json:"sampleRate"`
+	// ParentBased makes spans follow their parent's sampling decision, and only applies the sample
+	// rate to root spans
+	ParentBased bool `json:"parentBased,omitempty"`
 }
 
 type Observability struct {
@@ -75,24 +85,70 @@ func EndSpan(ctx context.Context) {
 	span.End()
 }
 func (o *Observability) Init(config ObservabilityConfig) error {
-	for _, p := range config.Pipelines {
-		err := o.createPipeline(p)
+	options := make([]sdktrace.TracerProviderOption, 0)
+	samplers := make([]sdktrace.Sampler, 0)
+	if len(config.Pipelines) > 64 {
+		return v1alpha2.NewCOAError(nil, "at most 64 tracing pipelines are supported", v1alpha2.BadConfig)
+	}
+	for i, p := range config.Pipelines {
+		processor, sampler, err := o.createPipeline(i, p)
 		if err != nil {
 			return err
 		}
+		options = append(options, sdktrace.WithSpanProcessor(processor))
+		samplers = append(samplers, sampler)
 	}
+	options = append(options,
+		sdktrace.WithSampler(anySampler{samplers: samplers}),
+		sdktrace.WithResource(createResource(config)))
+	provider := sdktrace.NewTracerProvider(options...)
+	o.TracerProvider = provider
+	otel.SetTracerProvider(provider)
 	propagator := propagation.NewCompositeTextMapPropagator(propagation.Baggage{}, propagation.TraceContext{})
 	otel.SetTextMapPropagator(propagator)
 	return nil
 }
-func (o *Observability) createPipeline(config PipelineConfig) error {
-	err := o.createExporter(config.Exporter)
-	if err != nil {
-		return err
+
+// Shutdown flushes pending spans to all pipelines and stops their exporters
+func (o *Observability) Shutdown(ctx context.Context) error {
+	if provider, ok := o.TracerProvider.(*sdktrace.TracerProvider); ok {
+		return provider.Shutdown(ctx)
 	}
 	return nil
 }
-func (o *Observability) createExporter(config ExporterConfig) error {
+
+func createResource(config ObservabilityConfig) *resource.Resource {
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = "Symphony API"
+	}
+	attributes := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+	}
+	if config.ServiceVersion != "" {
+		attributes = append(attributes, semconv.ServiceVersionKey.String(config.ServiceVersion))
+	}
+	if config.SiteId != "" {
+		attributes = append(attributes, attribute.String("symphony.site.id", config.SiteId))
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attributes...)
+}
+
+func (o *Observability) createPipeline(index int, config PipelineConfig) (sdktrace.SpanProcessor, sdktrace.Sampler, error) {
+	sampler, err := createSampler(config.Exporter.Sampler)
+	if err != nil {
+		return nil, nil, err
+	}
+	exporter, err := o.createExporter(config.Exporter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return samplingProcessor{
+		index: index,
+		next:  sdktrace.NewBatchSpanProcessor(exporter),
+	}, sampler, nil
+}
+func (o *Observability) createExporter(config ExporterConfig) (sdktrace.SpanExporter, error) {
 	var exporter sdktrace.SpanExporter
 	var err error
 	switch config.Type {
@@ -102,26 +158,17 @@ func (o *Observability) createExporter(config ExporterConfig) error {
 		} else {
 			exporter, err = exporters.NewConsoleExporter(o.Buffer)
 		}
-		if err != nil {
-			return err
-		}
 	case v1alpha2.TracingExporterZipkin:
-		exporter, err = exporters.NewZipkinExporter(config.BackendUrl, config.Sampler.SampleRate)
-		if err != nil {
-			return err
-		}
+		exporter, err = exporters.NewZipkinExporter(config.BackendUrl)
+	case v1alpha2.TracingExporterOTLPgRPC:
+		exporter, err = exporters.NewOTLPgRPCExporter(config.BackendUrl, config.Insecure, config.Headers)
+	case v1alpha2.TracingExporterOTLPHTTP:
+		exporter, err = exporters.NewOTLPHTTPExporter(config.BackendUrl, config.Insecure, config.Headers)
 	default:
-		return v1alpha2.NewCOAError(nil, fmt.Sprintf("exporter type '%s' is not supported", config.Type), v1alpha2.BadConfig)
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("exporter type '%s' is not supported", config.Type), v1alpha2.BadConfig)
 	}
-	batcher := sdktrace.NewBatchSpanProcessor(exporter)
-	//otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(batcher)))
-	//res, _ := resource.New(context.TODO(), resource.WithAttributes(attribute.String("service.name", "Symphony API (PAI)")))
-	//otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(batcher), sdktrace.WithResource(res)))
-	otel.SetTracerProvider(sdktrace.NewTracerProvider(
-		sdktrace.WithSpanProcessor(batcher),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String("Symphony API"),
-		))))
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	return exporter, nil
 }
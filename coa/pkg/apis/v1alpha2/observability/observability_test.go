@@ -7,10 +7,24 @@
 package observability
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestConsolePipeline(t *testing.T) {
@@ -47,3 +61,269 @@ func TestZipkinPipeline(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+// collectorStub is an in-process OTLP collector that records the spans it receives over gRPC and HTTP
+type collectorStub struct {
+	collectortrace.UnimplementedTraceServiceServer
+	lock  sync.Mutex
+	spans []*tracepb.Span
+	attrs map[string]string
+}
+
+func newCollectorStub() *collectorStub {
+	return &collectorStub{attrs: make(map[string]string)}
+}
+
+func (c *collectorStub) Export(ctx context.Context, request *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	c.record(request)
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+func (c *collectorStub) record(request *collectortrace.ExportTraceServiceRequest) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, rs := range request.ResourceSpans {
+		for _, a := range rs.Resource.Attributes {
+			c.attrs[a.Key] = a.Value.GetStringValue()
+		}
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *collectorStub) count() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.spans)
+}
+
+func (c *collectorStub) serveGRPC(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	collectortrace.RegisterTraceServiceServer(server, c)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func (c *collectorStub) serveHTTP(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		request := collectortrace.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(data, &request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.record(&request)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/v1/traces"
+}
+
+func startSpans(count int) {
+	for i := 0; i < count; i++ {
+		_, span := StartSpan("test", context.Background(), &map[string]string{"index": fmt.Sprintf("%d", i)})
+		span.End()
+	}
+}
+
+func TestOTLPgRPCPipeline(t *testing.T) {
+	collector := newCollectorStub()
+	endpoint := collector.serveGRPC(t)
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		ServiceName:    "symphony-test",
+		ServiceVersion: "1.2.3",
+		SiteId:         "hq",
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPgRPC,
+					BackendUrl: endpoint,
+					Insecure:   true,
+					Sampler: SamplerConfig{
+						SampleRate: "always",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	startSpans(3)
+	assert.Nil(t, ob.Shutdown(context.Background()))
+	assert.Equal(t, 3, collector.count())
+	assert.Equal(t, "symphony-test", collector.attrs["service.name"])
+	assert.Equal(t, "1.2.3", collector.attrs["service.version"])
+	assert.Equal(t, "hq", collector.attrs["symphony.site.id"])
+}
+
+func TestOTLPHTTPPipeline(t *testing.T) {
+	collector := newCollectorStub()
+	endpoint := collector.serveHTTP(t)
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPHTTP,
+					BackendUrl: endpoint,
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	startSpans(2)
+	assert.Nil(t, ob.Shutdown(context.Background()))
+	assert.Equal(t, 2, collector.count())
+	assert.Equal(t, "Symphony API", collector.attrs["service.name"])
+}
+
+func TestMultiplePipelinesWithSampling(t *testing.T) {
+	all := newCollectorStub()
+	none := newCollectorStub()
+	half := newCollectorStub()
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPgRPC,
+					BackendUrl: "http://" + all.serveGRPC(t),
+					Sampler:    SamplerConfig{SampleRate: "always"},
+				},
+			},
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPHTTP,
+					BackendUrl: none.serveHTTP(t),
+					Sampler:    SamplerConfig{SampleRate: "never"},
+				},
+			},
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPgRPC,
+					BackendUrl: half.serveGRPC(t),
+					Insecure:   true,
+					Sampler:    SamplerConfig{SampleRate: "0.5"},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	startSpans(200)
+	assert.Nil(t, ob.Shutdown(context.Background()))
+	assert.Equal(t, 200, all.count())
+	assert.Equal(t, 0, none.count())
+	assert.Greater(t, half.count(), 0)
+	assert.Less(t, half.count(), 200)
+}
+
+func TestNeverSampleDropsSpans(t *testing.T) {
+	collector := newCollectorStub()
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:       v1alpha2.TracingExporterOTLPHTTP,
+					BackendUrl: collector.serveHTTP(t),
+					Sampler:    SamplerConfig{SampleRate: "0"},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	startSpans(10)
+	assert.Nil(t, ob.Shutdown(context.Background()))
+	assert.Equal(t, 0, collector.count())
+}
+
+func TestParentBasedSampler(t *testing.T) {
+	sampler, err := createSampler(SamplerConfig{SampleRate: "never", ParentBased: true})
+	assert.Nil(t, err)
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), parent),
+		TraceID:       parent.TraceID(),
+		Name:          "child",
+	})
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	result = sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{2},
+		Name:          "root",
+	})
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+}
+
+func TestParentBasedPipelinesExportChildrenWithTheirRoot(t *testing.T) {
+	low, err := createSampler(SamplerConfig{SampleRate: "0.3", ParentBased: true})
+	assert.Nil(t, err)
+	high, err := createSampler(SamplerConfig{SampleRate: "0.7", ParentBased: true})
+	assert.Nil(t, err)
+	exporters := []*tracetest.InMemoryExporter{tracetest.NewInMemoryExporter(), tracetest.NewInMemoryExporter()}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(anySampler{samplers: []sdktrace.Sampler{low, high}}),
+		sdktrace.WithSpanProcessor(samplingProcessor{index: 0, next: sdktrace.NewSimpleSpanProcessor(exporters[0])}),
+		sdktrace.WithSpanProcessor(samplingProcessor{index: 1, next: sdktrace.NewSimpleSpanProcessor(exporters[1])}),
+	)
+	tracer := provider.Tracer("test")
+	for i := 0; i < 200; i++ {
+		ctx, root := tracer.Start(context.Background(), "root")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+		root.End()
+	}
+	// the in-memory exporter forgets its spans on shutdown
+	assert.Nil(t, provider.ForceFlush(context.Background()))
+
+	counts := make([]int, len(exporters))
+	for i, exporter := range exporters {
+		roots := make(map[trace.TraceID]bool)
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "root" {
+				roots[span.SpanContext.TraceID()] = true
+			}
+		}
+		for _, span := range exporter.GetSpans() {
+			if span.Name == "child" {
+				assert.True(t, roots[span.SpanContext.TraceID()], "pipeline %d exported a child without its root", i)
+			}
+		}
+		counts[i] = len(roots)
+	}
+	assert.Greater(t, counts[0], 0)
+	assert.Less(t, counts[0], counts[1])
+	assert.Less(t, counts[1], 200)
+}
+
+func TestInvalidSampleRate(t *testing.T) {
+	ob := Observability{}
+	err := ob.Init(ObservabilityConfig{
+		Pipelines: []PipelineConfig{
+			{
+				Exporter: ExporterConfig{
+					Type:    v1alpha2.TracingExporterConsole,
+					Sampler: SamplerConfig{SampleRate: "1.5"},
+				},
+			},
+		},
+	})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
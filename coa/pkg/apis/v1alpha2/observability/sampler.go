@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// createSampler creates a sampler from a sampler config. The sample rate is "always", "never" or a
+// ratio between 0 and 1. An empty sample rate samples all traces.
+func createSampler(config SamplerConfig) (sdktrace.Sampler, error) {
+	var sampler sdktrace.Sampler
+	switch strings.ToLower(config.SampleRate) {
+	case "", "always":
+		sampler = sdktrace.AlwaysSample()
+	case "never":
+		sampler = sdktrace.NeverSample()
+	default:
+		ratio, err := strconv.ParseFloat(config.SampleRate, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("sample rate '%s' is invalid, expected 'always', 'never' or a ratio between 0 and 1", config.SampleRate), v1alpha2.BadConfig)
+		}
+		sampler = sdktrace.TraceIDRatioBased(ratio)
+	}
+	if config.ParentBased {
+		sampler = sdktrace.ParentBased(sampler)
+	}
+	return sampler, nil
+}
+
+// sampledTraceStateKey is the tracestate entry that records which pipelines sampled a span, as a hex bit
+// mask of the pipeline indexes
+const sampledTraceStateKey = "symphony"
+
+// anySampler records a span when any of the pipeline samplers does, and records the decision of each
+// pipeline in the span's tracestate. Children of a local span are sampled by each pipeline as if the
+// parent's sampled flag were that pipeline's own decision, so a parent based pipeline doesn't export
+// the children of a span it dropped. Each pipeline then filters the recorded spans through a
+// samplingProcessor.
+type anySampler struct {
+	samplers []sdktrace.Sampler
+}
+
+func (a anySampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := trace.SpanContextFromContext(parameters.ParentContext)
+	parentMask, hasParentMask := sampledPipelines(parent)
+	var mask uint64
+	recordOnly := false
+	for i, s := range a.samplers {
+		p := parameters
+		if hasParentMask {
+			flags := parent.TraceFlags().WithSampled(parentMask&(1<<i) != 0)
+			p.ParentContext = trace.ContextWithSpanContext(parameters.ParentContext, parent.WithTraceFlags(flags))
+		}
+		switch s.ShouldSample(p).Decision {
+		case sdktrace.RecordAndSample:
+			mask |= 1 << i
+		case sdktrace.RecordOnly:
+			recordOnly = true
+		}
+	}
+	result := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: parent.TraceState(),
+	}
+	if mask != 0 {
+		result.Decision = sdktrace.RecordAndSample
+	} else if recordOnly {
+		result.Decision = sdktrace.RecordOnly
+	}
+	if state, err := result.Tracestate.Insert(sampledTraceStateKey, strconv.FormatUint(mask, 16)); err == nil {
+		result.Tracestate = state
+	}
+	return result
+}
+
+func (a anySampler) Description() string {
+	descriptions := make([]string, len(a.samplers))
+	for i, s := range a.samplers {
+		descriptions[i] = s.Description()
+	}
+	return fmt.Sprintf("AnyOf{%s}", strings.Join(descriptions, ","))
+}
+
+// sampledPipelines reads which pipelines sampled a span. The tracestate of remote spans isn't used, as
+// it was written by another process with its own pipelines.
+func sampledPipelines(span trace.SpanContext) (uint64, bool) {
+	if !span.IsValid() || span.IsRemote() {
+		return 0, false
+	}
+	value := span.TraceState().Get(sampledTraceStateKey)
+	if value == "" {
+		return 0, false
+	}
+	mask, err := strconv.ParseUint(value, 16, 64)
+	return mask, err == nil
+}
+
+// samplingProcessor forwards ended spans to the next processor when the pipeline sampled them
+type samplingProcessor struct {
+	index int
+	next  sdktrace.SpanProcessor
+}
+
+func (p samplingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p samplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if mask, ok := sampledPipelines(s.SpanContext()); ok && mask&(1<<p.index) != 0 {
+		p.next.OnEnd(s)
+	}
+}
+
+func (p samplingProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p samplingProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
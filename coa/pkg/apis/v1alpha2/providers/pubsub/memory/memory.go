@@ -8,9 +8,11 @@ package memory
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 )
 
@@ -58,14 +60,20 @@ func (i *InMemoryPubSubProvider) Init(config providers.IProviderConfig) error {
 	return nil
 }
 func (i *InMemoryPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
+	start := time.Now()
 	arr, ok := i.Subscribers[topic]
 	if ok && arr != nil {
 		for _, s := range arr {
+			metrics.AddQueueDepth("memory", topic, 1)
 			go func(handler v1alpha2.EventHandler, topic string, event v1alpha2.Event) {
-				handler(topic, event)
+				handleStart := time.Now()
+				err := handler(topic, event)
+				metrics.AddQueueDepth("memory", topic, -1)
+				metrics.ObserveHandle("memory", topic, metrics.Outcome(err), time.Since(handleStart))
 			}(s, topic, event)
 		}
 	}
+	metrics.ObservePublish("memory", topic, time.Since(start))
 	return nil
 }
 func (i *InMemoryPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
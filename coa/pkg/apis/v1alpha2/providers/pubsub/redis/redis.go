@@ -13,10 +13,12 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/metrics"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -33,11 +35,13 @@ type RedisPubSubProvider struct {
 	Ctx         context.Context
 	Cancel      context.CancelFunc
 	Context     *contexts.ManagerContext
+	lock        sync.Mutex
 }
 
 type RedisMessageWrapper struct {
 	MessageID string
 	Topic     string
+	Group     string
 	Message   interface{}
 	Handler   v1alpha2.EventHandler
 }
@@ -192,6 +196,7 @@ func (i *RedisPubSubProvider) worker() {
 			return
 		case msg := <-i.Queue:
 			i.processMessage(msg)
+			metrics.AddQueueDepth("redis", msg.Topic, -1)
 		}
 	}
 }
@@ -201,45 +206,64 @@ func (i *RedisPubSubProvider) processMessage(msg RedisMessageWrapper) error {
 	if err != nil {
 		return v1alpha2.NewCOAError(err, "failed to unmarshal event", v1alpha2.InternalError)
 	}
-	if err := msg.Handler(msg.Topic, evt); err != nil {
+	start := time.Now()
+	err = msg.Handler(msg.Topic, evt)
+	metrics.ObserveHandle("redis", msg.Topic, metrics.Outcome(err), time.Since(start))
+	if err != nil {
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to handle message %s", msg.MessageID), v1alpha2.InternalError)
 	}
-	if err := i.Client.XAck(msg.Topic, i.Config.ConsumerID, msg.MessageID).Err(); err != nil {
+	if err := i.Client.XAck(msg.Topic, msg.Group, msg.MessageID).Err(); err != nil {
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to acknowledge message %s", msg.MessageID), v1alpha2.InternalError)
 	}
 	return nil
 }
 
 func (i *RedisPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
+	start := time.Now()
 	_, err := i.Client.XAdd(&redis.XAddArgs{
 		Stream: topic,
 		Values: map[string]interface{}{"data": event},
 	}).Result()
+	metrics.ObservePublish("redis", topic, time.Since(start))
 	if err != nil {
 		mLog.Debugf("  P (Redis PubSub) : failed to publish message %v", err)
 		return v1alpha2.NewCOAError(err, "failed to publish message", v1alpha2.InternalError)
 	}
 	return nil
 }
+// Subscribe reads a topic with a consumer group of its own, so that every subscriber of a topic gets every message.
+// The first subscriber of a topic uses the ConsumerID group, and later subscribers add their order to it. Subscribers
+// are made in the same order on every start, so each one resumes its own group.
 func (i *RedisPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
-	err := i.Client.XGroupCreateMkStream(topic, i.Config.ConsumerID, "0").Err()
+	i.lock.Lock()
+	group := subscriberGroup(i.Config.ConsumerID, len(i.Subscribers[topic]))
+	i.Subscribers[topic] = append(i.Subscribers[topic], handler)
+	i.lock.Unlock()
+	err := i.Client.XGroupCreateMkStream(topic, group, "0").Err()
 	//Ignore BUSYGROUP errors
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		mLog.Debugf("  P (Redis PubSub) : failed to subscribe %v", err)
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to subsceribe to topic %s", topic), v1alpha2.InternalError)
 	}
-	go i.pollNewMessagesLoop(topic, handler)
-	go i.reclaimPendingMessagesLoop(topic, handler)
+	go i.pollNewMessagesLoop(topic, group, handler)
+	go i.reclaimPendingMessagesLoop(topic, group, handler)
 	return nil
 }
 
-func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, handler v1alpha2.EventHandler) {
+func subscriberGroup(consumerID string, index int) string {
+	if index == 0 {
+		return consumerID
+	}
+	return fmt.Sprintf("%s-%d", consumerID, index)
+}
+
+func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, group string, handler v1alpha2.EventHandler) {
 	for {
 		if i.Ctx.Err() != nil {
 			return
 		}
 		streams, err := i.Client.XReadGroup(&redis.XReadGroupArgs{
-			Group:    i.Config.ConsumerID,
+			Group:    group,
 			Consumer: i.Config.ConsumerID,
 			Streams:  []string{topic, ">"},
 			Count:    int64(i.Config.QueueDepth),
@@ -251,56 +275,61 @@ func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, handler v1alpha2
 			continue
 		}
 		for _, s := range streams {
-			i.enqueueMessages(s.Stream, handler, s.Messages)
+			i.enqueueMessages(s.Stream, group, handler, s.Messages)
 		}
 	}
 }
 
-func (i *RedisPubSubProvider) enqueueMessages(topic string, handler v1alpha2.EventHandler, msgs []redis.XMessage) {
+func (i *RedisPubSubProvider) enqueueMessages(topic string, group string, handler v1alpha2.EventHandler, msgs []redis.XMessage) {
 	for _, msg := range msgs {
-		rmsg := createRedisMessageWrapper(topic, handler, msg)
+		rmsg := createRedisMessageWrapper(topic, group, handler, msg)
+		// the depth is raised before the message is queued, as a worker can take it and lower the depth
+		// before this loop runs again
+		metrics.AddQueueDepth("redis", topic, 1)
 		select {
 		case i.Queue <- rmsg:
 		case <-i.Ctx.Done():
+			metrics.AddQueueDepth("redis", topic, -1)
 			return
 		}
 	}
 }
 
-func createRedisMessageWrapper(topic string, handler v1alpha2.EventHandler, msg redis.XMessage) RedisMessageWrapper {
+func createRedisMessageWrapper(topic string, group string, handler v1alpha2.EventHandler, msg redis.XMessage) RedisMessageWrapper {
 	var data interface{}
 	if dataValue, exists := msg.Values["data"]; exists && dataValue != nil {
 		data = dataValue
 	}
 	return RedisMessageWrapper{
 		Topic:     topic,
+		Group:     group,
 		Message:   data,
 		MessageID: msg.ID,
 		Handler:   handler,
 	}
 }
 
-func (i *RedisPubSubProvider) reclaimPendingMessagesLoop(topic string, handler v1alpha2.EventHandler) {
+func (i *RedisPubSubProvider) reclaimPendingMessagesLoop(topic string, group string, handler v1alpha2.EventHandler) {
 	if i.Config.ProcessingTimeout == 0 || i.Config.RedeliverInterval == 0 {
 		return
 	}
-	i.reclaimPendingMessages(topic, handler)
+	i.reclaimPendingMessages(topic, group, handler)
 	reclaimTicker := time.NewTicker(i.Config.RedeliverInterval)
 	for {
 		select {
 		case <-i.Ctx.Done():
 			return
 		case <-reclaimTicker.C:
-			i.reclaimPendingMessages(topic, handler)
+			i.reclaimPendingMessages(topic, group, handler)
 		}
 	}
 }
 
-func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, handler v1alpha2.EventHandler) {
+func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, group string, handler v1alpha2.EventHandler) {
 	for {
 		pendingResult, err := i.Client.XPendingExt(&redis.XPendingExtArgs{
 			Stream: topic,
-			Group:  i.Config.ConsumerID,
+			Group:  group,
 			Start:  "-",
 			End:    "+",
 			Count:  int64(i.Config.QueueDepth),
@@ -320,7 +349,7 @@ func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, handler v1alp
 		}
 		claimResult, err := i.Client.XClaim(&redis.XClaimArgs{
 			Stream:   topic,
-			Group:    i.Config.ConsumerID,
+			Group:    group,
 			Consumer: i.Config.ConsumerID,
 			MinIdle:  i.Config.ProcessingTimeout,
 			Messages: msgIDs,
@@ -329,7 +358,7 @@ func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, handler v1alp
 			mLog.Debugf("  P (Redis PubSub) : failed to reclaim pending message %v", err)
 			break
 		}
-		i.enqueueMessages(topic, handler, claimResult)
+		i.enqueueMessages(topic, group, handler, claimResult)
 		// If the Redis nil error is returned, it means some messages in the pending
 		// state no longer exist. We need to acknowledge these mesages to
 		// remove them from the pending list
@@ -343,16 +372,16 @@ func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, handler v1alp
 			for _, claimed := range claimResult {
 				delete(expectedMsgIDs, claimed.ID)
 			}
-			i.removeMessagesThatNoLongerExistFromPending(topic, expectedMsgIDs, handler)
+			i.removeMessagesThatNoLongerExistFromPending(topic, group, expectedMsgIDs, handler)
 		}
 	}
 }
 
-func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic string, messageIDs map[string]struct{}, handler v1alpha2.EventHandler) {
+func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic string, group string, messageIDs map[string]struct{}, handler v1alpha2.EventHandler) {
 	for pendingID := range messageIDs {
 		claimResultSingleMsg, err := i.Client.XClaim(&redis.XClaimArgs{
 			Stream:   topic,
-			Group:    i.Config.ConsumerID,
+			Group:    group,
 			Consumer: i.Config.ConsumerID,
 			MinIdle:  i.Config.ProcessingTimeout,
 			Messages: []string{pendingID},
@@ -362,10 +391,10 @@ func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic s
 			continue
 		}
 		if errors.Is(err, redis.Nil) {
-			if err = i.Client.XAck(topic, i.Config.ConsumerID, pendingID).Err(); err != nil {
-				mLog.Debugf("  P (Redis PubSub) : error acknowledging Redis message %s after failed claim for %s - %v", i.Config.ConsumerID, pendingID, err)
+			if err = i.Client.XAck(topic, group, pendingID).Err(); err != nil {
+				mLog.Debugf("  P (Redis PubSub) : error acknowledging Redis message %s after failed claim for %s - %v", group, pendingID, err)
 			} else {
-				i.enqueueMessages(topic, handler, claimResultSingleMsg)
+				i.enqueueMessages(topic, group, handler, claimResultSingleMsg)
 			}
 		}
 	}
@@ -8,10 +8,12 @@ package redis
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 )
@@ -311,3 +313,55 @@ func TestRedisPubSubProviderConfigFromMap(t *testing.T) {
 	assert.Equal(t, time.Duration(10), config.ProcessingTimeout)
 	assert.Equal(t, time.Duration(10), config.RedeliverInterval)
 }
+
+func TestSubscribersOnSameTopicGetEveryMessage(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := RedisPubSubProvider{}
+	err := provider.Init(RedisPubSubProviderConfig{
+		Name:            "test",
+		Host:            server.Addr(),
+		NumberOfWorkers: 2,
+		QueueDepth:      10,
+		ConsumerID:      "host-1",
+	})
+	assert.Nil(t, err)
+	defer provider.Cancel()
+
+	const count = 5
+	received1 := make(chan string, count)
+	received2 := make(chan string, count)
+	err = provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+		received1 <- message.Body.(string)
+		return nil
+	})
+	assert.Nil(t, err)
+	err = provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+		received2 <- message.Body.(string)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	expected := make([]string, 0, count)
+	for n := 0; n < count; n++ {
+		body := fmt.Sprintf("message-%d", n)
+		expected = append(expected, body)
+		assert.Nil(t, provider.Publish("job", v1alpha2.Event{Body: body}))
+	}
+	for _, received := range []chan string{received1, received2} {
+		messages := make([]string, 0, count)
+		for len(messages) < count {
+			select {
+			case m := <-received:
+				messages = append(messages, m)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("received %d of %d messages", len(messages), count)
+			}
+		}
+		assert.ElementsMatch(t, expected, messages)
+	}
+}
+
+func TestSubscriberGroup(t *testing.T) {
+	assert.Equal(t, "host-1", subscriberGroup("host-1", 0))
+	assert.Equal(t, "host-1-1", subscriberGroup("host-1", 1))
+}
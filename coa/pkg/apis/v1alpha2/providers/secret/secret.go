@@ -14,3 +14,9 @@ type ISecretProvider interface {
 	Init(config providers.IProviderConfig) error
 	Get(object string, field string) (string, error)
 }
+
+// IWithSecretProvider is implemented by providers that read secrets, such as credentials, through the
+// secret provider of their manager
+type IWithSecretProvider interface {
+	SetSecretProvider(provider ISecretProvider)
+}
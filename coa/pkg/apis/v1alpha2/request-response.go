@@ -9,6 +9,7 @@ package v1alpha2
 import (
 	"context"
 	"fmt"
+	"io"
 )
 
 type COARequest struct {
@@ -62,8 +63,15 @@ type COAResponse struct {
 	State       State             `json:"state"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	RedirectUri string            `json:"redirectUri,omitempty"`
+	// Stream, when set, writes a long-lived response body such as Server-Sent Events after the
+	// status and headers are sent. Body is ignored. Only the HTTP binding supports streaming.
+	Stream StreamWriter `json:"-"`
 }
 
+// StreamWriter writes a streamed response body. Flush sends buffered data to the client; it returns an
+// error once the client has disconnected, at which point the writer should return.
+type StreamWriter func(w io.Writer, flush func() error)
+
 func (c COAResponse) String() string {
 	return string(c.Body)
 }
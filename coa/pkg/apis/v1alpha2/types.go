@@ -110,19 +110,22 @@ func (s State) String() string {
 }
 
 const (
-	COAMetaHeader          = "COA_META_HEADER"
-	TracingExporterConsole = "tracing.exporters.console"
-	TracingExporterZipkin  = "tracing.exporters.zipkin"
-	ProvidersState         = "providers.state"
-	ProvidersConfig        = "providers.config"
-	ProvidersSecret        = "providers.secret"
-	ProvidersReference     = "providers.reference"
-	ProvidersProbe         = "providers.probe"
-	ProvidersUploader      = "providers.uploader"
-	ProvidersReporter      = "providers.reporter"
-	ProviderQueue          = "providers.queue"
-	ProviderLedger         = "providers.ledger"
-	StatusOutput           = "__status"
-	ErrorOutput            = "__error"
-	StateOutput            = "__state"
+	COAMetaHeader           = "COA_META_HEADER"
+	TracingExporterConsole  = "tracing.exporters.console"
+	TracingExporterZipkin   = "tracing.exporters.zipkin"
+	TracingExporterOTLPgRPC = "tracing.exporters.otlpgrpc"
+	TracingExporterOTLPHTTP = "tracing.exporters.otlphttp"
+	ProvidersState          = "providers.state"
+	ProvidersConfig         = "providers.config"
+	ProvidersSecret         = "providers.secret"
+	ProvidersReference      = "providers.reference"
+	ProvidersProbe          = "providers.probe"
+	ProvidersUploader       = "providers.uploader"
+	ProvidersReporter       = "providers.reporter"
+	ProviderQueue           = "providers.queue"
+	ProviderLedger          = "providers.ledger"
+	StatusOutput            = "__status"
+	ErrorOutput             = "__error"
+	StateOutput             = "__state"
+	TokenRevocationTopic    = "token-revocation"
 )
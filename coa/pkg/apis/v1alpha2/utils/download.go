@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// Download reads the content of a URL. A nil client uses http.DefaultClient.
+func Download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("'%s' is not a valid URL", url), v1alpha2.BadRequest)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to download '%s'", url), v1alpha2.InternalError)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read '%s'", url), v1alpha2.InternalError)
+	}
+	if response.StatusCode != http.StatusOK {
+		state := v1alpha2.FromHTTPResponseCode(response.StatusCode, data).State
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to download '%s': %s", url, response.Status), state)
+	}
+	return data, nil
+}
+
+// ReadSource reads a file that is either downloaded over HTTP(S) or read from an absolute local path
+func ReadSource(ctx context.Context, source string) ([]byte, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return Download(ctx, nil, source)
+	}
+	if !filepath.IsAbs(source) {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' is neither an HTTP URL nor an absolute path", source), v1alpha2.BadRequest)
+	}
+	return os.ReadFile(source)
+}
+
+// SHA256 is the hex-encoded SHA-256 checksum of data
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySHA256 checks data read from source against a hex-encoded SHA-256 checksum. An empty checksum
+// isn't checked.
+func VerifySHA256(source string, data []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	if SHA256(data) != strings.ToLower(checksum) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("checksum of '%s' doesn't match", source), v1alpha2.BadRequest)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("downloaded"))
+	}))
+	defer server.Close()
+	file := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(file, []byte("local"), 0644))
+
+	data, err := ReadSource(context.Background(), server.URL+"/file")
+	assert.Nil(t, err)
+	assert.Equal(t, "downloaded", string(data))
+	data, err = ReadSource(context.Background(), file)
+	assert.Nil(t, err)
+	assert.Equal(t, "local", string(data))
+
+	_, err = ReadSource(context.Background(), server.URL+"/missing")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.NotFound, err.(v1alpha2.COAError).State)
+	_, err = ReadSource(context.Background(), "relative/file")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
+
+func TestVerifySHA256(t *testing.T) {
+	data := []byte("data")
+	sum := SHA256(data)
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", sum)
+	assert.Nil(t, VerifySHA256("source", data, sum))
+	assert.Nil(t, VerifySHA256("source", data, strings.ToUpper(sum)))
+	assert.Nil(t, VerifySHA256("source", data, ""))
+	err := VerifySHA256("source", []byte("other"), sum)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
@@ -0,0 +1,25 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	token := func(claims string) string {
+		return "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	}
+	assert.Equal(t, time.Unix(1700000000, 0), TokenExpiry(token(`{"sub":"admin","exp":1700000000}`)))
+	assert.True(t, TokenExpiry(token(`{"sub":"admin"}`)).IsZero())
+	assert.True(t, TokenExpiry(token(`not json`)).IsZero())
+	assert.True(t, TokenExpiry("opaque-token").IsZero())
+}
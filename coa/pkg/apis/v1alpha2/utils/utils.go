@@ -9,6 +9,7 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -36,6 +37,22 @@ func UnmarshalDuration(duration string) (time.Duration, error) {
 	}
 }
 
+// ParsePositiveDuration parses a duration such as "30s", which must be positive. An empty value is the
+// default value.
+func ParsePositiveDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("duration %s isn't positive", value)
+	}
+	return duration, nil
+}
+
 func ParseProperty(val string) string {
 	if strings.HasPrefix(val, "$env:") {
 		return os.Getenv(val[5:])
@@ -26,6 +26,54 @@ type ProjectConfig struct {
 	SyncIntervalSeconds uint `json:"syncIntervalSeconds,omitempty"`
 
 	ValidationPolicies map[string][]ValidationPolicy `json:"validationPolicies,omitempty"`
+
+	// SymphonyAPI configures how controllers call the Symphony API
+	SymphonyAPI SymphonyAPIConfig `json:"symphonyApi,omitempty"`
+}
+
+// SymphonyAPIConfig is the Symphony API endpoint, credentials and polling intervals used by controllers
+type SymphonyAPIConfig struct {
+	// Url is the base URL of the Symphony API, defaults to http://symphony-service:8080/v1alpha2/
+	Url string `json:"url,omitempty"`
+	// CredentialsSecretRef is the Secret holding the user name and password. Without it, controllers
+	// sign in as admin with an empty password.
+	CredentialsSecretRef *CredentialsSecretReference `json:"credentialsSecretRef,omitempty"`
+	// TLS configures verification of the API's certificate for https URLs
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// ReconcileIntervalSeconds is how often instances and targets are re-deployed to correct drift,
+	// defaults to 60
+	ReconcileIntervalSeconds uint `json:"reconcileIntervalSeconds,omitempty"`
+	// ReconcileJitterPercent spreads re-deployments by up to this percentage of the reconcile interval,
+	// so that objects created together don't re-deploy together, defaults to 10
+	ReconcileJitterPercent *uint `json:"reconcileJitterPercent,omitempty"`
+	// WatchSummaries streams deployment summary events from the API's events vendor, so that the status
+	// of instances and targets is updated as soon as a deployment finishes, defaults to true
+	WatchSummaries *bool `json:"watchSummaries,omitempty"`
+	// DeletionPollIntervalSeconds is how often the removal of a deleted instance or target is checked,
+	// defaults to 10
+	DeletionPollIntervalSeconds uint `json:"deletionPollIntervalSeconds,omitempty"`
+	// DeletionTimeoutSeconds is how long to wait for a removal before the finalizer is dropped anyway,
+	// defaults to 300
+	DeletionTimeoutSeconds uint `json:"deletionTimeoutSeconds,omitempty"`
+}
+
+type CredentialsSecretReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the controller's namespace
+	Namespace string `json:"namespace,omitempty"`
+	// UsernameKey defaults to "username"
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey defaults to "password"
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+type TLSConfig struct {
+	// CACertFile is a PEM file with the CA certificates trusted for the API's certificate
+	CACertFile string `json:"caCertFile,omitempty"`
+	// ServerName overrides the host name verified against the API's certificate
+	ServerName string `json:"serverName,omitempty"`
+	// InsecureSkipVerify skips verification of the API's certificate
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
 
 type ValidationPolicy struct {
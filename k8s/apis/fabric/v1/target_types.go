@@ -22,11 +22,21 @@ type TargetStatus struct {
 	Properties         map[string]string           `json:"properties,omitempty"`
 	ProvisioningStatus apimodel.ProvisioningStatus `json:"provisioningStatus"`
 	LastModified       metav1.Time                 `json:"lastModified,omitempty"`
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Deployment mirrors the results of the last deployment
+	Deployment k8smodel.DeploymentStatus `json:"deployment,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.properties.status`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // Target is the Schema for the targets API
 type Target struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -13,6 +13,13 @@ import (
 
 type CatalogStatus struct {
 	Properties map[string]string `json:"properties"`
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -9,6 +9,8 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"reflect"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -62,6 +64,11 @@ func (r *Catalog) ValidateCreate() error {
 func (r *Catalog) ValidateUpdate(old runtime.Object) error {
 	cataloglog.Info("validate update", "name", r.Name)
 
+	// updates that don't change the spec, such as finalizer removals, are allowed even if the
+	// schema has been deleted since
+	if oldCatalog, ok := old.(*Catalog); ok && reflect.DeepEqual(oldCatalog.Spec, r.Spec) {
+		return nil
+	}
 	return r.validateUpdateCatalog()
 }
 
@@ -76,44 +83,48 @@ func (r *Catalog) validateCreateCatalog() error {
 	return r.checkSchema()
 }
 
+// checkSchema checks the catalog's properties against the schema catalog declared in its "schema"
+// metadata, the way the Symphony API validates catalogs
 func (r *Catalog) checkSchema() error {
-	if schemaName, ok := r.Spec.Metadata["schema"]; ok {
-		var catalogs CatalogList
-		err := myCatalogClient.List(context.Background(), &catalogs, client.InNamespace(r.Namespace), client.MatchingFields{".spec.name": schemaName})
-		if err != nil || len(catalogs.Items) == 0 {
-			return err
-		}
-
-		jData, _ := json.Marshal(catalogs.Items[0].Spec.Properties)
-		var properties map[string]interface{}
-		err = json.Unmarshal(jData, &properties)
-		if err != nil {
-			return v1alpha2.NewCOAError(err, "invalid schema", v1alpha2.ValidateFailed)
-		}
-		if spec, ok := properties["spec"]; ok {
-			var schemaObj utils.Schema
-			jData, _ := json.Marshal(spec)
-			err := json.Unmarshal(jData, &schemaObj)
-			if err != nil {
-				return v1alpha2.NewCOAError(err, "invalid schema", v1alpha2.ValidateFailed)
-			}
-			jData, _ = json.Marshal(r.Spec.Properties)
-			var properties map[string]interface{}
-			err = json.Unmarshal(jData, &properties)
-			if err != nil {
-				return v1alpha2.NewCOAError(err, "invalid properties", v1alpha2.ValidateFailed)
-			}
-			result, err := schemaObj.CheckProperties(properties, nil)
-			if err != nil {
-				return v1alpha2.NewCOAError(err, "invalid properties", v1alpha2.ValidateFailed)
-			}
-			if !result.Valid {
-				return v1alpha2.NewCOAError(err, "invalid properties", v1alpha2.ValidateFailed)
-			}
-		}
+	schemaName, ok := r.Spec.Metadata["schema"]
+	if !ok {
+		return nil
+	}
+	var catalogs CatalogList
+	err := myCatalogClient.List(context.Background(), &catalogs, client.InNamespace(r.Namespace), client.MatchingFields{".spec.name": schemaName})
+	if err != nil {
+		return err
+	}
+	if len(catalogs.Items) == 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("schema catalog '%s' is not found", schemaName), v1alpha2.ValidateFailed)
+	}
+	schemaProperties, err := catalogProperties(catalogs.Items[0])
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid schema", v1alpha2.ValidateFailed)
+	}
+	properties, err := catalogProperties(*r)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid properties", v1alpha2.ValidateFailed)
+	}
+	result, err := utils.CheckCatalogSchema(schemaProperties, properties)
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("properties don't match schema '%s': %s", schemaName, result.ErrorMessage()), v1alpha2.ValidateFailed)
 	}
 	return nil
 }
+
+func catalogProperties(catalog Catalog) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	if len(catalog.Spec.Properties.Raw) == 0 {
+		return properties, nil
+	}
+	err := json.Unmarshal(catalog.Spec.Properties.Raw, &properties)
+	return properties, err
+}
+
 func (r *Catalog) validateUpdateCatalog() error {
 	return r.checkSchema()
 }
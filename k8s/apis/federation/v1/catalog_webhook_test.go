@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"testing"
+
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testSchema = `{"spec":{"rules":{"email":{"pattern":"<email>"},"name":{"required":true}}}}`
+
+func newCatalog(name string, properties string, schema string) *Catalog {
+	catalog := &Catalog{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: k8smodel.CatalogSpec{
+			SiteId:     "hq",
+			Name:       name,
+			Type:       "config",
+			Properties: runtime.RawExtension{Raw: []byte(properties)},
+		},
+	}
+	if schema != "" {
+		catalog.Spec.Metadata = map[string]string{"schema": schema}
+	}
+	return catalog
+}
+
+var _ = Describe("Catalog webhook", func() {
+	BeforeEach(func() {
+		err := k8sClient.Create(ctx, newCatalog("email-schema", testSchema, ""))
+		if err != nil {
+			Expect(err.Error()).To(ContainSubstring("already exists"))
+		}
+	})
+
+	It("accepts a catalog that matches its schema", func() {
+		// the webhook reads schemas from the manager's cache, which may lag behind
+		Eventually(func() error {
+			return k8sClient.Create(ctx, newCatalog("valid-config", `{"name":"sample","email":"sample@contoso.com"}`, "email-schema"))
+		}).Should(Succeed())
+	})
+
+	It("rejects a catalog that doesn't match its schema", func() {
+		Eventually(func() string {
+			err := k8sClient.Create(ctx, newCatalog("invalid-config", `{"email":"sample"}`, "email-schema"))
+			if err == nil {
+				return ""
+			}
+			return err.Error()
+		}).Should(ContainSubstring("properties don't match schema 'email-schema'"))
+	})
+
+	It("rejects a catalog whose schema is missing", func() {
+		err := k8sClient.Create(ctx, newCatalog("orphan-config", `{"name":"sample"}`, "other-schema"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("schema catalog 'other-schema' is not found"))
+	})
+})
+
+func TestCatalogSchemaValidation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, AddToScheme(scheme))
+	// the fake client ignores field selectors, so it only holds the schema
+	myCatalogClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newCatalog("email-schema", testSchema, ""),
+	).Build()
+
+	assert.Nil(t, newCatalog("config", `{"name":"sample","email":"sample@contoso.com"}`, "email-schema").ValidateCreate())
+	assert.Nil(t, newCatalog("config", `{"name":"sample"}`, "").ValidateCreate())
+
+	err := newCatalog("config", `{"email":"sample"}`, "email-schema").ValidateCreate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "properties don't match schema 'email-schema': email: property does not match pattern: <email>; name: missing required property", err.Error())
+
+	// updates that don't change the spec are allowed
+	catalog := newCatalog("config", `{"email":"sample"}`, "email-schema")
+	assert.Nil(t, catalog.ValidateUpdate(catalog.DeepCopy()))
+}
+
+func TestCatalogMissingSchema(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, AddToScheme(scheme))
+	myCatalogClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	err := newCatalog("config", `{"name":"sample"}`, "email-schema").ValidateCreate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "schema catalog 'email-schema' is not found", err.Error())
+}
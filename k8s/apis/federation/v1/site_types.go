@@ -11,6 +11,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// SiteStatus is the status reported by a site, with the conditions maintained by the controller
+type SiteStatus struct {
+	apimodel.SiteStatus `json:",inline"`
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // Site is the Schema for the sites API
@@ -18,8 +30,8 @@ type Site struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   apimodel.SiteSpec   `json:"spec,omitempty"`
-	Status apimodel.SiteStatus `json:"status,omitempty"`
+	Spec   apimodel.SiteSpec `json:"spec,omitempty"`
+	Status SiteStatus        `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
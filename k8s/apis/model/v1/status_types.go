@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+// Condition types reported by Symphony objects. Ready is the summary condition that
+// kubectl wait and GitOps health checks use; the other conditions are only True while they apply.
+const (
+	ConditionReady       = "Ready"
+	ConditionReconciling = "Reconciling"
+	ConditionDegraded    = "Degraded"
+	ConditionStalled     = "Stalled"
+)
+
+// Condition reasons
+const (
+	ReasonSucceeded        = "Succeeded"
+	ReasonAccepted         = "Accepted"
+	ReasonDeploying        = "Deploying"
+	ReasonDeploymentFailed = "DeploymentFailed"
+	ReasonAPIError         = "SymphonyAPIError"
+	ReasonInvalidSpec      = "InvalidSpec"
+	ReasonRunning          = "Running"
+	ReasonPaused           = "Paused"
+	ReasonFailed           = "Failed"
+	ReasonOffline          = "Offline"
+)
+
+// DeploymentStatus mirrors the results of the last deployment of an instance or a target
+// +kubebuilder:object:generate=true
+type DeploymentStatus struct {
+	// Generation of the object that was deployed
+	Generation   int64  `json:"generation,omitempty"`
+	TargetCount  int    `json:"targetCount"`
+	SuccessCount int    `json:"successCount"`
+	Message      string `json:"message,omitempty"`
+	// Results per target, sorted by name
+	Targets []TargetDeploymentStatus `json:"targets,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type TargetDeploymentStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Results per component, sorted by name
+	Components []ComponentDeploymentStatus `json:"components,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ComponentDeploymentStatus struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
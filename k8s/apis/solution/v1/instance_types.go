@@ -8,6 +8,7 @@ package v1
 
 import (
 	apimodel "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -17,6 +18,18 @@ type InstanceStatus struct {
 	Properties         map[string]string           `json:"properties,omitempty"`
 	ProvisioningStatus apimodel.ProvisioningStatus `json:"provisioningStatus"`
 	LastModified       metav1.Time                 `json:"lastModified,omitempty"`
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Deployment mirrors the results of the last deployment
+	Deployment k8smodel.DeploymentStatus `json:"deployment,omitempty"`
+	// Dependencies are the generations of the solution and the targets, keyed by solution/<name> and
+	// target/<name>, that the last deployment was queued for
+	Dependencies map[string]int64 `json:"dependencies,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -24,6 +37,7 @@ type InstanceStatus struct {
 // +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.properties.status`
 // +kubebuilder:printcolumn:name="Targets",type=string,JSONPath=`.status.properties.targets`
 // +kubebuilder:printcolumn:name="Deployed",type=string,JSONPath=`.status.properties.deployed`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 
 // Instance is the Schema for the instances API
 type Instance struct {
@@ -15,6 +15,13 @@ import (
 type SolutionStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	Properties map[string]string `json:"properties,omitempty"`
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
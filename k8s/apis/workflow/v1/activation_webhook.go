@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var activationlog = logf.Log.WithName("activation-resource")
+var myActivationClient client.Client
+
+func (r *Activation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	myActivationClient = mgr.GetClient()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-workflow-symphony-v1-activation,mutating=false,failurePolicy=fail,sideEffects=None,groups=workflow.symphony,resources=activations,verbs=create;update,versions=v1,name=vactivation.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Activation{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Activation) ValidateCreate() error {
+	activationlog.Info("validate create", "name", r.Name)
+
+	return r.validateActivation()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Activation) ValidateUpdate(old runtime.Object) error {
+	activationlog.Info("validate update", "name", r.Name)
+
+	// updates that don't change the spec, such as finalizer removals, are allowed even if the
+	// campaign has been deleted since
+	if oldActivation, ok := old.(*Activation); ok && reflect.DeepEqual(oldActivation.Spec, r.Spec) {
+		return nil
+	}
+	return r.validateActivation()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Activation) ValidateDelete() error {
+	activationlog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+// validateActivation checks that the campaign exists in the activation's namespace, and has the stage
+// the activation starts from
+func (r *Activation) validateActivation() error {
+	if r.Spec.Campaign == "" {
+		return v1alpha2.NewCOAError(nil, "activation has no campaign", v1alpha2.ValidateFailed)
+	}
+	campaign := &Campaign{}
+	err := myActivationClient.Get(context.Background(), types.NamespacedName{Name: r.Spec.Campaign, Namespace: r.Namespace}, campaign)
+	if apierrors.IsNotFound(err) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("campaign '%s' is not found", r.Spec.Campaign), v1alpha2.ValidateFailed)
+	}
+	if err != nil {
+		return err
+	}
+	if r.Spec.Stage != "" {
+		if _, ok := campaign.Spec.Stages[r.Spec.Stage]; !ok {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("stage '%s' is not found in campaign '%s'", r.Spec.Stage, r.Spec.Campaign), v1alpha2.ValidateFailed)
+		}
+	}
+	return nil
+}
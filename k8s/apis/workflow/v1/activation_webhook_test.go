@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"testing"
+
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newActivation(name string, campaign string, stage string) *Activation {
+	return &Activation{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: k8smodel.ActivationSpec{
+			Campaign: campaign,
+			Stage:    stage,
+		},
+	}
+}
+
+var _ = Describe("Activation webhook", func() {
+	BeforeEach(func() {
+		campaign := newCampaign("activation-campaign", "deploy", map[string]k8smodel.StageSpec{
+			"deploy": {Name: "deploy", Provider: "providers.stage.mock"},
+		})
+		err := k8sClient.Create(ctx, campaign)
+		if err != nil {
+			Expect(err.Error()).To(ContainSubstring("already exists"))
+		}
+	})
+
+	It("accepts an activation of an existing campaign and stage", func() {
+		// the webhook reads campaigns from the manager's cache, which may lag behind
+		Eventually(func() error {
+			return k8sClient.Create(ctx, newActivation("valid-activation", "activation-campaign", "deploy"))
+		}).Should(Succeed())
+	})
+
+	It("rejects an activation of a missing campaign", func() {
+		err := k8sClient.Create(ctx, newActivation("missing-campaign", "other-campaign", ""))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("campaign 'other-campaign' is not found"))
+	})
+
+	It("rejects an activation of a missing stage", func() {
+		Eventually(func() string {
+			err := k8sClient.Create(ctx, newActivation("missing-stage", "activation-campaign", "verify"))
+			if err == nil {
+				return ""
+			}
+			return err.Error()
+		}).Should(ContainSubstring("stage 'verify' is not found in campaign 'activation-campaign'"))
+	})
+})
+
+func TestValidateActivation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, AddToScheme(scheme))
+	myActivationClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newCampaign("campaign", "deploy", map[string]k8smodel.StageSpec{
+			"deploy": {Name: "deploy", Provider: "providers.stage.mock"},
+		}),
+	).Build()
+
+	assert.Nil(t, newActivation("activation", "campaign", "").ValidateCreate())
+	assert.Nil(t, newActivation("activation", "campaign", "deploy").ValidateCreate())
+
+	err := newActivation("activation", "other", "").ValidateCreate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "campaign 'other' is not found", err.Error())
+
+	err = newActivation("activation", "campaign", "verify").ValidateCreate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "stage 'verify' is not found in campaign 'campaign'", err.Error())
+
+	err = newActivation("activation", "", "").ValidateCreate()
+	assert.NotNil(t, err)
+
+	// updates that don't change the spec are allowed after the campaign is deleted
+	activation := newActivation("activation", "other", "")
+	assert.Nil(t, activation.ValidateUpdate(activation.DeepCopy()))
+}
@@ -13,18 +13,25 @@ import (
 
 // CampaignStatus defines the observed state of Campaign
 type CampaignStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Conditions report Ready, Reconciling, Degraded and Stalled
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation last processed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 
 // Campaign is the Schema for the campaigns API
 type Campaign struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec k8smodel.CampaignSpec `json:"spec,omitempty"`
+	Spec   k8smodel.CampaignSpec `json:"spec,omitempty"`
+	Status CampaignStatus        `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
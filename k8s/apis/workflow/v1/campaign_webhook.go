@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var campaignlog = logf.Log.WithName("campaign-resource")
+
+func (r *Campaign) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-workflow-symphony-v1-campaign,mutating=false,failurePolicy=fail,sideEffects=None,groups=workflow.symphony,resources=campaigns,verbs=create;update,versions=v1,name=vcampaign.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Campaign{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Campaign) ValidateCreate() error {
+	campaignlog.Info("validate create", "name", r.Name)
+
+	return r.validateCampaign()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Campaign) ValidateUpdate(old runtime.Object) error {
+	campaignlog.Info("validate update", "name", r.Name)
+
+	if oldCampaign, ok := old.(*Campaign); ok && reflect.DeepEqual(oldCampaign.Spec, r.Spec) {
+		return nil
+	}
+	return r.validateCampaign()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Campaign) ValidateDelete() error {
+	campaignlog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+// validateCampaign checks that the first stage and the stages that stage selectors name exist, and that
+// stage providers are known. Stage selectors that are expressions are only evaluated when the campaign
+// runs, so they aren't checked.
+func (r *Campaign) validateCampaign() error {
+	errors := make([]string, 0)
+	if r.Spec.FirstStage != "" {
+		if _, ok := r.Spec.Stages[r.Spec.FirstStage]; !ok {
+			errors = append(errors, fmt.Sprintf("first stage '%s' is not found", r.Spec.FirstStage))
+		}
+	}
+	names := make([]string, 0, len(r.Spec.Stages))
+	for name := range r.Spec.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stage := r.Spec.Stages[name]
+		if !isStageProvider(stage.Provider) {
+			errors = append(errors, fmt.Sprintf("stage '%s' has unknown provider '%s'", name, stage.Provider))
+		}
+		if stage.StageSelector != "" && !strings.Contains(stage.StageSelector, "$") {
+			if _, ok := r.Spec.Stages[stage.StageSelector]; !ok {
+				errors = append(errors, fmt.Sprintf("stage '%s' selects stage '%s', which is not found", name, stage.StageSelector))
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid campaign: %s", strings.Join(errors, "; ")), v1alpha2.ValidateFailed)
+	}
+	return nil
+}
+
+// isStageProvider tells whether the Symphony API can create a stage provider of a type
+func isStageProvider(providerType string) bool {
+	for _, t := range stage.ProviderTypes {
+		if t == providerType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"testing"
+
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCampaign(name string, firstStage string, stages map[string]k8smodel.StageSpec) *Campaign {
+	return &Campaign{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: k8smodel.CampaignSpec{
+			FirstStage: firstStage,
+			Stages:     stages,
+		},
+	}
+}
+
+var _ = Describe("Campaign webhook", func() {
+	It("accepts a campaign whose stages exist", func() {
+		campaign := newCampaign("valid-campaign", "deploy", map[string]k8smodel.StageSpec{
+			"deploy": {Name: "deploy", Provider: "providers.stage.patch", StageSelector: "wait"},
+			"wait":   {Name: "wait", Provider: "providers.stage.wait", StageSelector: "${{$if($output(wait, status) == 200, '', 'wait')}}"},
+		})
+		Expect(k8sClient.Create(ctx, campaign)).To(Succeed())
+	})
+
+	It("rejects a campaign whose first stage is missing", func() {
+		campaign := newCampaign("missing-first-stage", "deploy", map[string]k8smodel.StageSpec{
+			"wait": {Name: "wait", Provider: "providers.stage.wait"},
+		})
+		err := k8sClient.Create(ctx, campaign)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("first stage 'deploy' is not found"))
+	})
+
+	It("rejects a campaign whose stage selector names a missing stage", func() {
+		campaign := newCampaign("missing-selected-stage", "deploy", map[string]k8smodel.StageSpec{
+			"deploy": {Name: "deploy", Provider: "providers.stage.patch", StageSelector: "verify"},
+		})
+		err := k8sClient.Create(ctx, campaign)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("stage 'deploy' selects stage 'verify', which is not found"))
+	})
+
+	It("rejects a campaign with an unknown stage provider", func() {
+		campaign := newCampaign("unknown-provider", "deploy", map[string]k8smodel.StageSpec{
+			"deploy": {Name: "deploy", Provider: "providers.stage.unknown"},
+		})
+		err := k8sClient.Create(ctx, campaign)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("stage 'deploy' has unknown provider 'providers.stage.unknown'"))
+	})
+})
+
+func TestValidateCampaign(t *testing.T) {
+	campaign := newCampaign("campaign", "deploy", map[string]k8smodel.StageSpec{
+		"deploy": {Name: "deploy", Provider: "providers.stage.patch", StageSelector: "wait"},
+		"wait":   {Name: "wait", Provider: "providers.stage.wait", StageSelector: "${{$output(wait, next)}}"},
+	})
+	assert.Nil(t, campaign.ValidateCreate())
+
+	campaign = newCampaign("campaign", "start", map[string]k8smodel.StageSpec{
+		"deploy": {Name: "deploy", Provider: "providers.stage.patch", StageSelector: "verify"},
+		"wait":   {Name: "wait", Provider: "providers.stage.unknown"},
+	})
+	err := campaign.ValidateCreate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "invalid campaign: first stage 'start' is not found; stage 'deploy' selects stage 'verify', which is not found; stage 'wait' has unknown provider 'providers.stage.unknown'", err.Error())
+
+	// updates that don't change the spec are allowed
+	assert.Nil(t, campaign.ValidateUpdate(campaign.DeepCopy()))
+}
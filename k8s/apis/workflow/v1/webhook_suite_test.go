@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"gopls-workspace/utils/webhooktest"
+)
+
+// These tests use Ginkgo (BDD-style Go testing framework). Refer to
+// http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+
+var k8sClient client.Client
+var testEnv *webhooktest.Environment
+var ctx = context.Background()
+
+// TestAPIs runs the webhook specs against a local API server, see webhooktest
+func TestAPIs(t *testing.T) {
+	if !webhooktest.Available() {
+		t.Skip("Skipping webhook tests as KUBEBUILDER_ASSETS isn't set")
+	}
+	RegisterFailHandler(Fail)
+
+	RunSpecsWithDefaultAndCustomReporters(t,
+		"Webhook Suite",
+		[]Reporter{printer.NewlineReporter{}})
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	By("bootstrapping test environment")
+	var err error
+	testEnv, err = webhooktest.Start(AddToScheme, func(mgr ctrl.Manager) error {
+		if err := (&Campaign{}).SetupWebhookWithManager(mgr); err != nil {
+			return err
+		}
+		return (&Activation{}).SetupWebhookWithManager(mgr)
+	})
+	Expect(err).NotTo(HaveOccurred())
+	k8sClient = testEnv.Client
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	err := testEnv.Stop()
+	Expect(err).NotTo(HaveOccurred())
+})
@@ -10,6 +10,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	configv1 "gopls-workspace/apis/config/v1"
 
@@ -45,7 +46,7 @@ func GetValidationPoilicies() (map[string][]configv1.ValidationPolicy, error) {
 		return nil, err
 	}
 
-	namespace, err := getNamespace()
+	namespace, err := GetNamespace()
 	if err != nil {
 		return nil, err
 	}
@@ -64,13 +65,15 @@ func GetValidationPoilicies() (map[string][]configv1.ValidationPolicy, error) {
 
 	return myConfig.ValidationPolicies, nil
 }
-func getNamespace() (string, error) {
+
+// GetNamespace returns the namespace the controller manager runs in
+func GetNamespace() (string, error) {
 	// read the namespace from the file
 	data, err := ioutil.ReadFile(namespaceFile)
 	if err != nil {
 		return "", err
 	}
-	return string(data), nil
+	return strings.TrimSpace(string(data)), nil
 }
 
 func CheckValidationPack(myName string, myValue, validationType string, pack []configv1.ValidationStruct) (string, error) {
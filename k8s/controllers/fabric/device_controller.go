@@ -9,18 +9,23 @@ package fabric
 import (
 	"context"
 
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	fabricv1 "gopls-workspace/apis/fabric/v1"
+	"gopls-workspace/utils"
 )
 
 // DeviceReconciler reconciles a Device object
 type DeviceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=fabric.symphony,resources=devices,verbs=get;list;watch;create;update;patch;delete
@@ -39,9 +44,16 @@ type DeviceReconciler struct {
 func (r *DeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
-	// TODO(user): your logic here
+	device := &fabricv1.Device{}
+	if err := r.Get(ctx, req.NamespacedName, device); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !device.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, device, &device.Status.Conditions, &device.Status.ObservedGeneration,
+		utils.StateReady, k8smodel.ReasonAccepted, "Device is available to targets")
 }
 
 // SetupWithManager sets up the controller with the Manager.
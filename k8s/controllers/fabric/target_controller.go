@@ -23,19 +23,38 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // TargetReconciler reconciles a Target object
 type TargetReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	ApiClient *api_utils.SymphonyAPIClient
+	Recorder  record.EventRecorder
+	// ReconciliationInterval is how often the target is re-deployed to correct drift
+	ReconciliationInterval time.Duration
+	// ReconciliationJitter spreads re-deployments by up to this fraction of the interval
+	ReconciliationJitter float64
+	// DeletionPollInterval is how often the removal of a deleted target is checked
+	DeletionPollInterval time.Duration
+	// DeletionTimeout is how long to wait for the removal before the finalizer is dropped anyway
+	DeletionTimeout time.Duration
+	// Summaries are events for targets with a new deployment summary, pushed by the Symphony API. When
+	// nil, the status is only updated when the target is reconciled.
+	Summaries <-chan event.GenericEvent
 }
 
 //+kubebuilder:rbac:groups=fabric.symphony,resources=targets,verbs=get;list;watch;create;update;patch;delete
@@ -77,7 +96,7 @@ func (r *TargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}
 		}
 
-		summary, err := api_utils.GetSummary(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", fmt.Sprintf("target-runtime-%s", target.ObjectMeta.Name), target.ObjectMeta.Namespace)
+		summary, err := r.ApiClient.GetSummary(ctx, fmt.Sprintf("target-runtime-%s", target.ObjectMeta.Name), target.ObjectMeta.Namespace)
 		if err != nil && !v1alpha2.IsNotFound(err) {
 			uErr := r.updateTargetStatusToReconciling(target, err)
 			if uErr != nil {
@@ -91,15 +110,15 @@ func (r *TargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			generationMatch = v == target.GetGeneration()
 		}
 
-		if generationMatch && time.Since(summary.Time) <= time.Duration(60)*time.Second { //TODO: this is 60 second interval. Make if configurable?
+		if generationMatch && time.Since(summary.Time) <= r.ReconciliationInterval {
 			err = r.updateTargetStatus(target, summary.Summary)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
-			return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: utils.DriftRequeueAfter(summary.Time, r.ReconciliationInterval, r.ReconciliationJitter)}, nil
 		} else {
-			// Queue a job every 60s or when the generation is changed
-			err = api_utils.QueueJob(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", target.ObjectMeta.Name, target.ObjectMeta.Namespace, false, true)
+			// Queue a job every reconciliation interval or when the generation is changed
+			err = r.ApiClient.QueueJob(ctx, target.ObjectMeta.Name, target.ObjectMeta.Namespace, false, true)
 			if err != nil {
 				uErr := r.updateTargetStatusToReconciling(target, err)
 				if uErr != nil {
@@ -110,7 +129,7 @@ func (r *TargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 			// Update status to Reconciling if there is a change on generation
 			// If users uninstall a component manually without modifying manifest
-			// files, jobs queued every 60s will catch the descrepdency and
+			// files, jobs queued every reconciliation interval will catch the descrepdency and
 			// re-deploy the uninstalled component. As users' behavior doesn't
 			// trigger generation change, this behavior won't change the status
 			// to reconciling.
@@ -121,12 +140,12 @@ func (r *TargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				}
 			}
 
-			return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: utils.DriftRequeueAfter(time.Now(), r.ReconciliationInterval, r.ReconciliationJitter)}, nil
 		}
 
 	} else { // remove
 		if controllerutil.ContainsFinalizer(target, myFinalizerName) {
-			err := api_utils.QueueJob(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", target.ObjectMeta.Name, target.ObjectMeta.Namespace, true, true)
+			err := r.ApiClient.QueueJob(ctx, target.ObjectMeta.Name, target.ObjectMeta.Namespace, true, true)
 
 			if err != nil {
 				uErr := r.updateTargetStatusToReconciling(target, err)
@@ -135,16 +154,17 @@ func (r *TargetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				}
 				return ctrl.Result{}, err
 			}
-			timeout := time.After(5 * time.Minute)
-			ticker := time.Tick(10 * time.Second) //TODO: configurable? adjust based on provider SLA?
+			timeout := time.After(r.DeletionTimeout)
+			ticker := time.NewTicker(r.DeletionPollInterval)
+			defer ticker.Stop()
 		loop:
 			for {
 				select {
 				case <-timeout:
 					// Timeout exceeded, assume deletion failed and proceed with finalization
 					break loop
-				case <-ticker:
-					summary, err := api_utils.GetSummary(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", fmt.Sprintf("target-runtime-%s", target.ObjectMeta.Name), target.ObjectMeta.Namespace)
+				case <-ticker.C:
+					summary, err := r.ApiClient.GetSummary(ctx, fmt.Sprintf("target-runtime-%s", target.ObjectMeta.Name), target.ObjectMeta.Namespace)
 					if err == nil && summary.Summary.IsRemoval == true && summary.Summary.SuccessCount == summary.Summary.TargetCount {
 						break loop
 					}
@@ -177,6 +197,10 @@ func (r *TargetReconciler) updateTargetStatusToReconciling(target *symphonyv1.Ta
 	target.Status.Properties["status-details"] = ""
 	if err != nil {
 		target.Status.Properties["status-details"] = fmt.Sprintf("Reconciling due to %s", err.Error())
+		state, reason := utils.ErrorState(err)
+		r.updateConditions(target, state, reason, fmt.Sprintf("Reconciling due to %s", err.Error()))
+	} else {
+		r.updateConditions(target, utils.StateReconciling, k8smodel.ReasonDeploying, fmt.Sprintf("Deploying generation %d", target.GetGeneration()))
 	}
 	r.updateProvisioningStatusToReconciling(target, err)
 	target.Status.LastModified = metav1.Now()
@@ -214,19 +238,37 @@ func (r *TargetReconciler) updateTargetStatus(target *symphonyv1.Target, summary
 		}
 	}
 
+	target.Status.Deployment = utils.DeploymentStatusFromSummary(target.GetGeneration(), summary)
+	if status == provisioningstates.Succeeded {
+		r.updateConditions(target, utils.StateReady, k8smodel.ReasonSucceeded, fmt.Sprintf("Deployed to %s of %s targets", successCount, targetCount))
+	} else {
+		r.updateConditions(target, utils.StateDegraded, k8smodel.ReasonDeploymentFailed, fmt.Sprintf("Deployed to %s of %s targets: %s", successCount, targetCount, summary.SummaryMessage))
+	}
+
 	r.updateProvisioningStatus(target, status, summary)
 	target.Status.LastModified = metav1.Now()
 	return r.Status().Update(context.Background(), target)
 }
 
+// updateConditions sets the target's conditions, and records an event when they changed
+func (r *TargetReconciler) updateConditions(target *symphonyv1.Target, state utils.ConditionState, reason string, message string) {
+	target.Status.ObservedGeneration = target.GetGeneration()
+	if utils.SetConditions(&target.Status.Conditions, target.GetGeneration(), state, reason, message) {
+		utils.RecordStateEvent(r.Recorder, target, state, message)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *TargetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	genChangePredicate := predicate.GenerationChangedPredicate{}
 	annotationPredicate := predicate.AnnotationChangedPredicate{}
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		WithEventFilter(predicate.Or(genChangePredicate, annotationPredicate)).
-		For(&symphonyv1.Target{}).
-		Complete(r)
+		For(&symphonyv1.Target{})
+	if r.Summaries != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Summaries}, &handler.EnqueueRequestForObject{})
+	}
+	return builder.Complete(r)
 }
 
 func (r *TargetReconciler) ensureOperationState(target *symphonyv1.Target, provisioningState string) {
@@ -9,13 +9,17 @@ package federation
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	federationv1 "gopls-workspace/apis/federation/v1"
+	"gopls-workspace/utils"
 
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 )
@@ -24,6 +28,9 @@ import (
 type CatalogReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	ApiClient *api_utils.SymphonyAPIClient
+	Recorder  record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=federation.symphony,resources=catalogs,verbs=get;list;watch;create;update;patch;delete
@@ -47,12 +54,23 @@ func (r *CatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if catalog.Status.Properties == nil {
+		catalog.Status.Properties = make(map[string]string)
+	}
+
 	if catalog.ObjectMeta.DeletionTimestamp.IsZero() { // update
 		jData, _ := json.Marshal(catalog.Spec)
-		err := api_utils.CatalogHook(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", jData)
+		err := r.ApiClient.CatalogHook(ctx, jData)
 		if err != nil {
+			state, reason := utils.ErrorState(err)
+			if uErr := utils.UpdateConditions(ctx, r.Client, r.Recorder, catalog, &catalog.Status.Conditions, &catalog.Status.ObservedGeneration,
+				state, reason, fmt.Sprintf("Failed to publish catalog: %s", err.Error())); uErr != nil {
+				return ctrl.Result{}, uErr
+			}
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, catalog, &catalog.Status.Conditions, &catalog.Status.ObservedGeneration,
+			utils.StateReady, k8smodel.ReasonAccepted, "Catalog is published to Symphony")
 	}
 
 	return ctrl.Result{}, nil
@@ -9,18 +9,23 @@ package federation
 import (
 	"context"
 
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	federationv1 "gopls-workspace/apis/federation/v1"
+	"gopls-workspace/utils"
 )
 
 // SiteReconciler reconciles a Site object
 type SiteReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=federation.symphony,resources=sites,verbs=get;list;watch;create;update;patch;delete
@@ -39,9 +44,21 @@ type SiteReconciler struct {
 func (r *SiteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
-	// TODO(user): your logic here
+	site := &federationv1.Site{}
+	if err := r.Get(ctx, req.NamespacedName, site); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !site.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	// sites report their own status, the conditions reflect whether the site is online
+	if !site.Status.IsOnline {
+		return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, site, &site.Status.Conditions, &site.Status.ObservedGeneration,
+			utils.StateDegraded, k8smodel.ReasonOffline, "Site is offline")
+	}
+	return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, site, &site.Status.Conditions, &site.Status.ObservedGeneration,
+		utils.StateReady, k8smodel.ReasonAccepted, "Site is online")
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"time"
 
+	fabricv1 "gopls-workspace/apis/fabric/v1"
 	symphonyv1 "gopls-workspace/apis/solution/v1"
 
 	"gopls-workspace/constants"
@@ -20,12 +21,16 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	apimodel "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	provisioningstates "github.com/eclipse-symphony/symphony/k8s/utils/models"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -41,6 +46,20 @@ import (
 type InstanceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	ApiClient *api_utils.SymphonyAPIClient
+	Recorder  record.EventRecorder
+	// ReconciliationInterval is how often the instance is re-deployed to correct drift
+	ReconciliationInterval time.Duration
+	// ReconciliationJitter spreads re-deployments by up to this fraction of the interval
+	ReconciliationJitter float64
+	// DeletionPollInterval is how often the removal of a deleted instance is checked
+	DeletionPollInterval time.Duration
+	// DeletionTimeout is how long to wait for the removal before the finalizer is dropped anyway
+	DeletionTimeout time.Duration
+	// Summaries are events for instances with a new deployment summary, pushed by the Symphony API.
+	// When nil, the status is only updated when the instance is reconciled.
+	Summaries <-chan event.GenericEvent
 }
 
 //+kubebuilder:rbac:groups=solution.symphony,resources=instances,verbs=get;list;watch;create;update;patch;delete
@@ -81,7 +100,7 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			}
 		}
 
-		summary, err := api_utils.GetSummary(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", instance.ObjectMeta.Name, instance.ObjectMeta.Namespace)
+		summary, err := r.ApiClient.GetSummary(ctx, instance.ObjectMeta.Name, instance.ObjectMeta.Namespace)
 		if err != nil && !v1alpha2.IsNotFound(err) {
 			uErr := r.updateInstanceStatusToReconciling(instance, err)
 			if uErr != nil {
@@ -95,15 +114,23 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			generationMatch = v == instance.GetGeneration()
 		}
 
-		if generationMatch && time.Since(summary.Time) <= time.Duration(60)*time.Second { //TODO: this is 60 second interval. Make if configurable?
+		// A change to the solution or the targets doesn't change the instance's generation, so the
+		// generations they were deployed with are compared as well
+		dependencies, err := r.getDependencies(ctx, instance)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		dependenciesMatch := dependenciesEqual(dependencies, instance.Status.Dependencies)
+
+		if generationMatch && dependenciesMatch && time.Since(summary.Time) <= r.ReconciliationInterval {
 			err = r.updateInstanceStatus(instance, summary.Summary)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
-			return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: utils.DriftRequeueAfter(summary.Time, r.ReconciliationInterval, r.ReconciliationJitter)}, nil
 		} else {
-			// Queue a job every 60s or when the generation is changed
-			err = api_utils.QueueJob(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", instance.ObjectMeta.Name, instance.ObjectMeta.Namespace, false, false)
+			// Queue a job every reconciliation interval, or when the generation or a dependency is changed
+			err = r.ApiClient.QueueJob(ctx, instance.ObjectMeta.Name, instance.ObjectMeta.Namespace, false, false)
 			if err != nil {
 				uErr := r.updateInstanceStatusToReconciling(instance, err)
 				if uErr != nil {
@@ -111,25 +138,26 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 				}
 				return ctrl.Result{}, err
 			}
+			instance.Status.Dependencies = dependencies
 
 			// Update status to Reconciling if there is a change on generation
 			// If users uninstall a component manually without modifying manifest
-			// files, jobs queued every 60s will catch the descrepdency and
+			// files, jobs queued every reconciliation interval will catch the descrepdency and
 			// re-deploy the uninstalled component. As users' behavior doesn't
 			// trigger generation change, this behavior won't change the status
 			// to reconciling.
-			if !generationMatch {
+			if !generationMatch || !dependenciesMatch {
 				err = r.updateInstanceStatusToReconciling(instance, nil)
 				if err != nil {
 					return ctrl.Result{}, err
 				}
 			}
 
-			return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: utils.DriftRequeueAfter(time.Now(), r.ReconciliationInterval, r.ReconciliationJitter)}, nil
 		}
 	} else { // delete
 		if controllerutil.ContainsFinalizer(instance, myFinalizerName) {
-			err := api_utils.QueueJob(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", instance.ObjectMeta.Name, instance.ObjectMeta.Namespace, true, false)
+			err := r.ApiClient.QueueJob(ctx, instance.ObjectMeta.Name, instance.ObjectMeta.Namespace, true, false)
 
 			if err != nil {
 				uErr := r.updateInstanceStatusToReconciling(instance, err)
@@ -138,16 +166,17 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 				}
 				return ctrl.Result{}, err
 			}
-			timeout := time.After(5 * time.Minute)
-			ticker := time.Tick(10 * time.Second) //TODO: configurable? adjust based on provider SLA?
+			timeout := time.After(r.DeletionTimeout)
+			ticker := time.NewTicker(r.DeletionPollInterval)
+			defer ticker.Stop()
 		loop:
 			for {
 				select {
 				case <-timeout:
 					// Timeout exceeded, assume deletion failed and proceed with finalization
 					break loop
-				case <-ticker:
-					summary, err := api_utils.GetSummary(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", instance.ObjectMeta.Name, instance.ObjectMeta.Namespace)
+				case <-ticker.C:
+					summary, err := r.ApiClient.GetSummary(ctx, instance.ObjectMeta.Name, instance.ObjectMeta.Namespace)
 					if err == nil && summary.Summary.IsRemoval == true && summary.Summary.SuccessCount == summary.Summary.TargetCount {
 						break loop
 					}
@@ -168,6 +197,46 @@ func (r *InstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	return ctrl.Result{}, nil
 }
 
+// getDependencies gets the generations of the solution and the targets the instance is deployed to
+func (r *InstanceReconciler) getDependencies(ctx context.Context, instance *symphonyv1.Instance) (map[string]int64, error) {
+	ret := make(map[string]int64)
+	solution := &symphonyv1.Solution{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: instance.Spec.Solution, Namespace: instance.Namespace}, solution)
+	if err == nil {
+		ret["solution/"+solution.Name] = solution.GetGeneration()
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	var targets fabricv1.TargetList
+	if err := r.Client.List(ctx, &targets, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, target := range targets.Items {
+		if matchTarget(instance, &target) {
+			ret["target/"+target.Name] = target.GetGeneration()
+		}
+	}
+	return ret, nil
+}
+
+func matchTarget(instance *symphonyv1.Instance, target *fabricv1.Target) bool {
+	return api_utils.MatchTarget(
+		model.InstanceState{Id: instance.Name, Spec: &instance.Spec},
+		model.TargetState{Id: target.Name, Spec: &model.TargetSpec{Properties: target.Spec.Properties}})
+}
+
+func dependenciesEqual(a map[string]int64, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *InstanceReconciler) ensureOperationState(instance *symphonyv1.Instance, provisioningState string) {
 	instance.Status.ProvisioningStatus.Status = provisioningState
 	instance.Status.ProvisioningStatus.OperationID = instance.ObjectMeta.Annotations[constants.AzureOperationKey]
@@ -184,6 +253,10 @@ func (r *InstanceReconciler) updateInstanceStatusToReconciling(instance *symphon
 	instance.Status.Properties["status-details"] = ""
 	if err != nil {
 		instance.Status.Properties["status-details"] = fmt.Sprintf("Reconciling due to %s", err.Error())
+		state, reason := utils.ErrorState(err)
+		r.updateConditions(instance, state, reason, fmt.Sprintf("Reconciling due to %s", err.Error()))
+	} else {
+		r.updateConditions(instance, utils.StateReconciling, k8smodel.ReasonDeploying, fmt.Sprintf("Deploying generation %d", instance.GetGeneration()))
 	}
 	r.updateProvisioningStatusToReconciling(instance, err)
 	instance.Status.LastModified = metav1.Now()
@@ -221,11 +294,26 @@ func (r *InstanceReconciler) updateInstanceStatus(instance *symphonyv1.Instance,
 		}
 	}
 
+	instance.Status.Deployment = utils.DeploymentStatusFromSummary(instance.GetGeneration(), summary)
+	if status == provisioningstates.Succeeded {
+		r.updateConditions(instance, utils.StateReady, k8smodel.ReasonSucceeded, fmt.Sprintf("Deployed to %s of %s targets", successCount, targetCount))
+	} else {
+		r.updateConditions(instance, utils.StateDegraded, k8smodel.ReasonDeploymentFailed, fmt.Sprintf("Deployed to %s of %s targets: %s", successCount, targetCount, summary.SummaryMessage))
+	}
+
 	r.updateProvisioningStatus(instance, status, summary)
 	instance.Status.LastModified = metav1.Now()
 	return r.Client.Status().Update(context.Background(), instance)
 }
 
+// updateConditions sets the instance's conditions, and records an event when they changed
+func (r *InstanceReconciler) updateConditions(instance *symphonyv1.Instance, state utils.ConditionState, reason string, message string) {
+	instance.Status.ObservedGeneration = instance.GetGeneration()
+	if utils.SetConditions(&instance.Status.Conditions, instance.GetGeneration(), state, reason, message) {
+		utils.RecordStateEvent(r.Recorder, instance, state, message)
+	}
+}
+
 func (r *InstanceReconciler) updateProvisioningStatus(instance *symphonyv1.Instance, provisioningStatus string, summary model.SummarySpec) {
 	r.ensureOperationState(instance, provisioningStatus)
 	// Start with a clean Error object and update all the fields
@@ -283,7 +371,7 @@ func (r *InstanceReconciler) updateProvisioningStatusToReconciling(instance *sym
 func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	generationChange := predicate.GenerationChangedPredicate{}
 	annotationChange := predicate.AnnotationChangedPredicate{}
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&symphonyv1.Instance{}).
 		WithEventFilter(predicate.Or(generationChange, annotationChange)).
 		Watches(&source.Kind{Type: &symphonyv1.Solution{}}, handler.EnqueueRequestsFromMapFunc(
@@ -311,5 +399,34 @@ func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				}
 				return ret
 			})).
-		Complete(r)
+		Watches(&source.Kind{Type: &fabricv1.Target{}}, handler.EnqueueRequestsFromMapFunc(
+			func(obj client.Object) []ctrl.Request {
+				ret := make([]ctrl.Request, 0)
+				targetObj := obj.(*fabricv1.Target)
+				var instances symphonyv1.InstanceList
+				error := mgr.GetClient().List(context.Background(), &instances, client.InNamespace(targetObj.Namespace))
+				if error != nil {
+					log.Log.Error(error, "Failed to list instances")
+					return ret
+				}
+
+				// Instances that were deployed to the target are enqueued as well, so that they're
+				// re-deployed when the target no longer matches
+				key := "target/" + targetObj.Name
+				for _, instance := range instances.Items {
+					if _, ok := instance.Status.Dependencies[key]; ok || matchTarget(&instance, targetObj) {
+						ret = append(ret, ctrl.Request{
+							NamespacedName: types.NamespacedName{
+								Name:      instance.Name,
+								Namespace: instance.Namespace,
+							},
+						})
+					}
+				}
+				return ret
+			}))
+	if r.Summaries != nil {
+		builder = builder.Watches(&source.Channel{Source: r.Summaries}, &handler.EnqueueRequestForObject{})
+	}
+	return builder.Complete(r)
 }
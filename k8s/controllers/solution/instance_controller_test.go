@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package solution
+
+import (
+	"context"
+	"testing"
+
+	fabricv1 "gopls-workspace/apis/fabric/v1"
+	solutionv1 "gopls-workspace/apis/solution/v1"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetDependencies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, solutionv1.AddToScheme(scheme))
+	assert.Nil(t, fabricv1.AddToScheme(scheme))
+
+	instance := &solutionv1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance1", Namespace: "default"},
+		Spec: model.InstanceSpec{
+			Solution: "solution1",
+			Target: model.TargetSelector{
+				Selector: map[string]string{"group": "edge"},
+			},
+		},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		instance,
+		&solutionv1.Solution{ObjectMeta: metav1.ObjectMeta{Name: "solution1", Namespace: "default", Generation: 3}},
+		&fabricv1.Target{
+			ObjectMeta: metav1.ObjectMeta{Name: "target1", Namespace: "default", Generation: 2},
+			Spec:       k8smodel.TargetSpec{Properties: map[string]string{"group": "edge"}},
+		},
+		&fabricv1.Target{
+			ObjectMeta: metav1.ObjectMeta{Name: "target2", Namespace: "default", Generation: 5},
+			Spec:       k8smodel.TargetSpec{Properties: map[string]string{"group": "cloud"}},
+		},
+		&fabricv1.Target{
+			ObjectMeta: metav1.ObjectMeta{Name: "target3", Namespace: "other", Generation: 1},
+			Spec:       k8smodel.TargetSpec{Properties: map[string]string{"group": "edge"}},
+		},
+	).Build()
+
+	r := &InstanceReconciler{Client: client, Scheme: scheme}
+	dependencies, err := r.getDependencies(context.Background(), instance)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"solution/solution1": 3, "target/target1": 2}, dependencies)
+
+	assert.True(t, dependenciesEqual(dependencies, map[string]int64{"target/target1": 2, "solution/solution1": 3}))
+	assert.False(t, dependenciesEqual(dependencies, map[string]int64{"target/target1": 3, "solution/solution1": 3}))
+	assert.False(t, dependenciesEqual(dependencies, nil))
+	assert.True(t, dependenciesEqual(map[string]int64{}, nil))
+}
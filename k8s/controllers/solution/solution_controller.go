@@ -9,18 +9,23 @@ package solution
 import (
 	"context"
 
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	solutionv1 "gopls-workspace/apis/solution/v1"
+	"gopls-workspace/utils"
 )
 
 // SolutionReconciler reconciles a Solution object
 type SolutionReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=solution.symphony,resources=solutions,verbs=get;list;watch;create;update;patch;delete
@@ -39,9 +44,16 @@ type SolutionReconciler struct {
 func (r *SolutionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
-	// TODO(user): your logic here
+	solution := &solutionv1.Solution{}
+	if err := r.Get(ctx, req.NamespacedName, solution); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !solution.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, solution, &solution.Status.Conditions, &solution.Status.ObservedGeneration,
+		utils.StateReady, k8smodel.ReasonAccepted, "Solution is available to instances")
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -13,11 +13,16 @@ import (
 	"strconv"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	workflowv1 "gopls-workspace/apis/workflow/v1"
+	"gopls-workspace/utils"
 
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -27,6 +32,9 @@ import (
 type ActivationReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	ApiClient *api_utils.SymphonyAPIClient
+	Recorder  record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=workflow.symphony,resources=activations,verbs=get;list;watch;create;update;patch;delete
@@ -52,14 +60,14 @@ func (r *ActivationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	if strconv.FormatInt(activation.Generation, 10) == activation.Status.ActivationGeneration {
+	if !activation.ObjectMeta.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
 
-	if activation.ObjectMeta.DeletionTimestamp.IsZero() {
+	if strconv.FormatInt(activation.Generation, 10) != activation.Status.ActivationGeneration {
 		log.Info(fmt.Sprintf("Activation status: %v", activation.Status.Status))
-		if !activation.Status.IsActive && activation.Status.Status != v1alpha2.Paused && activation.Status.Status != v1alpha2.Done && activation.Status.ActivationGeneration == "" {
-			err := api_utils.PublishActivationEvent(ctx, "http://symphony-service:8080/v1alpha2/", "admin", "", v1alpha2.ActivationData{
+		if !activation.Status.IsActive && activation.Status.Status != v1alpha2.Paused && activation.Status.Status != v1alpha2.Done && activation.Status.ActivationGeneration == "" && !isStarted(activation) {
+			err := r.ApiClient.PublishActivationEvent(ctx, v1alpha2.ActivationData{
 				Campaign:             activation.Spec.Campaign,
 				Activation:           activation.Name,
 				ActivationGeneration: strconv.FormatInt(activation.Generation, 10),
@@ -67,12 +75,48 @@ func (r *ActivationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				Inputs:               convertRawExtensionToMap(&activation.Spec.Inputs),
 			})
 			if err != nil {
+				state, reason := utils.ErrorState(err)
+				if uErr := r.updateConditions(ctx, activation, state, reason, fmt.Sprintf("Failed to start campaign %s: %s", activation.Spec.Campaign, err.Error())); uErr != nil {
+					return ctrl.Result{}, uErr
+				}
 				return ctrl.Result{}, err
 			}
+			return ctrl.Result{}, r.updateConditions(ctx, activation, utils.StateReconciling, k8smodel.ReasonRunning, fmt.Sprintf("Started campaign %s", activation.Spec.Campaign))
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// the stage manager reports progress through the status, conditions mirror it
+	state, reason, message := activationState(activation)
+	return ctrl.Result{}, r.updateConditions(ctx, activation, state, reason, message)
+}
+
+func (r *ActivationReconciler) updateConditions(ctx context.Context, activation *workflowv1.Activation, state utils.ConditionState, reason string, message string) error {
+	return utils.UpdateConditions(ctx, r.Client, r.Recorder, activation, &activation.Status.Conditions, &activation.Status.ObservedGeneration, state, reason, message)
+}
+
+// isStarted checks if the campaign was already started for the activation's generation, as the
+// controller's own status update triggers a reconcile before the stage manager reports progress
+func isStarted(activation *workflowv1.Activation) bool {
+	condition := meta.FindStatusCondition(activation.Status.Conditions, k8smodel.ConditionReconciling)
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == k8smodel.ReasonRunning &&
+		condition.ObservedGeneration == activation.Generation
+}
+
+// activationState maps the status reported by the stage manager to a condition state
+func activationState(activation *workflowv1.Activation) (utils.ConditionState, string, string) {
+	status := activation.Status
+	switch {
+	case status.Status == v1alpha2.Done:
+		return utils.StateReady, k8smodel.ReasonSucceeded, fmt.Sprintf("Campaign %s is done", activation.Spec.Campaign)
+	case status.Status == v1alpha2.Paused:
+		return utils.StateReconciling, k8smodel.ReasonPaused, fmt.Sprintf("Paused before stage %s", status.NextStage)
+	case status.Status >= v1alpha2.BadRequest && status.Status < v1alpha2.Running:
+		return utils.StateStalled, k8smodel.ReasonFailed, fmt.Sprintf("Failed at stage %s: %s", status.Stage, status.ErrorMessage)
+	case status.Stage != "":
+		return utils.StateReconciling, k8smodel.ReasonRunning, fmt.Sprintf("Running stage %s", status.Stage)
+	default:
+		return utils.StateReconciling, k8smodel.ReasonRunning, fmt.Sprintf("Started campaign %s", activation.Spec.Campaign)
+	}
 }
 
 func convertRawExtensionToMap(raw *runtime.RawExtension) map[string]interface{} {
@@ -9,18 +9,23 @@ package workflow
 import (
 	"context"
 
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	workflowv1 "gopls-workspace/apis/workflow/v1"
+	"gopls-workspace/utils"
 )
 
 // CampaignReconciler reconciles a Campaign object
 type CampaignReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=workflow.symphony,resources=campaigns,verbs=get;list;watch;create;update;patch;delete
@@ -39,9 +44,16 @@ type CampaignReconciler struct {
 func (r *CampaignReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
-	// TODO(user): your logic here
+	campaign := &workflowv1.Campaign{}
+	if err := r.Get(ctx, req.NamespacedName, campaign); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !campaign.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, utils.UpdateConditions(ctx, r.Client, r.Recorder, campaign, &campaign.Status.Conditions, &campaign.Status.ObservedGeneration,
+		utils.StateReady, k8smodel.ReasonAccepted, "Campaign is available to activations")
 }
 
 // SetupWithManager sets up the controller with the Manager.
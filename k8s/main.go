@@ -21,6 +21,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
@@ -30,7 +31,10 @@ import (
 	federationv1 "gopls-workspace/apis/federation/v1"
 	solutionv1 "gopls-workspace/apis/solution/v1"
 	workflowv1 "gopls-workspace/apis/workflow/v1"
+	"gopls-workspace/configutils"
 	"gopls-workspace/constants"
+	"gopls-workspace/migration"
+	"gopls-workspace/utils"
 
 	aicontrollers "gopls-workspace/controllers/ai"
 	fabriccontrollers "gopls-workspace/controllers/fabric"
@@ -61,6 +65,8 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var configFile string
+	var migrateLegacy bool
+	var migrationOptions migration.Options
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -69,6 +75,14 @@ func main() {
 	flag.StringVar(&configFile, "config", "", "The controller will laod its initial configuration from this file. "+
 		"Omit this flag to use the default configuration value. "+
 		"Command-line flags override configuration from this file.")
+	flag.BoolVar(&migrateLegacy, "migrate-legacy", false,
+		"Convert the symphony.microsoft.com/v1 solutions, targets and instances to the current API groups, then exit.")
+	flag.StringVar(&migrationOptions.Namespace, "migrate-legacy-namespace", "",
+		"The namespace to convert legacy objects in. Omit this flag to convert them in all namespaces.")
+	flag.BoolVar(&migrationOptions.DryRun, "migrate-legacy-dry-run", false,
+		"Validate the converted objects without creating them.")
+	flag.BoolVar(&migrationOptions.DeleteOriginals, "migrate-legacy-delete", false,
+		"Delete the legacy objects once they have been converted.")
 
 	opts := zap.Options{
 		Development: true,
@@ -80,6 +94,15 @@ func main() {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 	fmt.Println(constants.EulaMessage)
 	fmt.Println()
+
+	if migrateLegacy {
+		if err := migration.Run(ctrl.SetupSignalHandler(), ctrl.GetConfigOrDie(), migrationOptions, ctrl.Log.WithName("migration")); err != nil {
+			setupLog.Error(err, "unable to migrate legacy objects")
+			os.Exit(1)
+		}
+		return
+	}
+
 	var err error
 	ctrlConfig := configv1.ProjectConfig{}
 	options := ctrl.Options{
@@ -104,44 +127,85 @@ func main() {
 		os.Exit(1)
 	}
 
+	namespace, err := configutils.GetNamespace()
+	if err != nil {
+		namespace = "default"
+	}
+	// credentials are read with the API reader, as the manager's cache doesn't watch Secrets
+	apiClient, err := utils.NewSymphonyAPIClient(ctrlConfig.SymphonyAPI, mgr.GetAPIReader(), namespace)
+	if err != nil {
+		setupLog.Error(err, "unable to create Symphony API client")
+		os.Exit(1)
+	}
+	// deployment summaries pushed by the API update instance and target status without polling
+	var instanceSummaries, targetSummaries <-chan event.GenericEvent
+	if utils.WatchSummaries(ctrlConfig.SymphonyAPI) {
+		summaryWatcher := utils.NewSummaryWatcher(apiClient)
+		if err := mgr.Add(summaryWatcher); err != nil {
+			setupLog.Error(err, "unable to add summary watcher")
+			os.Exit(1)
+		}
+		instanceSummaries = summaryWatcher.Instances()
+		targetSummaries = summaryWatcher.Targets()
+	}
+
 	if err = (&solutioncontrollers.SolutionReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("solution-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Solution")
 		os.Exit(1)
 	}
 	if err = (&workflowcontrollers.CampaignReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("campaign-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Campaign")
 		os.Exit(1)
 	}
 	if err = (&workflowcontrollers.ActivationReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ApiClient: apiClient,
+		Recorder:  mgr.GetEventRecorderFor("activation-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Activation")
 		os.Exit(1)
 	}
 	if err = (&solutioncontrollers.InstanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ApiClient:              apiClient,
+		ReconciliationInterval: utils.ReconcileInterval(ctrlConfig.SymphonyAPI),
+		ReconciliationJitter:   utils.ReconcileJitter(ctrlConfig.SymphonyAPI),
+		DeletionPollInterval:   utils.DeletionPollInterval(ctrlConfig.SymphonyAPI),
+		DeletionTimeout:        utils.DeletionTimeout(ctrlConfig.SymphonyAPI),
+		Recorder:               mgr.GetEventRecorderFor("instance-controller"),
+		Summaries:              instanceSummaries,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Instance")
 		os.Exit(1)
 	}
 	if err = (&fabriccontrollers.TargetReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ApiClient:              apiClient,
+		ReconciliationInterval: utils.ReconcileInterval(ctrlConfig.SymphonyAPI),
+		ReconciliationJitter:   utils.ReconcileJitter(ctrlConfig.SymphonyAPI),
+		DeletionPollInterval:   utils.DeletionPollInterval(ctrlConfig.SymphonyAPI),
+		DeletionTimeout:        utils.DeletionTimeout(ctrlConfig.SymphonyAPI),
+		Recorder:               mgr.GetEventRecorderFor("target-controller"),
+		Summaries:              targetSummaries,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Target")
 		os.Exit(1)
 	}
 	if err = (&fabriccontrollers.DeviceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("device-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Device")
 		os.Exit(1)
@@ -168,15 +232,18 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&federationcontrollers.SiteReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("site-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Site")
 		os.Exit(1)
 	}
 	if err = (&federationcontrollers.CatalogReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ApiClient: apiClient,
+		Recorder:  mgr.GetEventRecorderFor("catalog-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Catalog")
 		os.Exit(1)
@@ -209,6 +276,14 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "Catalog")
 		os.Exit(1)
 	}
+	if err = (&workflowv1.Campaign{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Campaign")
+		os.Exit(1)
+	}
+	if err = (&workflowv1.Activation{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Activation")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
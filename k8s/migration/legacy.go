@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// Package migration converts objects of the legacy symphony.microsoft.com/v1 group to the current
+// solution.symphony and fabric.symphony groups
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	fabricv1 "gopls-workspace/apis/fabric/v1"
+	solutionv1 "gopls-workspace/apis/solution/v1"
+	legacyv1 "gopls-workspace/apis/symphony.microsoft.com/v1"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// LegacyGenerationAnnotation records the generation of the legacy object a converted object was created from
+	LegacyGenerationAnnotation = "migration.symphony/legacy-generation"
+	// LegacySourceAnnotation records the UID of the legacy object a converted object was created from
+	LegacySourceAnnotation = "migration.symphony/legacy-source"
+	// lastAppliedAnnotation holds the legacy manifest, which kubectl apply must not compare the converted object with
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// Actions reported for each legacy object
+const (
+	ActionMigrated       = "Migrated"
+	ActionValidated      = "Validated"
+	ActionAlreadyExists  = "AlreadyExists"
+	ActionConflict       = "Conflict"
+	ActionFailed         = "Failed"
+	ActionDeleted        = "Deleted"
+	ActionDeletionFailed = "DeletionFailed"
+)
+
+// Options control a migration
+type Options struct {
+	// Namespace limits the migration to one namespace. All namespaces are migrated when it's empty.
+	Namespace string
+	// DryRun validates the converted objects through the admission webhooks without creating them
+	DryRun bool
+	// DeleteOriginals deletes the legacy objects once they have been converted
+	DeleteOriginals bool
+}
+
+// Result is the outcome of migrating a legacy object
+type Result struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Action    string
+	Message   string
+}
+
+// Migrator converts legacy solutions, targets and instances. Converted objects are created through the
+// API server, so that the admission webhooks default and validate them.
+type Migrator struct {
+	Client  client.Client
+	Options Options
+}
+
+// NewScheme creates a scheme with the legacy and the current groups
+func NewScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(legacyv1.AddToScheme(scheme))
+	utilruntime.Must(solutionv1.AddToScheme(scheme))
+	utilruntime.Must(fabricv1.AddToScheme(scheme))
+	return scheme
+}
+
+// Run migrates the legacy objects of the cluster at config and logs the results. It returns an error
+// if any object failed to migrate.
+func Run(ctx context.Context, config *rest.Config, options Options, log logr.Logger) error {
+	c, err := client.New(config, client.Options{Scheme: NewScheme()})
+	if err != nil {
+		return err
+	}
+	migrator := &Migrator{Client: c, Options: options}
+	results, err := migrator.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	failures := 0
+	for _, result := range results {
+		if result.Action == ActionFailed || result.Action == ActionDeletionFailed {
+			failures++
+			log.Error(errors.New(result.Message), "legacy object not migrated", "kind", result.Kind, "namespace", result.Namespace, "name", result.Name, "action", result.Action)
+		} else if result.Action == ActionConflict {
+			log.Error(errors.New(result.Message), "legacy object kept", "kind", result.Kind, "namespace", result.Namespace, "name", result.Name, "action", result.Action)
+		} else {
+			log.Info("legacy object migrated", "kind", result.Kind, "namespace", result.Namespace, "name", result.Name, "action", result.Action)
+		}
+	}
+	log.Info("legacy migration finished", "objects", len(results), "failures", failures)
+	if failures > 0 {
+		return fmt.Errorf("%d legacy objects failed to migrate", failures)
+	}
+	return nil
+}
+
+// Migrate converts solutions and targets before the instances that reference them. When originals are
+// deleted, only those that were converted, or already had an object converted from them, are deleted.
+func (m *Migrator) Migrate(ctx context.Context) ([]Result, error) {
+	var solutions legacyv1.SolutionList
+	var targets legacyv1.TargetList
+	var instances legacyv1.InstanceList
+	for _, list := range []client.ObjectList{&solutions, &targets, &instances} {
+		if err := m.list(ctx, list); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]Result, 0)
+	originals := make([]client.Object, 0)
+	migrate := func(original client.Object, converted client.Object) {
+		result := m.create(ctx, converted)
+		results = append(results, result)
+		if result.Action == ActionMigrated || result.Action == ActionAlreadyExists {
+			originals = append(originals, original)
+		}
+	}
+	for i := range solutions.Items {
+		migrate(&solutions.Items[i], ConvertSolution(&solutions.Items[i]))
+	}
+	for i := range targets.Items {
+		migrate(&targets.Items[i], ConvertTarget(&targets.Items[i]))
+	}
+	for i := range instances.Items {
+		migrate(&instances.Items[i], ConvertInstance(&instances.Items[i]))
+	}
+
+	if m.Options.DeleteOriginals && !m.Options.DryRun {
+		for _, original := range originals {
+			results = append(results, m.delete(ctx, original))
+		}
+	}
+	return results, nil
+}
+
+// list lists legacy objects. A cluster without the legacy CRDs has nothing to migrate.
+func (m *Migrator) list(ctx context.Context, list client.ObjectList) error {
+	options := []client.ListOption{}
+	if m.Options.Namespace != "" {
+		options = append(options, client.InNamespace(m.Options.Namespace))
+	}
+	err := m.Client.List(ctx, list, options...)
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (m *Migrator) create(ctx context.Context, object client.Object) Result {
+	result := newResult(m.Client, object)
+	existing := object.DeepCopyObject().(client.Object)
+	err := m.Client.Get(ctx, client.ObjectKeyFromObject(object), existing)
+	if err == nil {
+		return existingResult(result, object, existing)
+	}
+	if !apierrors.IsNotFound(err) {
+		result.Action = ActionFailed
+		result.Message = err.Error()
+		return result
+	}
+	// a dry run goes through the admission webhooks without persisting the object
+	if err := m.Client.Create(ctx, object.DeepCopyObject().(client.Object), client.DryRunAll); err != nil {
+		result.Action = ActionFailed
+		result.Message = fmt.Sprintf("validation failed: %s", err.Error())
+		return result
+	}
+	if m.Options.DryRun {
+		result.Action = ActionValidated
+		return result
+	}
+	if err := m.Client.Create(ctx, object); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			if err := m.Client.Get(ctx, client.ObjectKeyFromObject(object), existing); err == nil {
+				return existingResult(result, object, existing)
+			}
+		}
+		result.Action = ActionFailed
+		result.Message = err.Error()
+		return result
+	}
+	result.Action = ActionMigrated
+	return result
+}
+
+// existingResult reports an object that already exists in the new group. Only an object converted from
+// the same legacy object counts as migrated: any other object is a conflict, and its legacy object,
+// along with the finalizers that clean up its components, is kept.
+func existingResult(result Result, converted client.Object, existing client.Object) Result {
+	source := converted.GetAnnotations()[LegacySourceAnnotation]
+	if source != "" && existing.GetAnnotations()[LegacySourceAnnotation] == source {
+		result.Action = ActionAlreadyExists
+		return result
+	}
+	result.Action = ActionConflict
+	result.Message = "an object that wasn't converted from the legacy object already exists"
+	return result
+}
+
+// delete deletes a legacy object. Its finalizers are removed first: no controller handles the legacy
+// group anymore, and a controller that did would remove the components the converted object now owns.
+func (m *Migrator) delete(ctx context.Context, object client.Object) Result {
+	result := newResult(m.Client, object)
+	result.Action = ActionDeleted
+	if len(object.GetFinalizers()) > 0 {
+		patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+		object.SetFinalizers(nil)
+		if err := m.Client.Patch(ctx, object, patch); err != nil && !apierrors.IsNotFound(err) {
+			result.Action = ActionDeletionFailed
+			result.Message = err.Error()
+			return result
+		}
+	}
+	if err := m.Client.Delete(ctx, object); err != nil && !apierrors.IsNotFound(err) {
+		result.Action = ActionDeletionFailed
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func newResult(c client.Client, object client.Object) Result {
+	kind := object.GetObjectKind().GroupVersionKind().Kind
+	if gvk, err := apiutil.GVKForObject(object, c.Scheme()); err == nil {
+		kind = gvk.GroupKind().String()
+	}
+	return Result{
+		Kind:      kind,
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+	}
+}
+
+// ConvertSolution converts a legacy solution to a solution.symphony/v1 solution
+func ConvertSolution(legacy *legacyv1.Solution) *solutionv1.Solution {
+	return &solutionv1.Solution{
+		ObjectMeta: convertObjectMeta(legacy.ObjectMeta),
+		Spec:       *legacy.Spec.DeepCopy(),
+	}
+}
+
+// ConvertInstance converts a legacy instance to a solution.symphony/v1 instance
+func ConvertInstance(legacy *legacyv1.Instance) *solutionv1.Instance {
+	return &solutionv1.Instance{
+		ObjectMeta: convertObjectMeta(legacy.ObjectMeta),
+		Spec:       *legacy.Spec.DeepCopy(),
+	}
+}
+
+// ConvertTarget converts a legacy target to a fabric.symphony/v1 target
+func ConvertTarget(legacy *legacyv1.Target) *fabricv1.Target {
+	return &fabricv1.Target{
+		ObjectMeta: convertObjectMeta(legacy.ObjectMeta),
+		Spec:       *legacy.Spec.DeepCopy(),
+	}
+}
+
+// convertObjectMeta keeps the name, namespace, labels and annotations of a legacy object, and records
+// its UID, and its generation, as a new object starts at generation 1
+func convertObjectMeta(legacy metav1.ObjectMeta) metav1.ObjectMeta {
+	ret := metav1.ObjectMeta{
+		Name:        legacy.Name,
+		Namespace:   legacy.Namespace,
+		Labels:      make(map[string]string),
+		Annotations: make(map[string]string),
+	}
+	for k, v := range legacy.Labels {
+		ret.Labels[k] = v
+	}
+	for k, v := range legacy.Annotations {
+		if k != lastAppliedAnnotation {
+			ret.Annotations[k] = v
+		}
+	}
+	ret.Annotations[LegacyGenerationAnnotation] = strconv.FormatInt(legacy.Generation, 10)
+	ret.Annotations[LegacySourceAnnotation] = string(legacy.UID)
+	return ret
+}
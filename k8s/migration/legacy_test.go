@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	fabricv1 "gopls-workspace/apis/fabric/v1"
+	solutionv1 "gopls-workspace/apis/solution/v1"
+	legacyv1 "gopls-workspace/apis/symphony.microsoft.com/v1"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func legacyObjects() []client.Object {
+	return []client.Object{
+		&legacyv1.Solution{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "solution1",
+				Namespace:  "default",
+				UID:        "solution1-uid",
+				Generation: 4,
+				Labels:     map[string]string{"app": "demo"},
+				Annotations: map[string]string{
+					"owner":               "team1",
+					lastAppliedAnnotation: "{}",
+				},
+			},
+			Spec: k8smodel.SolutionSpec{
+				Components: []k8smodel.ComponentSpec{{Name: "component1", Type: "container"}},
+			},
+		},
+		&legacyv1.Target{
+			ObjectMeta: metav1.ObjectMeta{Name: "target1", Namespace: "default", UID: "target1-uid", Generation: 2},
+			Spec:       k8smodel.TargetSpec{Properties: map[string]string{"group": "edge"}},
+		},
+		&legacyv1.Instance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "instance1",
+				Namespace:  "default",
+				UID:        "instance1-uid",
+				Generation: 1,
+				Finalizers: []string{"instance.symphony.microsoft.com/finalizer"},
+			},
+			Spec: model.InstanceSpec{Solution: "solution1", Target: model.TargetSelector{Name: "target1"}},
+		},
+	}
+}
+
+func TestConvertObjectMeta(t *testing.T) {
+	solution := ConvertSolution(legacyObjects()[0].(*legacyv1.Solution))
+	assert.Equal(t, "solution1", solution.Name)
+	assert.Equal(t, "default", solution.Namespace)
+	assert.Equal(t, map[string]string{"app": "demo"}, solution.Labels)
+	assert.Equal(t, map[string]string{"owner": "team1", LegacyGenerationAnnotation: "4", LegacySourceAnnotation: "solution1-uid"}, solution.Annotations)
+	assert.Equal(t, "component1", solution.Spec.Components[0].Name)
+}
+
+func TestMigrate(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(legacyObjects()...).Build()
+	migrator := &Migrator{Client: c}
+	results, err := migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	for _, result := range results {
+		assert.Equal(t, ActionMigrated, result.Action)
+	}
+	assert.Equal(t, "Solution.solution.symphony", results[0].Kind)
+	assert.Equal(t, "Target.fabric.symphony", results[1].Kind)
+	assert.Equal(t, "Instance.solution.symphony", results[2].Kind)
+
+	instance := &solutionv1.Instance{}
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "instance1", Namespace: "default"}, instance))
+	assert.Equal(t, "solution1", instance.Spec.Solution)
+	assert.Equal(t, "target1", instance.Spec.Target.Name)
+	assert.Empty(t, instance.Finalizers)
+	target := &fabricv1.Target{}
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "target1", Namespace: "default"}, target))
+	assert.Equal(t, "2", target.Annotations[LegacyGenerationAnnotation])
+
+	// a second run finds the converted objects and leaves them alone
+	results, err = migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	for _, result := range results {
+		assert.Equal(t, ActionAlreadyExists, result.Action)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(legacyObjects()...).Build()
+	migrator := &Migrator{Client: c, Options: Options{DryRun: true, DeleteOriginals: true}}
+	results, err := migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	for _, result := range results {
+		assert.Equal(t, ActionValidated, result.Action)
+	}
+
+	err = c.Get(context.Background(), types.NamespacedName{Name: "solution1", Namespace: "default"}, &solutionv1.Solution{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "solution1", Namespace: "default"}, &legacyv1.Solution{}))
+}
+
+func TestMigrateDeleteOriginals(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(legacyObjects()...).Build()
+	migrator := &Migrator{Client: c, Options: Options{DeleteOriginals: true}}
+	results, err := migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(results))
+	for _, result := range results[3:] {
+		assert.Equal(t, ActionDeleted, result.Action)
+	}
+
+	err = c.Get(context.Background(), types.NamespacedName{Name: "instance1", Namespace: "default"}, &legacyv1.Instance{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "instance1", Namespace: "default"}, &solutionv1.Instance{}))
+}
+
+func TestMigrateDeleteOriginalsConflict(t *testing.T) {
+	// an instance that wasn't converted from the legacy instance already exists
+	objects := append(legacyObjects(), &solutionv1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance1", Namespace: "default"},
+		Spec:       model.InstanceSpec{Solution: "solution2"},
+	})
+	c := fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(objects...).Build()
+	migrator := &Migrator{Client: c, Options: Options{DeleteOriginals: true}}
+	results, err := migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(results))
+	assert.Equal(t, ActionConflict, results[2].Action)
+	assert.Equal(t, "instance1", results[2].Name)
+	for _, result := range results[3:] {
+		assert.Equal(t, ActionDeleted, result.Action)
+		assert.NotEqual(t, "instance1", result.Name)
+	}
+
+	legacy := &legacyv1.Instance{}
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "instance1", Namespace: "default"}, legacy))
+	assert.Equal(t, []string{"instance.symphony.microsoft.com/finalizer"}, legacy.Finalizers)
+	instance := &solutionv1.Instance{}
+	assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "instance1", Namespace: "default"}, instance))
+	assert.Equal(t, "solution2", instance.Spec.Solution)
+}
+
+func TestMigrateNamespace(t *testing.T) {
+	objects := append(legacyObjects(), &legacyv1.Target{
+		ObjectMeta: metav1.ObjectMeta{Name: "target2", Namespace: "other"},
+	})
+	c := fake.NewClientBuilder().WithScheme(NewScheme()).WithObjects(objects...).Build()
+	migrator := &Migrator{Client: c, Options: Options{Namespace: "other"}}
+	results, err := migrator.Migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "target2", results[0].Name)
+	assert.Equal(t, ActionMigrated, results[0].Action)
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// ConditionState is the overall state reported through an object's conditions
+type ConditionState string
+
+const (
+	// StateReady sets Ready to True
+	StateReady ConditionState = "Ready"
+	// StateReconciling sets Reconciling to True, while work is in progress
+	StateReconciling ConditionState = "Reconciling"
+	// StateDegraded sets Degraded to True, when the last attempt failed and will be retried
+	StateDegraded ConditionState = "Degraded"
+	// StateStalled sets Stalled to True, when no progress can be made without a spec change
+	StateStalled ConditionState = "Stalled"
+)
+
+// Event reasons recorded by controllers
+const (
+	EventReconcileStarted   = "ReconcileStarted"
+	EventReconcileSucceeded = "ReconcileSucceeded"
+	EventReconcileFailed    = "ReconcileFailed"
+)
+
+// SetConditions sets the Ready, Reconciling, Degraded and Stalled conditions for a state. Ready carries
+// the reason and message; the other conditions carry them only when True. It returns whether the status
+// of any condition changed, so that events are only recorded on transitions.
+func SetConditions(conditions *[]metav1.Condition, generation int64, state ConditionState, reason string, message string) bool {
+	changed := false
+	set := func(conditionType string, isTrue bool) {
+		condition := metav1.Condition{
+			Type:               conditionType,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             reason,
+		}
+		if isTrue {
+			condition.Status = metav1.ConditionTrue
+			condition.Message = message
+		}
+		if conditionType == k8smodel.ConditionReady {
+			condition.Message = message
+		}
+		if existing := meta.FindStatusCondition(*conditions, conditionType); existing == nil || existing.Status != condition.Status {
+			changed = true
+		}
+		meta.SetStatusCondition(conditions, condition)
+	}
+	set(k8smodel.ConditionReady, state == StateReady)
+	set(k8smodel.ConditionReconciling, state == StateReconciling)
+	set(k8smodel.ConditionDegraded, state == StateDegraded)
+	set(k8smodel.ConditionStalled, state == StateStalled)
+	return changed
+}
+
+// UpdateConditions sets an object's conditions and observed generation, records an event when the
+// conditions changed, and updates the object's status only when something changed, so that status
+// updates don't trigger further reconciles
+func UpdateConditions(ctx context.Context, c client.Client, recorder record.EventRecorder, object client.Object, conditions *[]metav1.Condition, observedGeneration *int64, state ConditionState, reason string, message string) error {
+	previous := make([]metav1.Condition, len(*conditions))
+	copy(previous, *conditions)
+	generationChanged := *observedGeneration != object.GetGeneration()
+	*observedGeneration = object.GetGeneration()
+	if SetConditions(conditions, object.GetGeneration(), state, reason, message) {
+		RecordStateEvent(recorder, object, state, message)
+	}
+	if !generationChanged && reflect.DeepEqual(previous, *conditions) {
+		return nil
+	}
+	return c.Status().Update(ctx, object)
+}
+
+// IsReady checks if the Ready condition is True for the given generation
+func IsReady(conditions []metav1.Condition, generation int64) bool {
+	condition := meta.FindStatusCondition(conditions, k8smodel.ConditionReady)
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.ObservedGeneration == generation
+}
+
+// RecordStateEvent records the event for a state: ReconcileStarted for Reconciling, ReconcileSucceeded
+// for Ready and a ReconcileFailed warning for Degraded and Stalled. A nil recorder records nothing.
+func RecordStateEvent(recorder record.EventRecorder, object runtime.Object, state ConditionState, message string) {
+	if recorder == nil {
+		return
+	}
+	switch state {
+	case StateReady:
+		recorder.Event(object, corev1.EventTypeNormal, EventReconcileSucceeded, message)
+	case StateReconciling:
+		recorder.Event(object, corev1.EventTypeNormal, EventReconcileStarted, message)
+	default:
+		recorder.Event(object, corev1.EventTypeWarning, EventReconcileFailed, message)
+	}
+}
+
+// DeploymentStatusFromSummary mirrors a deployment summary into a structured status, with targets and
+// components sorted by name
+func DeploymentStatusFromSummary(generation int64, summary model.SummarySpec) k8smodel.DeploymentStatus {
+	ret := k8smodel.DeploymentStatus{
+		Generation:   generation,
+		TargetCount:  summary.TargetCount,
+		SuccessCount: summary.SuccessCount,
+		Message:      summary.SummaryMessage,
+	}
+	for name, target := range summary.TargetResults {
+		targetStatus := k8smodel.TargetDeploymentStatus{
+			Name:    name,
+			Status:  target.Status,
+			Message: target.Message,
+		}
+		for componentName, component := range target.ComponentResults {
+			targetStatus.Components = append(targetStatus.Components, k8smodel.ComponentDeploymentStatus{
+				Name:    componentName,
+				Status:  component.Status.String(),
+				Message: component.Message,
+			})
+		}
+		sort.Slice(targetStatus.Components, func(i, j int) bool {
+			return targetStatus.Components[i].Name < targetStatus.Components[j].Name
+		})
+		ret.Targets = append(ret.Targets, targetStatus)
+	}
+	sort.Slice(ret.Targets, func(i, j int) bool {
+		return ret.Targets[i].Name < ret.Targets[j].Name
+	})
+	return ret
+}
+
+// ErrorState is the state for a failed Symphony API call: Stalled when the API rejected the object as
+// invalid, as retrying can't succeed until the spec changes, and Reconciling otherwise
+func ErrorState(err error) (ConditionState, string) {
+	if coaE, ok := err.(v1alpha2.COAError); ok && coaE.State == v1alpha2.BadRequest {
+		return StateStalled, k8smodel.ReasonInvalidSpec
+	}
+	return StateReconciling, k8smodel.ReasonAPIError
+}
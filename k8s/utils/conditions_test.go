@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+
+	apimodel "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSetConditionsReady(t *testing.T) {
+	conditions := []metav1.Condition{}
+	changed := SetConditions(&conditions, 3, StateReady, k8smodel.ReasonSucceeded, "deployed")
+	assert.True(t, changed)
+	assert.Equal(t, 4, len(conditions))
+	assert.True(t, meta.IsStatusConditionTrue(conditions, k8smodel.ConditionReady))
+	assert.True(t, meta.IsStatusConditionFalse(conditions, k8smodel.ConditionReconciling))
+	assert.True(t, meta.IsStatusConditionFalse(conditions, k8smodel.ConditionDegraded))
+	assert.True(t, meta.IsStatusConditionFalse(conditions, k8smodel.ConditionStalled))
+	assert.True(t, IsReady(conditions, 3))
+	assert.False(t, IsReady(conditions, 4))
+
+	ready := meta.FindStatusCondition(conditions, k8smodel.ConditionReady)
+	assert.Equal(t, "deployed", ready.Message)
+	assert.Equal(t, int64(3), ready.ObservedGeneration)
+}
+
+func TestSetConditionsTransitions(t *testing.T) {
+	conditions := []metav1.Condition{}
+	SetConditions(&conditions, 1, StateReconciling, k8smodel.ReasonDeploying, "deploying")
+	assert.True(t, meta.IsStatusConditionTrue(conditions, k8smodel.ConditionReconciling))
+	assert.False(t, IsReady(conditions, 1))
+
+	// the same state again is not a transition
+	changed := SetConditions(&conditions, 1, StateReconciling, k8smodel.ReasonDeploying, "still deploying")
+	assert.False(t, changed)
+	assert.Equal(t, "still deploying", meta.FindStatusCondition(conditions, k8smodel.ConditionReconciling).Message)
+
+	changed = SetConditions(&conditions, 1, StateDegraded, k8smodel.ReasonDeploymentFailed, "1 of 2 targets failed")
+	assert.True(t, changed)
+	assert.True(t, meta.IsStatusConditionTrue(conditions, k8smodel.ConditionDegraded))
+	assert.True(t, meta.IsStatusConditionFalse(conditions, k8smodel.ConditionReconciling))
+	assert.Equal(t, "", meta.FindStatusCondition(conditions, k8smodel.ConditionReconciling).Message)
+
+	changed = SetConditions(&conditions, 2, StateStalled, k8smodel.ReasonInvalidSpec, "bad request")
+	assert.True(t, changed)
+	assert.True(t, meta.IsStatusConditionTrue(conditions, k8smodel.ConditionStalled))
+	assert.True(t, meta.IsStatusConditionFalse(conditions, k8smodel.ConditionDegraded))
+}
+
+func TestRecordStateEvent(t *testing.T) {
+	recorder := record.NewFakeRecorder(3)
+	object := &metav1.PartialObjectMetadata{}
+	RecordStateEvent(recorder, object, StateReconciling, "deploying")
+	RecordStateEvent(recorder, object, StateReady, "deployed")
+	RecordStateEvent(recorder, object, StateStalled, "bad request")
+	assert.Equal(t, "Normal ReconcileStarted deploying", <-recorder.Events)
+	assert.Equal(t, "Normal ReconcileSucceeded deployed", <-recorder.Events)
+	assert.Equal(t, "Warning ReconcileFailed bad request", <-recorder.Events)
+
+	// a nil recorder is ignored
+	RecordStateEvent(nil, object, StateReady, "deployed")
+}
+
+func TestDeploymentStatusFromSummary(t *testing.T) {
+	status := DeploymentStatusFromSummary(5, apimodel.SummarySpec{
+		TargetCount:    2,
+		SuccessCount:   1,
+		SummaryMessage: "1 failed",
+		TargetResults: map[string]apimodel.TargetResultSpec{
+			"target2": {
+				Status:  "OK",
+				Message: "",
+				ComponentResults: map[string]apimodel.ComponentResultSpec{
+					"b": {Status: v1alpha2.Updated, Message: "updated"},
+					"a": {Status: v1alpha2.Untouched},
+				},
+			},
+			"target1": {
+				Status:  "Failed",
+				Message: "timeout",
+			},
+		},
+	})
+	assert.Equal(t, int64(5), status.Generation)
+	assert.Equal(t, 2, status.TargetCount)
+	assert.Equal(t, 1, status.SuccessCount)
+	assert.Equal(t, "1 failed", status.Message)
+	assert.Equal(t, "target1", status.Targets[0].Name)
+	assert.Equal(t, "timeout", status.Targets[0].Message)
+	assert.Equal(t, "target2", status.Targets[1].Name)
+	assert.Equal(t, "a", status.Targets[1].Components[0].Name)
+	assert.Equal(t, v1alpha2.Untouched.String(), status.Targets[1].Components[0].Status)
+	assert.Equal(t, "b", status.Targets[1].Components[1].Name)
+	assert.Equal(t, "updated", status.Targets[1].Components[1].Message)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const (
+	// targetDeploymentPrefix prefixes the names of the deployments the API creates for targets
+	targetDeploymentPrefix = "target-runtime-"
+	summaryWatchMinRetry   = 5 * time.Second
+	summaryWatchMaxRetry   = 60 * time.Second
+)
+
+var summaryLog = ctrl.Log.WithName("summary-watcher")
+
+// SummaryWatcher streams deployment summary events from the Symphony API and sends a generic event for
+// the instance or target each summary is about, so that controllers update its status as soon as the
+// deployment finishes instead of at the next reconcile interval. It's added to the manager as a
+// runnable, and reconnects with a backoff when the stream fails.
+type SummaryWatcher struct {
+	ApiClient *api_utils.SymphonyAPIClient
+	instances chan event.GenericEvent
+	targets   chan event.GenericEvent
+}
+
+// NewSummaryWatcher creates a summary watcher that uses apiClient
+func NewSummaryWatcher(apiClient *api_utils.SymphonyAPIClient) *SummaryWatcher {
+	return &SummaryWatcher{
+		ApiClient: apiClient,
+		instances: make(chan event.GenericEvent),
+		targets:   make(chan event.GenericEvent),
+	}
+}
+
+// Instances are events for instances with a new deployment summary
+func (w *SummaryWatcher) Instances() <-chan event.GenericEvent {
+	return w.instances
+}
+
+// Targets are events for targets with a new deployment summary
+func (w *SummaryWatcher) Targets() <-chan event.GenericEvent {
+	return w.targets
+}
+
+// Start watches summary events until ctx is cancelled
+func (w *SummaryWatcher) Start(ctx context.Context) error {
+	retry := summaryWatchMinRetry
+	for {
+		received := false
+		err := w.ApiClient.WatchEvents(ctx, map[string]string{"topic": "summary"}, func(e model.WatchEvent) {
+			received = true
+			w.dispatch(ctx, e)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			retry = summaryWatchMinRetry
+		}
+		summaryLog.Error(err, "summary event stream failed, reconnecting", "after", retry.String())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > summaryWatchMaxRetry {
+			retry = summaryWatchMaxRetry
+		}
+	}
+}
+
+func (w *SummaryWatcher) dispatch(ctx context.Context, e model.WatchEvent) {
+	if e.Name == "" {
+		return
+	}
+	namespace := e.Scope
+	if namespace == "" {
+		namespace = "default"
+	}
+	destination := w.instances
+	name := e.Name
+	if strings.HasPrefix(name, targetDeploymentPrefix) {
+		destination = w.targets
+		name = strings.TrimPrefix(name, targetDeploymentPrefix)
+	}
+	object := &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	select {
+	case destination <- event.GenericEvent{Object: object}:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryWatcherDispatchesEvents(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1alpha2/users/auth", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"accessToken":"token","tokenType":"Bearer"}`))
+	})
+	mux.HandleFunc("/v1alpha2/events", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "summary", r.URL.Query().Get("topic"))
+		events := []model.WatchEvent{
+			{Id: 1, Topic: "summary", Kind: "instance", Name: "instance1", Scope: "ns1"},
+			{Id: 2, Topic: "summary", Kind: "instance", Name: "target-runtime-target1"},
+		}
+		for _, e := range events {
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "id: %d\nevent: summary\ndata: %s\n\n", e.Id, data)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	watcher := NewSummaryWatcher(api_utils.NewSymphonyAPIClient(server.URL+"/v1alpha2/", func(context.Context) (string, string, error) {
+		return "admin", "", nil
+	}, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Start(ctx)
+	}()
+
+	select {
+	case e := <-watcher.Instances():
+		assert.Equal(t, "instance1", e.Object.GetName())
+		assert.Equal(t, "ns1", e.Object.GetNamespace())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no instance event")
+	}
+	select {
+	case e := <-watcher.Targets():
+		assert.Equal(t, "target1", e.Object.GetName())
+		assert.Equal(t, "default", e.Object.GetNamespace())
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "no target event")
+	}
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestDriftRequeueAfter(t *testing.T) {
+	interval := time.Minute
+	assert.Equal(t, time.Duration(0), DriftRequeueAfter(time.Now().Add(-2*interval), interval, 0))
+
+	after := DriftRequeueAfter(time.Now(), interval, 0.1)
+	assert.True(t, after > 59*time.Second)
+	assert.True(t, after <= 66*time.Second)
+
+	after = DriftRequeueAfter(time.Now().Add(-30*time.Second), interval, 0)
+	assert.True(t, after > 29*time.Second)
+	assert.True(t, after <= 30*time.Second)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	configv1 "gopls-workspace/apis/config/v1"
+
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+const (
+	defaultReconcileInterval    = 60 * time.Second
+	defaultDeletionPollInterval = 10 * time.Second
+	defaultDeletionTimeout      = 5 * time.Minute
+	defaultReconcileJitter      = 0.1
+)
+
+// NewSymphonyAPIClient creates the Symphony API client shared by controllers. Credentials are read
+// from the configured Secret each time a token is issued, with reader, so that they can be rotated
+// without restarting the controller manager. namespace is used when the Secret reference has none.
+func NewSymphonyAPIClient(config configv1.SymphonyAPIConfig, reader client.Reader, namespace string) (*api_utils.SymphonyAPIClient, error) {
+	url := config.Url
+	if url == "" {
+		url = SymphonyAPIAddressBase
+	}
+	tlsConfig, err := symphonyAPITLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	credentials := func(context.Context) (string, string, error) {
+		return "admin", "", nil
+	}
+	if ref := config.CredentialsSecretRef; ref != nil {
+		credentials = secretCredentials(*ref, reader, namespace)
+	}
+	return api_utils.NewSymphonyAPIClient(url, credentials, tlsConfig), nil
+}
+
+// ReconcileInterval is how often instances and targets are re-deployed to correct drift
+func ReconcileInterval(config configv1.SymphonyAPIConfig) time.Duration {
+	return secondsOrDefault(config.ReconcileIntervalSeconds, defaultReconcileInterval)
+}
+
+// ReconcileJitter is the fraction of the reconcile interval by which re-deployments are spread
+func ReconcileJitter(config configv1.SymphonyAPIConfig) float64 {
+	if config.ReconcileJitterPercent == nil {
+		return defaultReconcileJitter
+	}
+	return float64(*config.ReconcileJitterPercent) / 100
+}
+
+// WatchSummaries tells whether deployment summary events are streamed from the Symphony API
+func WatchSummaries(config configv1.SymphonyAPIConfig) bool {
+	return config.WatchSummaries == nil || *config.WatchSummaries
+}
+
+// DriftRequeueAfter is how long to wait before re-deploying an object that was last deployed at
+// lastDeployed, to correct drift. A random delay of up to jitter times the interval is added.
+func DriftRequeueAfter(lastDeployed time.Time, interval time.Duration, jitter float64) time.Duration {
+	remaining := interval - time.Since(lastDeployed)
+	if remaining < 0 {
+		remaining = 0
+	}
+	if jitter > 0 {
+		remaining += time.Duration(rand.Float64() * jitter * float64(interval))
+	}
+	return remaining
+}
+
+// DeletionPollInterval is how often the removal of a deleted instance or target is checked
+func DeletionPollInterval(config configv1.SymphonyAPIConfig) time.Duration {
+	return secondsOrDefault(config.DeletionPollIntervalSeconds, defaultDeletionPollInterval)
+}
+
+// DeletionTimeout is how long to wait for a removal before finalizers are dropped anyway
+func DeletionTimeout(config configv1.SymphonyAPIConfig) time.Duration {
+	return secondsOrDefault(config.DeletionTimeoutSeconds, defaultDeletionTimeout)
+}
+
+func secondsOrDefault(seconds uint, defaultValue time.Duration) time.Duration {
+	if seconds == 0 {
+		return defaultValue
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func secretCredentials(ref configv1.CredentialsSecretReference, reader client.Reader, namespace string) api_utils.CredentialsProvider {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	usernameKey := ref.UsernameKey
+	if usernameKey == "" {
+		usernameKey = "username"
+	}
+	passwordKey := ref.PasswordKey
+	if passwordKey == "" {
+		passwordKey = "password"
+	}
+	return func(ctx context.Context) (string, string, error) {
+		secret := &corev1.Secret{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+			return "", "", fmt.Errorf("failed to read Symphony API credentials from Secret %s/%s: %s", namespace, ref.Name, err.Error())
+		}
+		username, ok := secret.Data[usernameKey]
+		if !ok {
+			return "", "", fmt.Errorf("Secret %s/%s has no '%s' key", namespace, ref.Name, usernameKey)
+		}
+		return string(username), string(secret.Data[passwordKey]), nil
+	}
+}
+
+func symphonyAPITLSConfig(config *configv1.TLSConfig) (*tls.Config, error) {
+	if config == nil {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Symphony API CA certificate: %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in '%s'", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
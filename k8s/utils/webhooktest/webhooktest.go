@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// Package webhooktest runs webhooks against a local API server for tests. The API server needs the envtest
+// binaries; set KUBEBUILDER_ASSETS to their directory, for example with `setup-envtest use -p path`.
+package webhooktest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const readyTimeout = 10 * time.Second
+
+// Environment is a local API server with the Symphony CRDs and webhooks installed, and a manager that serves
+// the webhooks
+type Environment struct {
+	Config  *rest.Config
+	Client  client.Client
+	testEnv *envtest.Environment
+	cancel  context.CancelFunc
+}
+
+// Available tells whether the envtest binaries are set up
+func Available() bool {
+	return os.Getenv("KUBEBUILDER_ASSETS") != ""
+}
+
+// Start starts the API server and a manager, and waits for the webhook server to be ready. addToScheme
+// registers the types of an API group, and setup registers their webhooks with the manager.
+func Start(addToScheme func(*k8sruntime.Scheme) error, setup func(ctrl.Manager) error) (*Environment, error) {
+	_, file, _, _ := runtime.Caller(0)
+	config := filepath.Join(filepath.Dir(file), "..", "..", "config", "oss")
+	env := &Environment{
+		testEnv: &envtest.Environment{
+			CRDDirectoryPaths:     []string{filepath.Join(config, "crd", "bases")},
+			ErrorIfCRDPathMissing: true,
+			WebhookInstallOptions: envtest.WebhookInstallOptions{
+				Paths: []string{filepath.Join(config, "webhook")},
+			},
+		},
+	}
+	var err error
+	env.Config, err = env.testEnv.Start()
+	if err != nil {
+		return nil, err
+	}
+	if err = env.start(addToScheme, setup); err != nil {
+		env.Stop()
+		return nil, err
+	}
+	return env, nil
+}
+
+func (e *Environment) start(addToScheme func(*k8sruntime.Scheme) error, setup func(ctrl.Manager) error) error {
+	scheme := k8sruntime.NewScheme()
+	if err := addToScheme(scheme); err != nil {
+		return err
+	}
+	if err := admissionv1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	var err error
+	e.Client, err = client.New(e.Config, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	webhookInstallOptions := &e.testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(e.Config, ctrl.Options{
+		Scheme:             scheme,
+		Host:               webhookInstallOptions.LocalServingHost,
+		Port:               webhookInstallOptions.LocalServingPort,
+		CertDir:            webhookInstallOptions.LocalServingCertDir,
+		LeaderElection:     false,
+		MetricsBindAddress: "0",
+	})
+	if err != nil {
+		return err
+	}
+	if err = setup(mgr); err != nil {
+		return err
+	}
+	var ctx context.Context
+	ctx, e.cancel = context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- mgr.Start(ctx)
+	}()
+
+	// wait for the webhook server to get ready
+	dialer := &net.Dialer{Timeout: time.Second}
+	addrPort := fmt.Sprintf("%s:%d", webhookInstallOptions.LocalServingHost, webhookInstallOptions.LocalServingPort)
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addrPort, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case err := <-errs:
+			return fmt.Errorf("manager stopped: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("webhook server isn't ready: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Stop stops the manager and the API server
+func (e *Environment) Stop() error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	return e.testEnv.Stop()
+}